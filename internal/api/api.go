@@ -61,6 +61,7 @@ func Init() {
 	}
 
 	if cfg.Mod.Username != "" {
+		username, password = cfg.Mod.Username, cfg.Mod.Password
 		Handler = middlewareAuth(cfg.Mod.Username, cfg.Mod.Password, Handler) // 2nd
 	}
 
@@ -195,11 +196,35 @@ func middlewareLog(next http.Handler) http.Handler {
 	})
 }
 
+// AuthFunc, if set, is asked about requests without valid basic auth, so a
+// module can accept its own credentials such as bearer tokens. The module's
+// handlers still decide what such a request may do.
+var AuthFunc func(r *http.Request) bool
+
+var username, password string
+
+// Authenticated reports whether the request comes from the local host or
+// carries the API username and password (false if none are configured)
+func Authenticated(r *http.Request) bool {
+	if isLocalRequest(r) {
+		return true
+	}
+	if username == "" {
+		return false
+	}
+	user, pass, ok := r.BasicAuth()
+	return ok && user == username && pass == password
+}
+
+func isLocalRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.RemoteAddr, "127.") || strings.HasPrefix(r.RemoteAddr, "[::1]") || r.RemoteAddr == "@"
+}
+
 func middlewareAuth(username, password string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.HasPrefix(r.RemoteAddr, "127.") && !strings.HasPrefix(r.RemoteAddr, "[::1]") && r.RemoteAddr != "@" {
+		if !isLocalRequest(r) {
 			user, pass, ok := r.BasicAuth()
-			if !ok || user != username || pass != password {
+			if (!ok || user != username || pass != password) && (AuthFunc == nil || !AuthFunc(r)) {
 				w.Header().Set("Www-Authenticate", `Basic realm="go2rtc"`)
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
@@ -242,6 +242,7 @@ func (a *Analyzer) analyzeFile(job AnalysisJob) (*DetectionResult, error) {
 	if err := writeSidecar(job.FilePath, result); err != nil {
 		return nil, fmt.Errorf("write sidecar: %w", err)
 	}
+	sidecarChanged(sidecarPath(job.FilePath))
 
 	log.Info().
 		Str("stream", job.StreamName).
@@ -334,28 +335,26 @@ func (a *Analyzer) QueueDepth() int {
 // PruneOldSidecars removes .json sidecar files whose recording no longer exists
 // or is older than retentionDays. Returns count pruned.
 func (a *Analyzer) PruneOldSidecars(retentionDays int) int {
-	basePath := ""
-	// Import would be circular; read base path from config via package-level func
-	basePath = getSidecarBasePath()
-	if basePath == "" {
-		return 0
-	}
+	// Import would be circular; read base paths from config via package-level func
 	cutoff := time.Now().AddDate(0, 0, -retentionDays)
 	pruned := 0
-	_ = filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-		if filepath.Ext(path) != ".json" {
-			return nil
-		}
-		if info.ModTime().Before(cutoff) {
-			if err := os.Remove(path); err == nil {
-				pruned++
+	for _, basePath := range getSidecarBasePaths() {
+		_ = filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
 			}
-		}
-		return nil
-	})
+			if filepath.Ext(path) != ".json" {
+				return nil
+			}
+			if info.ModTime().Before(cutoff) {
+				if err := os.Remove(path); err == nil {
+					sidecarChanged(path)
+					pruned++
+				}
+			}
+			return nil
+		})
+	}
 	return pruned
 }
 
@@ -398,12 +397,21 @@ func containsLabel(filter []string, label string) bool {
 	return false
 }
 
-// getSidecarBasePath returns the recording base path for sidecar pruning.
+// getSidecarBasePaths returns the recording base paths for sidecar pruning.
 // Avoids importing the ffmpeg package (circular import).
-var getSidecarBasePath = func() string { return "" }
+var getSidecarBasePaths = func() []string { return nil }
+
+// SetSidecarBasePaths allows the ffmpeg package to inject the recording base
+// paths (one per storage pool) without creating a circular import.
+func SetSidecarBasePaths(fn func() []string) {
+	getSidecarBasePaths = fn
+}
+
+// sidecarChanged is called after a sidecar was written or pruned
+var sidecarChanged = func(sidecar string) {}
 
-// SetSidecarBasePath allows the ffmpeg package to inject the recording base path
-// without creating a circular import.
-func SetSidecarBasePath(fn func() string) {
-	getSidecarBasePath = fn
+// SetSidecarListener lets the ffmpeg package keep the detection labels in its
+// recording index up to date.
+func SetSidecarListener(fn func(sidecar string)) {
+	sidecarChanged = fn
 }
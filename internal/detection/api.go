@@ -68,29 +68,31 @@ func apiDetectionAnalyze(w http.ResponseWriter, r *http.Request) {
 
 	if stream != "" {
 		// Queue all un-analysed files for a stream
-		basePath := getSidecarBasePath()
-		if basePath == "" {
+		basePaths := getSidecarBasePaths()
+		if len(basePaths) == 0 {
 			http.Error(w, "Recording base path not configured", http.StatusInternalServerError)
 			return
 		}
-		streamDir := filepath.Join(basePath, stream)
 		queued := 0
-		_ = filepath.Walk(streamDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil || info.IsDir() {
+		for _, basePath := range basePaths {
+			streamDir := filepath.Join(basePath, stream)
+			_ = filepath.Walk(streamDir, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return nil
+				}
+				ext := strings.ToLower(filepath.Ext(path))
+				if ext != ".mp4" && ext != ".mkv" && ext != ".avi" {
+					return nil
+				}
+				// Skip if sidecar already exists
+				if _, err := os.Stat(sidecarPath(path)); err == nil {
+					return nil
+				}
+				globalAnalyzer.Enqueue(stream, path)
+				queued++
 				return nil
-			}
-			ext := strings.ToLower(filepath.Ext(path))
-			if ext != ".mp4" && ext != ".mkv" && ext != ".avi" {
-				return nil
-			}
-			// Skip if sidecar already exists
-			if _, err := os.Stat(sidecarPath(path)); err == nil {
-				return nil
-			}
-			globalAnalyzer.Enqueue(stream, path)
-			queued++
-			return nil
-		})
+			})
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status": "queued",
@@ -0,0 +1,36 @@
+package ffmpeg
+
+import (
+	"net/http"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// apiRecordingAlerts lists the firing alerts, POST ?notifier=NAME sends a
+// test alert through a notifier
+func apiRecordingAlerts(w http.ResponseWriter, r *http.Request) {
+	if alerts == nil {
+		http.Error(w, "no alerts or notifiers configured", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		api.ResponseJSON(w, map[string]any{"alerts": alerts.firing()})
+
+	case "POST":
+		name := r.URL.Query().Get("notifier")
+		if _, ok := alerts.notifiers[name]; !ok {
+			http.Error(w, "unknown notifier: "+name, http.StatusNotFound)
+			return
+		}
+		if err := alerts.test(name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		api.ResponseJSON(w, map[string]any{"status": "sent", "notifier": name})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
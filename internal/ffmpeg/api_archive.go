@@ -0,0 +1,205 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// handleListArchivedRecordings lists the files cleanup moved to archive_path.
+// They are not indexed, so the archive is read on every request:
+//
+//	GET /api/recordings?archived=true[&stream=cam1][&date=2025-01-01][&limit=100][&offset=0][&grouped=false]
+func handleListArchivedRecordings(w http.ResponseWriter, r *http.Request, query map[string][]string) {
+	streamName := getQueryParam(query, "stream")
+	dateFilter := getQueryParam(query, "date")
+
+	limit := 100
+	if value := getQueryParam(query, "limit"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if value := getQueryParam(query, "offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid 'offset' parameter", http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+
+	recordings := archivedRecordings(streamName, dateFilter)
+	total := len(recordings)
+
+	page := []RecordingFile{}
+	if offset < total {
+		page = recordings[offset:min(offset+limit, total)]
+	}
+
+	response := map[string]interface{}{
+		"count":         len(page),
+		"total":         total,
+		"offset":        offset,
+		"limit":         limit,
+		"stream_filter": streamName,
+		"date_filter":   dateFilter,
+		"archived":      true,
+	}
+	if next := offset + len(page); next < total {
+		response["next_offset"] = next
+	}
+
+	respondListing(w, r, page, response)
+}
+
+// handleRestoreRecording moves an archived recording back into the active
+// recordings and indexes it again:
+//
+//	POST /api/recordings?restore=ID[&protect=true][&async=true]
+//
+// A restored recording past retention is archived again by the next cleanup,
+// unless it is protected. async moves it in a background job, a copy to
+// another disk takes a while.
+func handleRestoreRecording(w http.ResponseWriter, query map[string][]string) {
+	id := getQueryParam(query, "restore")
+
+	archived := findArchivedRecording(id)
+	if archived == nil {
+		http.Error(w, "Archived recording not found", http.StatusNotFound)
+		return
+	}
+
+	target := filepath.Join(selectStoragePool(archived.StreamName), archived.RelativePath)
+	if _, err := os.Stat(target); err == nil {
+		http.Error(w, "A recording already exists at "+archived.RelativePath, http.StatusConflict)
+		return
+	}
+
+	protect := getQueryParam(query, "protect") == "true"
+
+	if getQueryParam(query, "async") == "true" {
+		job, err := jobs.submit(JobRestore, archived.StreamName, func(ctx context.Context, progress func(float64)) (any, error) {
+			return restoreRecording(archived, target, protect)
+		})
+		respondJob(w, job, err)
+		return
+	}
+
+	recording, err := restoreRecording(archived, target, protect)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.ResponseJSON(w, recording)
+}
+
+// restoreRecording moves an archived recording to target and indexes it
+func restoreRecording(archived *RecordingFile, target string, protect bool) (*RecordingFile, error) {
+	if err := moveRecordingFile(archived.Path, target); err != nil {
+		log.Error().Err(err).Str("path", archived.Path).Str("target", target).Msg("[api] failed to restore recording")
+		return nil, fmt.Errorf("failed to restore recording: %w", err)
+	}
+
+	recordingIndex.Update(target)
+
+	recording := recordingIndex.GetByPath(target)
+	if recording == nil {
+		return nil, errors.New("restored recording could not be indexed")
+	}
+
+	if protect {
+		recording = recordingIndex.setProtected(recording.ID, true)
+	}
+	recordingIndex.Save()
+
+	log.Info().
+		Str("recording_id", recording.ID).
+		Str("stream", recording.StreamName).
+		Str("path", target).
+		Bool("protected", recording.Protected).
+		Msg("[api] recording restored from archive")
+
+	return recording, nil
+}
+
+// archivedRecordings returns the recordings in archive_path, newest first
+func archivedRecordings(streamName, dateFilter string) []RecordingFile {
+	archivePath := GetRecordingConfig().ArchivePath
+	if archivePath == "" {
+		return nil
+	}
+
+	var recordings []RecordingFile
+	_ = filepath.Walk(archivePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !isVideoFile(strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+
+		recording := newArchivedRecordingFile(archivePath, path, info)
+		if recording == nil {
+			return nil
+		}
+		if streamName != "" && recording.StreamName != streamName {
+			return nil
+		}
+		if dateFilter != "" && recording.DateGroup != dateFilter {
+			return nil
+		}
+
+		recordings = append(recordings, *recording)
+		return nil
+	})
+
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].StartTime.After(recordings[j].StartTime)
+	})
+
+	return recordings
+}
+
+// findArchivedRecording returns the archived recording with the given ID
+func findArchivedRecording(id string) *RecordingFile {
+	if id == "" {
+		return nil
+	}
+	for _, recording := range archivedRecordings("", "") {
+		if recording.ID == id {
+			return &recording
+		}
+	}
+	return nil
+}
+
+// newArchivedRecordingFile builds the metadata of an archived file. Its ID
+// matches the ID the recording had before it was archived.
+func newArchivedRecordingFile(archivePath, path string, info os.FileInfo) *RecordingFile {
+	rel, err := filepath.Rel(archivePath, path)
+	if err != nil {
+		return nil
+	}
+
+	recording := buildRecordingFile(recordingIDFromRel(rel), path, rel, info.Size(), info.ModTime())
+	recording.Archived = true
+
+	// Archived files aren't indexed, so they can't be played until restored
+	recording.DownloadURL = ""
+	recording.InfoURL = ""
+	recording.StreamURL = ""
+	recording.ThumbnailURL = ""
+	recording.PreviewURL = ""
+	recording.PreviewVTTURL = ""
+
+	return recording
+}
@@ -0,0 +1,70 @@
+package ffmpeg
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// apiRecordingsAudit queries the audit log of recording accesses, newest
+// first:
+//
+//	GET /api/recordings/audit[?since=...][&until=...][&action=download][&user=...][&stream=cam1][&recording=ID][&limit=100]
+func apiRecordingsAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !auditEnabled() {
+		http.Error(w, "Audit log is disabled, set 'audit_log'", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+
+	filter := AuditFilter{
+		Action:    query.Get("action"),
+		User:      query.Get("user"),
+		Stream:    query.Get("stream"),
+		Recording: query.Get("recording"),
+	}
+
+	var err error
+	if value := query.Get("since"); value != "" {
+		if filter.Since, err = parseTimeParam(value); err != nil {
+			http.Error(w, "Invalid 'since' parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("until"); value != "" {
+		if filter.Until, err = parseTimeParam(value); err != nil {
+			http.Error(w, "Invalid 'until' parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			http.Error(w, "Invalid 'limit' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := readAuditLog(filter, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("[audit] failed to read audit log")
+		http.Error(w, "Failed to read audit log", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+
+	api.ResponseJSON(w, map[string]any{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
@@ -0,0 +1,127 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// CalendarDay summarises the recordings of a stream for one day
+type CalendarDay struct {
+	Date            string  `json:"date"`
+	Recordings      int     `json:"recordings"` // files started that day
+	DurationSeconds float64 `json:"duration_seconds"`
+	Size            int64   `json:"size"`
+	SizeHuman       string  `json:"size_human"`
+	Coverage        float64 `json:"coverage_percent"` // of the day, or of the part until now for today
+}
+
+// Calendar summarises the recordings of a stream for one month
+type Calendar struct {
+	Stream          string        `json:"stream"`
+	Month           string        `json:"month"`
+	Days            []CalendarDay `json:"days"`
+	Recordings      int           `json:"recordings"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	Size            int64         `json:"size"`
+	SizeHuman       string        `json:"size_human"`
+	Coverage        float64       `json:"coverage_percent"`
+}
+
+// apiRecordingsCalendar returns per-day recording totals and coverage of a
+// stream for a month, e.g. for a heatmap:
+//
+//	GET /api/recordings/calendar?stream=cam1&month=2025-01[&tolerance=5s][&exact=true]
+func apiRecordingsCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	streamName := query.Get("stream")
+	if streamName == "" {
+		http.Error(w, "Missing 'stream' parameter", http.StatusBadRequest)
+		return
+	}
+
+	month := query.Get("month")
+	if month == "" {
+		month = time.Now().Format("2006-01")
+	}
+	first, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'month' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	tolerance := 5 * time.Second
+	if s := query.Get("tolerance"); s != "" {
+		if tolerance, err = parseDurationParam(s); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid 'tolerance' parameter: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Probing every file of a month is slow the first time, estimate by default
+	duration := estimatedRecordingDuration
+	if query.Get("exact") == "true" {
+		duration = recordingDuration
+	}
+
+	api.ResponseJSON(w, buildCalendar(streamName, first, tolerance, duration))
+}
+
+// buildCalendar summarises the days of the month starting at first, days in
+// the future are left out
+func buildCalendar(
+	streamName string, first time.Time, tolerance time.Duration,
+	duration func(recordings []RecordingFile, i int) time.Duration,
+) *Calendar {
+	calendar := &Calendar{
+		Stream: streamName,
+		Month:  first.Format("2006-01"),
+		Days:   []CalendarDay{},
+	}
+
+	next := first.AddDate(0, 1, 0)
+	recordings := findRecordingsInRange(streamName, first, next)
+
+	var elapsed float64
+	now := time.Now()
+
+	for day := first; day.Before(next) && day.Before(now); day = day.AddDate(0, 0, 1) {
+		dayEnd := day.AddDate(0, 0, 1)
+
+		timeline := buildTimelineFrom(recordings, streamName, day, dayEnd, tolerance, duration)
+
+		summary := CalendarDay{
+			Date:            day.Format("2006-01-02"),
+			DurationSeconds: timeline.RecordedSeconds,
+			Coverage:        timeline.Coverage,
+		}
+		for _, recording := range recordings {
+			if !recording.StartTime.Before(day) && recording.StartTime.Before(dayEnd) {
+				summary.Recordings++
+				summary.Size += recording.Size
+			}
+		}
+		summary.SizeHuman = formatFileSize(summary.Size)
+
+		calendar.Days = append(calendar.Days, summary)
+		calendar.Recordings += summary.Recordings
+		calendar.DurationSeconds += summary.DurationSeconds
+		calendar.Size += summary.Size
+		elapsed += timeline.End.Sub(timeline.Start).Seconds()
+	}
+
+	calendar.SizeHuman = formatFileSize(calendar.Size)
+	if elapsed > 0 {
+		calendar.Coverage = calendar.DurationSeconds / elapsed * 100
+	}
+
+	return calendar
+}
@@ -0,0 +1,204 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+// apiRecordingStreamConfig manages the entries of recording.streams, so
+// cameras can be added to or removed from recording without editing YAML:
+//
+//	GET    /api/recordings/config/streams[?name=cam1]
+//	POST   /api/recordings/config/streams?name=cam1[&persist=false] - create
+//	PUT    /api/recordings/config/streams?name=cam1[&persist=false] - create or replace
+//	PATCH  /api/recordings/config/streams?name=cam1[&persist=false] - update
+//	DELETE /api/recordings/config/streams?name=cam1[&persist=false]
+//
+// Bodies use the YAML keys of a stream entry, e.g.
+//
+//	{"enabled": true, "format": "mp4", "retention_days": 7}
+//
+// Only the runtime stream settings and a camera URL as source are accepted,
+// commands and ffmpeg templates stay in the config file.
+func apiRecordingStreamConfig(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+
+	if r.Method == "GET" {
+		cfg := GetRecordingConfig()
+		if name == "" {
+			entries := make(map[string]any, len(cfg.Streams))
+			for streamName, streamConfig := range cfg.Streams {
+				entries[streamName] = yamlSettings(redactStreamConfig(streamConfig))
+			}
+			api.ResponseJSON(w, map[string]any{"streams": entries})
+			return
+		}
+		streamConfig, ok := cfg.Streams[name]
+		if !ok {
+			http.Error(w, "Stream config not found", http.StatusNotFound)
+			return
+		}
+		api.ResponseJSON(w, streamConfigResponse(name, streamConfig))
+		return
+	}
+
+	if name == "" {
+		http.Error(w, "Missing 'name' parameter", http.StatusBadRequest)
+		return
+	}
+
+	var settings map[string]any
+	switch r.Method {
+	case "POST", "PUT", "PATCH":
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	case "DELETE":
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := checkRuntimeSettings(settings, slices.Concat(runtimeStreamSettings, []string{"source"})); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if source, ok := settings["source"]; ok {
+		if err := validateStreamSource(source); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	cfg := GetRecordingConfig()
+	streamConfig, exists := cfg.Streams[name]
+
+	switch {
+	case r.Method == "POST" && exists:
+		http.Error(w, "Stream config already exists", http.StatusConflict)
+		return
+	case (r.Method == "PATCH" || r.Method == "DELETE") && !exists:
+		http.Error(w, "Stream config not found", http.StatusNotFound)
+		return
+	case !exists && streams.Get(name) == nil && settings["source"] == nil && !isStreamPattern(name):
+		http.Error(w, fmt.Sprintf("Unknown stream %q, add it to streams or set a source", name), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != "PATCH" {
+		streamConfig = StreamRecordingConfig{}
+	}
+	if err := decodeSettings(settings, &streamConfig); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated := *cfg
+
+	// Copy on write, readers may be ranging over the current map
+	updated.Streams = make(map[string]StreamRecordingConfig, len(cfg.Streams)+1)
+	for streamName, item := range cfg.Streams {
+		updated.Streams[streamName] = item
+	}
+	if r.Method == "DELETE" {
+		delete(updated.Streams, name)
+	} else {
+		updated.Streams[name] = streamConfig
+	}
+
+	if err := validateRuntimeConfig(&updated); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	applyRecordingConfig(&updated)
+
+	log.Info().
+		Str("stream", name).
+		Str("method", r.Method).
+		Strs("settings", sortedKeys(settings)).
+		Str("remote", r.RemoteAddr).
+		Msg("[config] recording stream config changed at runtime")
+
+	response := map[string]any{"name": name, "deleted": r.Method == "DELETE"}
+	if r.Method != "DELETE" {
+		response = streamConfigResponse(name, streamConfig)
+	}
+	response["persisted"] = false
+
+	if r.URL.Query().Get("persist") != "false" {
+		path := []string{"recording", "streams", name}
+
+		var err error
+		switch r.Method {
+		case "DELETE":
+			// nil removes the key, don't create parents for it
+			err = app.PatchConfig(path, nil)
+		case "PATCH":
+			for key, value := range settings {
+				if err = patchRecordingConfigFile([]string{"recording", "streams", name, key}, value); err != nil {
+					break
+				}
+			}
+		default:
+			if len(settings) == 0 {
+				// An empty entry records the stream with the global settings
+				settings = map[string]any{"enabled": true}
+			}
+			err = patchRecordingConfigFile(path, settings)
+		}
+
+		if err != nil {
+			log.Warn().Err(err).Str("stream", name).Msg("[config] failed to write recording config")
+			response["persist_error"] = err.Error()
+		} else {
+			response["persisted"] = true
+		}
+	}
+
+	api.ResponseJSON(w, response)
+}
+
+// streamConfigResponse returns a stream entry with its YAML keys and the
+// settings the stream records with after merging the global ones, without
+// the passwords of its URLs
+func streamConfigResponse(name string, streamConfig StreamRecordingConfig) map[string]any {
+	return map[string]any{
+		"name":      name,
+		"config":    yamlSettings(redactStreamConfig(streamConfig)),
+		"effective": yamlSettings(redactStreamConfig(GetStreamRecordingConfig(name))),
+	}
+}
+
+// streamSourceSchemes are the sources the API may set, camera URLs only:
+// ffmpeg would also read local files through file:, concat: or pipe:
+var streamSourceSchemes = []string{"rtsp", "rtsps", "rtmp", "rtmps", "http", "https"}
+
+// validateStreamSource checks a source set through the API
+func validateStreamSource(value any) error {
+	source, ok := value.(string)
+	if !ok {
+		return errors.New("'source' must be a string")
+	}
+	if source == "" {
+		return nil // routed through go2rtc
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" || !slices.Contains(streamSourceSchemes, u.Scheme) {
+		return fmt.Errorf("'source' must be a %s URL", strings.Join(streamSourceSchemes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// DeleteResult describes the outcome of a delete request
+type DeleteResult struct {
+	Deleted    []string          `json:"deleted"`
+	Skipped    map[string]string `json:"skipped,omitempty"` // recording ID -> reason
+	FreedBytes int64             `json:"freed_bytes"`
+	DryRun     bool              `json:"dry_run,omitempty"`
+}
+
+// handleDeleteRecordings deletes a single recording by ID or all recordings
+// matching the stream and time range filters:
+//
+//	DELETE /api/recordings?id=ID
+//	DELETE /api/recordings?stream=cam1&start=2025-01-01&end=2025-01-08[&dry_run=true]
+func handleDeleteRecordings(w http.ResponseWriter, r *http.Request, query map[string][]string) {
+	var recordings []RecordingFile
+
+	if id := getQueryParam(query, "id"); id != "" {
+		recording := recordingIndex.Get(id)
+		if recording == nil {
+			http.Error(w, "Recording not found", http.StatusNotFound)
+			return
+		}
+		recordings = append(recordings, *recording)
+	} else {
+		streamName := getQueryParam(query, "stream")
+		dateFilter := getQueryParam(query, "date")
+		startStr := getQueryParam(query, "start")
+		endStr := getQueryParam(query, "end")
+
+		// Refuse to wipe the whole archive by accident
+		if streamName == "" && dateFilter == "" && startStr == "" && endStr == "" {
+			http.Error(w, "Missing 'id' or filter parameters (stream, date, start, end)", http.StatusBadRequest)
+			return
+		}
+
+		var start, end time.Time
+		var err error
+		if startStr != "" {
+			if start, err = parseTimeParam(startStr); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid 'start' parameter: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+		if endStr != "" {
+			if end, err = parseTimeParam(endStr); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid 'end' parameter: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		for _, recording := range recordingIndex.Query(streamName, dateFilter, 0) {
+			if !start.IsZero() && recording.StartTime.Before(start) {
+				continue
+			}
+			if !end.IsZero() && !recording.StartTime.Before(end) {
+				continue
+			}
+			recordings = append(recordings, recording)
+		}
+	}
+
+	dryRun := getQueryParam(query, "dry_run") == "true"
+	result := deleteRecordings(recordings, dryRun)
+
+	log.Info().
+		Int("deleted", len(result.Deleted)).
+		Int("skipped", len(result.Skipped)).
+		Int64("freed_bytes", result.FreedBytes).
+		Bool("dry_run", dryRun).
+		Msg("[api] deleted recordings")
+
+	api.ResponseJSON(w, result)
+}
+
+// deleteRecordings removes the recording files together with their detection
+// sidecars and cached thumbnails, and drops them from the index
+func deleteRecordings(recordings []RecordingFile, dryRun bool) *DeleteResult {
+	result := &DeleteResult{
+		Deleted: []string{},
+		Skipped: make(map[string]string),
+		DryRun:  dryRun,
+	}
+
+	var removed []string
+	for _, recording := range recordings {
+		if recording.Imported {
+			result.Skipped[recording.ID] = "imported recordings are read-only"
+			continue
+		}
+		if !isWithinBasePath(recording.Path) {
+			result.Skipped[recording.ID] = "outside recordings directory"
+			continue
+		}
+
+		if recording.Protected {
+			result.Skipped[recording.ID] = "protected"
+			continue
+		}
+
+		info, err := os.Stat(recording.Path)
+		if err != nil {
+			removed = append(removed, recording.Path)
+			result.Skipped[recording.ID] = "file not found"
+			continue
+		}
+		if time.Since(info.ModTime()) < time.Minute {
+			result.Skipped[recording.ID] = "recording in progress"
+			continue
+		}
+
+		if !dryRun {
+			if err = os.Remove(recording.Path); err != nil {
+				result.Skipped[recording.ID] = err.Error()
+				continue
+			}
+			removeRecordingArtifacts(&recording)
+			removed = append(removed, recording.Path)
+		}
+
+		result.Deleted = append(result.Deleted, recording.ID)
+		result.FreedBytes += info.Size()
+	}
+
+	recordingIndex.Remove(removed...)
+
+	return result
+}
+
+// removeRecordingArtifacts deletes files derived from a recording
+func removeRecordingArtifacts(recording *RecordingFile) {
+	sidecar := strings.TrimSuffix(recording.Path, filepath.Ext(recording.Path)) + ".json"
+	_ = os.Remove(sidecar)
+	_ = writeHold(recording.Path, false)
+
+	// Thumbnails and previews
+	thumbs, _ := filepath.Glob(filepath.Join(getThumbnailDir(), recording.ID+"_*"))
+	for _, thumb := range thumbs {
+		_ = os.Remove(thumb)
+	}
+}
+
+// isWithinBasePath reports whether path is inside one of the recordings directories
+func isWithinBasePath(path string) bool {
+	_, _, ok := storagePoolRel(path)
+	return ok
+}
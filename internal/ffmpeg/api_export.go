@@ -0,0 +1,409 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// apiRecordingsExport extracts a clip covering an arbitrary time range
+// from the stored segments of a stream:
+//
+//	GET|POST /api/recordings/export?stream=cam1&start=...&end=...[&format=mp4][&store=true][&async=true][&profile=whatsapp]
+//
+// transcode, height and bitrate transcode the clip like downloads, profile
+// applies a preset of them. async stores the clip in a background job.
+func apiRecordingsExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	streamName := query.Get("stream")
+	if streamName == "" {
+		http.Error(w, "Missing 'stream' parameter", http.StatusBadRequest)
+		return
+	}
+	// The stream names the clip file
+	if strings.ContainsAny(streamName, `/\`) || streamName == ".." {
+		http.Error(w, "Invalid 'stream' parameter", http.StatusBadRequest)
+		return
+	}
+
+	start, err := parseTimeParam(query.Get("start"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'start' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	end, err := parseTimeParam(query.Get("end"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'end' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !end.After(start) {
+		http.Error(w, "'end' must be after 'start'", http.StatusBadRequest)
+		return
+	}
+
+	e := &clipExport{stream: streamName, start: start, end: end, profileName: query.Get("profile")}
+
+	if e.profile, err = lookupExportProfile(e.profileName); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if e.transcode, err = parseTranscodeOptions(query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	e.segments = findRecordingsInRange(streamName, start, end)
+	if len(e.segments) == 0 {
+		http.Error(w, "No recordings found for the requested range", http.StatusNotFound)
+		return
+	}
+
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = strings.ToLower(e.profile.Format)
+	}
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(e.segments[0].Path), "."))
+		if !slices.Contains(exportFormats, format) {
+			format = "mkv" // takes the codecs of any recording
+		}
+	}
+	if !slices.Contains(exportFormats, format) {
+		http.Error(w, "Unsupported 'format', use "+strings.Join(exportFormats, ", "), http.StatusBadRequest)
+		return
+	}
+	e.name = fmt.Sprintf("%s_%s_%s.%s", streamName, start.Format("2006-01-02_15-04-05"), end.Format("15-04-05"), format)
+
+	if query.Get("async") == "true" {
+		if e.reencodes() && GetRecordingConfig().MaxTranscodes <= 0 {
+			transcodeUnavailable(w)
+			return
+		}
+		job, err := jobs.submit(JobExport, streamName, func(ctx context.Context, progress func(float64)) (any, error) {
+			if e.reencodes() {
+				release, err := waitTranscodeSlot(ctx)
+				if err != nil {
+					return nil, err
+				}
+				defer release()
+			}
+			return e.store(ctx, progress)
+		})
+		respondJob(w, job, err)
+		return
+	}
+
+	// Re-encoding counts against max_transcodes like transcoded downloads
+	if e.reencodes() {
+		release, ok := acquireTranscodeDownload()
+		if !ok {
+			transcodeUnavailable(w)
+			return
+		}
+		defer release()
+	}
+
+	// Either store the clip in the export directory or stream it back once
+	if query.Get("store") == "true" {
+		response, err := e.store(r.Context(), nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if checksum, ok := response["sha256"].(string); ok {
+			w.Header().Set("X-Checksum-SHA256", checksum)
+		}
+		api.ResponseJSON(w, response)
+		return
+	}
+
+	tmp, err := os.MkdirTemp("", "go2rtc-export-")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create temp directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(tmp)
+
+	output, err := e.output(tmp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = e.export(r.Context(), output, nil); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export clip: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if e.profile.Checksum {
+		checksum, _, err := fileSHA256(output)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to checksum clip: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Checksum-SHA256", checksum)
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to open clip: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w = throttleDownload(w, r)
+	w.Header().Set("Content-Type", recordingContentType(output))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", e.name))
+	http.ServeContent(w, r, e.name, time.Now(), file)
+}
+
+// exportFormats are the containers clips can be exported and merged to
+var exportFormats = []string{"mp4", "mkv", "mov", "ts"}
+
+// clipExport is a checked export request
+type clipExport struct {
+	stream      string
+	start, end  time.Time
+	segments    []RecordingFile
+	transcode   *transcodeOptions
+	profile     ExportProfile
+	profileName string
+	name        string // file name of the clip
+}
+
+// output returns the path of the clip in dir
+func (e *clipExport) output(dir string) (string, error) {
+	output := filepath.Join(dir, e.name)
+	if filepath.Base(output) != e.name || !pathWithin(output, dir) {
+		return "", fmt.Errorf("invalid clip name %q", e.name)
+	}
+	return output, nil
+}
+
+// reencodes reports whether the export counts against max_transcodes
+func (e *clipExport) reencodes() bool {
+	return e.transcode != nil && e.transcode.codec != "copy"
+}
+
+// export writes the clip to output
+func (e *clipExport) export(ctx context.Context, output string, progress func(float64)) error {
+	if err := exportClip(ctx, e.segments, e.start, e.end, e.transcode, output, progress); err != nil {
+		log.Error().Err(err).Str("stream", e.stream).Msg("[export] clip extraction failed")
+		return err
+	}
+
+	log.Info().
+		Str("stream", e.stream).
+		Time("start", e.start).
+		Time("end", e.end).
+		Int("segments", len(e.segments)).
+		Str("profile", e.profileName).
+		Str("output", output).
+		Msg("[export] clip extracted")
+	return nil
+}
+
+// store writes the clip to export_path and returns the response of the
+// export API, with the checksum and manifest of a checksum profile
+func (e *clipExport) store(ctx context.Context, progress func(float64)) (map[string]any, error) {
+	dir := GetRecordingConfig().ExportPath
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	output, err := e.output(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err = e.export(ctx, output, progress); err != nil {
+		_ = os.Remove(output)
+		return nil, fmt.Errorf("failed to export clip: %w", err)
+	}
+
+	response := map[string]any{
+		"stream":   e.stream,
+		"start":    e.start,
+		"end":      e.end,
+		"segments": len(e.segments),
+		"path":     output,
+	}
+
+	if e.profile.Checksum {
+		checksum, size, err := fileSHA256(output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum clip: %w", err)
+		}
+		response["sha256"] = checksum
+
+		manifest := &ExportManifest{
+			File:    e.name,
+			SHA256:  checksum,
+			Size:    size,
+			Stream:  e.stream,
+			Start:   e.start,
+			End:     e.end,
+			Profile: e.profileName,
+			Created: time.Now(),
+		}
+		if response["manifest"], err = writeExportManifest(manifest, output, e.segments); err != nil {
+			return nil, fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+
+	return response, nil
+}
+
+// exportClip runs ffmpeg to cut the [start, end) window out of the given
+// segments, concatenating them when the window spans multiple files. The
+// streams are copied without transcode options.
+func exportClip(ctx context.Context, segments []RecordingFile, start, end time.Time, transcode *transcodeOptions, output string, progress func(float64)) error {
+	offset := start.Sub(segments[0].StartTime)
+	if offset < 0 {
+		offset = 0
+	}
+	duration := end.Sub(start)
+
+	args := []string{"-hide_banner", "-v", "error"}
+
+	var input string
+	if len(segments) == 1 {
+		input = recordingInput(segments[0].Path)
+		args = append(args, ffmpegProtocolArgs(input)...)
+	} else {
+		// Use the concat demuxer so timestamps continue across segments
+		list := output + ".txt"
+		var sb strings.Builder
+		var inputs []string
+		for _, segment := range segments {
+			path, err := filepath.Abs(segment.Path)
+			if err != nil {
+				return err
+			}
+			path = recordingInput(path)
+			inputs = append(inputs, path)
+			sb.WriteString("file '" + strings.ReplaceAll(path, "'", `'\''`) + "'\n")
+		}
+		if err := os.WriteFile(list, []byte(sb.String()), 0644); err != nil {
+			return err
+		}
+		defer os.Remove(list)
+
+		args = append(args, ffmpegProtocolArgs(inputs...)...)
+		args = append(args, "-f", "concat", "-safe", "0")
+		input = list
+	}
+
+	args = append(args,
+		"-ss", formatSeconds(offset),
+		"-to", formatSeconds(offset+duration),
+		"-i", input,
+	)
+	if transcode != nil {
+		args = append(args, "-map", "0:v?", "-map", "0:a?")
+		args = append(args, transcode.videoArgs()...)
+	} else {
+		args = append(args, "-c", "copy")
+	}
+	args = append(args, "-y", output)
+
+	return runFFmpeg(ctx, args, duration, progress)
+}
+
+// apiRecordingsExportProfiles lists the export profiles, the built-in ones
+// and those of export_profiles:
+//
+//	GET /api/recordings/export/profiles
+func apiRecordingsExportProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profiles := make(map[string]ExportProfile, len(builtinExportProfiles))
+	for name, profile := range builtinExportProfiles {
+		profiles[name] = profile
+	}
+	for name, profile := range GetRecordingConfig().ExportProfiles {
+		profiles[name] = profile
+	}
+	api.ResponseJSON(w, profiles)
+}
+
+// findRecordingsInRange returns the recordings of a stream that overlap
+// the [start, end) window, oldest first. The end of each recording is taken
+// from the start of the next one when available, since filename based
+// estimates are often wrong for segmented output.
+func findRecordingsInRange(streamName string, start, end time.Time) []RecordingFile {
+	recordings := recordingIndex.Query(streamName, "", 0)
+
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].StartTime.Before(recordings[j].StartTime)
+	})
+
+	var result []RecordingFile
+	for i, recording := range recordings {
+		recordingEnd := recordingSpanEnd(recordings, i)
+		if recordingEnd.IsZero() {
+			recordingEnd = time.Now() // still being written
+		}
+
+		if recording.StartTime.Before(end) && recordingEnd.After(start) {
+			result = append(result, recording)
+		}
+	}
+
+	return result
+}
+
+// recordingSpanEnd returns when the i-th recording of a start-sorted list ends,
+// capped by the start of the next one. Zero means it is still being written.
+func recordingSpanEnd(recordings []RecordingFile, i int) time.Time {
+	recordingEnd := recordings[i].EndTime
+	if i+1 < len(recordings) && (recordingEnd.IsZero() || recordings[i+1].StartTime.Before(recordingEnd)) {
+		recordingEnd = recordings[i+1].StartTime
+	}
+	return recordingEnd
+}
+
+// parseTimeParam parses a time from a query parameter. Accepts RFC3339,
+// local "2006-01-02T15:04:05", the recording filename format and unix seconds.
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty value")
+	}
+
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02_15-04-05", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported time format: %s", s)
+}
+
+// formatSeconds formats a duration as fractional seconds for ffmpeg arguments
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,57 @@
+package ffmpeg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingsExportPath(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+
+	dir := t.TempDir()
+	exports := filepath.Join(dir, "exports")
+	setRecordingConfig(&RecordingConfig{BasePath: filepath.Join(dir, "recordings"), ExportPath: exports})
+
+	path := filepath.Join(dir, "recordings", "cam1", "cam1_2024-01-01_12-00-00.mp4")
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.Nil(t, os.WriteFile(path, []byte("recording"), 0644))
+	recordingIndex.Update(path)
+	t.Cleanup(func() { recordingIndex.Remove(path) })
+
+	export := func(stream, format string) int {
+		query := url.Values{
+			"stream": {stream}, "format": {format}, "store": {"true"},
+			"start": {"2024-01-01T12:00:00"}, "end": {"2024-01-01T12:00:30"},
+		}
+		w := httptest.NewRecorder()
+		apiRecordingsExport(w, httptest.NewRequest("POST", "/api/recordings/export?"+query.Encode(), nil))
+		return w.Code
+	}
+
+	for _, format := range []string{"mp4/../../../../tmp/x.mp4", "mp4/x", "exe", "mp4 "} {
+		require.Equal(t, http.StatusBadRequest, export("cam1", format), format)
+	}
+	for _, stream := range []string{"../cam1", `..\cam1`, "cam1/x", ".."} {
+		require.Equal(t, http.StatusBadRequest, export(stream, "mp4"), stream)
+	}
+	_, err := os.Stat(exports)
+	require.True(t, os.IsNotExist(err)) // ffmpeg never ran
+
+	e := &clipExport{name: "cam1_x.mp4"}
+	output, err := e.output(exports)
+	require.Nil(t, err)
+	require.Equal(t, filepath.Join(exports, "cam1_x.mp4"), output)
+
+	for _, name := range []string{"../x.mp4", "a/../../x.mp4", "a/x.mp4"} {
+		e.name = name
+		_, err = e.output(exports)
+		require.Error(t, err, name)
+	}
+}
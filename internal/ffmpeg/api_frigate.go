@@ -0,0 +1,380 @@
+package ffmpeg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/detection"
+)
+
+// frigateVersion is the Frigate release whose events API is mirrored
+const frigateVersion = "0.13.0-go2file"
+
+// frigatePrefix is where the Frigate compatible API lives, clients are
+// pointed at http://HOST:1984/frigate as if it was a Frigate server
+const frigatePrefix = "frigate/api/"
+
+// FrigateEvent is a recording in the schema of Frigate's /api/events
+type FrigateEvent struct {
+	ID                 string           `json:"id"`
+	Camera             string           `json:"camera"`
+	Label              string           `json:"label"`
+	SubLabel           *string          `json:"sub_label"`
+	TopScore           *float64         `json:"top_score"`
+	FalsePositive      bool             `json:"false_positive"`
+	Zones              []string         `json:"zones"`
+	StartTime          float64          `json:"start_time"`
+	EndTime            *float64         `json:"end_time"` // null while in progress
+	HasClip            bool             `json:"has_clip"`
+	HasSnapshot        bool             `json:"has_snapshot"`
+	RetainIndefinitely bool             `json:"retain_indefinitely"`
+	PlusID             *string          `json:"plus_id"`
+	Thumbnail          string           `json:"thumbnail,omitempty"` // base64 JPEG, with include_thumbnails=1
+	Data               FrigateEventData `json:"data"`
+
+	recording *RecordingFile
+	offset    time.Duration // first detection of the label
+}
+
+// FrigateEventData is the data object of a Frigate event
+type FrigateEventData struct {
+	Type       string   `json:"type"`
+	Score      *float64 `json:"score"`
+	TopScore   *float64 `json:"top_score"`
+	Attributes []string `json:"attributes"`
+}
+
+// frigateEventID builds a Frigate style ID, the start time and the
+// recording ID
+func frigateEventID(recording *RecordingFile) string {
+	return fmt.Sprintf("%.6f-%s", float64(recording.StartTime.UnixMicro())/1e6, recording.ID)
+}
+
+// frigateEventRecording returns the recording of a Frigate event ID
+func frigateEventRecording(eventID string) *RecordingFile {
+	_, id, ok := strings.Cut(eventID, "-")
+	if !ok {
+		return nil
+	}
+	return recordingIndex.Get(id)
+}
+
+// newFrigateEvent describes a recording as a Frigate event. Event recordings
+// and recordings with detections are events, other recordings are not.
+func newFrigateEvent(recording *RecordingFile) *FrigateEvent {
+	event := &FrigateEvent{
+		ID:                 frigateEventID(recording),
+		Camera:             recording.StreamName,
+		Zones:              []string{},
+		StartTime:          float64(recording.StartTime.UnixMicro()) / 1e6,
+		HasClip:            true,
+		HasSnapshot:        true,
+		RetainIndefinitely: recording.Protected || retainedForever(recording.Tags),
+		FalsePositive:      hasTags(recording.Tags, []string{"false-alarm"}) || hasTags(recording.Tags, []string{"false_positive"}),
+		Data:               FrigateEventData{Type: "object", Attributes: []string{}},
+		recording:          recording,
+		offset:             GetRecordingConfig().ThumbnailOffset,
+	}
+
+	if !recording.EndTime.IsZero() && recording.EndTime.Before(time.Now()) {
+		end := float64(recording.EndTime.UnixMicro()) / 1e6
+		event.EndTime = &end
+	}
+
+	// The label with the most confident detection
+	if result := loadDetectionResult(recording.Path); result != nil {
+		for _, d := range result.Detections {
+			if event.TopScore == nil || d.Confidence > *event.TopScore {
+				score := d.Confidence
+				event.Label, event.TopScore = d.Label, &score
+				event.offset = time.Duration(d.TimeSecs * float64(time.Second))
+			}
+		}
+	}
+
+	if event.Label == "" {
+		if !recording.Event && len(recording.DetectionLabels) == 0 {
+			return nil
+		}
+		switch {
+		case len(recording.DetectionLabels) > 0:
+			event.Label = recording.DetectionLabels[0]
+		case len(recording.Tags) > 0:
+			event.Label = recording.Tags[0]
+		default:
+			event.Label = "event"
+		}
+	}
+
+	event.Data.Score, event.Data.TopScore = event.TopScore, event.TopScore
+	return event
+}
+
+// loadDetectionResult reads the detection sidecar of a recording
+func loadDetectionResult(filePath string) *detection.DetectionResult {
+	data, err := os.ReadFile(strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".json")
+	if err != nil {
+		return nil
+	}
+	var result detection.DetectionResult
+	if err = json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return &result
+}
+
+// apiFrigate serves the parts of Frigate's HTTP API that dashboards and
+// integrations use to browse events:
+//
+//	GET /frigate/api/version
+//	GET /frigate/api/config
+//	GET /frigate/api/events[?camera=cam1,cam2][&label=person][&after=UNIX][&before=UNIX][&limit=100][&in_progress=0][&include_thumbnails=1]
+//	GET /frigate/api/events/ID
+//	GET /frigate/api/events/ID/thumbnail.jpg
+//	GET /frigate/api/events/ID/snapshot.jpg
+//	GET /frigate/api/events/ID/clip.mp4
+func apiFrigate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, path, _ := strings.Cut(r.URL.Path, "/"+frigatePrefix)
+
+	switch {
+	case path == "version":
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(frigateVersion))
+	case path == "config":
+		frigateConfig(w)
+	case path == "events":
+		frigateEvents(w, r)
+	case strings.HasPrefix(path, "events/"):
+		eventID, file, _ := strings.Cut(strings.TrimPrefix(path, "events/"), "/")
+		recording := frigateEventRecording(eventID)
+		if recording == nil {
+			http.Error(w, "Event not found", http.StatusNotFound)
+			return
+		}
+		event := newFrigateEvent(recording)
+		if event == nil {
+			http.Error(w, "Event not found", http.StatusNotFound)
+			return
+		}
+		frigateEvent(w, r, event, file)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// frigateConfig lists the recorded streams as cameras
+func frigateConfig(w http.ResponseWriter) {
+	cameras := make(map[string]any)
+	for _, name := range getStreamsToRecord() {
+		cameras[name] = map[string]any{
+			"name":      name,
+			"enabled":   true,
+			"record":    map[string]any{"enabled": true},
+			"snapshots": map[string]any{"enabled": true},
+			"zones":     map[string]any{},
+		}
+	}
+	api.ResponseJSON(w, map[string]any{"cameras": cameras})
+}
+
+func frigateEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Frigate accepts comma separated lists and "all"
+	list := func(names ...string) map[string]bool {
+		set := map[string]bool{}
+		for _, name := range names {
+			for _, value := range strings.Split(query.Get(name), ",") {
+				if value != "" && value != "all" {
+					set[value] = true
+				}
+			}
+		}
+		return set
+	}
+	cameras, labels := list("camera", "cameras"), list("label", "labels")
+
+	unixParam := func(name string) (time.Time, bool) {
+		value := query.Get(name)
+		if value == "" {
+			return time.Time{}, true
+		}
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		sec, frac := math.Modf(seconds)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	}
+	after, ok := unixParam("after")
+	if !ok {
+		http.Error(w, "Invalid 'after' parameter", http.StatusBadRequest)
+		return
+	}
+	before, ok := unixParam("before")
+	if !ok {
+		http.Error(w, "Invalid 'before' parameter", http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "Invalid 'limit' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	q := RecordingQuery{From: after, To: before}
+	if len(cameras) == 1 {
+		for camera := range cameras {
+			q.Stream = camera
+		}
+	}
+	recordings, _ := recordingIndex.Find(q)
+
+	events := []*FrigateEvent{}
+	for i := range recordings {
+		recording := &recordings[i]
+		if len(cameras) > 0 && !cameras[recording.StreamName] {
+			continue
+		}
+		if !after.IsZero() && recording.StartTime.Before(after) {
+			continue
+		}
+		if !before.IsZero() && !recording.StartTime.Before(before) {
+			continue
+		}
+
+		event := newFrigateEvent(recording)
+		if event == nil || len(labels) > 0 && !labels[event.Label] {
+			continue
+		}
+		switch query.Get("in_progress") {
+		case "0":
+			if event.EndTime == nil {
+				continue
+			}
+		case "1":
+			if event.EndTime != nil {
+				continue
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	// Newest first, like Frigate
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime > events[j].StartTime
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+
+	if query.Get("include_thumbnails") == "1" {
+		for _, event := range events {
+			event.Thumbnail = frigateThumbnail(event)
+		}
+	}
+
+	api.ResponseJSON(w, events)
+}
+
+// frigateThumbnail returns the event's thumbnail base64 encoded, empty if it
+// can't be generated
+func frigateThumbnail(event *FrigateEvent) string {
+	path, err := getRecordingThumbnail(event.recording, event.offset)
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func frigateEvent(w http.ResponseWriter, r *http.Request, event *FrigateEvent, file string) {
+	recording := event.recording
+
+	switch file {
+	case "":
+		api.ResponseJSON(w, event)
+
+	case "thumbnail.jpg", "snapshot.jpg":
+		path, err := getRecordingThumbnail(recording, event.offset)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to generate thumbnail: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		http.ServeFile(w, r, path)
+
+	case "clip.mp4":
+		if !isReadableRecordingPath(recording.Path) {
+			http.Error(w, "Access denied", http.StatusForbidden)
+			return
+		}
+
+		w = throttleDownload(w, r)
+
+		// Other containers are remuxed to MP4 while they are sent
+		if recordingContentType(recording.Path) != "video/mp4" {
+			serveTranscodedRecording(w, r, recording, &transcodeOptions{codec: "copy"}, "attachment")
+			return
+		}
+
+		reader, err := openRecording(recording.Path)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to open recording: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer reader.Close()
+
+		w.Header().Set("Content-Type", "video/mp4")
+		http.ServeContent(w, r, event.ID+".mp4", recording.EndTime, reader)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// frigatePermission requires download for clips and view otherwise
+func frigatePermission(r *http.Request) recordingPermission {
+	if strings.HasSuffix(r.URL.Path, "/clip.mp4") {
+		return permDownload
+	}
+	return permView
+}
+
+// frigatePathStream returns the stream of the event named in a Frigate API
+// path, for stream scoped tokens
+func frigatePathStream(path string) (string, bool) {
+	_, rest, ok := strings.Cut(path, "/"+frigatePrefix+"events/")
+	if !ok {
+		return "", false
+	}
+	eventID, _, _ := strings.Cut(rest, "/")
+	if recording := frigateEventRecording(eventID); recording != nil {
+		return recording.StreamName, true
+	}
+	return "", false
+}
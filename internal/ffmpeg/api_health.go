@@ -24,15 +24,22 @@ func apiRecordingHealth(w http.ResponseWriter, r *http.Request) {
 		statusCode = http.StatusServiceUnavailable
 	}
 
+	// Without ffmpeg no recording can start
+	ffmpegInfo := ffmpegStatus()
+	if ffmpegInfo.Error != "" {
+		statusCode = http.StatusServiceUnavailable
+	}
+
 	// Build response with enhanced watchdog info
 	response := map[string]interface{}{
-		"healthy":                 healthCheck.Healthy,
+		"healthy":                 healthCheck.Healthy && ffmpegInfo.Error == "",
 		"active_ffmpeg_processes": healthCheck.ActiveFFmpegProcesses,
 		"expected_recordings":     healthCheck.ExpectedRecordings,
 		"newest_recording_age":    healthCheck.NewestRecordingAge.String(),
 		"warnings":                healthCheck.Warnings,
 		"streams_with_issues":     healthCheck.StreamsWithIssues,
 		"watchdog":                watchdogStatus,
+		"ffmpeg":                  ffmpegInfo,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -40,6 +47,27 @@ func apiRecordingHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// apiRecordingsHealth reports whether recording works as ok, degraded or
+// critical for Docker HEALTHCHECK and Kubernetes probes. Only critical
+// answers 503, a degraded system still records.
+func apiRecordingsHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	health := systemHealth()
+
+	statusCode := http.StatusOK
+	if health.Status == healthCritical {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(health)
+}
+
 // apiWatchdog returns the current watchdog status
 func apiWatchdog(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
@@ -53,6 +81,25 @@ func apiWatchdog(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(status)
 }
 
+// apiRecordFailuresReset clears the auto-recording backoff of a stream, or
+// of all streams without the stream parameter
+func apiRecordFailuresReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	streamName := r.URL.Query().Get("stream")
+	ResetAutoRecordFailures(streamName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "reset",
+		"stream":   streamName,
+		"failures": GetAutoRecordFailures(),
+	})
+}
+
 // apiWatchdogReset resets watchdog state for a stream or all streams
 func apiWatchdogReset(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -0,0 +1,66 @@
+package ffmpeg
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// apiRecordHistory queries the history of ended recordings, the latest to
+// end first:
+//
+//	GET /api/record/history[?since=...][&until=...][&stream=cam1][&id=ID][&reason=failed][&limit=100]
+//
+// since and until select the sessions that were running in between.
+func apiRecordHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	filter := HistoryFilter{
+		Stream: query.Get("stream"),
+		ID:     query.Get("id"),
+		Reason: query.Get("reason"),
+	}
+
+	var err error
+	if value := query.Get("since"); value != "" {
+		if filter.Since, err = parseTimeParam(value); err != nil {
+			http.Error(w, "Invalid 'since' parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("until"); value != "" {
+		if filter.Until, err = parseTimeParam(value); err != nil {
+			http.Error(w, "Invalid 'until' parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			http.Error(w, "Invalid 'limit' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	sessions, err := readHistory(filter, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("[history] failed to read recording history")
+		http.Error(w, "Failed to read recording history", http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []RecordingSession{}
+	}
+
+	api.ResponseJSON(w, map[string]any{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
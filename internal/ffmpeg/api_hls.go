@@ -0,0 +1,152 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// apiRecordingsHLS serves stored segments as an HLS VOD playlist, so browsers
+// can play back an arbitrary window without downloading whole files:
+//
+//	GET /api/recordings/hls?stream=cam1&start=T&end=T  m3u8 playlist
+//	GET /api/recordings/hls?segment=ID                 MPEG-TS media segment
+func apiRecordingsHLS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if id := query.Get("segment"); id != "" {
+		handleHLSSegment(w, r, id)
+		return
+	}
+
+	streamName := query.Get("stream")
+	if streamName == "" {
+		http.Error(w, "Missing 'stream' parameter", http.StatusBadRequest)
+		return
+	}
+
+	start, err := parseTimeParam(query.Get("start"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'start' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	end, err := parseTimeParam(query.Get("end"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'end' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !end.After(start) {
+		http.Error(w, "'end' must be after 'start'", http.StatusBadRequest)
+		return
+	}
+
+	playlist := buildHLSPlaylist(findRecordingsInRange(streamName, start, end))
+	if playlist == "" {
+		http.Error(w, "No recordings found for the requested range", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write([]byte(playlist))
+}
+
+// buildHLSPlaylist returns an m3u8 VOD playlist with one media segment per
+// finished recording, or an empty string if there is nothing to play
+func buildHLSPlaylist(recordings []RecordingFile) string {
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].StartTime.Before(recordings[j].StartTime)
+	})
+
+	var sb strings.Builder
+	var target float64
+
+	for i, recording := range recordings {
+		// Files still being written can't be remuxed yet
+		recordingEnd := recordingSpanEnd(recordings, i)
+		if recordingEnd.IsZero() || !recordingEnd.After(recording.StartTime) {
+			continue
+		}
+
+		duration := recordingEnd.Sub(recording.StartTime).Seconds()
+		target = math.Max(target, duration)
+
+		// Every file starts with fresh timestamps
+		if sb.Len() > 0 {
+			sb.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		sb.WriteString("#EXT-X-PROGRAM-DATE-TIME:" + recording.StartTime.Format(time.RFC3339) + "\n")
+		fmt.Fprintf(&sb, "#EXTINF:%.3f,\n", duration)
+		sb.WriteString("hls?segment=" + url.QueryEscape(recording.ID) + "\n")
+	}
+
+	if sb.Len() == 0 {
+		return ""
+	}
+
+	return "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-PLAYLIST-TYPE:VOD\n" +
+		fmt.Sprintf("#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(target))) +
+		"#EXT-X-MEDIA-SEQUENCE:0\n" +
+		sb.String() +
+		"#EXT-X-ENDLIST\n"
+}
+
+// handleHLSSegment serves a recording as an MPEG-TS media segment, remuxing
+// other containers on the fly without re-encoding
+func handleHLSSegment(w http.ResponseWriter, r *http.Request, id string) {
+	recording := recordingIndex.Get(id)
+	if recording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+	if !isReadableRecordingPath(recording.Path) {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+
+	input := recordingInput(recording.Path)
+
+	if strings.ToLower(filepath.Ext(recording.Path)) == ".ts" {
+		if input == recording.Path {
+			http.ServeFile(w, r, recording.Path)
+			return
+		}
+		file, err := openRecording(recording.Path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+		http.ServeContent(w, r, filepath.Base(recording.Path), recording.EndTime, file)
+		return
+	}
+
+	cmd := exec.CommandContext(r.Context(), ffmpegBin(),
+		"-hide_banner", "-v", "error",
+		"-i", input,
+		"-map", "0:v?", "-map", "0:a?",
+		"-c", "copy",
+		"-f", "mpegts", "pipe:1",
+	)
+	cmd.Stdout = w
+
+	if err := cmd.Run(); err != nil && r.Context().Err() == nil {
+		log.Warn().Err(err).Str("recording_id", id).Msg("[api] failed to remux hls segment")
+	}
+}
@@ -0,0 +1,99 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// apiRecordingJobs lists, starts and cancels background jobs:
+//
+//	GET    /api/recordings/jobs[?id=ID][&state=running][&type=export][&stream=cam1]
+//	POST   /api/recordings/jobs?type=thumbnails&stream=cam1[&date=2025-01-15]
+//	DELETE /api/recordings/jobs?id=ID
+//
+// Exports, merges and restores start their jobs with ?async=true.
+func apiRecordingJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	streamName := query.Get("stream")
+
+	switch r.Method {
+	case "GET":
+		if id := query.Get("id"); id != "" {
+			job, ok := jobs.Get(id)
+			if !ok {
+				http.Error(w, errJobNotFound.Error(), http.StatusNotFound)
+				return
+			}
+			api.ResponseJSON(w, job)
+			return
+		}
+
+		state, typ := query.Get("state"), query.Get("type")
+
+		list := []Job{}
+		for _, job := range jobs.List() {
+			if (state == "" || job.State == state) && (typ == "" || job.Type == typ) &&
+				(streamName == "" || job.Stream == streamName) {
+				list = append(list, job)
+			}
+		}
+		api.ResponseJSON(w, map[string]any{
+			"jobs":  list,
+			"count": len(list),
+		})
+
+	case "POST":
+		if query.Get("type") != JobThumbnails {
+			http.Error(w, "Unsupported 'type', use thumbnails", http.StatusBadRequest)
+			return
+		}
+
+		if streamName == "" {
+			http.Error(w, "Missing 'stream' parameter", http.StatusBadRequest)
+			return
+		}
+
+		recordings := recordingIndex.Query(streamName, query.Get("date"), 0)
+		if len(recordings) == 0 {
+			http.Error(w, "No recordings found", http.StatusNotFound)
+			return
+		}
+
+		job, err := jobs.submit(JobThumbnails, streamName, func(ctx context.Context, progress func(float64)) (any, error) {
+			return generateThumbnails(ctx, recordings, progress)
+		})
+		respondJob(w, job, err)
+
+	case "DELETE":
+		job, err := jobs.Cancel(query.Get("id"))
+		switch {
+		case errors.Is(err, errJobNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, errJobFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			api.ResponseJSON(w, job)
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// respondJob answers a request that started a job with 202 and the job
+func respondJob(w http.ResponseWriter, job Job, err error) {
+	if err != nil {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Location", "/api/recordings/jobs?id="+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(job)
+}
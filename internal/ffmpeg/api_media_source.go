@@ -0,0 +1,271 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// mediaSourcePrefix starts the media content IDs, as Home Assistant names
+// media of a media source integration
+const mediaSourcePrefix = "media-source://go2file"
+
+// Home Assistant media classes
+const (
+	mediaClassDirectory = "directory"
+	mediaClassVideo     = "video"
+)
+
+// BrowseMedia is a node of the Home Assistant media browser
+type BrowseMedia struct {
+	Title              string         `json:"title"`
+	MediaClass         string         `json:"media_class"`
+	MediaContentID     string         `json:"media_content_id"`
+	MediaContentType   string         `json:"media_content_type"`
+	CanPlay            bool           `json:"can_play"`
+	CanExpand          bool           `json:"can_expand"`
+	ChildrenMediaClass string         `json:"children_media_class,omitempty"`
+	Thumbnail          string         `json:"thumbnail,omitempty"`
+	Children           []*BrowseMedia `json:"children,omitempty"`
+}
+
+// PlayMedia is a resolved media content ID
+type PlayMedia struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+}
+
+// mediaSourceID is a parsed media content ID: the root, a stream, a day of
+// a stream or a recording
+type mediaSourceID struct {
+	stream      string
+	date        string // YYYY-MM-DD
+	recordingID string
+}
+
+// parseMediaSourceID reads a media content ID with or without the
+// media-source:// prefix, e.g. "cam1/2025-01-15/ID"
+func parseMediaSourceID(value string) (*mediaSourceID, error) {
+	value = strings.TrimPrefix(value, mediaSourcePrefix)
+	value = strings.Trim(value, "/")
+
+	id := &mediaSourceID{}
+	if value == "" {
+		return id, nil
+	}
+
+	parts := strings.Split(value, "/")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid media content ID %q", value)
+	}
+	for i, part := range parts {
+		part, err := url.PathUnescape(part)
+		if err != nil || part == "" {
+			return nil, fmt.Errorf("invalid media content ID %q", value)
+		}
+		parts[i] = part
+	}
+
+	id.stream = parts[0]
+	if len(parts) > 1 {
+		id.date = parts[1]
+	}
+	if len(parts) > 2 {
+		id.recordingID = parts[2]
+	}
+	return id, nil
+}
+
+// mediaSourceContentID builds the media content ID of a node, path
+// segments are escaped so stream names may contain slashes
+func mediaSourceContentID(parts ...string) string {
+	id := mediaSourcePrefix
+	for _, part := range parts {
+		id += "/" + url.PathEscape(part)
+	}
+	return id
+}
+
+// apiRecordingMediaSource serves the recordings as a Home Assistant media
+// source, a tree of streams, days and recordings:
+//
+//	GET /api/recordings/media_source                                            the streams
+//	GET /api/recordings/media_source?media_content_id=media-source://go2file/cam1  days of a stream
+//	GET /api/recordings/media_source?media_content_id=media-source://go2file/cam1/2025-01-15
+//	GET /api/recordings/media_source?resolve=media-source://go2file/cam1/2025-01-15/ID
+func apiRecordingMediaSource(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if value := query.Get("resolve"); value != "" {
+		id, err := parseMediaSourceID(value)
+		if err != nil || id.recordingID == "" {
+			http.Error(w, "'resolve' must be the media content ID of a recording", http.StatusBadRequest)
+			return
+		}
+		recording := recordingIndex.Get(id.recordingID)
+		if recording == nil || recording.StreamName != id.stream {
+			http.Error(w, "Recording not found", http.StatusNotFound)
+			return
+		}
+		api.ResponseJSON(w, resolveMediaSource(recording))
+		return
+	}
+
+	id, err := parseMediaSourceID(query.Get("media_content_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var node *BrowseMedia
+	switch {
+	case id.recordingID != "":
+		recording := recordingIndex.Get(id.recordingID)
+		if recording == nil || recording.StreamName != id.stream {
+			http.Error(w, "Recording not found", http.StatusNotFound)
+			return
+		}
+		node = recordingMedia(recording)
+	case id.date != "":
+		node = browseMediaDay(id.stream, id.date)
+	case id.stream != "":
+		node = browseMediaStream(id.stream)
+	default:
+		node = browseMediaRoot()
+	}
+	if node == nil {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	api.ResponseJSON(w, node)
+}
+
+// mediaDirectory is an expandable node, its children are listed when it's
+// browsed
+func mediaDirectory(title, childrenClass string, parts ...string) *BrowseMedia {
+	return &BrowseMedia{
+		Title:              title,
+		MediaClass:         mediaClassDirectory,
+		MediaContentID:     mediaSourceContentID(parts...),
+		CanExpand:          true,
+		ChildrenMediaClass: childrenClass,
+	}
+}
+
+// browseMediaRoot lists the streams with recordings
+func browseMediaRoot() *BrowseMedia {
+	recordings, _ := recordingIndex.Find(RecordingQuery{})
+
+	counts := map[string]int{}
+	for _, recording := range recordings {
+		counts[recording.StreamName]++
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	root := mediaDirectory("Recordings", mediaClassDirectory)
+	root.Children = []*BrowseMedia{}
+	for _, name := range names {
+		root.Children = append(root.Children, mediaDirectory(name, mediaClassDirectory, name))
+	}
+	return root
+}
+
+// browseMediaStream lists the days of a stream, newest first
+func browseMediaStream(streamName string) *BrowseMedia {
+	recordings, _ := recordingIndex.Find(RecordingQuery{Stream: streamName})
+	if len(recordings) == 0 {
+		return nil
+	}
+
+	counts := map[string]int{}
+	for _, recording := range recordings {
+		counts[recording.DateGroup]++
+	}
+
+	dates := make([]string, 0, len(counts))
+	for date := range counts {
+		dates = append(dates, date)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
+
+	node := mediaDirectory(streamName, mediaClassDirectory, streamName)
+	for _, date := range dates {
+		title := fmt.Sprintf("%s (%d)", date, counts[date])
+		node.Children = append(node.Children, mediaDirectory(title, mediaClassVideo, streamName, date))
+	}
+	return node
+}
+
+// browseMediaDay lists the recordings of a stream on a day, newest first
+func browseMediaDay(streamName, date string) *BrowseMedia {
+	recordings, _ := recordingIndex.Find(RecordingQuery{Stream: streamName, Date: date})
+	if len(recordings) == 0 {
+		return nil
+	}
+
+	node := mediaDirectory(streamName+" "+date, mediaClassVideo, streamName, date)
+	for i := range recordings {
+		node.Children = append(node.Children, recordingMedia(&recordings[i]))
+	}
+	return node
+}
+
+// recordingMedia is the playable node of a recording, titled with its start
+// time, length and detections
+func recordingMedia(recording *RecordingFile) *BrowseMedia {
+	title := recording.StartTime.Format("15:04:05")
+	if recording.Duration != "" {
+		title += " (" + recording.Duration + ")"
+	}
+	if len(recording.DetectionLabels) > 0 {
+		title += " " + strings.Join(recording.DetectionLabels, ", ")
+	}
+
+	return &BrowseMedia{
+		Title:            title,
+		MediaClass:       mediaClassVideo,
+		MediaContentID:   mediaSourceContentID(recording.StreamName, recording.DateGroup, recording.ID),
+		MediaContentType: resolveMediaSource(recording).MimeType,
+		CanPlay:          !recording.Archived,
+		Thumbnail:        recording.ThumbnailURL,
+	}
+}
+
+// resolveMediaSource returns the URL Home Assistant plays a recording from.
+// Browsers only play MP4, other containers are remuxed while downloading.
+func resolveMediaSource(recording *RecordingFile) *PlayMedia {
+	play := &PlayMedia{
+		URL:      recording.DownloadURL + "&inline=true",
+		MimeType: "video/mp4",
+	}
+	if recordingContentType(recording.Path) != "video/mp4" {
+		play.URL += "&transcode=copy"
+	}
+	return play
+}
+
+// mediaSourceStream returns the stream named by the media content ID of a
+// request, for stream scoped tokens
+func mediaSourceStream(query url.Values) (string, bool) {
+	for _, param := range []string{"media_content_id", "resolve"} {
+		if id, err := parseMediaSourceID(query.Get(param)); err == nil && id.stream != "" {
+			return id.stream, true
+		}
+	}
+	return "", false
+}
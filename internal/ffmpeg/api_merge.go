@@ -0,0 +1,235 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// mergeMaxGap is the largest gap between two segments that still counts as
+// contiguous, segment boundaries are rarely exact
+const mergeMaxGap = time.Second * 5
+
+// mergedSuffix marks files written by the merge API, so a later merge of the
+// same range doesn't include them
+const mergedSuffix = "_merged"
+
+// apiRecordingsMerge joins the whole segments of a stream that overlap the
+// time range into one file without re-encoding:
+//
+//	POST /api/recordings/merge?stream=cam1&start=...&end=...[&format=mkv][&delete_segments=true][&allow_gaps=true][&async=true]
+//
+// The merged file is stored next to the recordings and returned as a new
+// recording entry, by the background job with async.
+func apiRecordingsMerge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	streamName := query.Get("stream")
+	if streamName == "" {
+		http.Error(w, "Missing 'stream' parameter", http.StatusBadRequest)
+		return
+	}
+
+	start, err := parseTimeParam(query.Get("start"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'start' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	end, err := parseTimeParam(query.Get("end"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'end' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !end.After(start) {
+		http.Error(w, "'end' must be after 'start'", http.StatusBadRequest)
+		return
+	}
+
+	segments := mergeSegments(streamName, start, end)
+	if len(segments) < 2 {
+		http.Error(w, "Less than two finished recordings found for the requested range", http.StatusNotFound)
+		return
+	}
+
+	for _, segment := range segments {
+		if segment.Imported {
+			http.Error(w, "Imported recordings are read-only, the merged file would be written next to them", http.StatusForbidden)
+			return
+		}
+	}
+
+	if query.Get("allow_gaps") != "true" {
+		if gap := findSegmentGap(segments); gap != "" {
+			http.Error(w, "Recordings are not contiguous: "+gap, http.StatusConflict)
+			return
+		}
+	}
+
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(segments[0].Path), ".")
+	}
+	if !slices.Contains(exportFormats, format) {
+		http.Error(w, "Unsupported 'format', use "+strings.Join(exportFormats, ", "), http.StatusBadRequest)
+		return
+	}
+
+	deleteSegments := query.Get("delete_segments") == "true"
+
+	if query.Get("async") == "true" {
+		job, err := jobs.submit(JobMerge, streamName, func(ctx context.Context, progress func(float64)) (any, error) {
+			return mergeStream(ctx, streamName, segments, format, deleteSegments, progress)
+		})
+		respondJob(w, job, err)
+		return
+	}
+
+	response, err := mergeStream(r.Context(), streamName, segments, format, deleteSegments, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.ResponseJSON(w, response)
+}
+
+// mergeStream merges the segments into a new recording and returns the
+// response of the merge API
+func mergeStream(ctx context.Context, streamName string, segments []RecordingFile, format string, deleteSegments bool, progress func(float64)) (map[string]any, error) {
+	first, last := segments[0], segments[len(segments)-1]
+
+	// Next to the first segment, with its start time so listings sort it in
+	path := strings.TrimSuffix(first.Path, filepath.Ext(first.Path))
+	output := uniqueRecordingPath(path + mergedSuffix + "." + format)
+
+	if err := mergeRecordings(ctx, segments, output, progress); err != nil {
+		_ = os.Remove(output)
+		log.Error().Err(err).Str("stream", streamName).Msg("[api] merging recordings failed")
+		return nil, fmt.Errorf("failed to merge recordings: %w", err)
+	}
+
+	// Recording times are derived from the file name and modification time
+	if !last.EndTime.IsZero() {
+		_ = os.Chtimes(output, time.Now(), last.EndTime)
+	}
+
+	if encryptionEnabled() {
+		if err := encryptRecordingFile(output); err != nil {
+			log.Error().Err(err).Str("file", output).Msg("[encryption] failed to encrypt merged recording")
+		}
+	}
+
+	recordingIndex.Update(output)
+	merged := recordingIndex.GetByPath(output)
+	if merged == nil {
+		return nil, errors.New("merged recording not found")
+	}
+
+	log.Info().
+		Str("stream", streamName).
+		Int("segments", len(segments)).
+		Str("output", output).
+		Msg("[api] merged recordings")
+
+	response := map[string]any{
+		"recording": merged,
+		"segments":  len(segments),
+	}
+
+	if deleteSegments {
+		response["deleted"] = deleteRecordings(segments, false)
+	}
+
+	return response, nil
+}
+
+// mergeSegments returns the finished continuous recordings of a stream that
+// overlap the range, oldest first
+func mergeSegments(streamName string, start, end time.Time) []RecordingFile {
+	var segments []RecordingFile
+	for _, recording := range findRecordingsInRange(streamName, start, end) {
+		if isEventRecordingFile(recording.Path) || isMergedRecordingFile(recording.Path) {
+			continue
+		}
+		if info, err := os.Stat(recording.Path); err != nil || time.Since(info.ModTime()) < time.Minute {
+			continue // still being written
+		}
+		segments = append(segments, recording)
+	}
+	return segments
+}
+
+// findSegmentGap describes the first gap between segments, if any
+func findSegmentGap(segments []RecordingFile) string {
+	for i := 1; i < len(segments); i++ {
+		prevEnd := segments[i-1].EndTime
+		if prevEnd.IsZero() {
+			continue
+		}
+		if gap := segments[i].StartTime.Sub(prevEnd); gap > mergeMaxGap {
+			return fmt.Sprintf("%s missing after %s", gap.Round(time.Second), prevEnd.Format(time.RFC3339))
+		}
+	}
+	return ""
+}
+
+// mergeRecordings joins the segments with the concat demuxer and copies the
+// streams, so the result has the quality of the originals
+func mergeRecordings(ctx context.Context, segments []RecordingFile, output string, progress func(float64)) error {
+	list := output + ".txt"
+
+	var sb strings.Builder
+	var inputs []string
+	for _, segment := range segments {
+		path, err := filepath.Abs(segment.Path)
+		if err != nil {
+			return err
+		}
+		path = recordingInput(path)
+		inputs = append(inputs, path)
+		sb.WriteString("file '" + strings.ReplaceAll(path, "'", `'\''`) + "'\n")
+	}
+	if err := os.WriteFile(list, []byte(sb.String()), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(list)
+
+	args := []string{"-hide_banner", "-v", "error"}
+	args = append(args, ffmpegProtocolArgs(inputs...)...)
+	args = append(args,
+		"-f", "concat", "-safe", "0",
+		"-i", list,
+		"-map", "0", "-c", "copy",
+	)
+	switch filepath.Ext(output) {
+	case ".mp4", ".mov":
+		args = append(args, "-movflags", "+faststart")
+	}
+	args = append(args, "-y", output)
+
+	// Unknown for a segment still without its end time
+	var duration time.Duration
+	if last := segments[len(segments)-1]; !last.EndTime.IsZero() {
+		duration = last.EndTime.Sub(segments[0].StartTime)
+	}
+
+	return runFFmpeg(ctx, args, duration, progress)
+}
+
+// isMergedRecordingFile reports whether the file was written by the merge API
+func isMergedRecordingFile(path string) bool {
+	return strings.Contains(filepath.Base(path), mergedSuffix)
+}
@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/api"
-	"github.com/AlexxIT/go2rtc/internal/streams"
 )
 
 func apiRecord(w http.ResponseWriter, r *http.Request) {
@@ -19,7 +18,11 @@ func apiRecord(w http.ResponseWriter, r *http.Request) {
 	case "GET":
 		handleGetRecordings(w, r, query)
 	case "POST":
-		handleStartRecording(w, r, query)
+		if query.Get("action") != "" {
+			handleRecordingAction(w, r, query)
+		} else {
+			handleStartRecording(w, r, query)
+		}
 	case "DELETE":
 		handleStopRecording(w, r, query)
 	default:
@@ -71,107 +74,69 @@ func handleStartRecording(w http.ResponseWriter, r *http.Request, query url.Valu
 		return
 	}
 	
-	// Check if stream exists
-	stream := streams.Get(streamName)
-	if stream == nil {
-		http.Error(w, fmt.Sprintf("Stream '%s' not found", streamName), http.StatusNotFound)
-		return
-	}
-	
-	// Parse recording configuration
-	config := RecordConfig{}
+	// The same as a POST to /api/v1/recordings
+	req := recordRequest{Stream: streamName, ID: query.Get("id")}
 	
 	// Required: filename
-	config.Filename = query.Get("filename")
-	if config.Filename == "" {
-		// Generate default filename with timestamp
-		timestamp := time.Now().Format("2006-01-02_15-04-05")
-		config.Filename = fmt.Sprintf("recordings/%s_%s.mp4", streamName, timestamp)
-	}
+	req.Config.Filename = query.Get("filename")
 	
 	// Optional: format (auto-detected from extension if not specified)
-	config.Format = query.Get("format")
+	req.Config.Format = query.Get("format")
 	
 	// Optional: duration limit
 	if durationStr := query.Get("duration"); durationStr != "" {
 		if duration, err := time.ParseDuration(durationStr); err == nil {
-			config.Duration = duration
+			req.Config.Duration = duration
 		} else {
 			// Try parsing as seconds
 			if seconds, err := strconv.Atoi(durationStr); err == nil {
-				config.Duration = time.Duration(seconds) * time.Second
+				req.Config.Duration = time.Duration(seconds) * time.Second
 			}
 		}
 	}
 	
+	// Optional: pre-roll from the stream's pre-record buffer (single-file recordings only)
+	if pre, err := parseDurationParam(query.Get("pre")); err == nil && pre > 0 {
+		req.Config.PreRoll = pre
+	}
+	
 	// Optional: video codec (will use global config default if not specified)
-	config.Video = query.Get("video")
+	req.Config.Video = query.Get("video")
 	
 	// Optional: audio codec (will use global config default if not specified)  
-	config.Audio = query.Get("audio")
+	req.Config.Audio = query.Get("audio")
 	
-	// Check for segmented recording
-	useSegments := query.Get("segments") == "true" || GlobalRecordingConfig.EnableSegments
+	// Check for segmented recording, enable_segments otherwise
+	if query.Get("segments") == "true" {
+		segments := true
+		req.Segments = &segments
+	}
 	
-	// Generate recording ID
-	recordingID := fmt.Sprintf("%s_%d", streamName, time.Now().Unix())
-	if customID := query.Get("id"); customID != "" {
-		recordingID = customID
+	response, err := startRecording(req)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
 	
-	log.Info().
-		Str("stream", streamName).
-		Str("recording_id", recordingID).
-		Bool("use_segments", useSegments).
-		Msg("[api] starting recording via API")
+	api.ResponseJSON(w, response)
+}
 
-	// Start recording (segmented or regular)
-	var response map[string]interface{}
-	
-	if useSegments {
-		// Start segmented recording
-		if err := GetSegmentedRecordingManager().StartSegmentedRecording(recordingID, streamName, config); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to start segmented recording: %v", err), http.StatusInternalServerError)
-			return
-		}
-		
-		// Get the segmented recording for response
-		segRecording := GetSegmentedRecordingManager().GetSegmentedRecording(recordingID)
-		if segRecording == nil {
-			http.Error(w, "Segmented recording not found after creation", http.StatusInternalServerError)
-			return
-		}
-		
-		response = map[string]interface{}{
-			"id":     recordingID,
-			"stream": streamName,
-			"type":   "segmented",
-			"config": config,
-			"status": segRecording.GetStatus(),
-		}
-	} else {
-		// Start regular recording
-		if err := GetRecordingManager().StartRecording(recordingID, streamName, config); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to start recording: %v", err), http.StatusInternalServerError)
-			return
-		}
-		
-		// Get the recording for response
-		recording := GetRecordingManager().GetRecording(recordingID)
-		if recording == nil {
-			http.Error(w, "Recording not found after creation", http.StatusInternalServerError)
-			return
-		}
-		
-		response = map[string]interface{}{
-			"id":     recordingID,
-			"stream": streamName,
-			"type":   "single",
-			"config": config,
-			"status": recording.GetStatus(),
-		}
+// handleRecordingAction changes the state of a running recording:
+//
+//	POST /api/record?id=ID&action=pause|resume|rotate
+func handleRecordingAction(w http.ResponseWriter, r *http.Request, query url.Values) {
+	recordingID := query.Get("id")
+	if recordingID == "" {
+		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+		return
 	}
-	
+
+	response, err := changeRecordingState(recordingID, query.Get("action"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
 	api.ResponseJSON(w, response)
 }
 
@@ -182,27 +147,35 @@ func handleStopRecording(w http.ResponseWriter, r *http.Request, query url.Value
 		return
 	}
 	
-	// Try stopping regular recording first
-	err := GetRecordingManager().StopRecording(recordingID)
-	if err != nil {
-		// If not found, try stopping segmented recording
-		err = GetSegmentedRecordingManager().StopSegmentedRecording(recordingID)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Recording not found: %v", err), http.StatusNotFound)
-			return
-		}
+	if err := stopRecording(recordingID); err != nil {
+		writeError(w, err)
+		return
 	}
 	
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
 }
 
+// apiRecordErrors returns the last recording error of each stream, cleared
+// when a recording of the stream starts:
+//
+//	GET /api/record/errors[?stream=cam1][&code=camera_unreachable]
 func apiRecordErrors(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	api.ResponseJSON(w, GetStreamErrors())
+
+	query := r.URL.Query()
+	streamName, code := query.Get("stream"), query.Get("code")
+
+	streamErrs := GetStreamErrors()
+	for name, streamErr := range streamErrs {
+		if (streamName != "" && name != streamName) || (code != "" && streamErr.Code != code) {
+			delete(streamErrs, name)
+		}
+	}
+	api.ResponseJSON(w, streamErrs)
 }
 
 func apiRecordConfigured(w http.ResponseWriter, r *http.Request) {
@@ -210,8 +183,9 @@ func apiRecordConfigured(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	names := make([]string, 0, len(GlobalRecordingConfig.Streams))
-	for name := range GlobalRecordingConfig.Streams {
+	configured := configuredStreams(GetRecordingConfig())
+	names := make([]string, 0, len(configured))
+	for name := range configured {
 		names = append(names, name)
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -231,7 +205,19 @@ func apiRecordingStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add configuration info
-	stats["config"] = GlobalRecordingConfig
+	stats["config"] = GetRecordingConfig()
+	stats["disk"] = diskMonitor.Status()
+	stats["quota_exceeded"] = quotaMonitor.Exceeded()
+	stats["listing_cache"] = recordingListings.Stats()
+	stats["auto_record_failures"] = GetAutoRecordFailures()
+	stats["ffmpeg"] = ffmpegStatus()
+	stats["limits"] = map[string]any{
+		"processes": recordingProcesses.status(),
+		"starts":    recordingStarts.status(),
+	}
+	if faststartJobs != nil {
+		stats["faststart"] = faststartJobs.Status()
+	}
 
 	api.ResponseJSON(w, stats)
 }
@@ -262,6 +248,7 @@ func apiRecordingCleanup(w http.ResponseWriter, r *http.Request) {
 		"timestamp":         time.Now(),
 		"files_deleted":     result.FilesDeleted,
 		"files_archived":    result.FilesArchived,
+		"files_tiered":      result.FilesTiered,
 		"space_reclaimed_mb": result.SpaceReclaimed,
 		"total_size_before_mb": result.TotalSizeBefore,
 		"total_size_after_mb":  result.TotalSizeAfter,
@@ -270,12 +257,53 @@ func apiRecordingCleanup(w http.ResponseWriter, r *http.Request) {
 		"details": map[string]interface{}{
 			"deleted_files":  result.DeletedFiles,
 			"archived_files": result.ArchivedFiles,
+			"tiered_files":   result.TieredFiles,
 		},
 	}
 
 	api.ResponseJSON(w, response)
 }
 
+// apiRecordingsCleanup deletes recordings older than a number of days,
+// ignoring retention and minimum file counts. Protected recordings are kept.
+// With dry_run nothing is deleted and the result lists what would be:
+//
+//	POST /api/recordings/cleanup?older_than=30[&dry_run=true]
+func apiRecordingsCleanup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	olderThan, err := strconv.Atoi(query.Get("older_than"))
+	if err != nil || olderThan <= 0 {
+		http.Error(w, "Invalid 'older_than' parameter, use a number of days", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := query.Get("dry_run") == "true"
+
+	log.Info().
+		Int("older_than_days", olderThan).
+		Bool("dry_run", dryRun).
+		Str("remote", r.RemoteAddr).
+		Msg("[api] cleanup requested")
+
+	result, err := ForceCleanupOldRecordings(olderThan, dryRun)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cleanup failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	api.ResponseJSON(w, struct {
+		*CleanupResult
+		OlderThanDays int  `json:"older_than_days"`
+		DryRun        bool `json:"dry_run"`
+	}{result, olderThan, dryRun})
+}
+
 func handleForceCleanup(w http.ResponseWriter, r *http.Request, query url.Values) {
 	// Parse parameters
 	olderThanDays := 3 // Default to 3 days
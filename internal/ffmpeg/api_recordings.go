@@ -1,19 +1,22 @@
 package ffmpeg
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 	
+	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/streams"
 )
 
@@ -27,6 +30,7 @@ type RecordingFile struct {
 	Size            int64     `json:"size"`
 	SizeHuman       string    `json:"size_human"`
 	Duration        string    `json:"duration,omitempty"`
+	DurationSeconds float64   `json:"duration_seconds,omitempty"` // probed duration, with ?exact=true
 	StartTime       time.Time `json:"start_time"`
 	EndTime         time.Time `json:"end_time,omitempty"`
 	Format          string    `json:"format"`
@@ -34,7 +38,19 @@ type RecordingFile struct {
 	DownloadURL     string    `json:"download_url"`
 	InfoURL         string    `json:"info_url"`
 	StreamURL       string    `json:"stream_url"`
+	ThumbnailURL    string    `json:"thumbnail_url"`
+	PreviewURL      string    `json:"preview_url,omitempty"`      // animated preview or sprite sheet, with preview_format
+	PreviewVTTURL   string    `json:"preview_vtt_url,omitempty"`  // sprite tile of each time, with preview_format: sprite
 	DetectionLabels []string  `json:"detection_labels,omitempty"` // from .json sidecar
+	Event           bool      `json:"event,omitempty"`            // recorded by an event trigger
+	Upload          *UploadStatus `json:"upload,omitempty"`        // offload to S3-compatible storage
+	Protected       bool      `json:"protected,omitempty"`        // legal hold, excluded from cleanup
+	Health          *RecordingHealth `json:"health,omitempty"`     // integrity check result
+	Tier            string    `json:"tier,omitempty"`             // "cold" once moved to cold_path
+	Archived        bool      `json:"archived,omitempty"`         // moved to archive_path by cleanup
+	Imported        bool      `json:"imported,omitempty"`         // from import_paths, read-only
+	Annotations     []Annotation `json:"annotations,omitempty"`   // bookmarks and notes, by offset
+	Tags            []string  `json:"tags,omitempty"`             // labels set through the API, e.g. "evidence"
 }
 
 // apiRecordings handles recording file listing and download requests
@@ -49,14 +65,56 @@ func apiRecordings(w http.ResponseWriter, r *http.Request) {
 			handleRecordingInfo(w, r, query)
 		} else if query.Get("play") != "" {
 			handleRecordingStream(w, r, query)
+		} else if query.Get("thumbnail") != "" {
+			handleRecordingThumbnail(w, r, query)
+		} else if query.Get("preview") != "" {
+			handleRecordingPreview(w, r, query)
+		} else if query.Get("archived") == "true" {
+			handleListArchivedRecordings(w, r, query)
 		} else {
 			handleListRecordings(w, r, query)
 		}
+	case "POST":
+		if query.Get("protect") != "" || query.Get("unprotect") != "" {
+			handleProtectRecording(w, query)
+		} else if query.Get("repair") != "" {
+			handleRepairRecording(w, query)
+		} else if query.Get("restore") != "" {
+			handleRestoreRecording(w, query)
+		} else {
+			http.Error(w, "Missing 'protect', 'unprotect', 'repair' or 'restore' parameter", http.StatusBadRequest)
+		}
+	case "DELETE":
+		handleDeleteRecordings(w, r, query)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// handleProtectRecording places or lifts a legal hold on a recording:
+//
+//	POST /api/recordings?protect=ID
+//	POST /api/recordings?unprotect=ID
+func handleProtectRecording(w http.ResponseWriter, query map[string][]string) {
+	id, protected := getQueryParam(query, "protect"), true
+	if id == "" {
+		id, protected = getQueryParam(query, "unprotect"), false
+	}
+
+	recording := recordingIndex.setProtected(id, protected)
+	if recording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	// Persist right away, a hold must survive a crash
+	recordingIndex.Save()
+
+	log.Info().Str("recording_id", id).Bool("protected", protected).Msg("[api] recording protection changed")
+
+	api.ResponseJSON(w, recording)
+}
+
 // getQueryParam is a helper function to get the first value from query params
 func getQueryParam(query map[string][]string, key string) string {
 	if values, exists := query[key]; exists && len(values) > 0 {
@@ -70,30 +128,84 @@ func handleListRecordings(w http.ResponseWriter, r *http.Request, query map[stri
 	streamName := getQueryParam(query, "stream")
 	dateFilter := getQueryParam(query, "date") // Format: YYYY-MM-DD
 	limit := 100 // Default limit
-	
+
 	if limitStr := getQueryParam(query, "limit"); limitStr != "" {
 		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
 			limit = parsed
 		}
 	}
-	
-	recordings, err := listRecordingFiles(streamName, dateFilter, limit)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list recordings: %v", err), http.StatusInternalServerError)
+
+	q := RecordingQuery{
+		Stream:    streamName,
+		Date:      dateFilter,
+		Sort:      getQueryParam(query, "sort"),
+		Note:      getQueryParam(query, "note"),
+		Tags:      normalizeTags(query["tag"]),
+		Ascending: getQueryParam(query, "order") == "asc",
+		Limit:     limit,
+	}
+
+	switch q.Sort {
+	case "", "start_time", "size", "duration":
+	default:
+		http.Error(w, "Invalid 'sort' parameter, use start_time, size or duration", http.StatusBadRequest)
 		return
 	}
-	
-	// Group recordings by date for easier navigation
-	grouped := groupRecordingsByDate(recordings)
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"recordings":      recordings,
-		"grouped":         grouped,
+	if order := getQueryParam(query, "order"); order != "" && order != "asc" && order != "desc" {
+		http.Error(w, "Invalid 'order' parameter, use asc or desc", http.StatusBadRequest)
+		return
+	}
+
+	// Time range: recordings overlapping [from, to]
+	for name, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if value := getQueryParam(query, name); value != "" {
+			parsed, err := parseTimeParam(value)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid '%s' parameter: %v", name, err), http.StatusBadRequest)
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	// Offset pagination, a cursor is the next_offset of the previous page
+	offset := getQueryParam(query, "offset")
+	if offset == "" {
+		offset = getQueryParam(query, "cursor")
+	}
+	if offset != "" {
+		parsed, err := strconv.Atoi(offset)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid 'offset' parameter", http.StatusBadRequest)
+			return
+		}
+		q.Offset = parsed
+	}
+
+	recordings, total := recordingIndex.Find(q)
+
+	// Replace filename based duration estimates with probed durations
+	if getQueryParam(query, "exact") == "true" {
+		for i := range recordings {
+			applyExactDuration(&recordings[i])
+		}
+	}
+
+	// The recordings and, for easier navigation, the recordings grouped by
+	// date are added while the response is written
+	response := map[string]interface{}{
 		"count":          len(recordings),
+		"total":          total,
+		"offset":         q.Offset,
+		"limit":          q.Limit,
 		"stream_filter":  streamName,
 		"date_filter":    dateFilter,
-	})
+	}
+	if next := q.Offset + len(recordings); next < total {
+		response["next_offset"] = next
+	}
+
+	respondListing(w, r, recordings, response)
 }
 
 // handleDownloadRecording serves recording files for download
@@ -105,35 +217,48 @@ func handleDownloadRecording(w http.ResponseWriter, r *http.Request, query map[s
 	}
 	
 	// Find the recording file by ID
-	recordings, err := listRecordingFiles("", "", 10000) // Get all recordings to find by ID
+	targetRecording := recordingIndex.Get(recordingID)
+	if targetRecording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+	
+	// Security check: ensure path is within recordings directory
+	if !isReadableRecordingPath(targetRecording.Path) {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+	
+	transcode, err := parseTranscodeOptions(query)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to find recording: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	
-	var targetRecording *RecordingFile
-	for _, recording := range recordings {
-		if recording.ID == recordingID {
-			targetRecording = &recording
-			break
-		}
+	w = throttleDownload(w, r)
+	
+	// Inline mode lets browsers play and seek the file directly in a <video> tag
+	disposition := "attachment"
+	if getQueryParam(query, "inline") == "true" {
+		disposition = "inline"
 	}
 	
-	if targetRecording == nil {
-		http.Error(w, "Recording not found", http.StatusNotFound)
+	if getQueryParam(query, "chapters") == "true" {
+		if transcode != nil {
+			http.Error(w, "'chapters' can't be combined with 'transcode'", http.StatusBadRequest)
+			return
+		}
+		serveRecordingWithChapters(w, r, targetRecording, disposition)
 		return
 	}
 	
-	// Security check: ensure path is within recordings directory
-	basePath := filepath.Clean(GlobalRecordingConfig.BasePath)
-	requestedPath := filepath.Clean(targetRecording.Path)
-	if !strings.HasPrefix(requestedPath, basePath) {
-		http.Error(w, "Access denied", http.StatusForbidden)
+	if transcode != nil {
+		serveTranscodedRecording(w, r, targetRecording, transcode, disposition)
 		return
 	}
 	
-	// Open the file
-	file, err := os.Open(targetRecording.Path)
+	// Open the file, encrypted recordings are decrypted on the fly
+	file, err := openRecording(targetRecording.Path)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to open recording: %v", err), http.StatusInternalServerError)
 		return
@@ -141,19 +266,44 @@ func handleDownloadRecording(w http.ResponseWriter, r *http.Request, query map[s
 	defer file.Close()
 	
 	// Get file info
-	fileInfo, err := file.Stat()
+	fileInfo, err := os.Stat(targetRecording.Path)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get file info: %v", err), http.StatusInternalServerError)
 		return
 	}
 	
-	// Set headers for download
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", targetRecording.Filename))
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
+	w.Header().Set("Content-Type", recordingContentType(targetRecording.Path))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=\"%s\"", disposition, targetRecording.Filename))
+	setFileETag(w, fileInfo)
 	
-	// Stream the file
-	io.Copy(w, file)
+	// ServeContent handles Range requests (206 Partial Content), Accept-Ranges
+	// and the conditional headers
+	http.ServeContent(w, r, targetRecording.Filename, fileInfo.ModTime(), file)
+}
+
+// recordingContentType returns the MIME type for a recording file based on its extension
+func recordingContentType(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".mp4", ".m4v":
+		return "video/mp4"
+	case ".mkv":
+		return "video/x-matroska"
+	case ".webm":
+		return "video/webm"
+	case ".mov":
+		return "video/quicktime"
+	case ".ts":
+		return "video/mp2t"
+	case ".avi":
+		return "video/x-msvideo"
+	case ".flv":
+		return "video/x-flv"
+	case ".wmv":
+		return "video/x-ms-wmv"
+	case ".3gp":
+		return "video/3gpp"
+	}
+	return "application/octet-stream"
 }
 
 // RecordingInfo represents detailed information about a recording file
@@ -196,37 +346,22 @@ func handleRecordingInfo(w http.ResponseWriter, r *http.Request, query map[strin
 	}
 	
 	// Find the recording file by ID
-	recordings, err := listRecordingFiles("", "", 10000) // Get all recordings to find by ID
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to find recording: %v", err), http.StatusInternalServerError)
-		return
-	}
-	
-	var targetRecording *RecordingFile
-	for _, recording := range recordings {
-		if recording.ID == recordingID {
-			targetRecording = &recording
-			break
-		}
-	}
-	
+	targetRecording := recordingIndex.Get(recordingID)
 	if targetRecording == nil {
 		http.Error(w, "Recording not found", http.StatusNotFound)
 		return
 	}
 	
-	// Get detailed info using ffprobe
-	info, err := getRecordingDetailedInfo(targetRecording)
+	// Get detailed info using ffprobe (cached in the index)
+	info, err := getRecordingInfo(targetRecording)
 	if err != nil {
 		log.Warn().Err(err).Str("recording", recordingID).Msg("[recording] failed to get detailed info, returning basic info")
 		// Return basic info if ffprobe fails
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(targetRecording)
+		respondJSONTagged(w, r, targetRecording)
 		return
 	}
 	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(info)
+	respondJSONTagged(w, r, info)
 }
 
 // handleRecordingStream creates a temporary stream source for a recording file
@@ -238,29 +373,40 @@ func handleRecordingStream(w http.ResponseWriter, r *http.Request, query map[str
 	}
 	
 	// Find the recording file by ID
-	recordings, err := listRecordingFiles("", "", 10000)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to find recording: %v", err), http.StatusInternalServerError)
+	targetRecording := recordingIndex.Get(recordingID)
+	if targetRecording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
 		return
 	}
 	
-	var targetRecording *RecordingFile
-	for _, recording := range recordings {
-		if recording.ID == recordingID {
-			targetRecording = &recording
-			break
-		}
-	}
-	
-	if targetRecording == nil {
-		http.Error(w, "Recording not found", http.StatusNotFound)
+	transcode, err := parseTranscodeOptions(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	
 	// Create an exec URL using FFmpeg to stream the file
 	streamName := fmt.Sprintf("recording_%s", recordingID)
 	// Use exec:ffmpeg to stream the file with re-streaming
-	fileURL := fmt.Sprintf("exec:ffmpeg -re -i %s -c copy -f rtsp {output}", targetRecording.Path)
+	fileArgs, err := execArgs(ffmpegBin(), "-re", "-i", recordingInput(targetRecording.Path), "-c", "copy", "-f", "rtsp", "{output}")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fileURL := "exec:" + fileArgs
+	
+	// The stream is already a copy, transcode=copy needs nothing else
+	if transcode != nil && transcode.codec != "copy" {
+		streamName += "_" + transcode.name()
+		if fileURL, err = transcode.source(recordingInput(targetRecording.Path)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !acquireTranscodePlay(streamName) {
+			transcodeUnavailable(w)
+			return
+		}
+	}
 	
 	// Check if stream already exists, if not create it
 	stream := streams.Get(streamName)
@@ -298,94 +444,103 @@ func handleRecordingStream(w http.ResponseWriter, r *http.Request, query map[str
 	json.NewEncoder(w).Encode(response)
 }
 
-// listRecordingFiles scans the recordings directory and returns file information
-func listRecordingFiles(streamFilter, dateFilter string, limit int) ([]RecordingFile, error) {
-	basePath := GlobalRecordingConfig.BasePath
-	var recordings []RecordingFile
-	
-	err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Continue on errors
-		}
-		
-		// Skip directories
-		if info.IsDir() {
-			return nil
-		}
-		
-		// Check if it's a video file
-		ext := strings.ToLower(filepath.Ext(path))
-		if !isVideoFile(ext) {
-			return nil
-		}
-		
-		// Parse recording information from path and filename
-		recording, parseErr := parseRecordingFile(path, info)
-		if parseErr != nil {
-			return nil // Skip files we can't parse
-		}
-		
-		// Apply stream filter
-		if streamFilter != "" && recording.StreamName != streamFilter {
-			return nil
-		}
-		
-		// Apply date filter
-		if dateFilter != "" {
-			recordingDate := recording.StartTime.Format("2006-01-02")
-			if recordingDate != dateFilter {
-				return nil
-			}
-		}
-		
-		recordings = append(recordings, *recording)
-		
-		// Apply limit
-		if len(recordings) >= limit {
-			return filepath.SkipDir // Stop walking
-		}
-		
-		return nil
-	})
-	
+// newRecordingFile builds recording metadata from a file on disk
+func newRecordingFile(id, filePath string, size int64, modTime time.Time) (*RecordingFile, error) {
+	name, err := nameRecording(filePath, modTime)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Sort by start time (newest first)
-	sort.Slice(recordings, func(i, j int) bool {
-		return recordings[i].StartTime.After(recordings[j].StartTime)
-	})
-	
-	return recordings, nil
+	recording := name.recordingFile(id, filePath, size, modTime)
+	recording.DetectionLabels = loadDetectionLabels(filePath)
+	return recording, nil
 }
 
-// parseRecordingFile extracts metadata from a recording file
-func parseRecordingFile(filePath string, info os.FileInfo) (*RecordingFile, error) {
-	basePath := GlobalRecordingConfig.BasePath
-	relativePath, err := filepath.Rel(basePath, filePath)
-	if err != nil {
-		return nil, err
+// buildRecordingFile derives the recording metadata from its file
+func buildRecordingFile(id, filePath, relativePath string, size int64, modTime time.Time) *RecordingFile {
+	recording := parseRecordingName(filePath, relativePath, modTime).recordingFile(id, filePath, size, modTime)
+	recording.DetectionLabels = loadDetectionLabels(filePath)
+	return recording
+}
+
+// recordingName is what the path of a recording tells about it. The index
+// keeps it with each entry, so listings filter without parsing every path.
+type recordingName struct {
+	relativePath string
+	stream       string
+	start, end   time.Time // from the filename, or the mod time
+	imported     bool
+	cold         bool
+}
+
+// nameRecording parses the path of a recording in a storage pool or import path
+func nameRecording(filePath string, modTime time.Time) (*recordingName, error) {
+	pool, relativePath, ok := storagePoolRel(filePath)
+	if !ok {
+		// Read-only recordings of import_paths
+		if imp, rel, ok := importPathRel(filePath); ok {
+			name := parseRecordingName(filePath, rel, modTime)
+			name.imported = true
+			if imp.Stream != "" {
+				name.stream = imp.Stream
+			}
+			return name, nil
+		}
+		return nil, fmt.Errorf("%s is outside the recordings directories", filePath)
 	}
-	
-	filename := info.Name()
+
+	name := parseRecordingName(filePath, relativePath, modTime)
+	name.cold = pool == GetRecordingConfig().ColdPath
+	return name, nil
+}
+
+// parseRecordingName reads the stream and start time from the path
+func parseRecordingName(filePath, relativePath string, modTime time.Time) *recordingName {
+	filename := filepath.Base(filePath)
 	
 	// Extract stream name from path or filename
 	streamName := extractStreamName(filePath, filename)
 	
 	// Extract timestamp from filename (prefer this over file mod time)
-	startTime, endTime := extractTimeFromFilename(filename, info.ModTime())
+	startTime, endTime := extractTimeFromFilename(filename, modTime)
+	
+	// filename_patterns for recordings written by other tools win
+	if parsed, ok := matchFilenamePatterns(recordingFilenamePatterns(filePath), relativePath); ok {
+		if parsed.stream != "" {
+			streamName = parsed.stream
+		}
+		if !parsed.start.IsZero() {
+			startTime, endTime = parsed.start, parsed.end
+			if endTime.IsZero() {
+				endTime = startTime.Add(estimateDuration(filename))
+			}
+		}
+	}
+
+	return &recordingName{relativePath: relativePath, stream: streamName, start: startTime, end: endTime}
+}
+
+// span returns the start and end of the recording, no end while it is
+// still being written
+func (n *recordingName) span(size int64, modTime time.Time) (time.Time, time.Time) {
+	if isActiveRecording(size, modTime) {
+		return n.start, time.Time{}
+	}
+	return n.start, n.end
+}
+
+// recordingFile builds the recording metadata, without detection labels
+func (n *recordingName) recordingFile(id, filePath string, size int64, modTime time.Time) *RecordingFile {
+	filename := filepath.Base(filePath)
+	relativePath, streamName := n.relativePath, n.stream
+	startTime, endTime := n.start, n.end
 	
 	// Check if file is currently being written to (active recording)
-	isActive := isActiveRecording(filePath, info)
+	isActive := isActiveRecording(size, modTime)
 	if isActive {
 		// For active recordings, don't set an end time
 		endTime = time.Time{}
 	}
 	
-	// Generate unique ID for this recording
-	id := generateRecordingID(filePath, startTime)
-	
 	// Determine format
 	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
 	
@@ -415,8 +570,8 @@ func parseRecordingFile(filePath string, info os.FileInfo) (*RecordingFile, erro
 		Filename:     filename,
 		Path:         filePath,
 		RelativePath: relativePath,
-		Size:         info.Size(),
-		SizeHuman:    formatFileSize(info.Size()),
+		Size:         size,
+		SizeHuman:    formatFileSize(size),
 		Duration:     durationStr,
 		StartTime:    startTime,
 		EndTime:      endTime,
@@ -425,10 +580,22 @@ func parseRecordingFile(filePath string, info os.FileInfo) (*RecordingFile, erro
 		DownloadURL:  fmt.Sprintf("/api/recordings?download=%s", id),
 		InfoURL:      fmt.Sprintf("/api/recordings?info=%s", id),
 		StreamURL:    fmt.Sprintf("stream.html?src=recording_%s", id),
-		DetectionLabels: loadDetectionLabels(filePath),
+		ThumbnailURL: fmt.Sprintf("/api/recordings?thumbnail=%s", id),
+		Event:        isEventRecordingFile(filePath),
+		Imported:     n.imported,
+	}
+	if n.cold {
+		recording.Tier = TierCold
 	}
 	
-	return recording, nil
+	if previewsEnabled() {
+		recording.PreviewURL = fmt.Sprintf("/api/recordings?preview=%s", id)
+		if GetRecordingConfig().PreviewFormat == PreviewSprite {
+			recording.PreviewVTTURL = recording.PreviewURL + "&vtt=true"
+		}
+	}
+	
+	return recording
 }
 
 // extractStreamName tries to extract stream name from path components
@@ -437,16 +604,11 @@ func extractStreamName(filePath, filename string) string {
 	parts := strings.Split(filepath.Dir(filePath), string(filepath.Separator))
 	
 	// Look for stream name in path components (skip common directory names)
-	skipDirs := map[string]bool{
-		"recordings": true,
-		"archive":    true,
-		"security":   true,
-		"indoor":     true,
-	}
+	skipDirs := GetRecordingConfig().StreamSkipDirs
 	
 	for i := len(parts) - 1; i >= 0; i-- {
 		part := parts[i]
-		if part != "" && !isDateComponent(part) && !skipDirs[part] {
+		if part != "" && !isDateComponent(part) && !slices.Contains(skipDirs, part) {
 			return part
 		}
 	}
@@ -459,6 +621,23 @@ func extractStreamName(filePath, filename string) string {
 	return "unknown"
 }
 
+var (
+	timestampSuffixRegexp = regexp.MustCompile(`_\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}$`)
+	partSuffixRegexp      = regexp.MustCompile(`_part\d+$`)
+	dateComponentRegexp   = regexp.MustCompile(`^(\d{4}|\d{1,2})$`) // Year, month, day
+
+	timestampRegexps = []*regexp.Regexp{
+		regexp.MustCompile(`(\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2})`), // 2025-01-01_12-00-00
+		regexp.MustCompile(`(\d{4}\d{2}\d{2}_\d{2}\d{2}\d{2})`),     // 20250101_120000
+		regexp.MustCompile(`(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2})`), // 2025-01-01T12:00:00
+	}
+	timestampFormats = []string{
+		"2006-01-02_15-04-05",
+		"20060102_150405",
+		"2006-01-02T15:04:05",
+	}
+)
+
 // extractStreamFromFilename tries to extract stream name from filename
 func extractStreamFromFilename(filename string) string {
 	// Remove extension
@@ -470,8 +649,8 @@ func extractStreamFromFilename(filename string) string {
 	// camera1_part001_2025-01-01_12-00-00
 	
 	// Remove common suffixes
-	baseName = regexp.MustCompile(`_\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}$`).ReplaceAllString(baseName, "")
-	baseName = regexp.MustCompile(`_part\d+$`).ReplaceAllString(baseName, "")
+	baseName = timestampSuffixRegexp.ReplaceAllString(baseName, "")
+	baseName = partSuffixRegexp.ReplaceAllString(baseName, "")
 	
 	if baseName != "" {
 		return baseName
@@ -486,23 +665,10 @@ func extractTimeFromFilename(filename string, fallback time.Time) (time.Time, ti
 	baseName := strings.TrimSuffix(filename, filepath.Ext(filename))
 	
 	// Look for timestamp patterns
-	patterns := []string{
-		`(\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2})`,         // 2025-01-01_12-00-00
-		`(\d{4}\d{2}\d{2}_\d{2}\d{2}\d{2})`,             // 20250101_120000
-		`(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2})`,         // 2025-01-01T12:00:00
-	}
-	
-	timeFormats := []string{
-		"2006-01-02_15-04-05",
-		"20060102_150405",
-		"2006-01-02T15:04:05",
-	}
-	
-	for i, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
+	for i, re := range timestampRegexps {
 		matches := re.FindStringSubmatch(baseName)
 		if len(matches) > 1 {
-			if parsedTime, err := time.ParseInLocation(timeFormats[i], matches[1], time.Local); err == nil {
+			if parsedTime, err := time.ParseInLocation(timestampFormats[i], matches[1], templateLocation()); err == nil {
 				// For segmented recordings, assume duration based on filename or default
 				duration := estimateDuration(filename)
 				endTime := parsedTime.Add(duration)
@@ -516,16 +682,15 @@ func extractTimeFromFilename(filename string, fallback time.Time) (time.Time, ti
 }
 
 // isActiveRecording checks if a recording file is currently being written to
-func isActiveRecording(filePath string, info os.FileInfo) bool {
+func isActiveRecording(size int64, modTime time.Time) bool {
 	// Check if file was modified recently (within last 2 minutes)
 	// This indicates it might be an active recording
-	modTime := info.ModTime()
 	if time.Since(modTime) < 2*time.Minute {
 		return true
 	}
 	
 	// Additional check: very small files might be just starting
-	if info.Size() < 1024*1024 { // Less than 1MB
+	if size < 1024*1024 { // Less than 1MB
 		return true
 	}
 	
@@ -537,7 +702,7 @@ func estimateDuration(filename string) time.Duration {
 	// Look for segment indicators
 	if strings.Contains(filename, "_part") {
 		// Assume segment duration from global config
-		return GlobalRecordingConfig.SegmentDuration
+		return GetRecordingConfig().SegmentDuration
 	}
 	
 	// Default assumption for non-segmented files
@@ -546,38 +711,95 @@ func estimateDuration(filename string) time.Duration {
 
 // isDateComponent checks if a string looks like a date component
 func isDateComponent(s string) bool {
-	patterns := []string{`^\d{4}$`, `^\d{2}$`, `^\d{1,2}$`} // Year, month, day
-	for _, pattern := range patterns {
-		if matched, _ := regexp.MatchString(pattern, s); matched {
-			return true
-		}
-	}
-	return false
+	return dateComponentRegexp.MatchString(s)
+}
+
+var videoExtensions = map[string]bool{
+	".mp4":  true,
+	".mkv":  true,
+	".avi":  true,
+	".mov":  true,
+	".wmv":  true,
+	".flv":  true,
+	".webm": true,
+	".m4v":  true,
+	".3gp":  true,
+	".ts":   true,
 }
 
 // isVideoFile checks if the file extension indicates a video file
 func isVideoFile(ext string) bool {
-	videoExtensions := map[string]bool{
-		".mp4":  true,
-		".mkv":  true,
-		".avi":  true,
-		".mov":  true,
-		".wmv":  true,
-		".flv":  true,
-		".webm": true,
-		".m4v":  true,
-		".3gp":  true,
-		".ts":   true,
-	}
 	return videoExtensions[ext]
 }
 
-// generateRecordingID generates a unique ID for a recording
-func generateRecordingID(filePath string, startTime time.Time) string {
-	// Use a combination of path hash and timestamp for uniqueness
-	pathHash := fmt.Sprintf("%x", []byte(filePath))[:8]
-	timeStr := startTime.Format("20060102150405")
-	return fmt.Sprintf("%s_%s", pathHash, timeStr)
+// generateRecordingID derives the ID of a recording from its path relative
+// to the recordings directory, so it stays the same across restarts, index
+// rebuilds and changes of the file's timestamps
+func generateRecordingID(filePath string) string {
+	key := filePath
+	if _, rel, ok := storagePoolRel(filePath); ok {
+		key = rel
+	}
+	return recordingIDFromRel(key)
+}
+
+// recordingIDFromRel returns the ID for a path relative to its storage directory
+func recordingIDFromRel(rel string) string {
+	sum := sha1.Sum([]byte(filepath.ToSlash(rel)))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// apiRecordingLookup finds a recording by its path, for systems that store
+// paths rather than IDs:
+//
+//	GET /api/recordings/lookup?path=cam1/2025-01-01/cam1_2025-01-01_12-00-00.mp4[&redirect=true]
+//
+// The path is relative to the recordings directory. With redirect the client
+// is sent straight to the download.
+func apiRecordingLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		http.Error(w, "Missing 'path' parameter", http.StatusBadRequest)
+		return
+	}
+
+	recording := lookupRecordingPath(path)
+	if recording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	if query.Get("redirect") == "true" {
+		http.Redirect(w, r, "../recordings?download="+url.QueryEscape(recording.ID), http.StatusFound)
+		return
+	}
+
+	api.ResponseJSON(w, recording)
+}
+
+// lookupRecordingPath returns the indexed recording at a path relative to one
+// of the storage pools or import paths, or an absolute one inside them
+func lookupRecordingPath(path string) *RecordingFile {
+	var candidates []string
+	for _, pool := range append(storagePools(), importRoots()...) {
+		candidates = append(candidates, filepath.Join(pool, filepath.FromSlash(path)))
+	}
+
+	for _, candidate := range append(candidates, path) {
+		if !isReadableRecordingPath(candidate) {
+			continue
+		}
+		if recording := recordingIndex.GetByPath(filepath.Clean(candidate)); recording != nil {
+			return recording
+		}
+	}
+	return nil
 }
 
 // formatFileSize converts bytes to human-readable format
@@ -597,9 +819,7 @@ func formatFileSize(bytes int64) string {
 // loadDetectionLabels reads the .json sidecar for a recording and returns
 // the unique labels found, or nil if no sidecar exists yet.
 func loadDetectionLabels(filePath string) []string {
-	ext := filepath.Ext(filePath)
-	sidecar := strings.TrimSuffix(filePath, ext) + ".json"
-	data, err := os.ReadFile(sidecar)
+	data, err := os.ReadFile(detectionSidecar(filePath))
 	if err != nil {
 		return nil
 	}
@@ -612,16 +832,19 @@ func loadDetectionLabels(filePath string) []string {
 	return result.Labels
 }
 
-// groupRecordingsByDate groups recordings by date for easier navigation
-func groupRecordingsByDate(recordings []RecordingFile) map[string][]RecordingFile {
-	grouped := make(map[string][]RecordingFile)
-	
-	for _, recording := range recordings {
-		date := recording.DateGroup
-		grouped[date] = append(grouped[date], recording)
+// detectionSidecar returns the path of the .json sidecar of a recording
+func detectionSidecar(filePath string) string {
+	return strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".json"
+}
+
+// sidecarModTime returns the mod time of the detection sidecar of a
+// recording, zero if it has none
+func sidecarModTime(filePath string) time.Time {
+	info, err := os.Stat(detectionSidecar(filePath))
+	if err != nil {
+		return time.Time{}
 	}
-	
-	return grouped
+	return info.ModTime()
 }
 
 // getRecordingDetailedInfo uses ffprobe to extract detailed media information
@@ -632,12 +855,12 @@ func getRecordingDetailedInfo(recording *RecordingFile) (*RecordingInfo, error)
 	}
 	
 	// Use ffprobe to get detailed information
-	cmd := exec.Command("ffprobe", 
+	cmd := exec.Command(ffprobeBin(), 
 		"-v", "quiet",
 		"-print_format", "json", 
 		"-show_format", 
 		"-show_streams",
-		recording.Path,
+		recordingInput(recording.Path),
 	)
 	
 	output, err := cmd.Output()
@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// apiRecordingReport returns the daily report of a day, yesterday by
+// default, as JSON or HTML:
+//
+//	GET /api/recordings/report?date=2025-01-15[&stream=cam1][&format=html]
+//
+// Written reports are returned as written, other days are summarised on
+// request.
+func apiRecordingReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	date := query.Get("date")
+	if date == "" {
+		date = time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'date' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	streamName := query.Get("stream")
+
+	report := loadDailyReport(date)
+	if report != nil && streamName != "" {
+		report = report.only(streamName)
+	}
+	if report == nil {
+		report = buildDailyReport(day, streamName)
+	}
+
+	switch query.Get("format") {
+	case "", "json":
+		api.ResponseJSON(w, report)
+	case "html":
+		html, err := report.html()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(html)
+	default:
+		http.Error(w, "Invalid 'format' parameter, expected json or html", http.StatusBadRequest)
+	}
+}
@@ -3,6 +3,9 @@ package ffmpeg
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,6 +13,10 @@ import (
 type ScheduleInfo struct {
 	StreamName    string    `json:"stream_name"`
 	Schedule      string    `json:"schedule"`
+	Schedules     []string  `json:"schedules"`
+	Blackouts     []string  `json:"blackouts,omitempty"`
+	Stop          string    `json:"stop,omitempty"`
+	Timezone      string    `json:"timezone"`
 	Duration      string    `json:"duration"`
 	NextRun       time.Time `json:"next_run"`
 	ActiveID      string    `json:"active_id,omitempty"`
@@ -40,7 +47,11 @@ func handleGetSchedules(w http.ResponseWriter, r *http.Request, query map[string
 	for streamName, schedule := range schedules {
 		info := ScheduleInfo{
 			StreamName:  streamName,
-			Schedule:    schedule.Schedule,
+			Schedule:    schedule.Schedules[0],
+			Schedules:   schedule.Schedules,
+			Blackouts:   schedule.Blackouts,
+			Stop:        schedule.Stop,
+			Timezone:    schedule.location.String(),
 			Duration:    schedule.Duration.String(),
 			NextRun:     schedule.NextRun,
 			ActiveID:    schedule.ActiveID,
@@ -64,11 +75,14 @@ func handleAddSchedule(w http.ResponseWriter, r *http.Request, query map[string]
 		return
 	}
 	
-	scheduleStr := getQueryParam(query, "schedule")
-	if scheduleStr == "" {
+	// Both may be repeated, e.g. schedule=mon-fri 08:00-18:00&blackout=12:00-13:00
+	schedules := query["schedule"]
+	if len(schedules) == 0 || schedules[0] == "" {
 		http.Error(w, "schedule parameter required", http.StatusBadRequest)
 		return
 	}
+	blackouts := query["blackout"]
+	stop := getQueryParam(query, "stop")
 	
 	// Parse duration (default to 1 hour)
 	duration := time.Hour
@@ -81,18 +95,28 @@ func handleAddSchedule(w http.ResponseWriter, r *http.Request, query map[string]
 		}
 	}
 	
+	timezone := getQueryParam(query, "timezone")
+
 	// Add schedule
-	if err := AddSchedule(streamName, scheduleStr, duration); err != nil {
+	if err := AddSchedule(streamName, schedules, blackouts, stop, duration, timezone); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	
+	// Kept across restarts unless persist=false
+	if getQueryParam(query, "persist") != "false" {
+		persistSchedule(streamName)
+	}
+	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"message": "Schedule added successfully",
 		"stream":  streamName,
-		"schedule": scheduleStr,
+		"schedules": schedules,
+		"blackouts": blackouts,
+		"stop":     stop,
+		"timezone": timezone,
 		"duration": duration.String(),
 	})
 }
@@ -106,6 +130,7 @@ func handleRemoveSchedule(w http.ResponseWriter, r *http.Request, query map[stri
 	}
 	
 	RemoveSchedule(streamName)
+	saveSchedules()
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -121,7 +146,48 @@ func apiSchedulerTest(w http.ResponseWriter, r *http.Request) {
 	
 	scheduleStr := getQueryParam(query, "schedule")
 	if scheduleStr == "" {
-		http.Error(w, "schedule parameter required", http.StatusBadRequest)
+		handleSchedulePreview(w, query)
+		return
+	}
+	
+	location, err := scheduleLocation(getQueryParam(query, "timezone"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"valid": false,
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	if isScheduleWindow(scheduleStr) {
+		window, err := parseScheduleWindow(scheduleStr)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"valid": false,
+				"error": err.Error(),
+			})
+			return
+		}
+		
+		// Calculate next few starts of the window
+		now := time.Now()
+		var nextRuns []time.Time
+		for i := 0; i < 5; i++ {
+			nextRun := window.nextStart(now, location)
+			nextRuns = append(nextRuns, nextRun)
+			now = nextRun
+		}
+		
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"valid":       true,
+			"schedule":    scheduleStr,
+			"description": "Time window, recorded from start to end",
+			"timezone":    location.String(),
+			"next_runs":   nextRuns,
+		})
 		return
 	}
 	
@@ -140,9 +206,9 @@ func apiSchedulerTest(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 	var nextRuns []time.Time
 	for i := 0; i < 5; i++ {
-		nextRun := calculateNextRun(parsed, now)
+		nextRun := calculateNextRun(parsed, now, location)
 		nextRuns = append(nextRuns, nextRun)
-		now = nextRun.Add(time.Minute)
+		now = nextRun
 	}
 	
 	// Get human-readable description
@@ -153,8 +219,11 @@ func apiSchedulerTest(w http.ResponseWriter, r *http.Request) {
 		"valid":       true,
 		"schedule":    scheduleStr,
 		"description": description,
+		"timezone":    location.String(),
 		"next_runs":   nextRuns,
 		"parsed": map[string]interface{}{
+			"every":    parsed.Every.String(),
+			"seconds":  parsed.Seconds,
 			"minutes":  parsed.Minutes,
 			"hours":    parsed.Hours,
 			"days":     parsed.Days,
@@ -164,6 +233,47 @@ func apiSchedulerTest(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleSchedulePreview returns the recording timeline of the active
+// schedules for the next days (7 by default) and where they overlap
+func handleSchedulePreview(w http.ResponseWriter, query map[string][]string) {
+	days := 7
+	if daysStr := getQueryParam(query, "days"); daysStr != "" {
+		var err error
+		if days, err = strconv.Atoi(daysStr); err != nil || days < 1 || days > 31 {
+			http.Error(w, "days must be between 1 and 31", http.StatusBadRequest)
+			return
+		}
+	}
+	
+	from := time.Now()
+	to := from.AddDate(0, 0, days)
+	
+	autoRecorded := map[string]bool{}
+	for _, streamName := range getStreamsToRecord() {
+		autoRecorded[streamName] = true
+	}
+	
+	filter := getQueryParam(query, "stream")
+	
+	previews := []SchedulePreview{}
+	for streamName, schedule := range GetSchedules() {
+		if filter != "" && streamName != filter {
+			continue
+		}
+		previews = append(previews, previewSchedule(schedule, from, to, autoRecorded[streamName]))
+	}
+	sort.Slice(previews, func(i, j int) bool {
+		return previews[i].StreamName < previews[j].StreamName
+	})
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":    from,
+		"to":      to,
+		"streams": previews,
+	})
+}
+
 // getScheduleDescription returns human-readable description of a schedule
 func getScheduleDescription(schedule string) string {
 	descriptions := map[string]string{
@@ -177,11 +287,22 @@ func getScheduleDescription(schedule string) string {
 		"30 23 * * 6":   "Saturday at 11:30 PM",
 		"0 12 1 * *":    "First day of every month at noon",
 		"0 0 1 1 *":     "January 1st at midnight",
+		"@yearly":       "January 1st at midnight",
+		"@annually":     "January 1st at midnight",
+		"@monthly":      "First day of every month at midnight",
+		"@weekly":       "Weekly on Sunday at midnight",
+		"@daily":        "Daily at midnight",
+		"@midnight":     "Daily at midnight",
+		"@hourly":       "Every hour",
 	}
 	
 	if desc, exists := descriptions[schedule]; exists {
 		return desc
 	}
 	
+	if interval, ok := strings.CutPrefix(schedule, "@every "); ok {
+		return "Every " + strings.TrimSpace(interval)
+	}
+	
 	return "Custom schedule (check next runs for details)"
 }
\ No newline at end of file
@@ -0,0 +1,75 @@
+package ffmpeg
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// apiRecordingSnapshot serves the periodic snapshots of a stream:
+//
+//	GET /api/recordings/snapshot?stream=cam1[&time=2025-01-15T14:30:00]
+//	GET /api/recordings/snapshot?stream=cam1&date=2025-01-15
+//
+// With time (or without it) it returns the JPEG closest to that time (the
+// latest one), with date the list of snapshots taken on that day.
+func apiRecordingSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	streamName := query.Get("stream")
+	if streamName == "" {
+		http.Error(w, "Missing 'stream' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if date := query.Get("date"); date != "" {
+		if _, err := time.Parse(snapshotDateLayout, date); err != nil {
+			http.Error(w, "Invalid 'date' parameter, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		snapshots := listSnapshots(streamName, date)
+		if snapshots == nil {
+			snapshots = []Snapshot{}
+		}
+		api.ResponseJSON(w, map[string]any{
+			"stream":    streamName,
+			"date":      date,
+			"snapshots": snapshots,
+		})
+		return
+	}
+
+	var t time.Time
+	if value := query.Get("time"); value != "" {
+		var err error
+		if t, err = parseTimeParam(value); err != nil {
+			http.Error(w, "Invalid 'time' parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	snapshot, ok := findSnapshot(streamName, t)
+	if !ok {
+		http.Error(w, "No snapshot found", http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(snapshot.Path)
+	if err != nil {
+		http.Error(w, "Snapshot not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("X-Snapshot-Time", snapshot.Time.Format(time.RFC3339))
+	http.ServeContent(w, r, filepath.Base(snapshot.Path), snapshot.Time, file)
+}
@@ -0,0 +1,108 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment so proxies
+// don't close it
+const sseKeepAlive = time.Second * 30
+
+// apiRecordingSSE pushes recording notifications as server-sent events, so
+// UIs can react to started/stopped recordings, new segments, cleanup results
+// and disk warnings instead of polling:
+//
+//	GET /api/recordings/sse[?stream=cam1][&types=segment_complete,disk_warning]
+//
+// Tokens limited to some streams only get the notifications of their
+// stream, not the ones of all streams like cleanup results.
+func apiRecordingSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	streamName := query.Get("stream")
+
+	// The token was checked to allow the stream
+	var scoped bool
+	if token := bearerToken(r); token != "" {
+		principal, _ := tokenPrincipal(token)
+		scoped = principal != nil && len(principal.streams) > 0
+	}
+
+	var types map[string]bool
+	if value := query.Get("types"); value != "" {
+		types = make(map[string]bool)
+		for _, typ := range strings.Split(value, ",") {
+			types[strings.TrimSpace(typ)] = true
+		}
+	}
+
+	events := make(chan RecordingNotification, 64)
+
+	unsubscribe := subscribeNotifications(func(n RecordingNotification) {
+		// Stream filters only apply to per-stream notifications
+		if streamName != "" && n.Stream != "" && n.Stream != streamName {
+			return
+		}
+		// Notifications of all streams list files of other streams
+		if scoped && n.Stream == "" {
+			return
+		}
+		if types != nil && !types[n.Type] {
+			return
+		}
+		select {
+		case events <- n:
+		default:
+			// Slow client, never block the recorder
+			log.Debug().Str("type", n.Type).Str("remote", r.RemoteAddr).Msg("[api] event stream full, dropping event")
+		}
+	})
+	defer unsubscribe()
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // nginx
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case n := <-events:
+			data, err := json.Marshal(n)
+			if err != nil {
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", n.Type, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
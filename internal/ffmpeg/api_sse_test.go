@@ -0,0 +1,59 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingSSEScope(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{APITokens: []RecordingAPIToken{
+		{Name: "all", Token: "all-token", Role: "viewer"},
+		{Name: "cam1", Token: "cam1-token", Role: "viewer", Streams: []string{"cam1"}},
+	}})
+
+	// Closed after the connections
+	server := httptest.NewServer(http.HandlerFunc(apiRecordingSSE))
+	t.Cleanup(server.Close)
+
+	connect := func(token string) *bufio.Reader {
+		req, err := http.NewRequest("GET", server.URL+"?stream=cam1", nil)
+		require.Nil(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+
+		reader := bufio.NewReader(res.Body)
+		line, err := reader.ReadString('\n')
+		require.Nil(t, err)
+		require.Equal(t, ": connected\n", line)
+		return reader
+	}
+	// next returns the type of the next event
+	next := func(reader *bufio.Reader) string {
+		for {
+			line, err := reader.ReadString('\n')
+			require.Nil(t, err)
+			if typ, ok := strings.CutPrefix(line, "event: "); ok {
+				return strings.TrimSpace(typ)
+			}
+		}
+	}
+
+	all, scoped := connect("all-token"), connect("cam1-token")
+
+	notify(NotifyCleanupResult, "", map[string]any{"deleted_files": []string{"cam2/cam2_2024-01-01_12-00-00.mp4"}})
+	notify(NotifySegmentComplete, "cam2", nil)
+	notify(NotifyRecordingStarted, "cam1", nil)
+
+	require.Equal(t, NotifyCleanupResult, next(all))
+	require.Equal(t, NotifyRecordingStarted, next(all))
+	require.Equal(t, NotifyRecordingStarted, next(scoped))
+}
@@ -0,0 +1,205 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// TimelineRange is a contiguous span of time, either recorded or missing
+type TimelineRange struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Duration   float64   `json:"duration_seconds"`
+	Recordings []string  `json:"recordings,omitempty"` // recording IDs covering the range
+}
+
+// Timeline describes recording coverage of a stream for one day
+type Timeline struct {
+	Stream          string          `json:"stream"`
+	Date            string          `json:"date"`
+	Start           time.Time       `json:"start"`
+	End             time.Time       `json:"end"`
+	Ranges          []TimelineRange `json:"ranges"`
+	Gaps            []TimelineRange `json:"gaps"`
+	RecordedSeconds float64         `json:"recorded_seconds"`
+	GapSeconds      float64         `json:"gap_seconds"`
+	Coverage        float64         `json:"coverage_percent"`
+}
+
+// apiRecordingsTimeline returns the recorded ranges and gaps of a stream for a day:
+//
+//	GET /api/recordings/timeline?stream=cam1&date=2025-01-15[&tolerance=5s]
+func apiRecordingsTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	streamName := query.Get("stream")
+	if streamName == "" {
+		http.Error(w, "Missing 'stream' parameter", http.StatusBadRequest)
+		return
+	}
+
+	date := query.Get("date")
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'date' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Segment boundaries are never perfectly aligned, ignore tiny holes
+	tolerance := 5 * time.Second
+	if s := query.Get("tolerance"); s != "" {
+		if tolerance, err = parseDurationParam(s); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid 'tolerance' parameter: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	api.ResponseJSON(w, buildTimeline(streamName, day, day.AddDate(0, 0, 1), tolerance))
+}
+
+// buildTimeline merges the recordings of a stream between start and end into
+// contiguous ranges and lists the gaps between them. The end is capped at now.
+func buildTimeline(streamName string, start, end time.Time, tolerance time.Duration) *Timeline {
+	recordings := findRecordingsInRange(streamName, start, end)
+	return buildTimelineFrom(recordings, streamName, start, end, tolerance, recordingDuration)
+}
+
+// buildTimelineFrom builds the timeline from a start-sorted list of recordings,
+// which may extend beyond start and end, using duration for their lengths
+func buildTimelineFrom(
+	recordings []RecordingFile, streamName string, start, end time.Time, tolerance time.Duration,
+	duration func(recordings []RecordingFile, i int) time.Duration,
+) *Timeline {
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+
+	timeline := &Timeline{
+		Stream: streamName,
+		Date:   start.Format("2006-01-02"),
+		Start:  start,
+		End:    end,
+		Ranges: []TimelineRange{},
+		Gaps:   []TimelineRange{},
+	}
+
+	if !end.After(start) {
+		return timeline
+	}
+
+	for i, recording := range recordings {
+		// Sorted by start, nothing after this one overlaps
+		if !recording.StartTime.Before(end) {
+			break
+		}
+
+		recordingStart := recording.StartTime
+		recordingEnd := recordingStart.Add(duration(recordings, i))
+
+		// Clip to the requested window
+		if recordingStart.Before(start) {
+			recordingStart = start
+		}
+		if recordingEnd.After(end) {
+			recordingEnd = end
+		}
+		if !recordingEnd.After(recordingStart) {
+			continue
+		}
+
+		n := len(timeline.Ranges)
+		if n > 0 && recordingStart.Sub(timeline.Ranges[n-1].End) <= tolerance {
+			last := &timeline.Ranges[n-1]
+			if recordingEnd.After(last.End) {
+				last.End = recordingEnd
+			}
+			last.Recordings = append(last.Recordings, recording.ID)
+			continue
+		}
+
+		timeline.Ranges = append(timeline.Ranges, TimelineRange{
+			Start:      recordingStart,
+			End:        recordingEnd,
+			Recordings: []string{recording.ID},
+		})
+	}
+
+	cursor := start
+	for i := range timeline.Ranges {
+		rng := &timeline.Ranges[i]
+		rng.Duration = rng.End.Sub(rng.Start).Seconds()
+		timeline.RecordedSeconds += rng.Duration
+
+		if rng.Start.Sub(cursor) > tolerance {
+			timeline.addGap(cursor, rng.Start)
+		}
+		cursor = rng.End
+	}
+	if end.Sub(cursor) > tolerance {
+		timeline.addGap(cursor, end)
+	}
+
+	timeline.Coverage = timeline.RecordedSeconds / end.Sub(start).Seconds() * 100
+
+	return timeline
+}
+
+func (t *Timeline) addGap(start, end time.Time) {
+	gap := TimelineRange{Start: start, End: end, Duration: end.Sub(start).Seconds()}
+	t.Gaps = append(t.Gaps, gap)
+	t.GapSeconds += gap.Duration
+}
+
+// recordingDuration returns the real length of the i-th recording of a
+// start-sorted list, falling back to the filename estimate if ffprobe fails
+func recordingDuration(recordings []RecordingFile, i int) time.Duration {
+	recording := recordings[i]
+
+	// Exact span reported by the segment muxer
+	if recording.DurationSeconds > 0 {
+		return seconds(recording.DurationSeconds)
+	}
+
+	// Still being written, covers everything up to now
+	if info, err := os.Stat(recording.Path); err == nil && time.Since(info.ModTime()) < 2*time.Minute {
+		return time.Since(recording.StartTime)
+	}
+
+	if info, err := getRecordingInfo(&recording); err == nil && info.Duration > 0 {
+		return time.Duration(info.Duration * float64(time.Second))
+	}
+
+	if recordingEnd := recordingSpanEnd(recordings, i); !recordingEnd.IsZero() {
+		return recordingEnd.Sub(recording.StartTime)
+	}
+	return estimateDuration(recording.Filename)
+}
+
+// estimatedRecordingDuration is recordingDuration without ffprobe, for
+// summaries over many files
+func estimatedRecordingDuration(recordings []RecordingFile, i int) time.Duration {
+	recording := recordings[i]
+
+	if recording.DurationSeconds > 0 {
+		return seconds(recording.DurationSeconds)
+	}
+	if info, err := os.Stat(recording.Path); err == nil && time.Since(info.ModTime()) < 2*time.Minute {
+		return time.Since(recording.StartTime) // still being written
+	}
+	if recordingEnd := recordingSpanEnd(recordings, i); !recordingEnd.IsZero() {
+		return recordingEnd.Sub(recording.StartTime)
+	}
+	return estimateDuration(recording.Filename)
+}
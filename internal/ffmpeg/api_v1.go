@@ -0,0 +1,451 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+// v1RecordingsPath is the REST resource of running recordings, the JSON
+// counterpart of /api/record
+const v1RecordingsPath = "api/v1/recordings"
+
+// maxRequestBody limits JSON request bodies
+const maxRequestBody = 1 << 20
+
+// recordRequest starts a recording, read from the query of /api/record or
+// the JSON body of /api/v1/recordings
+type recordRequest struct {
+	Stream   string       `json:"stream"`
+	ID       string       `json:"id,omitempty"`
+	Segments *bool        `json:"segments,omitempty"` // nil uses enable_segments
+	Config   RecordConfig `json:"config"`
+}
+
+// statusError is an error reported with an HTTP status
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func newStatusError(status int, format string, args ...any) error {
+	return &statusError{status: status, err: fmt.Errorf(format, args...)}
+}
+
+// writeError responds with the status of a statusError, 500 otherwise
+func writeError(w http.ResponseWriter, err error) {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		http.Error(w, statusErr.Error(), statusErr.status)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// UnmarshalJSON reads durations as Go duration strings like "10m", or as
+// nanoseconds like they are written
+func (c *RecordConfig) UnmarshalJSON(data []byte) error {
+	type plain RecordConfig
+	var v struct {
+		plain
+		Duration jsonDuration `json:"duration"`
+		PreRoll  jsonDuration `json:"pre_roll"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = RecordConfig(v.plain)
+	c.Duration, c.PreRoll = time.Duration(v.Duration), time.Duration(v.PreRoll)
+	return nil
+}
+
+type jsonDuration time.Duration
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		*d = jsonDuration(value)
+	case string:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = jsonDuration(duration)
+	case nil:
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	if *d < 0 {
+		return errors.New("negative duration")
+	}
+	return nil
+}
+
+// ffmpegProtocolRegexp matches outputs ffmpeg opens as a protocol, like
+// pipe:1 or tcp://host, drive letters are not protocols
+var ffmpegProtocolRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]+:`)
+
+// checkRecordingFilename keeps the files of recordings started through the
+// API in the storage pools, out of hidden files like the index or the audit
+// log. ffmpeg writes with -y.
+func checkRecordingFilename(filename string) error {
+	err := errors.New("'filename' must be a file in the recordings directory")
+	if ffmpegProtocolRegexp.MatchString(filename) {
+		return err
+	}
+	_, rel, ok := storagePoolRel(filename)
+	if !ok || rel == "." {
+		return err
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if strings.HasPrefix(name, ".") {
+			return err
+		}
+	}
+	return nil
+}
+
+// startRecording starts a single file or segmented recording and returns
+// its description
+func startRecording(req recordRequest) (map[string]interface{}, error) {
+	if req.Stream == "" {
+		return nil, newStatusError(http.StatusBadRequest, "missing stream name")
+	}
+	if req.Config.Filename != "" {
+		if err := checkRecordingFilename(req.Config.Filename); err != nil {
+			return nil, &statusError{status: http.StatusBadRequest, err: err}
+		}
+	}
+	if streams.Get(req.Stream) == nil {
+		return nil, newStatusError(http.StatusNotFound, "Stream '%s' not found", req.Stream)
+	}
+
+	config := req.Config
+	if config.Filename == "" {
+		// Generate default filename with timestamp
+		timestamp := time.Now().Format("2006-01-02_15-04-05")
+		config.Filename = uniqueRecordingPath(fmt.Sprintf("recordings/%s_%s.mp4", req.Stream, timestamp))
+	} else {
+		config.Filename = uniqueRecordingPath(config.Filename)
+	}
+
+	useSegments := GetRecordingConfig().EnableSegments
+	if req.Segments != nil {
+		useSegments = *req.Segments
+	}
+
+	recordingID := req.ID
+	if recordingID == "" {
+		recordingID = fmt.Sprintf("%s_%d", req.Stream, time.Now().Unix())
+	}
+
+	log.Info().
+		Str("stream", req.Stream).
+		Str("recording_id", recordingID).
+		Bool("use_segments", useSegments).
+		Msg("[api] starting recording via API")
+
+	if useSegments {
+		if err := GetSegmentedRecordingManager().StartSegmentedRecording(recordingID, req.Stream, config); isUnavailable(err) {
+			return nil, &statusError{status: http.StatusServiceUnavailable, err: err}
+		} else if isInsufficientStorage(err) {
+			return nil, &statusError{status: http.StatusInsufficientStorage, err: err}
+		} else if err != nil {
+			return nil, fmt.Errorf("Failed to start segmented recording: %w", err)
+		}
+
+		segRecording := GetSegmentedRecordingManager().GetSegmentedRecording(recordingID)
+		if segRecording == nil {
+			return nil, errors.New("Segmented recording not found after creation")
+		}
+
+		return map[string]interface{}{
+			"id":     recordingID,
+			"stream": req.Stream,
+			"type":   "segmented",
+			"config": config,
+			"status": segRecording.GetStatus(),
+		}, nil
+	}
+
+	if err := GetRecordingManager().StartRecording(recordingID, req.Stream, config); isUnavailable(err) {
+		return nil, &statusError{status: http.StatusServiceUnavailable, err: err}
+	} else if isInsufficientStorage(err) {
+		return nil, &statusError{status: http.StatusInsufficientStorage, err: err}
+	} else if err != nil {
+		return nil, fmt.Errorf("Failed to start recording: %w", err)
+	}
+
+	recording := GetRecordingManager().GetRecording(recordingID)
+	if recording == nil {
+		return nil, errors.New("Recording not found after creation")
+	}
+
+	return map[string]interface{}{
+		"id":     recordingID,
+		"stream": req.Stream,
+		"type":   "single",
+		"config": config,
+		"status": recording.GetStatus(),
+	}, nil
+}
+
+// changeRecordingState pauses, resumes or rotates a running recording and
+// returns its status
+func changeRecordingState(recordingID, action string) (map[string]interface{}, error) {
+	if action != "pause" && action != "resume" && action != "rotate" {
+		return nil, newStatusError(http.StatusBadRequest, "Unknown action '%s'", action)
+	}
+
+	if action != "pause" {
+		if err := diskMonitor.allowRecording(); err != nil {
+			return nil, &statusError{status: http.StatusInsufficientStorage, err: err}
+		}
+	}
+
+	var err error
+	var closed string
+	var status func() map[string]interface{}
+
+	if recording := GetRecordingManager().GetRecording(recordingID); recording != nil {
+		if action != "pause" {
+			if err = quotaMonitor.allowRecording(recording.Stream); err != nil {
+				return nil, &statusError{status: http.StatusInsufficientStorage, err: err}
+			}
+		}
+		switch action {
+		case "pause":
+			err = recording.Pause()
+		case "resume":
+			err = recording.Resume()
+		case "rotate":
+			closed, err = recording.Rotate()
+		}
+		status = recording.GetStatus
+	} else if segRecording := GetSegmentedRecordingManager().GetSegmentedRecording(recordingID); segRecording != nil {
+		if action != "pause" {
+			if err = quotaMonitor.allowRecording(segRecording.Stream); err != nil {
+				return nil, &statusError{status: http.StatusInsufficientStorage, err: err}
+			}
+		}
+		switch action {
+		case "pause":
+			err = segRecording.Pause()
+		case "resume":
+			err = segRecording.Resume()
+		case "rotate":
+			closed, err = segRecording.Rotate()
+		}
+		status = segRecording.GetStatus
+	} else {
+		return nil, newStatusError(http.StatusNotFound, "Recording not found")
+	}
+
+	if errors.Is(err, errInvalidTransition) {
+		return nil, &statusError{status: http.StatusConflict, err: err}
+	} else if err != nil {
+		return nil, fmt.Errorf("Failed to %s recording: %w", action, err)
+	}
+
+	log.Info().Str("recording_id", recordingID).Str("action", action).Msg("[api] recording state changed via API")
+
+	response := status()
+	if closed != "" {
+		// The finalized file, ready to download
+		recordingIndex.Update(closed)
+		if file := recordingIndex.GetByPath(closed); file != nil {
+			response["rotated"] = file
+		} else {
+			response["rotated_file"] = closed
+		}
+	}
+	return response, nil
+}
+
+// stopRecording stops a single file or segmented recording
+func stopRecording(recordingID string) error {
+	if err := GetRecordingManager().StopRecording(recordingID, ExitStopped); err != nil {
+		if err = GetSegmentedRecordingManager().StopSegmentedRecording(recordingID, ExitStopped); err != nil {
+			return newStatusError(http.StatusNotFound, "Recording not found: %v", err)
+		}
+	}
+	return nil
+}
+
+// runningRecordingStatus returns the status of a running recording, nil if
+// there is none with the ID
+func runningRecordingStatus(recordingID string) map[string]interface{} {
+	if recording := GetRecordingManager().GetRecording(recordingID); recording != nil {
+		status := recording.GetStatus()
+		status["type"] = "single"
+		return status
+	}
+	if segRecording := GetSegmentedRecordingManager().GetSegmentedRecording(recordingID); segRecording != nil {
+		return segRecording.GetStatus()
+	}
+	return nil
+}
+
+// apiV1Recordings is the JSON resource API of running recordings:
+//
+//	GET    /api/v1/recordings
+//	POST   /api/v1/recordings             {"stream": "cam1", "segments": true, "config": {"duration": "10m"}}
+//	GET    /api/v1/recordings/ID
+//	DELETE /api/v1/recordings/ID
+//	POST   /api/v1/recordings/ID/pause    or resume, rotate
+func apiV1Recordings(w http.ResponseWriter, r *http.Request) {
+	_, rest, _ := strings.Cut(r.URL.Path, "/"+v1RecordingsPath)
+	recordingID, action, _ := strings.Cut(strings.Trim(rest, "/"), "/")
+
+	switch {
+	case recordingID == "":
+		switch r.Method {
+		case "GET":
+			listV1Recordings(w)
+		case "POST":
+			createV1Recording(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+
+	case action == "":
+		switch r.Method {
+		case "GET":
+			status := runningRecordingStatus(recordingID)
+			if status == nil {
+				http.Error(w, "Recording not found", http.StatusNotFound)
+				return
+			}
+			api.ResponseJSON(w, status)
+		case "DELETE":
+			if err := stopRecording(recordingID); err != nil {
+				writeError(w, err)
+				return
+			}
+			api.ResponseJSON(w, map[string]string{"id": recordingID, "status": "stopped"})
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+
+	default:
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		response, err := changeRecordingState(recordingID, action)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		api.ResponseJSON(w, response)
+	}
+}
+
+// listV1Recordings lists the running recordings by ID
+func listV1Recordings(w http.ResponseWriter) {
+	var ids []string
+	for id := range GetRecordingManager().ListRecordings() {
+		ids = append(ids, id)
+	}
+	for id := range GetSegmentedRecordingManager().ListSegmentedRecordings() {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	recordings := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		if status := runningRecordingStatus(id); status != nil {
+			recordings = append(recordings, status)
+		}
+	}
+
+	api.ResponseJSON(w, map[string]any{"recordings": recordings})
+}
+
+func createV1Recording(w http.ResponseWriter, r *http.Request) {
+	var req recordRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := startRecording(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/"+v1RecordingsPath+"/"+response["id"].(string))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// v1RequestStream returns the stream of a request to /api/v1/recordings,
+// from the recording in the path or the body of a new recording, which is
+// put back for the handler
+func v1RequestStream(r *http.Request) (string, bool) {
+	_, rest, ok := strings.Cut(r.URL.Path, "/"+v1RecordingsPath)
+	if !ok {
+		return "", false
+	}
+
+	if recordingID, _, _ := strings.Cut(strings.Trim(rest, "/"), "/"); recordingID != "" {
+		if recording := GetRecordingManager().GetRecording(recordingID); recording != nil {
+			return recording.Stream, true
+		}
+		if segRecording := GetSegmentedRecordingManager().GetSegmentedRecording(recordingID); segRecording != nil {
+			return segRecording.Stream, true
+		}
+		return "", false
+	}
+
+	if r.Method != "POST" || r.Body == nil {
+		return "", false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "", false
+	}
+
+	var req struct {
+		Stream string `json:"stream"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.Stream == "" {
+		return "", false
+	}
+	return req.Stream, true
+}
+
+// isUnavailable reports whether a recording couldn't start for a temporary
+// reason, no free slot or no ffmpeg, answered with 503
+func isUnavailable(err error) bool {
+	return errors.Is(err, errNoRecordingSlot) || errors.Is(err, errFFmpegUnavailable)
+}
+
+// isInsufficientStorage reports a start refused for the disk or a quota
+func isInsufficientStorage(err error) bool {
+	return errors.Is(err, errDiskPaused) || errors.Is(err, errQuotaExceeded)
+}
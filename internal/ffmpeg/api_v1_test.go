@@ -0,0 +1,54 @@
+package ffmpeg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartRecordingFilename(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+
+	dir := t.TempDir()
+	setRecordingConfig(&RecordingConfig{BasePaths: []string{filepath.Join(dir, "local"), filepath.Join(dir, "usb")}})
+
+	for _, filename := range []string{
+		filepath.Join(dir, "local", "cam1", "clip.mp4"),
+		filepath.Join(dir, "usb", "clip.mkv"),
+	} {
+		require.Nil(t, checkRecordingFilename(filename), filename)
+	}
+
+	for _, filename := range []string{
+		"/etc/cron.d/x",
+		filepath.Join(dir, "local", "..", "x.mp4"),
+		filepath.Join(dir, "local"),
+		filepath.Join(dir, "local", ".recordings.index"),
+		filepath.Join(dir, "local", ".audit", "x.mp4"),
+		"pipe:1",
+		"tcp://192.168.1.10:9000",
+	} {
+		require.Error(t, checkRecordingFilename(filename), filename)
+	}
+
+	// Rejected before the stream is looked up
+	for _, body := range []string{
+		`{"stream": "cam1", "config": {"filename": "/tmp/x.mp4"}}`,
+		`{"stream": "cam1", "config": {"filename": "` + filepath.Join(dir, "local", "..", "..", "x.mp4") + `"}}`,
+	} {
+		w := httptest.NewRecorder()
+		createV1Recording(w, httptest.NewRequest("POST", "/api/v1/recordings", strings.NewReader(body)))
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w := httptest.NewRecorder()
+	handleStartRecording(w, httptest.NewRequest("POST", "/api/record", nil), map[string][]string{
+		"src": {"cam1"}, "filename": {"/tmp/x.mp4"},
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
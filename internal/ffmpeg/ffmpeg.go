@@ -14,6 +14,7 @@ import (
 	"github.com/AlexxIT/go2rtc/internal/streams"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/ffmpeg"
+	"github.com/AlexxIT/go2rtc/pkg/shell"
 	"github.com/rs/zerolog"
 )
 
@@ -53,26 +54,64 @@ func Init() {
 	streams.HandleFunc("ffmpeg", NewProducer)
 
 	api.HandleFunc("api/ffmpeg", apiFFmpeg)
-	api.HandleFunc("api/record", apiRecord)
-	api.HandleFunc("api/record/stats", apiRecordingStats)
-	api.HandleFunc("api/record/cleanup", apiRecordingCleanup)
-	api.HandleFunc("api/record/health", apiRecordingHealth)
-	api.HandleFunc("api/record/watchdog", apiWatchdog)
-	api.HandleFunc("api/record/configured", apiRecordConfigured)
-	api.HandleFunc("api/record/errors", apiRecordErrors)
-	api.HandleFunc("api/record/watchdog/reset", apiWatchdogReset)
-	api.HandleFunc("api/recordings", apiRecordings)
-	api.HandleFunc("api/schedule", apiScheduler)
-	api.HandleFunc("api/schedule/test", apiSchedulerTest)
+	handleRecordingFunc("api/record", requireReadWrite(permControl), apiRecord)
+	handleRecordingFunc("api/record/stats", requirePermission(permView), apiRecordingStats)
+	handleRecordingFunc("api/record/cleanup", requirePermission(permAdmin), apiRecordingCleanup)
+	handleRecordingFunc("api/record/health", requirePermission(permView), apiRecordingHealth)
+	handleRecordingFunc("api/record/watchdog", requireReadWrite(permAdmin), apiWatchdog)
+	handleRecordingFunc("api/record/configured", requirePermission(permView), apiRecordConfigured)
+	handleRecordingFunc("api/record/errors", requirePermission(permView), apiRecordErrors)
+	handleRecordingFunc("api/record/history", requirePermission(permView), apiRecordHistory)
+	handleRecordingFunc("api/record/watchdog/reset", requirePermission(permAdmin), apiWatchdogReset)
+	handleRecordingFunc("api/record/failures/reset", requirePermission(permAdmin), apiRecordFailuresReset)
+	handleRecordingFunc(v1RecordingsPath, requireReadWrite(permControl), apiV1Recordings)
+	handleRecordingFunc(v1RecordingsPath+"/", requireReadWrite(permControl), apiV1Recordings)
+	handleRecordingFunc("api/recordings", recordingsPermission, apiRecordings)
+	handleRecordingFunc("api/recordings/export", requirePermission(permDownload), apiRecordingsExport)
+	handleRecordingFunc("api/recordings/export/profiles", requirePermission(permView), apiRecordingsExportProfiles)
+	handleRecordingFunc("api/recordings/merge", requirePermission(permControl), apiRecordingsMerge)
+	handleRecordingFunc("api/recordings/jobs", requireReadWrite(permControl), apiRecordingJobs)
+	handleRecordingFunc("api/recordings/event", requireReadWrite(permControl), apiRecordingEvent)
+	handleRecordingFunc("api/recordings/hls", requirePermission(permView), apiRecordingsHLS)
+	handleRecordingFunc("api/recordings/health", requirePermission(permView), apiRecordingsHealth)
+	handleRecordingFunc("api/recordings/alerts", requireReadWrite(permAdmin), apiRecordingAlerts)
+	handleRecordingFunc("api/recordings/report", requirePermission(permView), apiRecordingReport)
+	handleRecordingFunc("api/recordings/metrics", requirePermission(permView), apiRecordingMetrics)
+	handleRecordingFunc("api/recordings/uploads", requireReadWrite(permAdmin), apiRecordingUploads)
+	handleRecordingFunc("api/recordings/lookup", requirePermission(permView), apiRecordingLookup)
+	handleRecordingFunc("api/recordings/timeline", requirePermission(permView), apiRecordingsTimeline)
+	handleRecordingFunc("api/recordings/calendar", requirePermission(permView), apiRecordingsCalendar)
+	handleRecordingFunc("api/recordings/sse", requirePermission(permView), apiRecordingSSE)
+	handleRecordingFunc("api/recordings/cleanup", requirePermission(permAdmin), apiRecordingsCleanup)
+	handleRecordingFunc("api/recordings/config", requirePermission(permAdmin), apiRecordingConfig)
+	handleRecordingFunc("api/recordings/config/streams", requirePermission(permAdmin), apiRecordingStreamConfig)
+	handleRecordingFunc("api/recordings/snapshot", requirePermission(permView), apiRecordingSnapshot)
+	handleRecordingFunc("api/recordings/audit", requirePermission(permAdmin), apiRecordingsAudit)
+	handleRecordingFunc("api/recordings/annotations", requireReadWrite(permControl), apiRecordingAnnotations)
+	handleRecordingFunc("api/recordings/tags", requireReadWrite(permControl), apiRecordingTags)
+	handleRecordingFunc("api/recordings/media_source", requirePermission(permView), apiRecordingMediaSource)
+	handleRecordingFunc(frigatePrefix, frigatePermission, apiFrigate)
+	handleRecordingFunc("api/schedule", requireReadWrite(permAdmin), apiScheduler)
+	handleRecordingFunc("api/schedule/test", requirePermission(permView), apiSchedulerTest)
+
+	// Bearer tokens of the recording API get past the API's basic auth
+	api.AuthFunc = recordingTokenAuth
+
+	// rtsp://HOST:8554/replay/STREAM?start=TIME plays recordings, with the
+	// recording API tokens and audit log
+	rtsp.HandleStreamFunc(replayPrefix, replayStream)
 
 	// Load recording configuration
 	LoadRecordingConfig()
 
+	// Let ffmpeg finalize open recordings when go2rtc is stopped
+	shell.OnShutdown(ShutdownRecordings)
+
 	// Wire detection base-path accessor (avoids circular import)
 	InitDetection()
 
 	// Start auto-recordings if enabled
-	if GlobalRecordingConfig.AutoStart || len(GlobalRecordingConfig.Streams) > 0 {
+	if GetRecordingConfig().AutoStart || len(GetRecordingConfig().Streams) > 0 {
 		go func() {
 			// Delay to ensure streams are fully initialized
 			time.Sleep(time.Second * 10)
@@ -86,6 +125,7 @@ func Init() {
 		time.Sleep(time.Second * 15)
 		StartScheduler()
 		LoadSchedulesFromConfig()
+		LoadPersistedSchedules()
 	}()
 
 	device.Init(defaults["bin"])
@@ -2,10 +2,13 @@ package ffmpeg
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,8 +19,11 @@ import (
 
 // StreamError holds the last known error for a stream's recording process.
 type StreamError struct {
-	Error     string    `json:"error"`
-	Timestamp time.Time `json:"timestamp"`
+	Code        string    `json:"code"` // one of the ErrorCode constants
+	Error       string    `json:"error"`
+	ExitCode    int       `json:"exit_code,omitempty"` // of ffmpeg, if it ran
+	RecordingID string    `json:"recording_id,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
 }
 
 var streamErrors = struct {
@@ -25,9 +31,10 @@ var streamErrors = struct {
 	m map[string]StreamError
 }{m: make(map[string]StreamError)}
 
-func setStreamError(streamName, errMsg string) {
+func setStreamError(streamName string, streamErr StreamError) {
+	streamErr.Timestamp = time.Now()
 	streamErrors.Lock()
-	streamErrors.m[streamName] = StreamError{Error: errMsg, Timestamp: time.Now()}
+	streamErrors.m[streamName] = streamErr
 	streamErrors.Unlock()
 }
 
@@ -87,32 +94,64 @@ type RecordConfig struct {
 	Duration time.Duration `json:"duration,omitempty"`
 	Video    string        `json:"video,omitempty"`
 	Audio    string        `json:"audio,omitempty"`
+	Event    bool          `json:"event,omitempty"` // Single bounded file, never segmented
+	PreRoll  time.Duration `json:"pre_roll,omitempty"` // Prepend buffered footage (requires buffer_time)
 }
 
+// Recording states
+const (
+	StateStarting  = "starting"
+	StateRecording = "recording"
+	StatePaused    = "paused"
+	StateStopping  = "stopping"
+	StateFailed    = "failed"    // recorder exited on its own with an error
+	StateFinalized = "finalized" // stopped and the file is complete
+)
+
+var errInvalidTransition = errors.New("invalid recording state transition")
+
 type Recording struct {
 	ID        string        `json:"id"`
 	Config    RecordConfig  `json:"config"`
 	Stream    string        `json:"stream"`
-	StartTime time.Time     `json:"start_time"`
-	Duration  time.Duration `json:"duration,omitempty"`
-	Active    bool          `json:"active"`
+	StartTime time.Time     `json:"start_time"` // start of the current file
+	Duration  time.Duration `json:"duration,omitempty"` // time recorded, pauses excluded
+	State     string        `json:"state"`
+	Active    bool          `json:"active"` // writing, i.e. starting or recording
+	Progress  *FFmpegProgress `json:"progress,omitempty"` // latest ffmpeg -progress report of the current file
 	PID       int           `json:"pid,omitempty"`
-
-	cmd *exec.Cmd
-	mu  sync.Mutex
+	Resources *ProcessUsage `json:"resources,omitempty"` // CPU and memory of the ffmpeg process
+
+	cmd      *exec.Cmd
+	segments *segmentList  // completed segments of the ffmpeg segment muxer
+	stop     chan struct{} // closes the native recorder
+	done     chan struct{} // closed once the recorder has exited and finalized the file
+	cpuSample processSample // CPU time at the last resource check
+	overLimit int           // resource checks in a row above a limit
+
+	started    time.Time // start of the recording, StartTime moves on with every file
+	ended      time.Time
+	files      []string // files of the runs that have exited
+	stopReason string   // why it was stopped, one of the Exit reasons
+	lastError  StreamError // error the recorder last exited with
+	inHistory  bool
+	mu       sync.Mutex
 }
 
 func NewRecording(id, streamName string, config RecordConfig) *Recording {
+	now := time.Now()
 	return &Recording{
 		ID:        id,
 		Config:    config,
 		Stream:    streamName,
-		StartTime: time.Now(),
+		StartTime: now,
+		State:     StateStarting,
 		Active:    false,
+		started:   now,
 	}
 }
 
-func (r *Recording) Start() error {
+func (r *Recording) Start() (err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	
@@ -126,7 +165,14 @@ func (r *Recording) Start() error {
 		return fmt.Errorf("recording already active")
 	}
 	
-	cfg := GlobalRecordingConfig
+	r.State = StateStarting
+	defer func() {
+		if err != nil {
+			r.State = StateFailed
+		}
+	}()
+	
+	cfg := GetRecordingConfig()
 
 	// Generate filename if not provided
 	if r.Config.Filename == "" {
@@ -147,8 +193,18 @@ func (r *Recording) Start() error {
 		}
 	}
 	
+	// The native recorder writes directly from the internal stream without ffmpeg
+	if streamConfig := GetStreamRecordingConfig(r.Stream); isNativeRecorder(streamConfig) {
+		return r.startNative(streamConfig)
+	}
+	
+	if err := ffmpegAvailable(); err != nil {
+		return err
+	}
+	
 	// Determine the recording source (direct RTSP or internal routing)
 	recordingSource := GetRecordingSource(r.Stream, rtsp.Port)
+	usePipe := false
 	
 		
 	// Check if we're using direct source or need to validate internal stream
@@ -162,10 +218,20 @@ func (r *Recording) Start() error {
 				Msg("[recording] internal source stream not found")
 			return fmt.Errorf("internal source stream '%s' not found", r.Stream)
 		}
-		log.Info().
-			Str("recording_id", r.ID).
-			Str("stream", r.Stream).
-			Msg("[recording] using internal RTSP routing")
+		
+		// Pipe mode attaches to the running producer instead of opening a second RTSP session
+		if usePipe = isPipeInput(GetStreamRecordingConfig(r.Stream)); usePipe {
+			recordingSource = "pipe:0"
+			log.Info().
+				Str("recording_id", r.ID).
+				Str("stream", r.Stream).
+				Msg("[recording] using internal stream pipe")
+		} else {
+			log.Info().
+				Str("recording_id", r.ID).
+				Str("stream", r.Stream).
+				Msg("[recording] using internal RTSP routing")
+		}
 	} else {
 		// Using direct source
 		log.Info().
@@ -188,29 +254,14 @@ func (r *Recording) Start() error {
 		audio = cfg.DefaultAudio
 	}
 	
-	// Create exec URL that uses FFmpeg to record stream to file
-	execURL := fmt.Sprintf("exec:ffmpeg -i %s", recordingSource)
-	
-	// Add video codec
-	if video == "copy" {
-		execURL += " -c:v copy"
-	} else {
-		if codec := defaults[video]; codec != "" {
-			execURL += " " + codec
-		} else {
-			execURL += " -c:v " + video
-		}
-	}
-	
-	// Add audio codec  
-	if audio == "copy" {
-		execURL += " -c:a copy"
-	} else {
-		if codec := defaults[audio]; codec != "" {
-			execURL += " " + codec
-		} else {
-			execURL += " -c:a " + audio
-		}
+	streamConfig := GetStreamRecordingConfig(r.Stream)
+	if video == "copy" && hasQualityLimits(streamConfig) {
+		// Scaling, frame rate and bitrate limits only apply to transcodes
+		log.Info().
+			Str("recording_id", r.ID).
+			Str("stream", r.Stream).
+			Msg("[recording] quality limits set, transcoding video to h264")
+		video = "h264"
 	}
 	
 	// Add output format and file
@@ -232,9 +283,54 @@ func (r *Recording) Start() error {
 		}
 	}
 	
+	// Codecs and formats become options, they must not smuggle in others
+	if err := checkRecordingArgs(video, audio, format, streamConfig); err != nil {
+		return err
+	}
+	hwInput, videoCodec, videoFilters := recordingVideoArgs(video, streamConfig)
+	
+	// The command is built as an argument list, paths and sources with
+	// spaces or quotes stay single arguments
+	args := append([]string{ffmpegBin()}, progressArgs...)
+	args = append(args, hwInput...)
+	if usePipe {
+		args = append(args, "-f", "mpegts")
+	}
+	// Input options belong to the input a template replaces
+	inputAt := len(args)
+	args = append(args, inputArgs(recordingSource, streamConfig)...)
+	args = append(args, "-i", recordingSource)
+	outputAt := len(args)
+	
+	// Add video codec
+	args = append(args, videoCodec...)
+	if len(videoFilters) > 0 {
+		args = append(args, "-vf", strings.Join(videoFilters, ","))
+	}
+	
+	// Add audio codec
+	if audio == "copy" {
+		args = append(args, "-c:a", "copy")
+	} else if codec := defaults[audio]; codec != "" {
+		args = append(args, strings.Fields(codec)...)
+	} else {
+		args = append(args, "-c:a", audio)
+	}
+	
+	// With restream the output is also published for live viewing, event
+	// recordings run next to the continuous one and would publish twice
+	var restream string
+	if !r.Config.Event {
+		if restream = restreamName(r.Stream, streamConfig); restream != "" {
+			ensureRestream(restream)
+		}
+	}
+
 	// Add segmentation parameters if enabled
-	streamConfig := GetStreamRecordingConfig(r.Stream)
-	if streamConfig.EnableSegments != nil && *streamConfig.EnableSegments {
+	var preFiles []string
+	var segments *segmentList
+	segmented := recorderSegments(streamConfig, r.Config)
+	if segmented {
 		// Use FFmpeg segment muxer for automatic file splitting
 		segmentTime := int(streamConfig.SegmentDuration.Seconds())
 		if segmentTime <= 0 {
@@ -249,8 +345,27 @@ func (r *Recording) Start() error {
 		// This will create files like: stream_2025-01-01_12-00-00.mp4, stream_2025-01-01_12-10-00.mp4, etc.
 		segmentPattern := filepath.Join(dir, r.Stream+"_%Y-%m-%d_%H-%M-%S"+ext)
 		
-		execURL += fmt.Sprintf(" -f segment -segment_time %d -segment_format %s -reset_timestamps 1", segmentTime, format)
-		execURL += fmt.Sprintf(" -strftime 1 -y %s", segmentPattern)
+		// Registers each segment with the index as soon as ffmpeg closes it
+		segments = newSegmentList(r, dir)
+		options := []muxerOption{
+			{"segment_time", strconv.Itoa(segmentTime)},
+			{"segment_format", format},
+			{"reset_timestamps", "1"},
+			{"segment_list", segments.path},
+			{"segment_list_type", "csv"},
+			{"strftime", "1"},
+		}
+		
+		// The muxer only cuts on keyframes, a transcode places one at every cut
+		var forced []string
+		if keyframeAligned(streamConfig) {
+			forced = forceKeyframesArgs(video, segmentTime)
+			args = append(args, forced...)
+		}
+		if delta := segmentTimeDelta(forced != nil); delta > 0 {
+			options = append(options, muxerOption{"segment_time_delta", strconv.FormatFloat(delta.Seconds(), 'f', -1, 64)})
+		}
+		args = append(args, outputArgs("segment", options, segmentPattern, restream)...)
 		
 		log.Info().
 			Str("recording_id", r.ID).
@@ -258,19 +373,36 @@ func (r *Recording) Start() error {
 			Str("segment_pattern", segmentPattern).
 			Msg("[SEGMENTATION] Configured for automatic file splitting")
 	} else {
-		execURL += fmt.Sprintf(" -f %s -y %s", format, r.Config.Filename)
+		output := r.Config.Filename
+		
+		// With a pre-roll the live part is recorded separately and joined with
+		// the buffered chunks once ffmpeg exits
+		if r.Config.PreRoll > 0 {
+			if preFiles = preBufferManager.Snapshot(r.Stream, r.Config.PreRoll); preFiles != nil {
+				output = r.Config.Filename + ".part"
+				format = "mpegts"
+				log.Debug().
+					Str("recording_id", r.ID).
+					Int("chunks", len(preFiles)).
+					Msg("[recording] prepending pre-record buffer")
+			}
+		}
+		
+		args = append(args, outputArgs(format, nil, output, restream)...)
 	}
 	
+	// A per-stream template replaces the input and places the generated output
+	if streamConfig.FFmpegTemplate != "" {
+		templateArgs, err := expandFFmpegTemplate(streamConfig.FFmpegTemplate, recordingSource, args[outputAt:], r.Stream)
+		if err != nil {
+			return fmt.Errorf("invalid ffmpeg_template: %w", err)
+		}
+		args = append(args[:inputAt:inputAt], templateArgs...)
+	}
 	
-	
-	// Strip "exec:" prefix — we run FFmpeg directly, not via go2rtc's producer pipeline.
-	// The producer mechanism expects FFmpeg to feed data back into go2rtc, but recording
+	// We run FFmpeg directly, not via go2rtc's exec producer pipeline. The
+	// producer expects FFmpeg to feed data back into go2rtc, but recording
 	// writes to files only, so we manage the process ourselves.
-	ffmpegArgs := strings.TrimPrefix(execURL, "exec:")
-	args := strings.Fields(ffmpegArgs)
-	if len(args) < 2 {
-		return fmt.Errorf("invalid ffmpeg command: %s", ffmpegArgs)
-	}
 
 	log.Info().
 		Str("recording_id", r.ID).
@@ -280,10 +412,25 @@ func (r *Recording) Start() error {
 
 	var stderrBuf bytes.Buffer
 	cmd := exec.Command(args[0], args[1:]...)
-	cmd.Stdout = nil
 	cmd.Stderr = &stderrBuf
 
+	// Live statistics, the writer is closed once the process has been reaped
+	progressReader, progressWriter := io.Pipe()
+	cmd.Stdout = progressWriter
+
+	var pipe *pipeInput
+	if usePipe {
+		var err error
+		if pipe, err = newPipeInput(r.Stream, cmd); err != nil {
+			return fmt.Errorf("failed to attach to stream: %w", err)
+		}
+	}
+
 	if err := cmd.Start(); err != nil {
+		if pipe != nil {
+			pipe.Close()
+		}
+		_ = progressWriter.Close()
 		log.Error().
 			Err(err).
 			Str("recording_id", r.ID).
@@ -292,27 +439,86 @@ func (r *Recording) Start() error {
 		return fmt.Errorf("failed to start ffmpeg: %w", err)
 	}
 
+	if cfg.FFmpegNice != 0 {
+		if err := setProcessNice(cmd.Process.Pid, cfg.FFmpegNice); err != nil {
+			log.Warn().Err(err).Int("pid", cmd.Process.Pid).Msg("[recording] failed to set ffmpeg nice level")
+		}
+	}
+
 	r.cmd = cmd
+	r.segments = segments
 	r.PID = cmd.Process.Pid
+	r.Progress = nil
+	r.Resources = nil
+	r.State = StateRecording
 	r.Active = true
 	r.StartTime = time.Now()
 	clearStreamError(r.Stream)
+	recordingState.add(r)
+
+	if pipe != nil {
+		pipe.Run()
+	}
+	if segments != nil {
+		go segments.follow(r.StartTime)
+	}
+	go readProgress(progressReader, r.setProgress)
+
+	done := make(chan struct{})
+	r.done = done
 
 	// Reap the process when it exits so we don't accumulate zombies
 	go func() {
-		_ = cmd.Wait()
+		defer close(done)
+		waitErr := cmd.Wait()
+		_ = progressWriter.Close()
+		if pipe != nil {
+			pipe.Close()
+		}
+		recordingState.remove(r.ID)
 		r.mu.Lock()
 		r.Active = false
+		r.exited(waitErr)
+		r.mu.Unlock()
+		if preFiles != nil {
+			if err := finalizePreRoll(preFiles, r.Config.Filename+".part", r.Config.Filename); err != nil {
+				log.Error().Err(err).Str("recording_id", r.ID).Msg("[recording] failed to prepend pre-record buffer")
+			}
+		}
+		files := []string{r.Config.Filename}
+		if segments != nil {
+			segments.close()
+			files = files[:0]
+			for _, segment := range segments.Segments() {
+				files = append(files, segment.Path)
+			}
+		}
+		// Register finished files (including segments ffmpeg couldn't list after a kill) in the index
+		recordingIndex.UpdateDir(filepath.Dir(r.Config.Filename))
+		if !segmented {
+			onSegmentComplete(r.Stream, r.Config.Filename)
+		}
+		r.mu.Lock()
+		r.files = append(r.files, files...)
+		failed, killed := r.State == StateFailed, r.stopReason == ExitResourceLimit
 		r.mu.Unlock()
-		if stderrBuf.Len() > 0 {
-			errMsg := extractFFmpegError(stderrBuf.String())
-			setStreamError(r.Stream, errMsg)
+		if stderrBuf.Len() > 0 || failed {
+			streamErr := ffmpegStreamError(r.ID, stderrBuf.String(), waitErr)
+			if killed {
+				streamErr.Code = ErrorCodeResourceLimit
+			}
+			setStreamError(r.Stream, streamErr)
+			r.mu.Lock()
+			r.lastError = streamErr
+			r.mu.Unlock()
 			log.Error().
 				Str("recording_id", r.ID).
 				Str("stream", r.Stream).
-				Str("error", errMsg).
+				Str("code", streamErr.Code).
+				Str("error", streamErr.Error).
+				Int("exit_code", streamErr.ExitCode).
 				Str("ffmpeg_stderr", stderrBuf.String()).
-				Msg("[recording] ffmpeg exited with output")
+				Msg("[recording] ffmpeg exited with an error")
 		} else {
 			log.Debug().
 				Str("recording_id", r.ID).
@@ -327,18 +533,25 @@ func (r *Recording) Start() error {
 		Str("output_file", r.Config.Filename).
 		Msg("[recording] active and writing to file")
 	
-	// Handle duration limit
-	if r.Config.Duration > 0 {
+	// Handle duration limit, a pause ends this run and resuming schedules the remainder
+	if limit := r.Config.Duration; limit > 0 {
 		log.Debug().
 			Str("recording_id", r.ID).
-			Dur("duration", r.Config.Duration).
+			Dur("duration", limit).
 			Msg("[recording] scheduled stop after duration")
 		go func() {
-			time.Sleep(r.Config.Duration)
+			timer := time.NewTimer(limit)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-done:
+				return
+			}
 			log.Info().
 				Str("recording_id", r.ID).
 				Dur("duration", r.Config.Duration).
 				Msg("[recording] stopping recording after duration limit")
+			r.setStopReason(ExitDuration)
 			r.Stop()
 		}()
 	}
@@ -355,6 +568,20 @@ func (r *Recording) Stop() error {
 		Str("stream", r.Stream).
 		Msg("[recording] stopping recording session")
 	
+	// Nothing is written while paused, the last file is already complete
+	if r.State == StatePaused {
+		r.State = StateFinalized
+		r.ended = time.Now()
+		if r.stopReason == "" {
+			r.stopReason = ExitStopped
+		}
+		log.Info().
+			Str("recording_id", r.ID).
+			Dur("duration", r.Duration).
+			Msg("[recording] paused recording completed")
+		return nil
+	}
+	
 	if !r.Active {
 		log.Debug().
 			Str("recording_id", r.ID).
@@ -362,30 +589,164 @@ func (r *Recording) Stop() error {
 		return nil
 	}
 	
-	duration := time.Since(r.StartTime)
+	if r.stopReason == "" {
+		r.stopReason = ExitStopped
+	}
+	r.State = StateStopping
+	r.interrupt()
+	r.Active = false
+	r.Duration += time.Since(r.StartTime)
+	
+	log.Info().
+		Str("recording_id", r.ID).
+		Str("stream", r.Stream).
+		Str("output_file", r.Config.Filename).
+		Dur("duration", r.Duration).
+		Msg("[recording] recording completed")
+	
+	return nil
+}
 
-	if r.cmd != nil && r.cmd.Process != nil {
+// interrupt asks the recorder to finalize the current file and exit. Must be
+// called with r.mu held.
+func (r *Recording) interrupt() {
+	if r.cmd != nil && r.cmd.Process != nil && r.processAliveLocked() {
 		// Send SIGINT first so FFmpeg can flush/finalise the output file cleanly
 		if err := r.cmd.Process.Signal(os.Interrupt); err != nil {
 			// Fallback to kill if interrupt not supported (e.g. Windows)
 			_ = r.cmd.Process.Kill()
+		} else {
+			escalateStop(r.ID, r.cmd.Process, r.done)
 		}
-		r.cmd = nil
 	}
 	
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// exited moves the state on once the recorder has exited. A pause or a
+// completed stop is kept, an exit nobody asked for is a failure unless the
+// recorder finished cleanly. Must be called with r.mu held.
+func (r *Recording) exited(err error) {
+	switch r.State {
+	case StateStopping:
+		r.State = StateFinalized
+		r.ended = time.Now()
+	case StateStarting, StateRecording:
+		if err != nil {
+			r.State = StateFailed
+		} else {
+			r.State = StateFinalized
+		}
+		r.ended = time.Now()
+		r.Duration += r.ended.Sub(r.StartTime)
+	}
+}
+
+// finished reports whether the recording has ended and will not resume
+func (r *Recording) finished() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.State == StateFailed || r.State == StateFinalized
+}
+
+// Pause stops writing but keeps the recording, Resume continues it in a new file
+func (r *Recording) Pause() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if r.State != StateRecording {
+		return fmt.Errorf("%w: recording is %s", errInvalidTransition, r.State)
+	}
+	
+	elapsed := time.Since(r.StartTime)
+	r.State = StatePaused
+	r.interrupt()
 	r.Active = false
-	r.Duration = duration
+	r.Duration += elapsed
+	
+	// The duration limit counts recorded time only
+	if r.Config.Duration > 0 {
+		if r.Config.Duration -= elapsed; r.Config.Duration < time.Second {
+			r.Config.Duration = time.Second
+		}
+	}
 	
 	log.Info().
 		Str("recording_id", r.ID).
 		Str("stream", r.Stream).
 		Str("output_file", r.Config.Filename).
-		Dur("duration", duration).
-		Msg("[recording] recording completed")
+		Msg("[recording] recording paused")
 	
 	return nil
 }
 
+// Resume starts writing a paused recording again, into a new file
+func (r *Recording) Resume() error {
+	r.mu.Lock()
+	if r.State != StatePaused {
+		state := r.State
+		r.mu.Unlock()
+		return fmt.Errorf("%w: recording is %s", errInvalidTransition, state)
+	}
+	r.mu.Unlock()
+	
+	// The previous run must have finalized its file before we move on
+	if err := r.waitExited(); err != nil {
+		return err
+	}
+	
+	r.mu.Lock()
+	if r.State != StatePaused {
+		state := r.State
+		r.mu.Unlock()
+		return fmt.Errorf("%w: recording is %s", errInvalidTransition, state)
+	}
+	format := strings.TrimPrefix(filepath.Ext(r.Config.Filename), ".")
+	r.Config.Filename = GenerateRecordingPath(r.Stream, time.Now(), format, 0)
+	r.Config.PreRoll = 0 // buffered footage is from the pause
+	r.mu.Unlock()
+	
+	return r.Start()
+}
+
+// Rotate finalizes the current file and continues in a new one. Returns the
+// path of the finalized file.
+func (r *Recording) Rotate() (string, error) {
+	if err := r.Pause(); err != nil {
+		return "", err
+	}
+	if err := r.waitExited(); err != nil {
+		return "", err
+	}
+	
+	var closed string
+	if segments := r.completedSegments(); len(segments) > 0 {
+		closed = segments[len(segments)-1].Path
+	}
+	
+	return closed, r.Resume()
+}
+
+// waitExited waits until the recorder of the last run has exited
+func (r *Recording) waitExited() error {
+	r.mu.Lock()
+	done := r.done
+	r.mu.Unlock()
+	
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(stopTimeout() + time.Second*5):
+		return fmt.Errorf("recording %s is still finalizing", r.ID)
+	}
+}
+
 func (r *Recording) GetStatus() map[string]interface{} {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -396,9 +757,29 @@ func (r *Recording) GetStatus() map[string]interface{} {
 		"filename":  r.Config.Filename,
 		"format":    r.Config.Format,
 		"active":    r.Active,
+		"state":     r.State,
 		"start_time": r.StartTime,
 	}
 	
+	if r.segments != nil {
+		if segments := r.segments.Segments(); len(segments) > 0 {
+			status["segments_completed"] = len(segments)
+			status["last_segment"] = segments[len(segments)-1]
+		}
+	}
+
+	if r.Progress != nil {
+		status["progress"] = r.Progress
+	}
+
+	if r.Resources != nil {
+		status["resources"] = r.Resources
+	}
+
+	if r.lastError.Code != "" {
+		status["last_error"] = r.lastError
+	}
+
 	if r.Active {
 		status["duration"] = time.Since(r.StartTime)
 		if r.Config.Duration > 0 {
@@ -426,34 +807,67 @@ func GetRecordingManager() *RecordingManager {
 }
 
 func (rm *RecordingManager) StartRecording(id, streamName string, config RecordConfig) error {
+	err := diskMonitor.allowRecording()
+	if err == nil {
+		err = quotaMonitor.allowRecording(streamName)
+	}
+	if err != nil {
+		recordingMetrics.addFailedStart(streamName)
+		streamErr := newStreamError(id, err)
+		setStreamError(streamName, streamErr)
+		addFailedStartHistory(id, streamName, config, false, streamErr)
+		return err
+	}
+
+	// Wait for max_recording_processes and max_concurrent_starts before
+	// locking, a queued start must not block the other recordings
+	release, err := acquireRecordingSlots(streamName)
+	if err != nil {
+		return err
+	}
+
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 	
 	if _, exists := rm.recordings[id]; exists {
+		release()
 		return fmt.Errorf("recording with ID %s already exists", id)
 	}
 	
 	recording := NewRecording(id, streamName, config)
 	if err := recording.Start(); err != nil {
+		release()
+		recordingMetrics.addFailedStart(streamName)
+		streamErr := newStreamError(id, err)
+		setStreamError(streamName, streamErr)
+		addFailedStartHistory(id, streamName, config, false, streamErr)
 		return err
 	}
 	
 	rm.recordings[id] = recording
+	notify(NotifyRecordingStarted, streamName, recording.GetStatus())
 	
-	// Auto-cleanup when recording stops
+	// Auto-cleanup when recording stops, paused recordings are kept
 	go func() {
-		for recording.Active {
+		for !recording.finished() {
 			time.Sleep(time.Second)
 		}
+		release()
 		rm.mu.Lock()
-		delete(rm.recordings, id)
+		if rm.recordings[id] == recording {
+			delete(rm.recordings, id)
+		}
 		rm.mu.Unlock()
+		notify(NotifyRecordingStopped, streamName, recording.GetStatus())
+		recording.addToHistory()
+		runRecordingHook(recording)
 	}()
 	
 	return nil
 }
 
-func (rm *RecordingManager) StopRecording(id string) error {
+// StopRecording stops a recording, the reason is kept in its history
+func (rm *RecordingManager) StopRecording(id, reason string) error {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 	
@@ -462,6 +876,7 @@ func (rm *RecordingManager) StopRecording(id string) error {
 		return fmt.Errorf("recording with ID %s not found", id)
 	}
 	
+	recording.setStopReason(reason)
 	err := recording.Stop()
 	delete(rm.recordings, id)
 	return err
@@ -496,11 +911,27 @@ func (rm *RecordingManager) IsStreamRecording(streamName string) bool {
 	return false
 }
 
-func (rm *RecordingManager) StopAll() {
+// isStreamPaused reports whether a recording of the stream is paused
+func isStreamPaused(streamName string) bool {
+	for _, recording := range GetRecordingManager().ListRecordings() {
+		if recording.Stream == streamName && recording.State == StatePaused {
+			return true
+		}
+	}
+	for _, recording := range GetSegmentedRecordingManager().ListSegmentedRecordings() {
+		if recording.Stream == streamName && recording.State == StatePaused {
+			return true
+		}
+	}
+	return false
+}
+
+func (rm *RecordingManager) StopAll(reason string) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 	
 	for id, recording := range rm.recordings {
+		recording.setStopReason(reason)
 		recording.Stop()
 		delete(rm.recordings, id)
 	}
@@ -0,0 +1,510 @@
+package ffmpeg
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alert rule conditions
+const (
+	AlertNotRecording  = "not_recording"  // a stream that should record, always or by its schedule, doesn't
+	AlertNoSegments    = "no_segments"    // a recording stream finished no segment for a while
+	AlertDiskLow       = "disk_low"       // free space of the recordings volume below a percent
+	AlertCleanupFailed = "cleanup_failed" // the last scheduled cleanup failed
+	AlertStreamError   = "stream_error"   // a stream that should record fails with an error code
+	AlertQuotaExceeded = "quota_exceeded" // recordings are stopped over max_total_size with quota_mode enforce
+	AlertEvent         = "event"          // a recording notification, e.g. recording_stalled
+)
+
+// Alert states
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+	AlertReport   = "report" // the daily report, see report_notifiers
+)
+
+// alertCheckInterval is how often the alert rules are evaluated
+const alertCheckInterval = time.Second * 30
+
+// AlertRule raises an alert while its condition holds
+type AlertRule struct {
+	Name      string        `yaml:"name"`      // shown in alerts, defaults to the condition
+	Condition string        `yaml:"condition"` // not_recording, no_segments, disk_low, cleanup_failed, stream_error, quota_exceeded or event
+	Streams   []string      `yaml:"streams"`   // stream names, globs or "group:NAME", all streams if empty
+	For       time.Duration `yaml:"for"`       // how long the condition must hold (not_recording and stream_error 2m, no_segments 15m)
+	Threshold float64       `yaml:"threshold"` // disk_low: free percent (default 10)
+	Codes     []string      `yaml:"codes"`     // stream_error: error codes, e.g. auth_failed (default all)
+	Events    []string      `yaml:"events"`    // event: notification types (default recording_stalled, disk_warning, resource_limit, segment_corrupt)
+	Notifiers []string      `yaml:"notifiers"` // notifier names, all notifiers if empty
+	Cooldown  time.Duration `yaml:"cooldown"`  // repeat a firing alert at most this often (default 1h)
+}
+
+// Alert is a firing or resolved alert as delivered to the notifiers
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Condition string    `json:"condition"`
+	Stream    string    `json:"stream,omitempty"`
+	State     string    `json:"state"`
+	Message   string    `json:"message"`
+	Since     time.Time `json:"since"` // when the condition started to hold
+	Time      time.Time `json:"time"`
+	Data      any       `json:"data,omitempty"` // the report of daily reports
+}
+
+// title is the short form of an alert, e.g. for mail subjects
+func (a Alert) title() string {
+	title := "[" + strings.ToUpper(a.State) + "] " + a.Rule
+	if a.Stream != "" {
+		title += " " + a.Stream
+	}
+	return title
+}
+
+func (a Alert) text() string {
+	return a.title() + ": " + a.Message
+}
+
+// normalize applies the defaults of the rule's condition and checks that its
+// notifiers exist
+func (rule *AlertRule) normalize(notifiers map[string]alertNotifier) error {
+	switch rule.Condition {
+	case AlertNotRecording:
+		rule.For = cmp.Or(rule.For, time.Minute*2) // auto-recording restarts first
+	case AlertNoSegments:
+		rule.For = cmp.Or(rule.For, time.Minute*15)
+	case AlertDiskLow:
+		rule.Threshold = cmp.Or(rule.Threshold, 10)
+	case AlertCleanupFailed, AlertQuotaExceeded:
+	case AlertStreamError:
+		rule.For = cmp.Or(rule.For, time.Minute*2)
+		for _, code := range rule.Codes {
+			if !slices.Contains(errorCodes, code) {
+				return fmt.Errorf("unknown error code %q", code)
+			}
+		}
+	case AlertEvent:
+		if len(rule.Events) == 0 {
+			for typ := range alertTypes {
+				rule.Events = append(rule.Events, typ)
+			}
+			sort.Strings(rule.Events)
+		}
+	default:
+		return fmt.Errorf("unknown condition %q", rule.Condition)
+	}
+
+	rule.Name = cmp.Or(rule.Name, rule.Condition)
+	rule.Cooldown = cmp.Or(rule.Cooldown, time.Hour)
+
+	for _, name := range rule.Notifiers {
+		if notifiers[name] == nil {
+			return fmt.Errorf("unknown notifier %q", name)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to a stream, alerts about no
+// stream in particular apply to every rule
+func (rule *AlertRule) matches(streamName string) bool {
+	if streamName == "" || len(rule.Streams) == 0 {
+		return true
+	}
+	for _, key := range rule.Streams {
+		if matchStreamKey(GetRecordingConfig(), key, streamName) {
+			return true
+		}
+	}
+	return false
+}
+
+// pending is how long the condition must hold before the alert fires, the
+// no_segments condition already includes its duration
+func (rule *AlertRule) pending() time.Duration {
+	if rule.Condition == AlertNoSegments {
+		return 0
+	}
+	return rule.For
+}
+
+// alertState is a condition of a rule that holds for a stream
+type alertState struct {
+	alert  Alert
+	firing bool
+	sent   time.Time // last delivery, repeated after the cooldown
+}
+
+// alerter evaluates the alert rules and delivers their alerts
+type alerter struct {
+	rules     []AlertRule
+	notifiers map[string]alertNotifier
+
+	states      map[string]*alertState // by rule index and stream
+	eventSent   map[string]time.Time   // last delivery of event alerts
+	lastSegment map[string]time.Time   // last finished segment or recording start by stream
+	started     time.Time
+	mu          sync.Mutex
+}
+
+var alerts *alerter
+
+// startAlerting evaluates the alert rules if any notifiers or rules are
+// configured. Invalid ones are logged and skipped.
+func startAlerting() {
+	cfg := GetRecordingConfig()
+	if len(cfg.Alerts) == 0 && len(cfg.Notifiers) == 0 {
+		return
+	}
+
+	a := &alerter{
+		notifiers:   make(map[string]alertNotifier),
+		states:      make(map[string]*alertState),
+		eventSent:   make(map[string]time.Time),
+		lastSegment: make(map[string]time.Time),
+		started:     time.Now(),
+	}
+
+	for _, notifierConfig := range cfg.Notifiers {
+		notifier, err := newAlertNotifier(notifierConfig)
+		if err != nil {
+			log.Error().Err(err).Str("notifier", notifierConfig.Name).Msg("[alerts] invalid notifier, skipped")
+			continue
+		}
+		a.notifiers[notifierConfig.Name] = notifier
+	}
+
+	for _, rule := range cfg.Alerts {
+		if err := rule.normalize(a.notifiers); err != nil {
+			log.Error().Err(err).Str("rule", rule.Name).Msg("[alerts] invalid alert rule, skipped")
+			continue
+		}
+		a.rules = append(a.rules, rule)
+	}
+
+	alerts = a
+	subscribeNotifications(a.handle)
+	go a.run()
+
+	log.Info().Int("rules", len(a.rules)).Int("notifiers", len(a.notifiers)).Msg("[alerts] alerting started")
+}
+
+func (a *alerter) run() {
+	ticker := time.NewTicker(alertCheckInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		a.evaluate(now)
+	}
+}
+
+// handle delivers event alerts and tracks finished segments. It runs on the
+// notifying goroutine, delivery happens in the background.
+func (a *alerter) handle(n RecordingNotification) {
+	if n.Stream != "" && (n.Type == NotifySegmentComplete || n.Type == NotifyRecordingStarted) {
+		a.mu.Lock()
+		a.lastSegment[n.Stream] = n.Time
+		a.mu.Unlock()
+	}
+
+	for i, rule := range a.rules {
+		if rule.Condition != AlertEvent || !slices.Contains(rule.Events, n.Type) || !rule.matches(n.Stream) {
+			continue
+		}
+
+		key := fmt.Sprintf("%d/%s/%s", i, n.Stream, n.Type)
+		a.mu.Lock()
+		if sent, ok := a.eventSent[key]; ok && n.Time.Sub(sent) < rule.Cooldown {
+			a.mu.Unlock()
+			continue
+		}
+		a.eventSent[key] = n.Time
+		a.mu.Unlock()
+
+		a.send(rule, Alert{
+			Rule:      rule.Name,
+			Condition: rule.Condition,
+			Stream:    n.Stream,
+			State:     AlertFiring,
+			Message:   notificationMessage(n.Type),
+			Since:     n.Time,
+			Time:      n.Time,
+		})
+	}
+}
+
+// evaluate checks the conditions of all rules except events
+func (a *alerter) evaluate(now time.Time) {
+	var expected, recording map[string]bool
+	var disk *DiskStatus
+	var cleanup *CleanupRun
+	var streamErrs map[string]StreamError
+
+	for i := range a.rules {
+		rule := &a.rules[i]
+		active := map[string]string{} // message by stream, "" for the whole system
+
+		switch rule.Condition {
+		case AlertNotRecording:
+			if expected == nil {
+				expected = expectedRecordingStreams(now)
+			}
+			for streamName, scheduled := range expected {
+				if !rule.matches(streamName) || isAlreadyRecording(streamName) {
+					continue
+				}
+				if scheduled {
+					active[streamName] = "not recording during its schedule"
+				} else {
+					active[streamName] = "not recording"
+				}
+			}
+
+		case AlertNoSegments:
+			if recording == nil {
+				recording = recordingStreams()
+			}
+			for streamName := range recording {
+				if !rule.matches(streamName) {
+					continue
+				}
+				a.mu.Lock()
+				last := a.lastSegment[streamName]
+				a.mu.Unlock()
+				if last.Before(a.started) {
+					last = a.started
+				}
+				if idle := now.Sub(last); idle >= rule.For {
+					active[streamName] = fmt.Sprintf("no segment finished for %v", idle.Round(time.Minute))
+				}
+			}
+
+		case AlertDiskLow:
+			if disk == nil {
+				status := currentDiskStatus()
+				disk = &status
+			}
+			if disk.TotalBytes > 0 && disk.FreePercent < rule.Threshold {
+				active[""] = fmt.Sprintf("%.1f%% disk space free on %s (threshold %.1f%%)", disk.FreePercent, disk.Path, rule.Threshold)
+			}
+
+		case AlertCleanupFailed:
+			if cleanup == nil {
+				run := LastCleanup()
+				cleanup = &run
+			}
+			if cleanup.Error != "" {
+				active[""] = "cleanup failed: " + cleanup.Error
+			}
+
+		case AlertQuotaExceeded:
+			for streamName, status := range quotaMonitor.Exceeded() {
+				if streamName == "" {
+					active[""] = fmt.Sprintf("recordings use %d MB of the %d MB quota, all recordings stopped", status.UsedMB, status.LimitMB)
+				} else if rule.matches(streamName) {
+					active[streamName] = fmt.Sprintf("%d MB used of the %d MB quota, recording stopped", status.UsedMB, status.LimitMB)
+				}
+			}
+
+		case AlertStreamError:
+			if expected == nil {
+				expected = expectedRecordingStreams(now)
+			}
+			if streamErrs == nil {
+				streamErrs = GetStreamErrors()
+			}
+			// Errors stay until the next start, only those of streams that
+			// should be recording are current
+			for streamName, streamErr := range streamErrs {
+				if _, ok := expected[streamName]; !ok || !rule.matches(streamName) || isAlreadyRecording(streamName) {
+					continue
+				}
+				if len(rule.Codes) > 0 && !slices.Contains(rule.Codes, streamErr.Code) {
+					continue
+				}
+				active[streamName] = streamErr.Code + ": " + streamErr.Error
+			}
+
+		default:
+			continue
+		}
+
+		a.update(i, active, now)
+	}
+}
+
+// update fires the alerts of a rule whose condition held long enough,
+// repeats them after the cooldown and resolves those that no longer hold
+func (a *alerter) update(index int, active map[string]string, now time.Time) {
+	rule := a.rules[index]
+	prefix := fmt.Sprintf("%d/", index)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for streamName, message := range active {
+		key := prefix + streamName
+		state := a.states[key]
+		if state == nil {
+			state = &alertState{alert: Alert{
+				Rule:      rule.Name,
+				Condition: rule.Condition,
+				Stream:    streamName,
+				Since:     now,
+			}}
+			a.states[key] = state
+		}
+		state.alert.Message = message
+
+		if now.Sub(state.alert.Since) < rule.pending() {
+			continue
+		}
+		if !state.firing || now.Sub(state.sent) >= rule.Cooldown {
+			state.firing = true
+			state.sent = now
+
+			alert := state.alert
+			alert.State = AlertFiring
+			alert.Time = now
+			a.send(rule, alert)
+		}
+	}
+
+	for key, state := range a.states {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if _, ok := active[state.alert.Stream]; ok {
+			continue
+		}
+		delete(a.states, key)
+
+		if state.firing {
+			alert := state.alert
+			alert.State = AlertResolved
+			alert.Message = "resolved after " + now.Sub(alert.Since).Round(time.Second).String()
+			alert.Time = now
+			a.send(rule, alert)
+		}
+	}
+}
+
+// send logs an alert and delivers it to the rule's notifiers in the
+// background, a slow backend must not hold up the others
+func (a *alerter) send(rule AlertRule, alert Alert) {
+	event := log.Warn()
+	if alert.State == AlertResolved {
+		event = log.Info()
+	}
+	event.Str("rule", alert.Rule).Str("stream", alert.Stream).Str("state", alert.State).Msg("[alerts] " + alert.Message)
+
+	names := rule.Notifiers
+	if len(names) == 0 {
+		for name := range a.notifiers {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		notifier := a.notifiers[name]
+		go func() {
+			if err := notifier.send(alert); err != nil {
+				log.Warn().Err(err).Str("notifier", name).Str("rule", alert.Rule).Msg("[alerts] failed to send alert")
+			}
+		}()
+	}
+}
+
+// firing returns the alerts that currently fire, oldest first
+func (a *alerter) firing() []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	list := []Alert{}
+	for _, state := range a.states {
+		if state.firing {
+			alert := state.alert
+			alert.State = AlertFiring
+			alert.Time = state.sent
+			list = append(list, alert)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Since.Before(list[j].Since)
+	})
+	return list
+}
+
+// test sends a test alert through a notifier and waits for the result
+func (a *alerter) test(name string) error {
+	notifier := a.notifiers[name]
+	if notifier == nil {
+		return fmt.Errorf("unknown notifier %q", name)
+	}
+
+	now := time.Now()
+	return notifier.send(Alert{
+		Rule:    "test",
+		State:   AlertFiring,
+		Message: "test alert, the notifier works",
+		Since:   now,
+		Time:    now,
+	})
+}
+
+// expectedRecordingStreams returns the streams that should be recording now,
+// true for streams expected because one of their schedules is active
+func expectedRecordingStreams(now time.Time) map[string]bool {
+	expected := map[string]bool{}
+
+	schedules := GetSchedules()
+	for _, streamName := range getStreamsToRecordForHealthCheck() {
+		if schedules[streamName] == nil {
+			expected[streamName] = false
+		}
+	}
+	for streamName, schedule := range schedules {
+		if until, _ := schedule.activeUntil(now); !until.IsZero() && !isStreamPaused(streamName) {
+			expected[streamName] = true
+		}
+	}
+	return expected
+}
+
+// recordingStreams returns the streams with a running, not paused recording
+func recordingStreams() map[string]bool {
+	streams := map[string]bool{}
+	for _, recording := range GetRecordingManager().ListRecordings() {
+		if recording.Active {
+			streams[recording.Stream] = true
+		}
+	}
+	for _, recording := range GetSegmentedRecordingManager().ListSegmentedRecordings() {
+		if recording.Active {
+			streams[recording.Stream] = true
+		}
+	}
+	for streamName := range streams {
+		if isStreamPaused(streamName) {
+			delete(streams, streamName)
+		}
+	}
+	return streams
+}
+
+// notificationMessage describes a notification type for event alerts
+func notificationMessage(typ string) string {
+	switch typ {
+	case NotifyRecordingStalled:
+		return "recording stalled and was restarted"
+	case NotifyDiskWarning:
+		return "disk almost full, new recordings paused"
+	case NotifyResourceLimit:
+		return "recording process exceeded its resource limit and was killed"
+	}
+	return strings.ReplaceAll(typ, "_", " ")
+}
@@ -0,0 +1,91 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRules(t *testing.T) {
+	sent := make(chan Alert, 10)
+	notifiers := map[string]alertNotifier{
+		"test": alertNotifierFunc(func(alert Alert) error {
+			sent <- alert
+			return nil
+		}),
+	}
+
+	rule := AlertRule{Condition: AlertNotRecording, Cooldown: time.Hour}
+	require.Nil(t, rule.normalize(notifiers))
+	require.Equal(t, AlertNotRecording, rule.Name)
+	require.Equal(t, time.Minute*2, rule.For)
+
+	require.NotNil(t, (&AlertRule{Condition: "unknown"}).normalize(notifiers))
+	require.NotNil(t, (&AlertRule{Condition: AlertDiskLow, Notifiers: []string{"missing"}}).normalize(notifiers))
+
+	a := &alerter{rules: []AlertRule{rule}, notifiers: notifiers, states: map[string]*alertState{}}
+	now := time.Now()
+	down := map[string]string{"cam1": "not recording"}
+
+	// Fires once the condition held for the rule's duration
+	a.update(0, down, now)
+	a.update(0, down, now.Add(time.Minute))
+	require.Len(t, sent, 0)
+
+	a.update(0, down, now.Add(time.Minute*2))
+	alert := <-sent
+	require.Equal(t, AlertFiring, alert.State)
+	require.Equal(t, "cam1", alert.Stream)
+	require.Equal(t, now, alert.Since)
+	require.Len(t, a.firing(), 1)
+
+	// Repeated only after the cooldown
+	a.update(0, down, now.Add(time.Minute*30))
+	require.Len(t, sent, 0)
+	a.update(0, down, now.Add(time.Minute*63))
+	require.Equal(t, AlertFiring, (<-sent).State)
+
+	a.update(0, map[string]string{}, now.Add(time.Minute*64))
+	require.Equal(t, AlertResolved, (<-sent).State)
+	require.Len(t, a.firing(), 0)
+
+	// A condition that clears before firing is never sent
+	a.update(0, down, now.Add(time.Minute*70))
+	a.update(0, map[string]string{}, now.Add(time.Minute*71))
+	require.Len(t, sent, 0)
+}
+
+func TestAlertNotifiers(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	alert := Alert{Rule: "disk", Condition: AlertDiskLow, State: AlertFiring, Message: "5.0% disk space free"}
+
+	telegram, err := newAlertNotifier(AlertNotifierConfig{Name: "tg", Type: "telegram", URL: server.URL, Token: "TOKEN", ChatID: "42"})
+	require.Nil(t, err)
+	require.Nil(t, telegram.send(alert))
+	require.Equal(t, "/botTOKEN/sendMessage", (<-requests).URL.Path)
+	require.Equal(t, map[string]any{"chat_id": "42", "text": "[FIRING] disk: 5.0% disk space free"}, <-bodies)
+
+	webhook, err := newAlertNotifier(AlertNotifierConfig{Name: "hook", Type: "webhook", URL: server.URL})
+	require.Nil(t, err)
+	require.Nil(t, webhook.send(alert))
+	<-requests
+	require.Equal(t, "disk_low", (<-bodies)["condition"])
+
+	_, err = newAlertNotifier(AlertNotifierConfig{Name: "mail", Type: "email", URL: "mail.example.com"})
+	require.NotNil(t, err)
+	_, err = newAlertNotifier(AlertNotifierConfig{Name: "pager", Type: "pager"})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,244 @@
+package ffmpeg
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// annotationMaxText limits the text of a bookmark or note
+const annotationMaxText = 1000
+
+// Annotation is a timestamped bookmark or note on a recording, e.g.
+// "package stolen" at 14:32
+type Annotation struct {
+	ID      string    `json:"id"`
+	Offset  float64   `json:"offset"` // seconds into the recording
+	Time    time.Time `json:"time"`   // wall clock time at the offset
+	Text    string    `json:"text"`
+	User    string    `json:"user,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// hasAnnotation reports whether any annotation contains text, ignoring case
+func hasAnnotation(annotations []Annotation, text string) bool {
+	text = strings.ToLower(text)
+	for _, annotation := range annotations {
+		if strings.Contains(strings.ToLower(annotation.Text), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// apiRecordingAnnotations lists, adds and removes the bookmarks and notes of
+// a recording:
+//
+//	GET    /api/recordings/annotations?id=ID
+//	POST   /api/recordings/annotations?id=ID&text=...&offset=SECONDS
+//	POST   /api/recordings/annotations?id=ID&text=...&time=2025-01-15T14:32:00
+//	DELETE /api/recordings/annotations?id=ID&annotation=ANNOTATION_ID
+func apiRecordingAnnotations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	id := query.Get("id")
+	if id == "" {
+		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+		return
+	}
+
+	recording := recordingIndex.Get(id)
+	if recording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		annotations := recording.Annotations
+		if annotations == nil {
+			annotations = []Annotation{}
+		}
+		api.ResponseJSON(w, map[string]any{
+			"recording_id": recording.ID,
+			"annotations":  annotations,
+		})
+
+	case "POST":
+		annotation, err := newAnnotation(r, recording)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if recordingIndex.addAnnotation(recording.ID, *annotation) == nil {
+			http.Error(w, "Recording not found", http.StatusNotFound)
+			return
+		}
+		recordingIndex.Save()
+
+		log.Info().Str("recording_id", recording.ID).Str("annotation", annotation.ID).Msg("[api] annotation added")
+
+		api.ResponseJSON(w, annotation)
+
+	case "DELETE":
+		annotationID := query.Get("annotation")
+		if annotationID == "" {
+			http.Error(w, "Missing 'annotation' parameter", http.StatusBadRequest)
+			return
+		}
+
+		if _, found := recordingIndex.removeAnnotation(recording.ID, annotationID); !found {
+			http.Error(w, "Annotation not found", http.StatusNotFound)
+			return
+		}
+		recordingIndex.Save()
+
+		log.Info().Str("recording_id", recording.ID).Str("annotation", annotationID).Msg("[api] annotation removed")
+
+		api.ResponseJSON(w, map[string]any{
+			"recording_id": recording.ID,
+			"deleted":      annotationID,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// newAnnotation reads an annotation from the text and offset or time
+// parameters
+func newAnnotation(r *http.Request, recording *RecordingFile) (*Annotation, error) {
+	query := r.URL.Query()
+
+	text := strings.TrimSpace(query.Get("text"))
+	if text == "" {
+		return nil, fmt.Errorf("missing 'text' parameter")
+	}
+	if len(text) > annotationMaxText {
+		return nil, fmt.Errorf("'text' is longer than %d bytes", annotationMaxText)
+	}
+
+	var offset time.Duration
+	switch {
+	case query.Get("offset") != "":
+		seconds, err := strconv.ParseFloat(query.Get("offset"), 64)
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("invalid 'offset' parameter")
+		}
+		offset = time.Duration(seconds * float64(time.Second))
+	case query.Get("time") != "":
+		t, err := parseTimeParam(query.Get("time"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'time' parameter: %w", err)
+		}
+		if offset = t.Sub(recording.StartTime); offset < 0 {
+			return nil, fmt.Errorf("'time' is before the recording starts")
+		}
+	}
+
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	// The holder of a recording API token, or the API user
+	var principal *recordingPrincipal
+	if token := bearerToken(r); token != "" {
+		principal, _ = tokenPrincipal(token)
+	}
+
+	return &Annotation{
+		ID:      hex.EncodeToString(b),
+		Offset:  offset.Seconds(),
+		Time:    recording.StartTime.Add(offset),
+		Text:    text,
+		User:    auditUser(r, principal),
+		Created: time.Now(),
+	}, nil
+}
+
+// chapterMetadata writes the annotations as chapters in ffmpeg's metadata
+// format, each lasting until the next one or the end of the recording
+func chapterMetadata(annotations []Annotation, duration time.Duration) string {
+	escape := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+
+	var sb strings.Builder
+	sb.WriteString(";FFMETADATA1\n")
+
+	for i, annotation := range annotations {
+		start := int64(annotation.Offset * 1000)
+		end := duration.Milliseconds()
+		if i+1 < len(annotations) {
+			end = int64(annotations[i+1].Offset * 1000)
+		}
+		if end <= start {
+			end = start + 1
+		}
+		fmt.Fprintf(&sb, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n", start, end, escape.Replace(annotation.Text))
+	}
+
+	return sb.String()
+}
+
+// serveRecordingWithChapters streams the recording remuxed to Matroska with
+// its annotations as chapters
+func serveRecordingWithChapters(w http.ResponseWriter, r *http.Request, recording *RecordingFile, disposition string) {
+	if len(recording.Annotations) == 0 {
+		http.Error(w, "The recording has no annotations", http.StatusNotFound)
+		return
+	}
+
+	var duration time.Duration
+	if info, err := getRecordingInfo(recording); err == nil {
+		duration = time.Duration(info.Duration * float64(time.Second))
+	} else if !recording.EndTime.IsZero() {
+		duration = recording.EndTime.Sub(recording.StartTime)
+	}
+
+	meta, err := os.CreateTemp("", "go2rtc-chapters-*.txt")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to write chapters: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(meta.Name())
+
+	_, err = meta.WriteString(chapterMetadata(recording.Annotations, duration))
+	if closeErr := meta.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to write chapters: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	input := recordingInput(recording.Path)
+
+	args := []string{"-hide_banner", "-v", "error"}
+	args = append(args, ffmpegProtocolArgs(input)...)
+	args = append(args,
+		"-i", input,
+		"-f", "ffmetadata", "-i", meta.Name(),
+		"-map", "0", "-map_chapters", "1",
+		"-c", "copy",
+		"-f", "matroska", "pipe:1",
+	)
+
+	filename := strings.TrimSuffix(recording.Filename, filepath.Ext(recording.Filename)) + ".mkv"
+	w.Header().Set("Content-Type", "video/x-matroska")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=\"%s\"", disposition, filename))
+
+	cmd := exec.CommandContext(r.Context(), ffmpegBin(), args...)
+	cmd.Stdout = w
+
+	if err = cmd.Run(); err != nil && r.Context().Err() == nil {
+		log.Warn().Err(err).Str("recording_id", recording.ID).Msg("[api] failed to export recording with chapters")
+	}
+}
@@ -0,0 +1,157 @@
+package ffmpeg
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/shell"
+)
+
+// expandFFmpegTemplate fills a stream's ffmpeg_template. {input} is the
+// recording source, {output} the generated codec, format and file options and
+// {stream} the stream name, e.g.
+//
+//	-rtsp_transport tcp -timeout 5000000 -i {input} -map 0 -metadata title={stream} {output}
+//
+// The template is split into arguments before the placeholders are filled,
+// quotes keep an argument with spaces together. The output is appended when
+// the template doesn't place it, so a template can also consist of input
+// options only.
+func expandFFmpegTemplate(template, input string, output []string, stream string) ([]string, error) {
+	fields := shell.QuoteSplit(template)
+	if fields == nil {
+		return nil, errors.New("unbalanced quotes")
+	}
+
+	replacer := strings.NewReplacer("{input}", input, "{stream}", stream)
+
+	var args []string
+	var placed bool
+	for _, field := range fields {
+		if field == "{output}" {
+			args = append(args, output...)
+			placed = true
+		} else {
+			args = append(args, replacer.Replace(field))
+		}
+	}
+	if !placed {
+		args = append(args, output...)
+	}
+	return args, nil
+}
+
+// hasQualityLimits reports whether the stream constrains resolution, frame
+// rate or bitrate, which requires a video transcode
+func hasQualityLimits(streamConfig StreamRecordingConfig) bool {
+	return streamConfig.Width > 0 || streamConfig.Height > 0 ||
+		streamConfig.Framerate > 0 || streamConfig.BitrateLimit != ""
+}
+
+// scaleFilter returns the scale filter for the stream's width and height, a
+// missing side keeps the aspect ratio like the #width/#height source params
+func scaleFilter(streamConfig StreamRecordingConfig) string {
+	if streamConfig.Width <= 0 && streamConfig.Height <= 0 {
+		return ""
+	}
+
+	width, height := "-2", "-2" // even sizes for h264/h265 encoders
+	if streamConfig.Width > 0 {
+		width = strconv.Itoa(streamConfig.Width)
+	}
+	if streamConfig.Height > 0 {
+		height = strconv.Itoa(streamConfig.Height)
+	}
+	return "scale=" + width + ":" + height
+}
+
+// outputQualityArgs returns the frame rate and bitrate options for the
+// video encoder
+func outputQualityArgs(streamConfig StreamRecordingConfig) []string {
+	var args []string
+	if streamConfig.Framerate > 0 {
+		args = append(args, "-r", strconv.Itoa(streamConfig.Framerate))
+	}
+	if b := streamConfig.BitrateLimit; b != "" {
+		// https://trac.ffmpeg.org/wiki/Limiting%20the%20output%20bitrate
+		args = append(args, "-b:v", b, "-maxrate", b, "-bufsize", b)
+	}
+	return args
+}
+
+// forcedKeyframeDelta is the segment_time_delta used when keyframes are
+// forced, the forced frame's timestamp may be rounded just below the cut
+const forcedKeyframeDelta = 50 * time.Millisecond
+
+// keyframeAligned reports whether the segments of a stream start with a
+// keyframe
+func keyframeAligned(streamConfig StreamRecordingConfig) bool {
+	return streamConfig.KeyframeAlign != nil && *streamConfig.KeyframeAlign
+}
+
+// forceKeyframesArgs returns the encoder option placing a keyframe at every
+// segment cut. Stream copies keep the camera's keyframes, so the cut waits
+// for the next one.
+func forceKeyframesArgs(video string, segmentTime int) []string {
+	if video == "copy" || segmentTime <= 0 {
+		return nil
+	}
+	return []string{"-force_key_frames", "expr:gte(t,n_forced*" + strconv.Itoa(segmentTime) + ")"}
+}
+
+// segmentTimeDelta returns the segment muxer's tolerance for cutting on a
+// keyframe before the segment time
+func segmentTimeDelta(forced bool) time.Duration {
+	if delta := GetRecordingConfig().SegmentTimeDelta; delta > 0 || !forced {
+		return delta
+	}
+	return forcedKeyframeDelta
+}
+
+// validRTSPTransport reports whether ffmpeg knows an rtsp_transport, auto
+// leaves the choice to ffmpeg
+func validRTSPTransport(transport string) bool {
+	switch transport {
+	case "", "auto", "tcp", "udp", "udp_multicast", "http", "https":
+		return true
+	}
+	return false
+}
+
+// inputArgs returns the ffmpeg options for the recording source: RTSP
+// transport, I/O timeout, HTTP reconnects and probing
+func inputArgs(source string, streamConfig StreamRecordingConfig) []string {
+	var args []string
+
+	if source != "pipe:0" {
+		scheme, _, _ := strings.Cut(source, "://")
+		switch scheme {
+		case "rtsp", "rtsps":
+			if t := streamConfig.RTSPTransport; t != "" && t != "auto" {
+				args = append(args, "-rtsp_transport", t)
+			}
+			if streamConfig.InputTimeout > 0 {
+				args = append(args, "-timeout", strconv.FormatInt(streamConfig.InputTimeout.Microseconds(), 10))
+			}
+		case "http", "https":
+			if streamConfig.Reconnect != nil && *streamConfig.Reconnect {
+				args = append(args, "-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "10")
+			}
+			fallthrough
+		default:
+			if streamConfig.InputTimeout > 0 {
+				args = append(args, "-rw_timeout", strconv.FormatInt(streamConfig.InputTimeout.Microseconds(), 10))
+			}
+		}
+	}
+
+	if streamConfig.AnalyzeDuration > 0 {
+		args = append(args, "-analyzeduration", strconv.FormatInt(streamConfig.AnalyzeDuration.Microseconds(), 10))
+	}
+	if streamConfig.ProbeSize > 0 {
+		args = append(args, "-probesize", strconv.FormatInt(streamConfig.ProbeSize, 10))
+	}
+	return args
+}
@@ -0,0 +1,233 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// AuditEntry is one access to recordings in the audit log
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"` // list, play, replay, download, export or delete
+	User      string    `json:"user"`
+	RemoteIP  string    `json:"remote_ip"`
+	Stream    string    `json:"stream,omitempty"`
+	Recording string    `json:"recording,omitempty"`
+	Query     string    `json:"query,omitempty"` // request parameters, without the token
+	Status    int       `json:"status"`
+}
+
+var auditMu sync.Mutex
+
+func auditEnabled() bool {
+	return GetRecordingConfig().AuditLog != ""
+}
+
+// auditAction returns the audited action of a request to a recording route,
+// empty for requests that aren't audited
+func auditAction(pattern string, r *http.Request) string {
+	query := r.URL.Query()
+
+	switch pattern {
+	case "api/recordings":
+		switch r.Method {
+		case "GET":
+			switch {
+			case query.Get("download") != "":
+				return "download"
+			case query.Get("play") != "":
+				return "play"
+			case query.Get("info") == "" && query.Get("thumbnail") == "" && query.Get("preview") == "":
+				return "list"
+			}
+		case "DELETE":
+			return "delete"
+		}
+	case "api/recordings/hls":
+		// Only the playlist, not every segment
+		if query.Get("segment") == "" {
+			return "play"
+		}
+	case "api/recordings/export":
+		return "export"
+	case frigatePrefix:
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/clip.mp4"):
+			return "download"
+		case strings.HasSuffix(r.URL.Path, "/events"):
+			return "list"
+		}
+	}
+	return ""
+}
+
+// auditUser names who made a request: the token holder, the API user or the
+// local host
+func auditUser(r *http.Request, principal *recordingPrincipal) string {
+	switch {
+	case principal != nil:
+		if principal.name != "" {
+			return "token:" + principal.name
+		}
+		return "token"
+	case api.Authenticated(r):
+		if user, _, ok := r.BasicAuth(); ok {
+			return user
+		}
+		return "local"
+	}
+	return "anonymous"
+}
+
+// newAuditEntry describes a request before it's handled, a deleted
+// recording can't be looked up afterwards
+func newAuditEntry(r *http.Request, action string) *AuditEntry {
+	query := r.URL.Query()
+	query.Del("token")
+
+	entry := &AuditEntry{
+		Time:     time.Now(),
+		Action:   action,
+		RemoteIP: r.RemoteAddr,
+		Query:    query.Encode(),
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		entry.RemoteIP = host
+	}
+	for _, param := range recordingIDParams {
+		if id := query.Get(param); id != "" {
+			entry.Recording = id
+			break
+		}
+	}
+	// The stream of the recording, not the one the client claims
+	if streamName, ok := recordingIDStream(entry.Recording); ok {
+		entry.Stream = streamName
+	} else {
+		entry.Stream, _, _ = requestStream(r)
+	}
+	return entry
+}
+
+// writeAuditEntry appends one JSON line, the file is never rewritten
+func writeAuditEntry(entry AuditEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	path := GetRecordingConfig().AuditLog
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		var f *os.File
+		if f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err == nil {
+			_, err = f.Write(append(data, '\n'))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("[audit] failed to write audit log")
+	}
+}
+
+// AuditFilter selects audit log entries, zero fields match everything
+type AuditFilter struct {
+	Since     time.Time
+	Until     time.Time
+	Action    string
+	User      string
+	Stream    string
+	Recording string
+}
+
+func (f AuditFilter) matches(entry *AuditEntry) bool {
+	return (f.Since.IsZero() || !entry.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || entry.Time.Before(f.Until)) &&
+		(f.Action == "" || entry.Action == f.Action) &&
+		(f.User == "" || entry.User == f.User) &&
+		(f.Stream == "" || entry.Stream == f.Stream) &&
+		(f.Recording == "" || entry.Recording == f.Recording)
+}
+
+// readAuditLog returns the matching entries, newest first, at most limit
+// of them (all if limit is 0). It doesn't block writers: lines are appended
+// whole, a line still being written is skipped as invalid.
+func readAuditLog(filter AuditFilter, limit int) ([]AuditEntry, error) {
+	f, err := os.Open(GetRecordingConfig().AuditLog)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || !filter.matches(&entry) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// The file is in write order
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// statusRecorder remembers the status code a handler responded with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
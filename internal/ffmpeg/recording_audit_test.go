@@ -0,0 +1,46 @@
+package ffmpeg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditEntryStream(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+
+	dir := t.TempDir()
+	setRecordingConfig(&RecordingConfig{
+		BasePath: dir,
+		AuditLog: filepath.Join(dir, ".audit.log"),
+	})
+
+	path := filepath.Join(dir, "cam2", "cam2_2024-01-01_12-00-00.mp4")
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.Nil(t, os.WriteFile(path, []byte("recording"), 0644))
+	recordingIndex.Update(path)
+	t.Cleanup(func() { recordingIndex.Remove(path) })
+	id := recordingIndex.GetByPath(path).ID
+
+	// The recording's stream, whatever stream the client names
+	r := httptest.NewRequest("GET", "/api/recordings?download="+url.QueryEscape(id)+"&stream=cam1", nil)
+	entry := newAuditEntry(r, "download")
+	require.Equal(t, id, entry.Recording)
+	require.Equal(t, "cam2", entry.Stream)
+
+	r = httptest.NewRequest("GET", "/api/recordings?stream=cam1", nil)
+	require.Equal(t, "cam1", newAuditEntry(r, "list").Stream)
+
+	entry.Status = http.StatusOK
+	writeAuditEntry(*entry)
+	entries, err := readAuditLog(AuditFilter{Stream: "cam2"}, 0)
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, id, entries[0].Recording)
+}
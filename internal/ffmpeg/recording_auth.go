@@ -0,0 +1,395 @@
+package ffmpeg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// RecordingAPIToken grants a bearer token access to the recording API
+type RecordingAPIToken struct {
+	Name    string   `yaml:"name"`    // shown in logs
+	Token   string   `yaml:"token"`   // secret sent as "Authorization: Bearer TOKEN" or ?token=
+	Role    string   `yaml:"role"`    // viewer, operator or admin
+	Streams []string `yaml:"streams"` // stream names, globs or "group:NAME", all streams if empty
+}
+
+// recordingPermission is what a request does, each role allows everything up
+// to its level
+type recordingPermission int
+
+const (
+	permView     recordingPermission = iota // list, play and inspect recordings
+	permDownload                            // download and export files
+	permControl                             // start and stop recordings, events, holds, merges
+	permDelete                              // delete recordings
+	permAdmin                               // configuration, cleanup, schedules, resets
+)
+
+var recordingRoles = map[string]recordingPermission{
+	"viewer":   permView,
+	"operator": permControl,
+	"admin":    permAdmin,
+}
+
+// recordingPrincipal is the holder of a valid token
+type recordingPrincipal struct {
+	name    string
+	level   recordingPermission
+	streams []string
+}
+
+// recordingRoutes are the patterns registered with handleRecordingFunc
+var recordingRoutes = map[string]bool{}
+
+// handleRecordingFunc registers a recording API handler that requires the
+// permission returned by required when recording API tokens are configured,
+// and audits it when audit_log is set
+func handleRecordingFunc(pattern string, required func(r *http.Request) recordingPermission, handler http.HandlerFunc) {
+	recordingRoutes["/"+pattern] = true
+	api.HandleFunc(pattern, withRecordingAuth(pattern, required, handler))
+}
+
+// requirePermission requires the same permission for every request
+func requirePermission(permission recordingPermission) func(r *http.Request) recordingPermission {
+	return func(r *http.Request) recordingPermission {
+		return permission
+	}
+}
+
+// requireReadWrite requires view for GET requests and write otherwise
+func requireReadWrite(write recordingPermission) func(r *http.Request) recordingPermission {
+	return func(r *http.Request) recordingPermission {
+		if r.Method == "GET" {
+			return permView
+		}
+		return write
+	}
+}
+
+// recordingsPermission classifies the requests of /api/recordings
+func recordingsPermission(r *http.Request) recordingPermission {
+	switch r.Method {
+	case "GET":
+		if r.URL.Query().Get("download") != "" {
+			return permDownload
+		}
+		return permView
+	case "DELETE":
+		return permDelete
+	}
+	return permControl
+}
+
+func recordingAuthEnabled() bool {
+	return len(GetRecordingConfig().APITokens) > 0 || GetRecordingConfig().JWTSecret != ""
+}
+
+// withRecordingAuth checks a request against the recording API tokens and
+// writes accesses to recordings to the audit log
+func withRecordingAuth(pattern string, required func(r *http.Request) recordingPermission, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var principal *recordingPrincipal
+
+		if auditEnabled() {
+			if action := auditAction(pattern, r); action != "" {
+				entry := newAuditEntry(r, action)
+				recorder := &statusRecorder{ResponseWriter: w}
+				w = recorder
+				defer func() {
+					entry.User = auditUser(r, principal)
+					entry.Status = recorder.status
+					if entry.Status == 0 {
+						entry.Status = http.StatusOK // the handler wrote nothing
+					}
+					writeAuditEntry(*entry)
+				}()
+			}
+		}
+
+		var ok bool
+		if principal, ok = authorizeRecordingRequest(w, r, required(r)); ok {
+			handler(w, r)
+		}
+	}
+}
+
+// authorizeRecordingRequest responds with an error unless the request may
+// do what it asks. With the API credentials or from the local host
+// everything is allowed, without any tokens configured the recording API is
+// as open as the rest of the API. The principal is nil without a token.
+func authorizeRecordingRequest(w http.ResponseWriter, r *http.Request, required recordingPermission) (*recordingPrincipal, bool) {
+	principal, err := checkRecordingAccess(r, required)
+	if err != nil {
+		if err.challenge != "" {
+			w.Header().Set("WWW-Authenticate", err.challenge)
+		}
+		http.Error(w, err.message, err.status)
+		return principal, false
+	}
+	return principal, true
+}
+
+// recordingAccessError is why a request was refused
+type recordingAccessError struct {
+	status    int
+	message   string
+	challenge string // WWW-Authenticate header of a 401
+}
+
+func (e *recordingAccessError) Error() string {
+	return e.message
+}
+
+// checkRecordingAccess is authorizeRecordingRequest without a response, for
+// requests that don't come over HTTP like RTSP replays
+func checkRecordingAccess(r *http.Request, required recordingPermission) (*recordingPrincipal, *recordingAccessError) {
+	if !recordingAuthEnabled() {
+		return nil, nil
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		if api.Authenticated(r) {
+			return nil, nil
+		}
+		return nil, &recordingAccessError{http.StatusUnauthorized, "Unauthorized", `Bearer realm="go2rtc"`}
+	}
+
+	principal, err := tokenPrincipal(token)
+	if err != nil {
+		log.Debug().Err(err).Str("remote", r.RemoteAddr).Msg("[api] rejected recording API token")
+		return nil, &recordingAccessError{http.StatusUnauthorized, "Unauthorized", `Bearer realm="go2rtc", error="invalid_token"`}
+	}
+
+	if principal.level < required {
+		return principal, &recordingAccessError{status: http.StatusForbidden, message: "Forbidden: the token's role doesn't allow this"}
+	}
+
+	if len(principal.streams) > 0 {
+		streamName, ok, err := requestStream(r)
+		if err != nil {
+			return principal, &recordingAccessError{status: http.StatusForbidden, message: "Forbidden: " + err.Error()}
+		}
+		if !ok {
+			return principal, &recordingAccessError{status: http.StatusForbidden, message: "Forbidden: the token is limited to some streams, pass 'stream'"}
+		}
+		if !principal.allows(streamName) {
+			return principal, &recordingAccessError{status: http.StatusForbidden, message: "Forbidden: the token doesn't allow this stream"}
+		}
+	}
+
+	return principal, nil
+}
+
+// recordingTokenAuth lets requests with a valid token past the API's basic
+// auth, for recording routes only
+func recordingTokenAuth(r *http.Request) bool {
+	if !recordingAuthEnabled() || !isRecordingRoute(r.URL.Path) {
+		return false
+	}
+	token := bearerToken(r)
+	if token == "" {
+		return false
+	}
+	_, err := tokenPrincipal(token)
+	return err == nil
+}
+
+func isRecordingRoute(path string) bool {
+	for route := range recordingRoutes {
+		if strings.HasSuffix(path, route) {
+			return true
+		}
+		// Patterns ending in a slash match their subtree
+		if strings.HasSuffix(route, "/") && strings.Contains(path, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the token of the Authorization header or the token
+// query parameter, which players and <video> tags can't set headers for
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.URL.Query().Get("token")
+}
+
+// tokenPrincipal checks a configured token or, with jwt_secret, a JWT
+func tokenPrincipal(token string) (*recordingPrincipal, error) {
+	cfg := GetRecordingConfig()
+
+	for _, configured := range cfg.APITokens {
+		if configured.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(configured.Token)) != 1 {
+			continue
+		}
+		level, ok := recordingRoles[configured.Role]
+		if !ok {
+			return nil, errors.New("unknown role " + configured.Role)
+		}
+		return &recordingPrincipal{name: configured.Name, level: level, streams: configured.Streams}, nil
+	}
+
+	if cfg.JWTSecret != "" && strings.Count(token, ".") == 2 {
+		return jwtPrincipal(token, cfg.JWTSecret, time.Now())
+	}
+
+	return nil, errors.New("unknown token")
+}
+
+// jwtClaims are the claims read from a recording API JWT
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Role      string   `json:"role"`
+	Streams   []string `json:"streams"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+}
+
+// jwtPrincipal verifies an HS256 JWT signed with secret
+func jwtPrincipal(token, secret string, now time.Time) (*recordingPrincipal, error) {
+	parts := strings.Split(token, ".")
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("invalid JWT header")
+	}
+	var head struct {
+		Alg string `json:"alg"`
+	}
+	if err = json.Unmarshal(header, &head); err != nil || head.Alg != "HS256" {
+		return nil, errors.New("JWT must be signed with HS256")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("invalid JWT signature")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("invalid JWT signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("invalid JWT payload")
+	}
+	var claims jwtClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("invalid JWT claims")
+	}
+
+	if claims.ExpiresAt != 0 && now.Unix() >= claims.ExpiresAt {
+		return nil, errors.New("JWT expired")
+	}
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return nil, errors.New("JWT not valid yet")
+	}
+
+	level, ok := recordingRoles[claims.Role]
+	if !ok {
+		return nil, errors.New("unknown role " + claims.Role)
+	}
+
+	return &recordingPrincipal{name: claims.Subject, level: level, streams: claims.Streams}, nil
+}
+
+// allows reports whether the token may access the stream
+func (p *recordingPrincipal) allows(streamName string) bool {
+	for _, key := range p.streams {
+		if matchStreamKey(GetRecordingConfig(), key, streamName) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingIDParams are the query parameters that name a recording
+var recordingIDParams = []string{"id", "download", "info", "play", "thumbnail", "preview", "protect", "unprotect", "repair", "restore", "segment"}
+
+var errStreamMismatch = errors.New("the request names recordings of another stream")
+
+// requestStream returns the stream a request is about. The recordings, jobs
+// and events it names are resolved first, the stream parameters must agree
+// with them: a token limited to cam1 must not reach cam2 footage by adding
+// stream=cam1. errStreamMismatch if the request names more than one stream.
+func requestStream(r *http.Request) (string, bool, error) {
+	query := r.URL.Query()
+
+	var streams []string
+	for _, param := range recordingIDParams {
+		for _, id := range query[param] {
+			if streamName, ok := recordingIDStream(id); ok {
+				streams = append(streams, streamName)
+			}
+		}
+	}
+
+	// Background jobs belong to the stream they were started for
+	for _, id := range query["id"] {
+		if job, ok := jobs.Get(id); ok && job.Stream != "" {
+			streams = append(streams, job.Stream)
+		}
+	}
+
+	for _, path := range query["path"] {
+		if recording := lookupRecordingPath(path); recording != nil {
+			streams = append(streams, recording.StreamName)
+		}
+	}
+
+	if streamName, ok := mediaSourceStream(query); ok {
+		streams = append(streams, streamName)
+	}
+	if streamName, ok := v1RequestStream(r); ok {
+		streams = append(streams, streamName)
+	}
+	if streamName, ok := frigatePathStream(r.URL.Path); ok {
+		streams = append(streams, streamName)
+	}
+
+	// /api/record names the stream src, the Frigate API the camera
+	for _, param := range []string{"stream", "src", "camera"} {
+		for _, streamName := range query[param] {
+			if streamName != "" {
+				streams = append(streams, streamName)
+			}
+		}
+	}
+
+	if len(streams) == 0 {
+		return "", false, nil
+	}
+	for _, streamName := range streams[1:] {
+		if streamName != streams[0] {
+			return "", false, errStreamMismatch
+		}
+	}
+	return streams[0], true, nil
+}
+
+// recordingIDStream returns the stream of an indexed or running recording
+func recordingIDStream(id string) (string, bool) {
+	if id == "" {
+		return "", false
+	}
+	if recording := recordingIndex.Get(id); recording != nil {
+		return recording.StreamName, true
+	}
+	if recording := GetRecordingManager().GetRecording(id); recording != nil {
+		return recording.Stream, true
+	}
+	return "", false
+}
@@ -0,0 +1,197 @@
+package ffmpeg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// signJWT returns an HS256 JWT, or one with another alg header
+func signJWT(t *testing.T, alg, secret string, claims map[string]any) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	require.Nil(t, err)
+	payload, err := json.Marshal(claims)
+	require.Nil(t, err)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func authorize(token, target string, required recordingPermission) int {
+	r := httptest.NewRequest("GET", target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	if _, ok := authorizeRecordingRequest(w, r, required); ok {
+		return http.StatusOK
+	}
+	return w.Code
+}
+
+func TestRecordingAuth(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+
+	dir := t.TempDir()
+	setRecordingConfig(&RecordingConfig{
+		BasePath:  dir,
+		IndexPath: filepath.Join(dir, ".recordings.index"),
+		APITokens: []RecordingAPIToken{
+			{Name: "viewer", Token: "viewer-token", Role: "viewer"},
+			{Name: "operator", Token: "operator-token", Role: "operator"},
+			{Name: "admin", Token: "admin-token", Role: "admin"},
+			{Name: "cam1", Token: "cam1-token", Role: "admin", Streams: []string{"cam1"}},
+		},
+	})
+
+	path := filepath.Join(dir, "cam2", "cam2_2024-01-01_12-00-00.mp4")
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.Nil(t, os.WriteFile(path, []byte("recording"), 0644))
+	recordingIndex.Update(path)
+	t.Cleanup(func() { recordingIndex.Remove(path) })
+	recording := recordingIndex.GetByPath(path)
+	require.NotNil(t, recording)
+	require.Equal(t, "cam2", recording.StreamName)
+
+	// Roles allow everything up to their level
+	require.Equal(t, http.StatusUnauthorized, authorize("", "/api/recordings", permView))
+	require.Equal(t, http.StatusUnauthorized, authorize("unknown", "/api/recordings", permView))
+	require.Equal(t, http.StatusOK, authorize("viewer-token", "/api/recordings", permView))
+	require.Equal(t, http.StatusForbidden, authorize("viewer-token", "/api/recordings", permDownload))
+	require.Equal(t, http.StatusOK, authorize("operator-token", "/api/recordings", permControl))
+	require.Equal(t, http.StatusForbidden, authorize("operator-token", "/api/recordings", permDelete))
+	require.Equal(t, http.StatusOK, authorize("admin-token", "/api/recordings", permAdmin))
+
+	// Tokens limited to streams
+	require.Equal(t, http.StatusOK, authorize("cam1-token", "/api/recordings?stream=cam1", permView))
+	require.Equal(t, http.StatusForbidden, authorize("cam1-token", "/api/recordings?stream=cam2", permView))
+	require.Equal(t, http.StatusForbidden, authorize("cam1-token", "/api/recordings", permView))
+	require.Equal(t, http.StatusForbidden, authorize("cam1-token", "/api/recordings?stream=cam1&stream=cam2", permView))
+
+	// The recording a request names wins over the stream it claims
+	id := url.QueryEscape(recording.ID)
+	for _, param := range []string{"download", "info", "play", "id", "protect"} {
+		target := "/api/recordings?" + param + "=" + id
+		require.Equal(t, http.StatusForbidden, authorize("cam1-token", target, permView), param)
+		require.Equal(t, http.StatusForbidden, authorize("cam1-token", target+"&stream=cam1", permView), param)
+		require.Equal(t, http.StatusOK, authorize("admin-token", target+"&stream=cam1", permView), param)
+	}
+	require.Equal(t, http.StatusForbidden, authorize("cam1-token", "/api/recordings/lookup?stream=cam1&path=cam2/cam2_2024-01-01_12-00-00.mp4", permView))
+
+	streamName, ok, err := requestStream(httptest.NewRequest("GET", "/api/recordings?download="+id, nil))
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, "cam2", streamName)
+	_, _, err = requestStream(httptest.NewRequest("GET", "/api/recordings?download="+id+"&stream=cam1", nil))
+	require.ErrorIs(t, err, errStreamMismatch)
+}
+
+func TestRecordingAuthJWT(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{JWTSecret: "secret"})
+
+	now := time.Now()
+	claims := map[string]any{"sub": "nvr", "role": "operator", "streams": []string{"cam1"}, "exp": now.Add(time.Hour).Unix()}
+
+	principal, err := jwtPrincipal(signJWT(t, "HS256", "secret", claims), "secret", now)
+	require.Nil(t, err)
+	require.Equal(t, "nvr", principal.name)
+	require.Equal(t, permControl, principal.level)
+	require.True(t, principal.allows("cam1"))
+	require.False(t, principal.allows("cam2"))
+
+	token := signJWT(t, "HS256", "secret", claims)
+	require.Equal(t, http.StatusOK, authorize(token, "/api/recordings?stream=cam1", permControl))
+	require.Equal(t, http.StatusForbidden, authorize(token, "/api/recordings?stream=cam2", permControl))
+	require.Equal(t, http.StatusForbidden, authorize(token, "/api/recordings?stream=cam1", permDelete))
+
+	// Signature
+	_, err = jwtPrincipal(signJWT(t, "HS256", "other", claims), "secret", now)
+	require.Error(t, err)
+	tampered := signJWT(t, "HS256", "secret", map[string]any{"role": "viewer"})
+	admin := signJWT(t, "HS256", "secret", map[string]any{"role": "admin"})
+	_, err = jwtPrincipal(tampered[:len(tampered)-43]+admin[len(admin)-43:], "secret", now)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, authorize(signJWT(t, "HS256", "other", claims), "/api/recordings?stream=cam1", permView))
+
+	// alg
+	for _, alg := range []string{"none", "HS512", "RS256", ""} {
+		_, err = jwtPrincipal(signJWT(t, alg, "secret", claims), "secret", now)
+		require.Error(t, err, alg)
+	}
+
+	// exp and nbf
+	claims["exp"] = now.Add(-time.Second).Unix()
+	_, err = jwtPrincipal(signJWT(t, "HS256", "secret", claims), "secret", now)
+	require.ErrorContains(t, err, "expired")
+	claims["exp"] = now.Unix()
+	_, err = jwtPrincipal(signJWT(t, "HS256", "secret", claims), "secret", now)
+	require.ErrorContains(t, err, "expired")
+	delete(claims, "exp")
+	claims["nbf"] = now.Add(time.Minute).Unix()
+	_, err = jwtPrincipal(signJWT(t, "HS256", "secret", claims), "secret", now)
+	require.ErrorContains(t, err, "not valid yet")
+
+	// Unknown role
+	_, err = jwtPrincipal(signJWT(t, "HS256", "secret", map[string]any{"role": "root"}), "secret", now)
+	require.Error(t, err)
+}
+
+func TestReplayAuth(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+
+	dir := t.TempDir()
+	setRecordingConfig(&RecordingConfig{
+		BasePath: dir,
+		AuditLog: filepath.Join(dir, ".audit.log"),
+		APITokens: []RecordingAPIToken{
+			{Name: "viewer", Token: "viewer-token", Role: "viewer"},
+			{Name: "cam1", Token: "cam1-token", Role: "viewer", Streams: []string{"cam1"}},
+		},
+	})
+
+	replay := func(streamName, token, remoteAddr string) int {
+		query := url.Values{"start": {"2025-01-15T14:30:00"}}
+		if token != "" {
+			query.Set("token", token)
+		}
+		if err := authorizeReplay(streamName, query, remoteAddr); err != nil {
+			var accessErr *recordingAccessError
+			require.ErrorAs(t, err, &accessErr)
+			return accessErr.status
+		}
+		return http.StatusOK
+	}
+
+	require.Equal(t, http.StatusUnauthorized, replay("cam1", "", "192.168.1.20:50000"))
+	require.Equal(t, http.StatusUnauthorized, replay("cam1", "unknown", "192.168.1.20:50000"))
+	require.Equal(t, http.StatusOK, replay("cam1", "", "127.0.0.1:50000"))
+	require.Equal(t, http.StatusOK, replay("cam2", "viewer-token", "192.168.1.20:50000"))
+	require.Equal(t, http.StatusOK, replay("cam1", "cam1-token", "192.168.1.20:50000"))
+	require.Equal(t, http.StatusForbidden, replay("cam2", "cam1-token", "192.168.1.20:50000"))
+
+	entries, err := readAuditLog(AuditFilter{Action: "replay"}, 0)
+	require.Nil(t, err)
+	require.Len(t, entries, 6)
+	entry := entries[0] // newest first
+	require.Equal(t, "token:cam1", entry.User)
+	require.Equal(t, "192.168.1.20", entry.RemoteIP)
+	require.Equal(t, "cam2", entry.Stream)
+	require.Equal(t, http.StatusForbidden, entry.Status)
+	require.NotContains(t, entry.Query, "cam1-token")
+}
@@ -1,8 +1,9 @@
 package ffmpeg
 
 import (
+	"errors"
 	"fmt"
-	"os/exec"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,12 +14,180 @@ import (
 // AutoRecordingManager handles automatic recording startup
 type AutoRecordingManager struct {
 	started bool
-	failedStreams map[string]time.Time // Track failed streams and when they failed
+	failedStreams map[string]*StreamFailure // Track failed streams and when to retry them
+	startedAt map[string]time.Time // Last auto start of each stream
 	mu sync.Mutex
 }
 
 var autoRecordingManager = &AutoRecordingManager{
-	failedStreams: make(map[string]time.Time),
+	failedStreams: make(map[string]*StreamFailure),
+	startedAt: make(map[string]time.Time),
+}
+
+// StreamFailure tracks consecutive failed auto-recording attempts of a stream
+type StreamFailure struct {
+	Failures    int       `json:"failures"`
+	Code        string    `json:"code,omitempty"` // error code of the last failure
+	LastError   string    `json:"last_error,omitempty"`
+	LastFailure time.Time `json:"last_failure"`
+	NextAttempt time.Time `json:"next_attempt"`
+}
+
+// A recording that runs this long counts as a successful start
+const autoRecordStableAfter = time.Minute
+
+// shouldAttempt reports whether the backoff of a failing stream has expired
+func (m *AutoRecordingManager) shouldAttempt(streamName string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	failure := m.failedStreams[streamName]
+	return failure == nil || !time.Now().Before(failure.NextAttempt)
+}
+
+// attempted records the result of an auto start
+func (m *AutoRecordingManager) attempted(streamName string, err error) {
+	if errors.Is(err, errNoRecordingSlot) {
+		return // not the camera's fault, the next check tries again
+	}
+	if err != nil {
+		m.failed(streamName, newStreamError("", err))
+		return
+	}
+
+	m.mu.Lock()
+	m.startedAt[streamName] = time.Now()
+	m.mu.Unlock()
+}
+
+// observe checks the outcome of the last auto start. A recording that died
+// shortly after starting is a failure, one that kept running clears the
+// failure count.
+func (m *AutoRecordingManager) observe(streamName string, recording bool) {
+	m.mu.Lock()
+	startedAt, ok := m.startedAt[streamName]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	running := time.Since(startedAt)
+
+	if recording {
+		if running < autoRecordStableAfter {
+			m.mu.Unlock()
+			return
+		}
+		delete(m.startedAt, streamName)
+		failure := m.failedStreams[streamName]
+		delete(m.failedStreams, streamName)
+		m.mu.Unlock()
+
+		if failure != nil {
+			log.Info().
+				Str("stream", streamName).
+				Int("failures", failure.Failures).
+				Msg("[recording] auto-recording recovered")
+		}
+		return
+	}
+
+	delete(m.startedAt, streamName)
+	m.mu.Unlock()
+
+	if running < autoRecordStableAfter {
+		streamErr, ok := GetStreamErrors()[streamName]
+		if !ok {
+			streamErr = StreamError{Code: ErrorCodeFFmpegExit, Error: "recording stopped shortly after start"}
+		}
+		m.failed(streamName, streamErr)
+	}
+}
+
+// failed counts a failure and schedules the next attempt with the backoff of
+// its error code
+func (m *AutoRecordingManager) failed(streamName string, streamErr StreamError) {
+	m.mu.Lock()
+	failure := m.failedStreams[streamName]
+	if failure == nil {
+		failure = &StreamFailure{}
+		m.failedStreams[streamName] = failure
+	}
+	failure.Failures++
+	failure.Code = streamErr.Code
+	failure.LastError = streamErr.Error
+	failure.LastFailure = time.Now()
+
+	backoff := failureBackoff(streamErr.Code, failure.Failures)
+	failure.NextAttempt = failure.LastFailure.Add(backoff)
+	failures := failure.Failures
+	m.mu.Unlock()
+
+	log.Warn().
+		Str("stream", streamName).
+		Str("code", streamErr.Code).
+		Str("error", streamErr.Error).
+		Int("failures", failures).
+		Dur("retry_in", backoff).
+		Msg("[recording] auto-recording failed, backing off")
+}
+
+// failureBackoff returns how long auto-recording waits after the failures of
+// a stream: exponential from the check interval up to auto_record_max_backoff.
+// A full disk, a quota or a missing ffmpeg isn't the camera's fault, those streams are
+// tried at every check and start once the gate opens. A source that rejects
+// the credentials, doesn't exist or sends an unsupported codec won't recover
+// by itself and waits the longest right away.
+func failureBackoff(code string, failures int) time.Duration {
+	cfg := GetRecordingConfig()
+
+	backoff := cfg.AutoRecordCheckInterval
+	if backoff <= 0 {
+		backoff = time.Second * 10
+	}
+	maxBackoff := cfg.AutoRecordMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Minute * 5
+	}
+
+	switch code {
+	case ErrorCodeDiskFull, ErrorCodeQuotaExceeded, ErrorCodeFFmpegMissing:
+		return backoff
+	case ErrorCodeAuthFailed, ErrorCodeSourceNotFound, ErrorCodeCodecUnsupported, ErrorCodePermission:
+		return max(backoff, maxBackoff)
+	}
+
+	for i := 1; i < failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// GetAutoRecordFailures returns the streams whose auto-recording is failing
+func GetAutoRecordFailures() map[string]StreamFailure {
+	autoRecordingManager.mu.Lock()
+	defer autoRecordingManager.mu.Unlock()
+
+	failures := make(map[string]StreamFailure, len(autoRecordingManager.failedStreams))
+	for streamName, failure := range autoRecordingManager.failedStreams {
+		failures[streamName] = *failure
+	}
+	return failures
+}
+
+// ResetAutoRecordFailures clears the backoff of a stream (all streams if
+// empty) so the next check starts it right away
+func ResetAutoRecordFailures(streamName string) {
+	autoRecordingManager.mu.Lock()
+	defer autoRecordingManager.mu.Unlock()
+
+	if streamName == "" {
+		autoRecordingManager.failedStreams = make(map[string]*StreamFailure)
+		return
+	}
+	delete(autoRecordingManager.failedStreams, streamName)
 }
 
 // StartAutoRecordings begins automatic recording for configured streams
@@ -40,7 +209,7 @@ func StartAutoRecordings() {
 
 // getStreamsToRecord returns list of streams that should be recorded
 func getStreamsToRecord() []string {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	streamsToRecord := []string{}
 	
 	// Case 1: Global auto_start with no specific stream configs - record all available streams
@@ -53,7 +222,11 @@ func getStreamsToRecord() []string {
 	}
 	
 	// Case 2: Specific stream configurations - only record explicitly configured streams
-	for streamName, streamConfig := range cfg.Streams {
+	for streamName, streamConfig := range configuredStreams(cfg) {
+		// Event-only streams are recorded by triggers, not continuously
+		if streamConfig.RecordOnMotion {
+			continue
+		}
 		if streamConfig.Enabled != nil && *streamConfig.Enabled {
 			streamsToRecord = append(streamsToRecord, streamName)
 			log.Debug().
@@ -80,6 +253,14 @@ func getStreamsToRecord() []string {
 	return streamsToRecord
 }
 
+// sortByPriority orders streams by their priority, highest first, so they
+// take free recording slots first
+func sortByPriority(streamNames []string) {
+	sort.SliceStable(streamNames, func(i, j int) bool {
+		return GetStreamRecordingConfig(streamNames[i]).Priority > GetStreamRecordingConfig(streamNames[j]).Priority
+	})
+}
+
 // startAllEnabledRecordings starts all configured recordings in parallel at startup
 func startAllEnabledRecordings() {
 	// Longer initial delay to ensure RTSP server and exec module are fully initialized
@@ -88,6 +269,7 @@ func startAllEnabledRecordings() {
 	
 	// Get streams that should be recorded (combination of available streams and configured direct sources)
 	streamsToRecord := getStreamsToRecord()
+	sortByPriority(streamsToRecord)
 	log.Info().
 		Int("stream_count", len(streamsToRecord)).
 		Strs("streams", streamsToRecord).
@@ -132,7 +314,9 @@ func startAllEnabledRecordings() {
 				return
 			}
 			
-			if err := startAutoRecording(stream, streamConfig); err != nil {
+			err := startAutoRecording(stream, streamConfig)
+			autoRecordingManager.attempted(stream, err)
+			if err != nil {
 				log.Error().Err(err).Str("stream", stream).Msg("[recording] failed to start auto-recording")
 			} else {
 				log.Info().Str("stream", stream).Msg("[recording] started auto-recording")
@@ -153,7 +337,7 @@ func monitorAndAutoRecord() {
 	time.Sleep(time.Second * 5)
 
 	// Use configurable check interval
-	checkInterval := GlobalRecordingConfig.AutoRecordCheckInterval
+	checkInterval := GetRecordingConfig().AutoRecordCheckInterval
 	if checkInterval <= 0 {
 		checkInterval = time.Second * 10 // Default to 10 seconds
 	}
@@ -186,6 +370,7 @@ func monitorAndAutoRecord() {
 func checkAndStartAutoRecordings() {
 	// Get only the streams that should be recorded
 	streamsToCheck := getStreamsToRecord()
+	sortByPriority(streamsToCheck)
 	
 	// Check each configured stream
 	for _, streamName := range streamsToCheck {
@@ -201,6 +386,7 @@ func checkAndStartAutoRecordings() {
 			
 			// We already filtered for streams that should record, so check if already recording
 			actuallyRecording := isStreamActuallyRecording(streamName)
+			autoRecordingManager.observe(streamName, actuallyRecording)
 				
 			if !actuallyRecording {
 
@@ -212,7 +398,25 @@ func checkAndStartAutoRecordings() {
 					return
 				}
 
-				if err := startAutoRecording(streamName, streamConfig); err != nil {
+				// Offline cameras are retried with backoff instead of every check
+				if !autoRecordingManager.shouldAttempt(streamName) {
+					log.Debug().
+						Str("stream", streamName).
+						Msg("[recording] auto-recording backing off, skipping")
+					return
+				}
+
+				// All slots taken, the next check tries again
+				if !isNativeRecorder(streamConfig) && !recordingProcesses.available() {
+					log.Debug().
+						Str("stream", streamName).
+						Msg("[recording] no free recording slot, skipping")
+					return
+				}
+
+				err := startAutoRecording(streamName, streamConfig)
+				autoRecordingManager.attempted(streamName, err)
+				if err != nil {
 					log.Error().Err(err).Str("stream", streamName).Msg("[recording] failed to start auto-recording")
 				} else {
 					log.Info().Str("stream", streamName).Msg("[recording] started auto-recording")
@@ -244,7 +448,7 @@ func isAlreadyRecording(streamName string) bool {
 	regularRecordings := GetRecordingManager().ListRecordings()
 		
 	for _, recording := range regularRecordings {
-		if recording.Stream == streamName && recording.Active {
+		if recording.Stream == streamName && (recording.Active || recording.State == StatePaused) {
 			return true
 		}
 	}
@@ -260,23 +464,9 @@ func isAlreadyRecording(streamName string) bool {
 	return false
 }
 
-// isFFmpegProcessRunning checks if any FFmpeg process is recording for the given stream
+// isFFmpegProcessRunning checks if any FFmpeg process started by us is recording the given stream
 func isFFmpegProcessRunning(streamName string) bool {
-	// Use pgrep to find FFmpeg processes that contain the stream name
-	cmd := fmt.Sprintf("pgrep -f 'ffmpeg.*rtsp://.*/%s'", streamName)
-	
-	result, err := exec.Command("sh", "-c", cmd).Output()
-	if err != nil {
-		// pgrep returns exit code 1 if no processes found, which is normal
-		return false
-	}
-	
-	pids := strings.TrimSpace(string(result))
-	if pids != "" {
-		return true
-	}
-	
-	return false
+	return len(trackedFFmpegPIDs(streamName)) > 0
 }
 
 // isStreamActuallyRecording combines internal state and process checks
@@ -319,34 +509,26 @@ func startAutoRecording(streamName string, streamConfig StreamRecordingConfig) e
 	}
 }
 
-// GenerateRecordingPathWithTemplates creates the full path using custom templates
-func GenerateRecordingPathWithTemplates(streamName string, startTime time.Time, format string, segmentNum int, pathTemplate, filenameTemplate string) string {
-	cfg := GlobalRecordingConfig
+// stopAutoRecordingsForStream stops the auto-started recordings of a stream,
+// manual and event recordings keep running
+func stopAutoRecordingsForStream(streamName string) {
+	prefix := "auto_" + streamName + "_"
 
-	// Use custom templates if provided, otherwise fall back to global
-	if pathTemplate == "" {
-		pathTemplate = cfg.PathTemplate
-	}
-	if filenameTemplate == "" {
-		filenameTemplate = cfg.FilenameTemplate
+	for id, recording := range GetRecordingManager().ListRecordings() {
+		if recording.Stream == streamName && strings.HasPrefix(id, prefix) {
+			if err := GetRecordingManager().StopRecording(id, ExitDisabled); err != nil {
+				log.Error().Err(err).Str("id", id).Msg("[recording] failed to stop auto-recording")
+			}
+		}
 	}
 
-	// Use the existing function but with custom templates
-	originalPathTemplate := cfg.PathTemplate
-	originalFilenameTemplate := cfg.FilenameTemplate
-
-	// Temporarily override templates
-	cfg.PathTemplate = pathTemplate
-	cfg.FilenameTemplate = filenameTemplate
-
-	// Generate path
-	path := GenerateRecordingPath(streamName, startTime, format, segmentNum)
-
-	// Restore original templates
-	cfg.PathTemplate = originalPathTemplate
-	cfg.FilenameTemplate = originalFilenameTemplate
-
-	return path
+	for id, recording := range GetSegmentedRecordingManager().ListSegmentedRecordings() {
+		if recording.Stream == streamName && strings.HasPrefix(id, prefix) {
+			if err := GetSegmentedRecordingManager().StopSegmentedRecording(id, ExitDisabled); err != nil {
+				log.Error().Err(err).Str("id", id).Msg("[recording] failed to stop auto segmented recording")
+			}
+		}
+	}
 }
 
 // StopAutoRecordings stops all auto-started recordings
@@ -357,7 +539,7 @@ func StopAutoRecordings() {
 	regularRecordings := GetRecordingManager().ListRecordings()
 	for id, recording := range regularRecordings {
 		if recording.Active && len(id) > 5 && id[:5] == "auto_" {
-			if err := GetRecordingManager().StopRecording(id); err != nil {
+			if err := GetRecordingManager().StopRecording(id, ExitStopped); err != nil {
 				log.Error().Err(err).Str("id", id).Msg("[recording] failed to stop auto-recording")
 			} else {
 				log.Info().Str("id", id).Msg("[recording] stopped auto-recording")
@@ -368,7 +550,7 @@ func StopAutoRecordings() {
 	segmentedRecordings := GetSegmentedRecordingManager().ListSegmentedRecordings()
 	for id, recording := range segmentedRecordings {
 		if recording.Active && len(id) > 5 && id[:5] == "auto_" {
-			if err := GetSegmentedRecordingManager().StopSegmentedRecording(id); err != nil {
+			if err := GetSegmentedRecordingManager().StopSegmentedRecording(id, ExitStopped); err != nil {
 				log.Error().Err(err).Str("id", id).Msg("[recording] failed to stop auto segmented recording")
 			} else {
 				log.Info().Str("id", id).Msg("[recording] stopped auto segmented recording")
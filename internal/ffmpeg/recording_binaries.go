@@ -0,0 +1,224 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/ffmpeg"
+)
+
+// errFFmpegUnavailable is returned when a recording needs ffmpeg and the
+// binary can't be run
+var errFFmpegUnavailable = errors.New("ffmpeg not available")
+
+// requiredMuxers are the muxers recordings use: the containers, the segment
+// muxer and MPEG-TS for pre-roll and pipe input
+var requiredMuxers = []string{"mp4", "matroska", "mpegts", "segment"}
+
+// ffmpegRecheckInterval is how often a missing ffmpeg is looked for again,
+// so installing it doesn't need a restart
+const ffmpegRecheckInterval = time.Minute
+
+// FFmpegStatus is the result of checking the ffmpeg and ffprobe binaries
+type FFmpegStatus struct {
+	Path          string    `json:"path"`
+	Version       string    `json:"version,omitempty"`
+	FFprobePath   string    `json:"ffprobe_path"`
+	FFprobeFound  bool      `json:"ffprobe_found"`
+	MissingMuxers []string  `json:"missing_muxers,omitempty"`
+	Warnings      []string  `json:"warnings,omitempty"`
+	Error         string    `json:"error,omitempty"` // ffmpeg can't run, recordings fail to start
+	CheckedAt     time.Time `json:"checked_at"`
+}
+
+var ffmpegCheck struct {
+	status FFmpegStatus
+	mu     sync.Mutex
+}
+
+// ffmpegBin returns the ffmpeg binary recordings run
+func ffmpegBin() string {
+	ffmpegCheck.mu.Lock()
+	defer ffmpegCheck.mu.Unlock()
+	return cmp.Or(ffmpegCheck.status.Path, defaults["bin"])
+}
+
+// ffprobeBin returns the ffprobe binary recordings are probed with
+func ffprobeBin() string {
+	ffmpegCheck.mu.Lock()
+	defer ffmpegCheck.mu.Unlock()
+	return cmp.Or(ffmpegCheck.status.FFprobePath, "ffprobe")
+}
+
+// ffmpegStatus returns the last check, checking again if ffmpeg was missing
+// for a while
+func ffmpegStatus() FFmpegStatus {
+	ffmpegCheck.mu.Lock()
+	status := ffmpegCheck.status
+	ffmpegCheck.mu.Unlock()
+
+	if status.CheckedAt.IsZero() || (status.Error != "" && time.Since(status.CheckedAt) > ffmpegRecheckInterval) {
+		status = checkFFmpeg()
+	}
+	return status
+}
+
+// ffmpegAvailable returns errFFmpegUnavailable with the reason when ffmpeg
+// can't be run
+func ffmpegAvailable() error {
+	if status := ffmpegStatus(); status.Error != "" {
+		return fmt.Errorf("%w: %s", errFFmpegUnavailable, status.Error)
+	}
+	return nil
+}
+
+// checkFFmpeg resolves the ffmpeg and ffprobe binaries, verifies the version
+// and the muxers recordings need and stores the result
+func checkFFmpeg() FFmpegStatus {
+	cfg := GetRecordingConfig()
+
+	status := FFmpegStatus{CheckedAt: time.Now()}
+	status.Path = findBinary(cmp.Or(cfg.FFmpegPath, defaults["bin"]))
+	status.FFprobePath = cfg.FFprobePath
+	if status.FFprobePath == "" {
+		status.FFprobePath = siblingBinary(status.Path, "ffprobe")
+	}
+
+	if out, err := exec.Command(status.Path, "-version").Output(); err != nil {
+		status.Error = err.Error()
+	} else {
+		var libavformat string
+		status.Version, libavformat = ffmpeg.ParseVersion(out)
+		if libavformat != "" && libavformat < ffmpeg.Version50 {
+			status.Warnings = append(status.Warnings, "ffmpeg "+status.Version+" is older than 5.0, some options may be unsupported")
+		}
+
+		muxers, err := listMuxers(status.Path)
+		if err != nil {
+			status.Warnings = append(status.Warnings, "can't list muxers: "+err.Error())
+		}
+		required := requiredMuxers
+		if hasRestreams(cfg) {
+			required = append(slices.Clone(required), "tee")
+		}
+		for _, name := range required {
+			if muxers != nil && !muxers[name] {
+				status.MissingMuxers = append(status.MissingMuxers, name)
+			}
+		}
+	}
+
+	_, err := exec.LookPath(status.FFprobePath)
+	status.FFprobeFound = err == nil
+
+	ffmpegCheck.mu.Lock()
+	ffmpegCheck.status = status
+	ffmpegCheck.mu.Unlock()
+
+	switch {
+	case status.Error != "":
+		log.Error().Str("path", status.Path).Str("error", status.Error).
+			Msg("[recording] ffmpeg not available, recordings can't start until it is installed or ffmpeg_path is set")
+	default:
+		log.Info().Str("path", status.Path).Str("version", status.Version).Str("ffprobe", status.FFprobePath).
+			Msg("[recording] ffmpeg found")
+		for _, warning := range status.Warnings {
+			log.Warn().Str("path", status.Path).Msg("[recording] " + warning)
+		}
+		if len(status.MissingMuxers) > 0 {
+			log.Warn().Strs("muxers", status.MissingMuxers).Msg("[recording] ffmpeg lacks muxers, recordings using them will fail")
+		}
+		if !status.FFprobeFound {
+			log.Warn().Str("path", status.FFprobePath).Msg("[recording] ffprobe not found, durations and integrity checks are unavailable")
+		}
+	}
+
+	return status
+}
+
+// findBinary returns the path of a binary: as configured if it contains a
+// directory or is in PATH, otherwise a binary bundled next to go2rtc
+func findBinary(name string) string {
+	if strings.ContainsRune(name, filepath.Separator) || strings.Contains(name, "/") {
+		return name
+	}
+	if _, err := exec.LookPath(name); err == nil {
+		return name
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return name
+	}
+	file := name
+	if runtime.GOOS == "windows" && filepath.Ext(file) == "" {
+		file += ".exe"
+	}
+	for _, dir := range []string{".", "ffmpeg", "bin", filepath.Join("ffmpeg", "bin")} {
+		path := filepath.Join(filepath.Dir(exe), dir, file)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return name
+}
+
+// siblingBinary returns the binary name from the directory of bin, e.g. the
+// ffprobe of a bundled ffmpeg
+func siblingBinary(bin, name string) string {
+	if dir := filepath.Dir(bin); dir != "." {
+		path := filepath.Join(dir, name+filepath.Ext(bin))
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return findBinary(name)
+}
+
+// listMuxers returns the names of the muxers the ffmpeg binary supports
+func listMuxers(bin string) (map[string]bool, error) {
+	out, err := exec.Command(bin, "-hide_banner", "-muxers").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// " E mp4             MP4 (MPEG-4 Part 14)", after a legend ending with --
+	muxers := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	var list bool
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if !list {
+			list = len(fields) > 0 && strings.Trim(fields[0], "-") == ""
+			continue
+		}
+		if len(fields) >= 2 && strings.Contains(fields[0], "E") {
+			for _, name := range strings.Split(fields[1], ",") {
+				muxers[name] = true
+			}
+		}
+	}
+	return muxers, nil
+}
+
+// hasRestreams reports whether a stream publishes its recording, which
+// needs the tee muxer
+func hasRestreams(cfg *RecordingConfig) bool {
+	for _, streamConfig := range cfg.Streams {
+		if streamConfig.Restream != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,284 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/rtsp"
+)
+
+// preBufferChunk is the length of each file in the circular pre-record buffer
+const preBufferChunk = 2 * time.Second
+
+// PreBuffer keeps the last few seconds of a stream on disk (ideally tmpfs)
+// as a ring of short MPEG-TS chunks written by ffmpeg's segment muxer
+type PreBuffer struct {
+	Stream   string
+	Duration time.Duration
+	Dir      string
+
+	cmd *exec.Cmd
+	mu  sync.Mutex
+}
+
+// PreBufferManager runs one pre-record buffer per stream
+type PreBufferManager struct {
+	buffers map[string]*PreBuffer
+	mu      sync.Mutex
+}
+
+var preBufferManager = &PreBufferManager{
+	buffers: make(map[string]*PreBuffer),
+}
+
+// getBufferRoot returns the directory holding pre-record buffers, preferring
+// shared memory so the ring buffer does not wear out disks
+func getBufferRoot() string {
+	if GetRecordingConfig().BufferPath != "" {
+		return GetRecordingConfig().BufferPath
+	}
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		return "/dev/shm/go2rtc-buffer"
+	}
+	return filepath.Join(os.TempDir(), "go2rtc-buffer")
+}
+
+// startPreBuffers starts buffers for event-triggered streams that have a pre-roll
+func startPreBuffers() {
+	for streamName, streamConfig := range configuredStreams(GetRecordingConfig()) {
+		if !streamConfig.RecordOnMotion {
+			continue
+		}
+		if pre := GetStreamRecordingConfig(streamName).EventPreTime; pre > 0 {
+			preBufferManager.Start(streamName, pre)
+		}
+	}
+}
+
+// Start begins buffering the stream if it isn't buffered already
+func (pm *PreBufferManager) Start(streamName string, duration time.Duration) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, ok := pm.buffers[streamName]; ok {
+		return
+	}
+
+	buffer := &PreBuffer{
+		Stream:   streamName,
+		Duration: duration,
+		Dir:      filepath.Join(getBufferRoot(), streamName),
+	}
+	pm.buffers[streamName] = buffer
+
+	go buffer.run()
+}
+
+// Snapshot copies the buffered chunks covering the last duration into a new
+// temp directory, so they survive the ring being overwritten. Returns nil if
+// the stream has no buffer or nothing is buffered yet.
+func (pm *PreBufferManager) Snapshot(streamName string, duration time.Duration) []string {
+	pm.mu.Lock()
+	buffer := pm.buffers[streamName]
+	pm.mu.Unlock()
+
+	if buffer == nil {
+		return nil
+	}
+
+	return buffer.snapshot(duration)
+}
+
+// StopAll kills the buffering processes, the buffers hold nothing worth finalizing
+func (pm *PreBufferManager) StopAll() {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for _, buffer := range pm.buffers {
+		buffer.mu.Lock()
+		if buffer.cmd != nil && buffer.cmd.Process != nil {
+			_ = buffer.cmd.Process.Kill()
+		}
+		buffer.mu.Unlock()
+	}
+}
+
+// run keeps the buffering ffmpeg alive until the process exits
+func (b *PreBuffer) run() {
+	for {
+		if err := b.runOnce(); err != nil {
+			log.Warn().Err(err).Str("stream", b.Stream).Msg("[buffer] pre-record buffer stopped, restarting")
+		}
+		time.Sleep(time.Second * 10)
+	}
+}
+
+func (b *PreBuffer) runOnce() error {
+	// Start from an empty ring so stale chunks are never prepended
+	_ = os.RemoveAll(b.Dir)
+	if err := os.MkdirAll(b.Dir, 0755); err != nil {
+		return err
+	}
+
+	source := GetRecordingSource(b.Stream, rtsp.Port)
+
+	// Keep a couple of spare chunks so the oldest one is complete when copied
+	wrap := int(b.Duration/preBufferChunk) + 3
+
+	args := []string{
+		"-hide_banner", "-v", "error",
+		"-i", source,
+		"-map", "0", "-c", "copy",
+		"-f", "segment",
+		"-segment_time", strconv.Itoa(int(preBufferChunk.Seconds())),
+		"-segment_wrap", strconv.Itoa(wrap),
+		"-segment_format", "mpegts",
+		"-reset_timestamps", "1",
+		filepath.Join(b.Dir, "buffer_%03d.ts"),
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(ffmpegBin(), args...)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.cmd = cmd
+	b.mu.Unlock()
+
+	log.Info().
+		Str("stream", b.Stream).
+		Dur("duration", b.Duration).
+		Str("dir", b.Dir).
+		Msg("[buffer] pre-record buffer started")
+
+	err := cmd.Wait()
+
+	b.mu.Lock()
+	b.cmd = nil
+	b.mu.Unlock()
+
+	if stderr.Len() > 0 {
+		return fmt.Errorf("%s", extractFFmpegError(stderr.String()))
+	}
+	return err
+}
+
+func (b *PreBuffer) snapshot(duration time.Duration) []string {
+	entries, err := os.ReadDir(b.Dir)
+	if err != nil {
+		return nil
+	}
+
+	type chunk struct {
+		path    string
+		modTime time.Time
+	}
+
+	var chunks []chunk
+	cutoff := time.Now().Add(-duration - preBufferChunk)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.Size() == 0 || !strings.HasSuffix(entry.Name(), ".ts") {
+			continue
+		}
+		if info.ModTime().After(cutoff) {
+			chunks = append(chunks, chunk{filepath.Join(b.Dir, entry.Name()), info.ModTime()})
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].modTime.Before(chunks[j].modTime)
+	})
+
+	dir, err := os.MkdirTemp(getBufferRoot(), "snapshot-")
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for i, c := range chunks {
+		dst := filepath.Join(dir, fmt.Sprintf("%03d.ts", i))
+		if err = copyFile(c.path, dst); err != nil {
+			continue
+		}
+		files = append(files, dst)
+	}
+
+	return files
+}
+
+// finalizePreRoll joins the pre-record chunks and the live part into the
+// final output file, then removes the temporary files
+func finalizePreRoll(preFiles []string, live, output string) error {
+	defer func() {
+		if len(preFiles) > 0 {
+			_ = os.RemoveAll(filepath.Dir(preFiles[0]))
+		}
+	}()
+
+	var sb strings.Builder
+	for _, path := range append(preFiles, live) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		sb.WriteString("file '" + strings.ReplaceAll(abs, "'", `'\''`) + "'\n")
+	}
+
+	list := live + ".txt"
+	if err := os.WriteFile(list, []byte(sb.String()), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(list)
+
+	cmd := exec.Command(ffmpegBin(),
+		"-hide_banner", "-v", "error",
+		"-f", "concat", "-safe", "0", "-i", list,
+		"-map", "0", "-c", "copy",
+		"-y", output,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		// Keep the live part so the recording isn't lost
+		_ = os.Rename(live, strings.TrimSuffix(output, filepath.Ext(output))+".ts")
+		return fmt.Errorf("%w: %s", err, extractFFmpegError(string(out)))
+	}
+
+	return os.Remove(live)
+}
+
+// copyFile copies a regular file from src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
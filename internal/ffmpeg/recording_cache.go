@@ -0,0 +1,119 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxCachedListings bounds the cached filters, dashboards poll a handful
+const maxCachedListings = 100
+
+type cachedListing struct {
+	recordings []RecordingFile
+	total      int
+	expires    time.Time
+}
+
+// listingCache keeps the results of index queries for listing_cache_ttl, so
+// dashboards polling every few seconds don't filter and sort every entry each
+// time. Any change of the index and the start, stop or cleanup of recordings
+// drop it. The TTL bounds what changes without the index: the durations of
+// active recordings and sidecars written by other tools without the watcher.
+type listingCache struct {
+	entries    map[string]cachedListing
+	generation int // increased by every invalidation
+	hits       int64
+	misses     int64
+	mu         sync.Mutex
+}
+
+var recordingListings = &listingCache{entries: make(map[string]cachedListing)}
+
+// startListingCache drops cached listings on the recording events that
+// change them without an index update, like the start of a recording
+func startListingCache() {
+	subscribeNotifications(func(n RecordingNotification) {
+		switch n.Type {
+		case NotifyRecordingStarted, NotifyRecordingStopped, NotifySegmentComplete, NotifyCleanupResult:
+			recordingListings.invalidate()
+		}
+	})
+}
+
+// listingKey identifies the results of a query
+func listingKey(q RecordingQuery) string {
+	return fmt.Sprintf("%#v", q)
+}
+
+// get returns a copy of the cached results of a query, callers may change it
+func (c *listingCache) get(key string, now time.Time) ([]RecordingFile, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		c.misses++
+		return nil, 0, false
+	}
+	c.hits++
+	return slices.Clone(entry.recordings), entry.total, true
+}
+
+// put caches the results of a query read at the generation, unless the cache
+// was invalidated since
+func (c *listingCache) put(key string, generation int, recordings []RecordingFile, total int, now time.Time) {
+	ttl := GetRecordingConfig().ListingCacheTTL
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if len(c.entries) >= maxCachedListings {
+		for key, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxCachedListings {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = cachedListing{recordings: slices.Clone(recordings), total: total, expires: now.Add(ttl)}
+}
+
+// currentGeneration is read before the index, so results of an index that
+// changed while they were built are not cached
+func (c *listingCache) currentGeneration() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// invalidate drops all cached listings
+func (c *listingCache) invalidate() {
+	c.mu.Lock()
+	c.generation++
+	clear(c.entries)
+	c.mu.Unlock()
+}
+
+// ListingCacheStats is the listing cache part of /api/record/stats
+type ListingCacheStats struct {
+	Entries int   `json:"entries"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+// Stats returns the cached filters and how often the cache answered
+func (c *listingCache) Stats() ListingCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ListingCacheStats{Entries: len(c.entries), Hits: c.hits, Misses: c.misses}
+}
@@ -0,0 +1,58 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListingCache(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{ListingCacheTTL: time.Second * 5})
+
+	c := &listingCache{entries: make(map[string]cachedListing)}
+	now := time.Now()
+	key := listingKey(RecordingQuery{Stream: "cam1", Limit: 100})
+	require.NotEqual(t, key, listingKey(RecordingQuery{Stream: "cam1", Limit: 100, Offset: 100}))
+
+	_, _, ok := c.get(key, now)
+	require.False(t, ok)
+
+	c.put(key, c.currentGeneration(), []RecordingFile{{ID: "a"}, {ID: "b"}}, 10, now)
+	recordings, total, ok := c.get(key, now.Add(time.Second))
+	require.True(t, ok)
+	require.Equal(t, 10, total)
+	require.Len(t, recordings, 2)
+
+	// Callers get their own copy
+	recordings[0].Duration = "changed"
+	recordings, _, _ = c.get(key, now)
+	require.Empty(t, recordings[0].Duration)
+
+	_, _, ok = c.get(key, now.Add(time.Second*6))
+	require.False(t, ok)
+
+	// Results of an index read before an invalidation are not cached
+	generation := c.currentGeneration()
+	c.invalidate()
+	c.put(key, generation, []RecordingFile{{ID: "a"}}, 1, now)
+	_, _, ok = c.get(key, now)
+	require.False(t, ok)
+
+	c.put(key, c.currentGeneration(), []RecordingFile{{ID: "a"}}, 1, now)
+	c.invalidate()
+	_, _, ok = c.get(key, now)
+	require.False(t, ok)
+
+	// Disabled with listing_cache_ttl 0
+	GetRecordingConfig().ListingCacheTTL = 0
+	c.put(key, c.currentGeneration(), []RecordingFile{{ID: "a"}}, 1, now)
+	_, _, ok = c.get(key, now)
+	require.False(t, ok)
+
+	stats := c.Stats()
+	require.Equal(t, int64(2), stats.Hits)
+	require.Zero(t, stats.Entries)
+}
@@ -3,11 +3,11 @@ package ffmpeg
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -31,7 +31,12 @@ type HealthCheckResult struct {
 
 // shouldProtectFromCleanup determines if a file should be protected from deletion
 func shouldProtectFromCleanup(rec CleanupRecordingInfo, streamRecordingCount int, totalRecordingCount int) (bool, string) {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
+
+	// Legal hold set through the API
+	if recordingIndex.isProtected(rec.Path) {
+		return true, "protected"
+	}
 
 	// Check minimum files per stream
 	minPerStream := cfg.MinimumFilesPerStream
@@ -72,20 +77,59 @@ func getStreamRecordingCounts(recordings []CleanupRecordingInfo) (map[string]int
 	return streamCounts, len(recordings)
 }
 
+// CleanupRun is the outcome of the last scheduled cleanup
+type CleanupRun struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+var lastCleanup struct {
+	run CleanupRun
+	mu  sync.Mutex
+}
+
+// LastCleanup returns the last scheduled cleanup, a zero time if none ran yet
+func LastCleanup() CleanupRun {
+	lastCleanup.mu.Lock()
+	defer lastCleanup.mu.Unlock()
+	return lastCleanup.run
+}
+
+// scheduledCleanup runs the cleanup and remembers when and how it ended
+func scheduledCleanup() error {
+	err := runCleanup()
+
+	run := CleanupRun{Time: time.Now()}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	lastCleanup.mu.Lock()
+	lastCleanup.run = run
+	lastCleanup.mu.Unlock()
+
+	return err
+}
+
 // cleanupRoutine runs the cleanup process at regular intervals
 func cleanupRoutine() {
 	// Run immediately on startup before waiting for the first interval
-	if err := runCleanup(); err != nil {
+	if !inCleanupWindow() {
+		log.Info().Str("cleanup_window", GetRecordingConfig().CleanupWindow).Msg("[cleanup] outside cleanup window, skipping startup cleanup")
+	} else if err := scheduledCleanup(); err != nil {
 		log.Error().Err(err).Msg("[recording] startup cleanup failed")
 	}
 
-	ticker := time.NewTicker(GlobalRecordingConfig.CleanupInterval)
+	ticker := time.NewTicker(GetRecordingConfig().CleanupInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			if err := runCleanup(); err != nil {
+			if !inCleanupWindow() {
+				log.Debug().Str("cleanup_window", GetRecordingConfig().CleanupWindow).Msg("[cleanup] outside cleanup window, skipping")
+				continue
+			}
+			if err := scheduledCleanup(); err != nil {
 				log.Error().Err(err).Msg("[recording] cleanup failed")
 			}
 		}
@@ -98,10 +142,10 @@ func healthCheckRoutine() {
 	time.Sleep(time.Minute * 2)
 
 	log.Info().
-		Dur("interval", GlobalRecordingConfig.HealthCheckInterval).
+		Dur("interval", GetRecordingConfig().HealthCheckInterval).
 		Msg("[health-check] starting independent health check routine")
 
-	ticker := time.NewTicker(GlobalRecordingConfig.HealthCheckInterval)
+	ticker := time.NewTicker(GetRecordingConfig().HealthCheckInterval)
 	defer ticker.Stop()
 
 	for {
@@ -141,8 +185,8 @@ func performHealthCheckAndRecover() {
 // runCleanup performs the cleanup operation
 func runCleanup() error {
 	// Pre-check: Verify we're not at minimum file thresholds before cleanup
-	cfg := GlobalRecordingConfig
-	recordings, err := findRecordingFiles(cfg.BasePath)
+	cfg := GetRecordingConfig()
+	recordings, err := findAllRecordingFiles()
 	if err != nil {
 		return fmt.Errorf("failed to find recording files for pre-check: %w", err)
 	}
@@ -155,6 +199,7 @@ func runCleanup() error {
 		minTotal = 10
 	}
 	if totalCount <= minTotal {
+		moveToColdTier(nil)
 		log.Info().
 			Int("total_files", totalCount).
 			Int("minimum_required", minTotal).
@@ -182,6 +227,7 @@ func runCleanup() error {
 	}
 
 	if allAtMinimum && len(streamCounts) > 0 {
+		moveToColdTier(nil)
 		log.Info().Msg("[recording] skipping cleanup - all streams at minimum file threshold")
 		return nil
 	}
@@ -192,11 +238,12 @@ func runCleanup() error {
 
 // runCleanupWithStats performs cleanup and returns detailed statistics
 func runCleanupWithStats() (*CleanupResult, error) {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	
 	result := &CleanupResult{
 		DeletedFiles:  []string{},
 		ArchivedFiles: []string{},
+		TieredFiles:   []string{},
 		Policies:      []string{},
 	}
 	
@@ -209,10 +256,15 @@ func runCleanupWithStats() (*CleanupResult, error) {
 		Int64("max_total_size_mb", cfg.MaxTotalSize).
 		Bool("move_to_archive", cfg.MoveToArchive).
 		Str("archive_path", cfg.ArchivePath).
+		Str("cold_path", cfg.ColdPath).
+		Int("hot_days", cfg.HotDays).
 		Msg("[recording] starting cleanup with configuration")
 
+	// Older recordings go to the cold tier first, retention covers both tiers
+	moveToColdTier(result)
+
 	// Find all recording files
-	recordings, err := findRecordingFiles(cfg.BasePath)
+	recordings, err := findAllRecordingFiles()
 	if err != nil {
 		return result, fmt.Errorf("failed to find recording files: %w", err)
 	}
@@ -284,7 +336,7 @@ func runCleanupWithStats() (*CleanupResult, error) {
 	}
 
 	// Calculate final size
-	finalRecordings, err := findRecordingFiles(cfg.BasePath)
+	finalRecordings, err := findAllRecordingFiles()
 	if err == nil {
 		var totalSizeAfter int64
 		for _, rec := range finalRecordings {
@@ -298,10 +350,17 @@ func runCleanupWithStats() (*CleanupResult, error) {
 		result.StreamsAffected = append(result.StreamsAffected, stream)
 	}
 
+	// Drop removed files from the recording index
+	recordingIndex.Remove(result.DeletedFiles...)
+	recordingIndex.Remove(result.ArchivedFiles...)
+	recordingMetrics.addCleanup(result)
+	notify(NotifyCleanupResult, "", result)
+
 	// Log detailed cleanup summary
 	log.Info().
 		Int("files_deleted", result.FilesDeleted).
 		Int("files_archived", result.FilesArchived).
+		Int("files_tiered", result.FilesTiered).
 		Int64("space_reclaimed_mb", result.SpaceReclaimed).
 		Int64("size_before_mb", result.TotalSizeBefore).
 		Int64("size_after_mb", result.TotalSizeAfter).
@@ -316,10 +375,44 @@ func runCleanupWithStats() (*CleanupResult, error) {
 	return result, nil
 }
 
-// findRecordingFiles recursively finds all recording files in the base path
+// recordingDurations returns the length of each recording of a time-sorted
+// list like the timeline, from the exact span or probe the index keeps and
+// estimated from the next recording's start otherwise
+func recordingDurations(recordings []CleanupRecordingInfo) []time.Duration {
+	files := make([]RecordingFile, len(recordings))
+	for i, rec := range recordings {
+		if recording := recordingIndex.GetByPath(rec.Path); recording != nil {
+			files[i] = *recording
+		} else {
+			files[i] = RecordingFile{Path: rec.Path, Filename: filepath.Base(rec.Path), StartTime: rec.RecordingTime}
+		}
+	}
+
+	durations := make([]time.Duration, len(files))
+	for i := range files {
+		durations[i] = max(estimatedRecordingDuration(files, i), 0)
+	}
+	return durations
+}
+
+// findRecordingFiles recursively finds all recording files in the base path,
+// from the index instead of walking the tree while the pools are watched
 func findRecordingFiles(basePath string) ([]CleanupRecordingInfo, error) {
 	var recordings []CleanupRecordingInfo
 
+	if indexWatch.Active() {
+		// An unmounted disk is still an error, not an empty pool
+		if _, err := os.Stat(basePath); err != nil {
+			return nil, err
+		}
+		for _, entry := range recordingIndex.entriesUnder(basePath) {
+			if isRecordingFile(filepath.Ext(entry.Path)) {
+				recordings = append(recordings, newCleanupRecordingInfo(entry.Path, basePath, entry.ModTime, entry.Size))
+			}
+		}
+		return recordings, nil
+	}
+
 	err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -335,23 +428,7 @@ func findRecordingFiles(basePath string) ([]CleanupRecordingInfo, error) {
 			return nil
 		}
 
-		// Extract stream name from path
-		streamName := extractStreamFromPath(path, basePath)
-
-		// Extract recording time from filename
-		recordingTime := extractRecordingTimeFromPath(path)
-		if recordingTime.IsZero() {
-			// Fallback to file modification time if we can't parse filename
-			recordingTime = info.ModTime()
-		}
-
-		recordings = append(recordings, CleanupRecordingInfo{
-			Path:          path,
-			ModTime:       info.ModTime(),
-			RecordingTime: recordingTime,
-			Size:          info.Size(),
-			Stream:        streamName,
-		})
+		recordings = append(recordings, newCleanupRecordingInfo(path, basePath, info.ModTime(), info.Size()))
 
 		return nil
 	})
@@ -359,6 +436,43 @@ func findRecordingFiles(basePath string) ([]CleanupRecordingInfo, error) {
 	return recordings, err
 }
 
+// newCleanupRecordingInfo describes a recording file found in the base path
+func newCleanupRecordingInfo(path, basePath string, modTime time.Time, size int64) CleanupRecordingInfo {
+	// Extract recording time from filename
+	recordingTime := extractRecordingTimeFromPath(path)
+	if recordingTime.IsZero() {
+		// Fallback to file modification time if we can't parse filename
+		recordingTime = modTime
+	}
+
+	return CleanupRecordingInfo{
+		Path:          path,
+		ModTime:       modTime,
+		RecordingTime: recordingTime,
+		Size:          size,
+		Stream:        extractStreamFromPath(path, basePath),
+	}
+}
+
+// findAllRecordingFiles finds the recording files of all storage pools
+func findAllRecordingFiles() ([]CleanupRecordingInfo, error) {
+	var recordings []CleanupRecordingInfo
+	var err error
+	for _, basePath := range storagePools() {
+		found, walkErr := findRecordingFiles(basePath)
+		if walkErr != nil {
+			// One unmounted disk must not stop the cleanup of the others
+			log.Warn().Err(walkErr).Str("path", basePath).Msg("[cleanup] failed to read storage pool")
+			err = walkErr
+		}
+		recordings = append(recordings, found...)
+	}
+	if len(recordings) == 0 && err != nil {
+		return nil, err
+	}
+	return recordings, nil
+}
+
 // isRecordingFile checks if the file extension indicates a recording
 func isRecordingFile(ext string) bool {
 	recordingExtensions := []string{".mp4", ".mkv", ".avi", ".mov", ".ts", ".flv", ".webm"}
@@ -408,7 +522,7 @@ func extractStreamFromPath(filePath, basePath string) string {
 
 // cleanupStreamWithStats applies cleanup policies and returns statistics
 func cleanupStreamWithStats(streamName string, recordings []CleanupRecordingInfo) (*CleanupResult, error) {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	result := &CleanupResult{
 		DeletedFiles:  []string{},
 		ArchivedFiles: []string{},
@@ -438,42 +552,102 @@ func cleanupStreamWithStats(streamName string, recordings []CleanupRecordingInfo
 		Int("recording_count", len(recordings)).
 		Int64("stream_size_mb", streamSizeMB).
 		Int("max_recordings_limit", maxRecordings).
+		Int64("max_total_size_mb", streamConfig.MaxTotalSize).
 		Msg("[recording] processing stream cleanup")
 
 	var toDelete []CleanupRecordingInfo
 	// Use per-stream retention if configured, fall back to global
-	var retentionDuration time.Duration
-	if streamConfig.RetentionHours > 0 {
-		retentionDuration = time.Duration(streamConfig.RetentionHours) * time.Hour
-	} else if streamConfig.RetentionDays > 0 {
-		retentionDuration = time.Duration(streamConfig.RetentionDays) * 24 * time.Hour
-	} else {
-		retentionDuration = GetRetentionDuration()
-	}
+	retentionDuration := streamConfig.RetentionDuration()
 	cutoffTime := time.Now().Add(-retentionDuration)
 
+	// Event recordings may have their own retention class
+	eventRetentionDuration := streamConfig.EventRetentionDuration()
+	eventCutoffTime := time.Now().Add(-eventRetentionDuration)
+
 	log.Debug().
 		Str("stream", streamName).
 		Int("stream_retention_days", streamConfig.RetentionDays).
 		Int("stream_retention_hours", streamConfig.RetentionHours).
 		Dur("retention_duration", retentionDuration).
+		Dur("event_retention_duration", eventRetentionDuration).
 		Time("cutoff_time", cutoffTime).
 		Msg("[recording] applying retention policy")
 
 	// Apply retention time policy (use recording time, not file modification time)
+	var expired, expiredEvents, expiredTagged int
 	for _, rec := range recordings {
-		if rec.RecordingTime.Before(cutoffTime) {
+		cutoff := cutoffTime
+		event := isEventRecordingFile(rec.Path)
+		if event {
+			cutoff = eventCutoffTime
+		}
+		// A tagged recording is kept as long as its tag says, forever with 0
+		retention, tagged := tagRetention(recordingIndex.tagsOf(rec.Path))
+		if tagged {
+			if retention == 0 {
+				continue
+			}
+			cutoff = time.Now().Add(-retention)
+		}
+		if rec.RecordingTime.Before(cutoff) {
 			toDelete = append(toDelete, rec)
+			switch {
+			case tagged:
+				expiredTagged++
+			case event:
+				expiredEvents++
+			default:
+				expired++
+			}
 			log.Debug().
 				Str("file", rec.Path).
+				Bool("event", event).
 				Time("recording_time", rec.RecordingTime).
-				Time("cutoff_time", cutoffTime).
+				Time("cutoff_time", cutoff).
 				Msg("[cleanup] marking file for deletion based on recording time")
 		}
 	}
-	if len(toDelete) > 0 {
+	if expired > 0 {
 		result.Policies = append(result.Policies, fmt.Sprintf("retention_%s", streamName))
 	}
+	if expiredEvents > 0 {
+		result.Policies = append(result.Policies, fmt.Sprintf("event_retention_%s", streamName))
+	}
+	if expiredTagged > 0 {
+		result.Policies = append(result.Policies, fmt.Sprintf("tag_retention_%s", streamName))
+	}
+
+	// Thin older recordings down to one per hour, then one per day
+	if thinned := thinRecordings(recordings, streamConfig, time.Now()); len(thinned) > 0 {
+		marked := make(map[string]bool, len(toDelete))
+		for _, rec := range toDelete {
+			marked[rec.Path] = true
+		}
+		var added int
+		for _, rec := range thinned {
+			// Tagged recordings follow their tag's retention
+			if _, tagged := tagRetention(recordingIndex.tagsOf(rec.Path)); tagged {
+				continue
+			}
+			if !marked[rec.Path] {
+				log.Debug().
+					Str("file", rec.Path).
+					Time("recording_time", rec.RecordingTime).
+					Msg("[cleanup] marking file for deletion by thinning")
+				toDelete = append(toDelete, rec)
+				added++
+			}
+		}
+		if added > 0 {
+			log.Info().
+				Str("stream", streamName).
+				Int("files", added).
+				Int("thin_after_days", streamConfig.ThinAfterDays).
+				Int("thin_hourly_days", streamConfig.ThinHourlyDays).
+				Msg("[recording] thinning older recordings")
+			result.Policies = append(result.Policies, fmt.Sprintf("thinning_%s", streamName))
+		}
+	}
 
 	// Apply max recordings per stream policy (use stream-specific limit if configured)
 	if maxRecordings > 0 && len(recordings) > maxRecordings {
@@ -511,8 +685,88 @@ func cleanupStreamWithStats(streamName string, recordings []CleanupRecordingInfo
 			Msg("[recording] stream within max recordings limit")
 	}
 
+	// Apply per-stream footage limit, oldest recordings go first
+	if limit := streamConfig.MaxTotalDuration; limit > 0 {
+		marked := make(map[string]bool, len(toDelete))
+		for _, rec := range toDelete {
+			marked[rec.Path] = true
+		}
+		durations := recordingDurations(recordings)
+		var kept time.Duration
+		for i, rec := range recordings {
+			if !marked[rec.Path] {
+				kept += durations[i]
+			}
+		}
+
+		if kept > limit {
+			log.Info().
+				Str("stream", streamName).
+				Dur("current_duration", kept).
+				Dur("limit", limit).
+				Msg("[recording] enforcing stream duration limit")
+
+			for i, rec := range recordings {
+				if kept <= limit {
+					break
+				}
+				if marked[rec.Path] {
+					continue
+				}
+				log.Debug().
+					Str("file", rec.Path).
+					Time("recording_time", rec.RecordingTime).
+					Dur("duration", durations[i]).
+					Msg("[cleanup] marking file for deletion due to stream duration limit")
+				toDelete = append(toDelete, rec)
+				kept -= durations[i]
+			}
+			result.Policies = append(result.Policies, fmt.Sprintf("max_duration_%s", streamName))
+		}
+	}
+
+	// Apply per-stream size limit, oldest recordings go first
+	if streamConfig.MaxTotalSize > 0 {
+		maxBytes := streamConfig.MaxTotalSize * 1024 * 1024
+
+		marked := make(map[string]bool, len(toDelete))
+		for _, rec := range toDelete {
+			marked[rec.Path] = true
+		}
+		var keptBytes int64
+		for _, rec := range recordings {
+			if !marked[rec.Path] {
+				keptBytes += rec.Size
+			}
+		}
+
+		if keptBytes > maxBytes {
+			log.Info().
+				Str("stream", streamName).
+				Int64("current_size_mb", keptBytes/1024/1024).
+				Int64("limit_mb", streamConfig.MaxTotalSize).
+				Msg("[recording] enforcing stream size limit")
+
+			for _, rec := range recordings {
+				if keptBytes <= maxBytes {
+					break
+				}
+				if marked[rec.Path] {
+					continue
+				}
+				log.Debug().
+					Str("file", rec.Path).
+					Time("recording_time", rec.RecordingTime).
+					Msg("[cleanup] marking file for deletion due to stream size limit")
+				toDelete = append(toDelete, rec)
+				keptBytes -= rec.Size
+			}
+			result.Policies = append(result.Policies, fmt.Sprintf("max_size_%s", streamName))
+		}
+	}
+
 	// Get total recording count for protection check
-	allRecordings, _ := findRecordingFiles(cfg.BasePath)
+	allRecordings, _ := findAllRecordingFiles()
 	_, totalCount := getStreamRecordingCounts(allRecordings)
 	currentStreamCount := len(recordings)
 
@@ -533,6 +787,8 @@ func cleanupStreamWithStats(streamName string, recordings []CleanupRecordingInfo
 
 		result.SpaceReclaimed += rec.Size / 1024 / 1024 // Convert to MB
 
+		cleanupThrottle.wait()
+
 		if cfg.MoveToArchive && cfg.ArchivePath != "" {
 			if err := archiveFile(rec, streamName); err != nil {
 				log.Error().Err(err).Str("file", rec.Path).Msg("[recording] failed to archive file")
@@ -576,7 +832,7 @@ func cleanupStream(streamName string, recordings []CleanupRecordingInfo) error {
 
 // enforceGlobalSizeLimitWithStats enforces size limits and returns statistics
 func enforceGlobalSizeLimitWithStats(recordings []CleanupRecordingInfo) (*CleanupResult, error) {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	result := &CleanupResult{
 		DeletedFiles:  []string{},
 		ArchivedFiles: []string{},
@@ -640,6 +896,8 @@ func enforceGlobalSizeLimitWithStats(recordings []CleanupRecordingInfo) (*Cleanu
 
 		result.SpaceReclaimed += rec.Size / 1024 / 1024 // Convert to MB
 
+		cleanupThrottle.wait()
+
 		if cfg.MoveToArchive && cfg.ArchivePath != "" {
 			if err := archiveFile(rec, rec.Stream); err != nil {
 				log.Error().Err(err).Str("file", rec.Path).Msg("[recording] failed to archive file for size limit")
@@ -685,20 +943,15 @@ func enforceGlobalSizeLimit(recordings []CleanupRecordingInfo) error {
 
 // archiveFile moves a file to the archive directory
 func archiveFile(rec CleanupRecordingInfo, streamName string) error {
-	cfg := GlobalRecordingConfig
-	
-	// Create archive path structure similar to original
-	archiveSubPath := filepath.Join(streamName, rec.ModTime.Format("2006/01/02"))
-	archiveDir := filepath.Join(cfg.ArchivePath, archiveSubPath)
-	
-	if err := os.MkdirAll(archiveDir, 0755); err != nil {
-		return fmt.Errorf("failed to create archive directory: %w", err)
+	cfg := GetRecordingConfig()
+
+	// Keep the path relative to its pool, so a restore puts the file back in place
+	_, archiveSubPath, ok := storagePoolRel(rec.Path)
+	if !ok {
+		archiveSubPath = filepath.Join(streamName, rec.ModTime.Format("2006/01/02"), filepath.Base(rec.Path))
 	}
 
-	archivePath := filepath.Join(archiveDir, filepath.Base(rec.Path))
-	
-	// Move the file
-	if err := os.Rename(rec.Path, archivePath); err != nil {
+	if err := moveRecordingFile(rec.Path, filepath.Join(cfg.ArchivePath, archiveSubPath)); err != nil {
 		return fmt.Errorf("failed to move file to archive: %w", err)
 	}
 
@@ -707,9 +960,7 @@ func archiveFile(rec CleanupRecordingInfo, streamName string) error {
 
 // GetRecordingStats returns statistics about recordings
 func GetRecordingStats() (map[string]interface{}, error) {
-	cfg := GlobalRecordingConfig
-	
-	recordings, err := findRecordingFiles(cfg.BasePath)
+	recordings, err := findAllRecordingFiles()
 	if err != nil {
 		return nil, err
 	}
@@ -751,6 +1002,11 @@ func GetRecordingStats() (map[string]interface{}, error) {
 		stats["newest_recording"] = newestTime
 	}
 
+	// Per stream breakdown and when the limits are reached at this rate
+	streamStats := streamStorageStats(recordings, time.Now())
+	stats["stream_stats"] = streamStats
+	stats["forecast"] = storageForecast(streamStats, totalSize, diskMonitor.Status())
+
 	return stats, nil
 }
 
@@ -761,6 +1017,8 @@ type CleanupResult struct {
 	SpaceReclaimed  int64               `json:"space_reclaimed_mb"`
 	DeletedFiles    []string            `json:"deleted_files"`
 	ArchivedFiles   []string            `json:"archived_files"`
+	FilesTiered     int                 `json:"files_tiered"`   // moved to the cold tier
+	TieredFiles     []string            `json:"tiered_files"`
 	StreamsAffected []string            `json:"streams_affected"`
 	TotalSizeBefore int64               `json:"total_size_before_mb"`
 	TotalSizeAfter  int64               `json:"total_size_after_mb"`
@@ -807,7 +1065,7 @@ func extractRecordingTimeFromPath(filePath string) time.Time {
 			timestampStr := fmt.Sprintf("%s-%s-%s %s:%s:%s", year, month, day, hour, min, sec)
 			
 			// Parse timestamp
-			if parsedTime, err := time.ParseInLocation("2006-01-02 15:04:05", timestampStr, time.Local); err == nil {
+			if parsedTime, err := time.ParseInLocation("2006-01-02 15:04:05", timestampStr, templateLocation()); err == nil {
 				log.Debug().
 					Str("filename", filename).
 					Time("extracted_time", parsedTime).
@@ -823,8 +1081,6 @@ func extractRecordingTimeFromPath(filePath string) time.Time {
 
 // ForceCleanupOldRecordings performs aggressive cleanup ignoring normal retention rules
 func ForceCleanupOldRecordings(olderThanDays int, dryRun bool) (*CleanupResult, error) {
-	cfg := GlobalRecordingConfig
-
 	result := &CleanupResult{
 		DeletedFiles:  []string{},
 		ArchivedFiles: []string{},
@@ -837,7 +1093,7 @@ func ForceCleanupOldRecordings(olderThanDays int, dryRun bool) (*CleanupResult,
 		Msg("[cleanup] starting aggressive cleanup")
 
 	// Find all recording files
-	recordings, err := findRecordingFiles(cfg.BasePath)
+	recordings, err := findAllRecordingFiles()
 	if err != nil {
 		return result, fmt.Errorf("failed to find recording files: %w", err)
 	}
@@ -871,6 +1127,11 @@ func ForceCleanupOldRecordings(olderThanDays int, dryRun bool) (*CleanupResult,
 		}
 
 		if timeToCheck.Before(cutoffTime) {
+			if recordingIndex.isProtected(rec.Path) {
+				log.Info().Str("file", rec.Path).Msg("[cleanup] skipping protected file")
+				continue
+			}
+
 			result.SpaceReclaimed += rec.Size / 1024 / 1024 // Convert to MB
 
 			if dryRun {
@@ -899,7 +1160,11 @@ func ForceCleanupOldRecordings(olderThanDays int, dryRun bool) (*CleanupResult,
 
 	// Calculate final size
 	if !dryRun {
-		finalRecordings, err := findRecordingFiles(cfg.BasePath)
+		recordingIndex.Remove(result.DeletedFiles...)
+		recordingMetrics.addCleanup(result)
+		notify(NotifyCleanupResult, "", result)
+
+		finalRecordings, err := findAllRecordingFiles()
 		if err == nil {
 			var totalSizeAfter int64
 			for _, rec := range finalRecordings {
@@ -931,7 +1196,7 @@ func performHealthCheck() HealthCheckResult {
 	result.ExpectedRecordings = len(streamsToRecord)
 
 	// Check 1: Verify FFmpeg processes are running for expected streams
-	result.ActiveFFmpegProcesses = countActiveFFmpegProcesses()
+	result.ActiveFFmpegProcesses = countActiveFFmpegProcesses() + countActiveNativeRecordings()
 
 	if result.ExpectedRecordings > 0 && result.ActiveFFmpegProcesses == 0 {
 		result.Healthy = false
@@ -942,8 +1207,8 @@ func performHealthCheck() HealthCheckResult {
 	}
 
 	// Check 2: Verify new recordings are being created
-	cfg := GlobalRecordingConfig
-	recordings, err := findRecordingFiles(cfg.BasePath)
+	cfg := GetRecordingConfig()
+	recordings, err := findAllRecordingFiles()
 	if err != nil {
 		result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to find recordings: %v", err))
 	} else if len(recordings) > 0 {
@@ -995,7 +1260,7 @@ func performHealthCheck() HealthCheckResult {
 
 // getStreamsToRecordForHealthCheck returns streams that should be recording
 func getStreamsToRecordForHealthCheck() []string {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	var streamsToRecord []string
 
 	// If global auto_start with no specific configs, we expect all streams to be recording
@@ -1015,7 +1280,10 @@ func getStreamsToRecordForHealthCheck() []string {
 		}
 	} else {
 		// Check specifically configured streams
-		for streamName, streamConfig := range cfg.Streams {
+		for streamName, streamConfig := range configuredStreams(cfg) {
+			if streamConfig.RecordOnMotion {
+				continue // event-only streams are not expected to record continuously
+			}
 			if streamConfig.Enabled != nil && *streamConfig.Enabled {
 				streamsToRecord = append(streamsToRecord, streamName)
 			} else if streamConfig.Enabled == nil {
@@ -1029,7 +1297,8 @@ func getStreamsToRecordForHealthCheck() []string {
 	streamMap := make(map[string]bool)
 	uniqueStreams := []string{}
 	for _, stream := range streamsToRecord {
-		if !streamMap[stream] {
+		// Paused through the API, not expected to write anything
+		if !streamMap[stream] && !isStreamPaused(stream) {
 			streamMap[stream] = true
 			uniqueStreams = append(uniqueStreams, stream)
 		}
@@ -1040,26 +1309,12 @@ func getStreamsToRecordForHealthCheck() []string {
 
 // countActiveFFmpegProcesses counts running FFmpeg recording processes
 func countActiveFFmpegProcesses() int {
-	// Use pgrep to find FFmpeg processes that are recording (contain segment or output file)
-	cmd := exec.Command("pgrep", "-f", "ffmpeg.*-f (segment|mp4|matroska|avi|mov)")
-	output, err := cmd.Output()
-	if err != nil {
-		// pgrep returns exit code 1 if no processes found
-		return 0
-	}
-
-	pids := strings.TrimSpace(string(output))
-	if pids == "" {
-		return 0
-	}
-
-	// Count PIDs
-	return len(strings.Split(pids, "\n"))
+	return len(trackedFFmpegPIDs(""))
 }
 
 // checkStreamHealth checks if a specific stream is recording properly
 func checkStreamHealth(streamName string, allRecordings []CleanupRecordingInfo) bool {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 
 	// Find recordings for this stream
 	var streamRecordings []CleanupRecordingInfo
@@ -1100,10 +1355,13 @@ func checkStreamHealth(streamName string, allRecordings []CleanupRecordingInfo)
 		return false
 	}
 
+	// Native recordings run in-process, there is no FFmpeg to look for
+	if isNativeRecorder(GetStreamRecordingConfig(streamName)) {
+		return isAlreadyRecording(streamName)
+	}
+
 	// Check if FFmpeg process is running for this stream
-	cmd := fmt.Sprintf("pgrep -f 'ffmpeg.*%s'", streamName)
-	result, err := exec.Command("sh", "-c", cmd).Output()
-	if err != nil || strings.TrimSpace(string(result)) == "" {
+	if !isFFmpegProcessRunning(streamName) {
 		log.Warn().
 			Str("stream", streamName).
 			Msg("[health-check] No FFmpeg process found for stream")
@@ -1201,8 +1459,8 @@ func attemptRecovery(healthCheck HealthCheckResult) {
 		killAllFFmpegRecordingProcesses()
 
 		// Stop all current recordings in internal tracking
-		GetRecordingManager().StopAll()
-		GetSegmentedRecordingManager().StopAll()
+		GetRecordingManager().StopAll(ExitRecovery)
+		GetSegmentedRecordingManager().StopAll(ExitRecovery)
 
 		// Wait for cleanup
 		time.Sleep(3 * time.Second)
@@ -1223,47 +1481,12 @@ func attemptRecovery(healthCheck HealthCheckResult) {
 
 // killFFmpegProcessesForStream kills all FFmpeg processes recording a specific stream
 func killFFmpegProcessesForStream(streamName string) error {
-	// Find FFmpeg processes for this stream
-	cmd := fmt.Sprintf("pgrep -f 'ffmpeg.*%s'", streamName)
-	result, err := exec.Command("sh", "-c", cmd).Output()
-	if err != nil {
-		// pgrep returns exit code 1 if no processes found, which is fine
-		return nil
-	}
-
-	pids := strings.TrimSpace(string(result))
-	if pids == "" {
-		return nil
-	}
-
-	// Kill each process
-	for _, pidStr := range strings.Split(pids, "\n") {
-		pidStr = strings.TrimSpace(pidStr)
-		if pidStr == "" {
-			continue
-		}
-
-		log.Info().
-			Str("stream", streamName).
-			Str("pid", pidStr).
-			Msg("[recovery] killing stuck FFmpeg process")
-
-		// Try graceful kill first (SIGTERM)
-		killCmd := exec.Command("kill", pidStr)
-		if err := killCmd.Run(); err != nil {
-			log.Warn().
-				Err(err).
-				Str("pid", pidStr).
-				Msg("[recovery] SIGTERM failed, trying SIGKILL")
-
-			// Force kill if graceful fails (SIGKILL)
-			killCmd = exec.Command("kill", "-9", pidStr)
-			if err := killCmd.Run(); err != nil {
-				log.Error().
-					Err(err).
-					Str("pid", pidStr).
-					Msg("[recovery] failed to kill process")
-			}
+	for _, recording := range trackedRecordings(streamName) {
+		if recording.kill(ExitRecovery) {
+			log.Info().
+				Str("stream", streamName).
+				Int("pid", recording.PID).
+				Msg("[recovery] killed stuck FFmpeg process")
 		}
 	}
 
@@ -1274,34 +1497,12 @@ func killFFmpegProcessesForStream(streamName string) error {
 func killAllFFmpegRecordingProcesses() {
 	log.Warn().Msg("[recovery] killing all FFmpeg recording processes")
 
-	// Find all FFmpeg recording processes
-	cmd := "pgrep -f 'ffmpeg.*-f (segment|mp4|matroska|avi|mov)'"
-	result, err := exec.Command("sh", "-c", cmd).Output()
-	if err != nil {
-		return
-	}
-
-	pids := strings.TrimSpace(string(result))
-	if pids == "" {
-		return
-	}
-
-	// Kill each process
-	for _, pidStr := range strings.Split(pids, "\n") {
-		pidStr = strings.TrimSpace(pidStr)
-		if pidStr == "" {
-			continue
-		}
-
-		log.Info().
-			Str("pid", pidStr).
-			Msg("[recovery] killing FFmpeg recording process")
-
-		// Try graceful kill first
-		killCmd := exec.Command("kill", pidStr)
-		if err := killCmd.Run(); err != nil {
-			// Force kill if graceful fails
-			exec.Command("kill", "-9", pidStr).Run()
+	for _, recording := range trackedRecordings("") {
+		if recording.kill(ExitRecovery) {
+			log.Info().
+				Int("pid", recording.PID).
+				Str("stream", recording.Stream).
+				Msg("[recovery] killed FFmpeg recording process")
 		}
 	}
 
@@ -1318,7 +1519,7 @@ func stopExistingRecordings(streamName string) {
 				Str("stream", streamName).
 				Str("recording_id", id).
 				Msg("[recovery] stopping existing recording")
-			GetRecordingManager().StopRecording(id)
+			GetRecordingManager().StopRecording(id, ExitRecovery)
 		}
 	}
 
@@ -1330,7 +1531,7 @@ func stopExistingRecordings(streamName string) {
 				Str("stream", streamName).
 				Str("recording_id", id).
 				Msg("[recovery] stopping existing segmented recording")
-			GetSegmentedRecordingManager().StopSegmentedRecording(id)
+			GetSegmentedRecordingManager().StopSegmentedRecording(id, ExitRecovery)
 		}
 	}
 }
\ No newline at end of file
@@ -0,0 +1,41 @@
+package ffmpeg
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingDurations(t *testing.T) {
+	dir := t.TempDir() // files that don't exist are not being written
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+
+	recordings := []CleanupRecordingInfo{
+		{Path: filepath.Join(dir, "cam1_2024-01-01_12-00-00.mp4"), RecordingTime: start},
+		{Path: filepath.Join(dir, "cam1_2024-01-01_12-10-00.mp4"), RecordingTime: start.Add(time.Minute * 10)},
+		{Path: filepath.Join(dir, "cam1_2024-01-01_12-12-30.mp4"), RecordingTime: start.Add(time.Minute*12 + time.Second*30)},
+	}
+
+	// Up to the next recording, the last one by the filename estimate
+	require.Equal(t, []time.Duration{time.Minute * 10, time.Second * 150, estimateDuration("cam1_2024-01-01_12-12-30.mp4")},
+		recordingDurations(recordings))
+}
+
+func TestMaxTotalDurationConfig(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{
+		MaxTotalDuration: time.Hour * 72,
+		Streams: map[string]StreamRecordingConfig{
+			"cam2": {MaxTotalDuration: time.Hour * 24},
+		},
+	})
+
+	require.Equal(t, time.Hour*72, GetStreamRecordingConfig("cam1").MaxTotalDuration)
+	require.Equal(t, time.Hour*24, GetStreamRecordingConfig("cam2").MaxTotalDuration)
+
+	GetRecordingConfig().MaxTotalDuration = -time.Hour
+	require.Error(t, validateRuntimeConfig(GetRecordingConfig()))
+}
@@ -0,0 +1,55 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// codecName matches ffmpeg codec, encoder and format names
+	codecName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	// bitrateValue matches ffmpeg bitrates like 2000k or 1.5M
+	bitrateValue = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[kKmMgG]?$`)
+)
+
+// checkRecordingArgs rejects codecs, formats and bitrates that aren't a
+// plain name or value. They come from the API and the config and end up as
+// ffmpeg arguments, where "copy -f rtsp rtsp://..." would add an output.
+func checkRecordingArgs(video, audio, format string, streamConfig StreamRecordingConfig) error {
+	for _, codec := range []struct{ kind, name string }{{"video", video}, {"audio", audio}} {
+		if _, ok := defaults[codec.name]; !ok && !codecName.MatchString(codec.name) {
+			return fmt.Errorf("invalid %s codec %q", codec.kind, codec.name)
+		}
+	}
+	if !codecName.MatchString(format) {
+		return fmt.Errorf("invalid format %q", format)
+	}
+	if b := streamConfig.BitrateLimit; b != "" && !bitrateValue.MatchString(b) {
+		return fmt.Errorf("invalid bitrate_limit %q", b)
+	}
+	return nil
+}
+
+// execArgs joins arguments for an exec: or ffmpeg: source. go2rtc splits
+// these with shell.QuoteSplit, which knows quotes but no escapes, so an
+// argument with spaces is quoted with the quote it doesn't contain.
+func execArgs(args ...string) (string, error) {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg == "":
+			return "", errors.New("empty argument")
+		case !strings.ContainsAny(arg, " \t\r\n\"'"):
+			quoted[i] = arg
+		case !strings.Contains(arg, `"`):
+			quoted[i] = `"` + arg + `"`
+		case !strings.Contains(arg, `'`):
+			quoted[i] = `'` + arg + `'`
+		default:
+			return "", fmt.Errorf("argument with both quote characters: %s", arg)
+		}
+	}
+	return strings.Join(quoted, " "), nil
+}
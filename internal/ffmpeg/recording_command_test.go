@@ -0,0 +1,47 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/AlexxIT/go2rtc/pkg/shell"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecArgs(t *testing.T) {
+	args := []string{"-re", "-i", "/media/front door/it's 12:00.mp4", `/media/"quoted".mp4`, "{output}"}
+
+	s, err := execArgs(args...)
+	require.Nil(t, err)
+	require.Equal(t, args, shell.QuoteSplit(s))
+
+	_, err = execArgs(`both "quotes" and 'quotes'`)
+	require.NotNil(t, err)
+}
+
+func TestExpandFFmpegTemplate(t *testing.T) {
+	output := []string{"-c:v", "copy", "-f", "mp4", "-y", "/media/front door/1.mp4"}
+
+	args, err := expandFFmpegTemplate(`-i {input} -metadata "title={stream} cam" {output}`, "rtsp://cam/1", output, "front")
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"-i", "rtsp://cam/1", "-metadata", "title=front cam",
+		"-c:v", "copy", "-f", "mp4", "-y", "/media/front door/1.mp4",
+	}, args)
+
+	// Without {output} the output is appended
+	args, err = expandFFmpegTemplate("-i {input}", "pipe:0", output, "front")
+	require.Nil(t, err)
+	require.Equal(t, append([]string{"-i", "pipe:0"}, output...), args)
+
+	_, err = expandFFmpegTemplate(`-i {input} -metadata "title`, "pipe:0", output, "front")
+	require.NotNil(t, err)
+}
+
+func TestCheckRecordingArgs(t *testing.T) {
+	require.Nil(t, checkRecordingArgs("h264", "copy", "mp4", StreamRecordingConfig{BitrateLimit: "1.5M"}))
+	require.Nil(t, checkRecordingArgs("libx264", "pcm_s16le", "matroska", StreamRecordingConfig{}))
+
+	require.NotNil(t, checkRecordingArgs("copy -f rtsp rtsp://evil/x", "copy", "mp4", StreamRecordingConfig{}))
+	require.NotNil(t, checkRecordingArgs("copy", "copy", "mp4 -y /etc/x", StreamRecordingConfig{}))
+	require.NotNil(t, checkRecordingArgs("copy", "copy", "mp4", StreamRecordingConfig{BitrateLimit: "2M -y"}))
+}
@@ -3,8 +3,11 @@ package ffmpeg
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/app"
@@ -14,37 +17,76 @@ type StreamRecordingConfig struct {
 	// Override global settings per stream
 	Enabled          *bool         `yaml:"enabled"`           // Enable recording for this stream
 	Source           string        `yaml:"source"`            // Direct RTSP source URL (overrides stream routing)
+	BasePath         string        `yaml:"base_path"`         // Pin this stream to one storage directory
 	PathTemplate     string        `yaml:"path_template"`     // Custom path template for this stream
 	FilenameTemplate string        `yaml:"filename_template"` // Custom filename template
+	Alias            string        `yaml:"alias"`             // Name used for {stream_alias} in templates (default stream name)
 	Format           string        `yaml:"format"`            // Output format for this stream
+	Recorder         string        `yaml:"recorder"`          // "ffmpeg" or "native" for this stream
+	InputMode        string        `yaml:"input_mode"`        // "rtsp" or "pipe" for this stream
+	Restream         string        `yaml:"restream"`          // go2rtc stream the recording also publishes to, e.g. "{stream}_live"
 	
 	// Stream-specific segmentation
 	SegmentDuration  time.Duration `yaml:"segment_duration"`  // Custom segment duration
 	MaxFileSize      int64         `yaml:"max_file_size"`     // Custom max file size
 	EnableSegments   *bool         `yaml:"enable_segments"`   // Enable/disable segments for this stream
+	Segmenter        string        `yaml:"segmenter"`         // "ffmpeg" or "manager" for this stream
+	Faststart        *bool         `yaml:"faststart"`         // Move the MP4 index to the front of finished files
+	KeyframeAlign    *bool         `yaml:"keyframe_align"`    // Start every segment with a keyframe
+	VerifySegments   *bool         `yaml:"verify_segments"`   // Probe every finished segment of this stream
 	
 	// Stream-specific retention
 	RetentionDays    int           `yaml:"retention_days"`    // Custom retention days
 	RetentionHours   int           `yaml:"retention_hours"`   // Custom retention hours
+	EventRetentionDays int         `yaml:"event_retention_days"` // Custom retention days for event recordings
 	MaxRecordings    int           `yaml:"max_recordings"`    // Custom max recordings
+	MaxTotalSize     int64         `yaml:"max_total_size"`    // Max storage for this stream in MB (0 = only the global limit)
+	MaxTotalDuration time.Duration `yaml:"max_total_duration"` // Max footage kept for this stream, e.g. 72h
+	ThinAfterDays    int           `yaml:"thin_after_days"`   // Custom days before recordings are thinned
+	ThinHourlyDays   int           `yaml:"thin_hourly_days"`  // Custom days one recording per hour is kept
 	
 	// Stream-specific quality
 	Video            string        `yaml:"video"`             // Video codec for this stream
 	Audio            string        `yaml:"audio"`             // Audio codec for this stream
 	BitrateLimit     string        `yaml:"bitrate_limit"`     // Bitrate limit for this stream
+	HWAccel          string        `yaml:"hwaccel"`           // GPU for transcodes: vaapi, nvenc, qsv, v4l2m2m, rkmpp, videotoolbox or auto
+	HWAccelDevice    string        `yaml:"hwaccel_device"`    // e.g. /dev/dri/renderD128 (vaapi, qsv) or GPU index (nvenc)
+	FFmpegTemplate   string        `yaml:"ffmpeg_template"`   // Raw ffmpeg arguments with {input}, {output} and {stream}
+	OnSegmentComplete   string     `yaml:"on_segment_complete"`   // Command run for every finished file
+	OnRecordingComplete string     `yaml:"on_recording_complete"` // Command run when a recording ends
+	
+	// Stream-specific ffmpeg input
+	RTSPTransport    string        `yaml:"rtsp_transport"`    // "tcp", "udp" or "auto" for this stream
+	InputTimeout     time.Duration `yaml:"input_timeout"`     // Give up on a source silent this long
+	Reconnect        *bool         `yaml:"reconnect"`         // Reconnect HTTP sources after errors
+	AnalyzeDuration  time.Duration `yaml:"analyze_duration"`  // How long ffmpeg analyzes the input
+	ProbeSize        int64         `yaml:"probe_size"`        // Bytes ffmpeg reads to detect the streams
 	
 	// Stream-specific behavior
 	AutoStart        *bool         `yaml:"auto_start"`        // Auto-start for this stream
 	RestartOnError   *bool         `yaml:"restart_on_error"`  // Restart behavior for this stream
+	Priority         int           `yaml:"priority"`          // Higher starts first when recordings wait for a slot
 	
 	// Schedule-based recording
 	Schedule         string        `yaml:"schedule"`          // Cron-like schedule (future feature)
+	Schedules        []string      `yaml:"schedules"`         // More cron expressions or time windows like "mon-fri 08:00-18:00"
+	Blackouts        []string      `yaml:"blackouts"`         // Time windows the schedule never records in, like "12:00-13:00"
+	ScheduleStop     string        `yaml:"schedule_stop"`     // Cron expression ending recordings started by schedule, e.g. "0 18 * * 1-5"
+	ScheduleTimezone string        `yaml:"schedule_timezone"` // Timezone of the schedule, e.g. "America/New_York" (default local)
 	RecordOnMotion   bool          `yaml:"record_on_motion"`  // Record only on motion detection
+	MotionTopic      string        `yaml:"motion_topic"`      // MQTT topic that triggers event recordings
+	MotionONVIF      string        `yaml:"motion_onvif"`      // ONVIF camera URL to subscribe for motion events
+	EventPreTime     time.Duration `yaml:"event_pre_time"`    // Pre-roll for event recordings (default buffer_time)
+	EventPostTime    time.Duration `yaml:"event_post_time"`   // Post-roll for event recordings
 
 	// Post-recording object detection
 	Detection        bool          `yaml:"detection"`           // Enable post-recording detection for this stream
 	DetectionInterval int          `yaml:"detection_interval"`  // Seconds between sampled frames (overrides global)
 	DetectionLabels  []string      `yaml:"detection_labels"`    // Label filter override for this stream
+
+	// Snapshots
+	SnapshotInterval      time.Duration `yaml:"snapshot_interval"`       // Capture a JPEG this often (overrides global)
+	SnapshotRetentionDays int           `yaml:"snapshot_retention_days"` // Days to keep snapshots (overrides global)
 	
 	// Quality settings
 	Width            int           `yaml:"width"`             // Force specific width
@@ -54,28 +96,91 @@ type StreamRecordingConfig struct {
 
 type RecordingConfig struct {
 	// Storage settings
-	BasePath        string `yaml:"base_path"`         // Base directory for all recordings
+	BasePath        string `yaml:"-"`                 // Primary pool, also holds the index, state and thumbnails
+	BasePaths       StoragePaths `yaml:"base_path"`   // Base directory, or a list of storage pools
+	StoragePolicy   string  `yaml:"storage_policy"`   // Pool placement: "fill_first" (default) or "round_robin"
+	PoolMinFree     float64 `yaml:"pool_min_free"`    // Skip pools with less free space (percent)
 	PathTemplate    string `yaml:"path_template"`     // Directory structure template
 	FilenameTemplate string `yaml:"filename_template"` // Filename template
+	PathTimezone    string `yaml:"path_timezone"`     // Timezone of template timestamps: "local" (default), "utc" or e.g. "Europe/Berlin"
 	DefaultFormat   string `yaml:"default_format"`    // Default output format
+	Recorder        string `yaml:"recorder"`          // "ffmpeg" (default) or "native" built-in muxer
+	InputMode       string `yaml:"input_mode"`        // "rtsp" (default) restreams via localhost, "pipe" feeds ffmpeg stdin from the running stream
 	CreateDirectories bool `yaml:"create_directories"` // Auto-create directories
+	FFmpegPath      string `yaml:"ffmpeg_path"`       // ffmpeg binary of recordings (default go2rtc's ffmpeg bin, or one bundled next to go2rtc)
+	FFprobePath     string `yaml:"ffprobe_path"`      // ffprobe binary (default the ffprobe next to ffmpeg, or in PATH)
+	IndexPath       string        `yaml:"index_path"`     // Recording index file (default {base_path}/.recordings.index)
+	IndexInterval   time.Duration `yaml:"index_interval"` // How often to reconcile the index with disk
+	IndexWatch      bool          `yaml:"index_watch"`    // Follow the storage pools with inotify instead of reconciling every index_interval (Linux)
+	IndexRescanInterval time.Duration `yaml:"index_rescan_interval"` // How often to reconcile the index with disk while index_watch works
+	StatePath       string        `yaml:"state_path"`     // Running recordings for recovery after restart (default {base_path}/.recordings.state)
+	HistoryPath     string        `yaml:"history_path"`      // Log of ended recording sessions (default {base_path}/.recordings.history)
+	HistoryRetention time.Duration `yaml:"history_retention"` // Forget sessions that ended longer ago (0 keeps all)
 
 	// Segmentation settings
 	SegmentDuration  time.Duration `yaml:"segment_duration"`  // Duration before starting new file
 	MaxFileSize      int64         `yaml:"max_file_size"`     // Max file size in MB before new file
 	EnableSegments   bool          `yaml:"enable_segments"`   // Enable automatic segmentation
+	Segmenter        string        `yaml:"segmenter"`         // "ffmpeg" (default) splits in the recorder process, "manager" restarts it per segment
+	Faststart        bool          `yaml:"faststart"`         // Remux finished MP4s with the moov atom first
+	KeyframeAlign    bool          `yaml:"keyframe_align"`    // Force keyframes at segment cuts and overlap segment handovers
+	SegmentTimeDelta time.Duration `yaml:"segment_time_delta"` // Tolerance of the segment muxer for cutting on a keyframe (0 = ffmpeg default)
+	VerifySegments   bool          `yaml:"verify_segments"`   // Probe every finished segment and restart the capture of unusable ones
 
 	// Retention policy
 	RetentionDays    int   `yaml:"retention_days"`    // Days to keep recordings
 	RetentionHours   int   `yaml:"retention_hours"`   // Hours to keep recordings (more granular)
+	EventRetentionDays int `yaml:"event_retention_days"` // Days to keep event recordings (0 = same as continuous)
 	MaxRecordings    int   `yaml:"max_recordings"`    // Max recordings per stream
 	MaxTotalSize     int64 `yaml:"max_total_size"`    // Max total storage in MB
+	MaxTotalDuration time.Duration `yaml:"max_total_duration"` // Max footage kept per stream, by recorded duration (0 = no limit)
+	QuotaMode        string `yaml:"quota_mode"`       // "cleanup" (default) lets the cleanup enforce max_total_size, "enforce" also stops recordings over it
+	ThinAfterDays    int   `yaml:"thin_after_days"`   // Keep everything this many days, then thin (0 = disabled)
+	ThinHourlyDays   int   `yaml:"thin_hourly_days"`  // Then keep one recording per hour this many days, one per day after
+	TagRetention     map[string]int `yaml:"tag_retention"` // Days to keep recordings with a tag, 0 = never deleted by cleanup
+
+	// Disk space protection, percent of the recordings volume left free
+	DiskLowWatermark  float64       `yaml:"disk_low_watermark"`  // Refuse new recordings below this (0 = disabled)
+	DiskHighWatermark float64       `yaml:"disk_high_watermark"` // Delete oldest recordings below this (0 = disabled)
+	DiskCheckInterval time.Duration `yaml:"disk_check_interval"` // How often free space is checked
 
 	// Cleanup settings
 	EnableCleanup    bool          `yaml:"enable_cleanup"`    // Enable automatic cleanup
 	CleanupInterval  time.Duration `yaml:"cleanup_interval"`  // How often to run cleanup
+	CleanupWindow    string        `yaml:"cleanup_window"`    // Daily time range for scheduled cleanup, e.g. "02:00-05:00"
+	CleanupFilesPerMinute int      `yaml:"cleanup_files_per_minute"` // Max files deleted, archived or moved per minute (0 = unlimited)
+	ArchiveRateLimit float64       `yaml:"archive_rate_limit"` // Max MB/s when copying to archive_path or cold_path (0 = unlimited)
+	DownloadRateLimit      float64 `yaml:"download_rate_limit"`       // Max MB/s of each recording download or export (0 = unlimited)
+	DownloadTotalRateLimit float64 `yaml:"download_total_rate_limit"` // Max MB/s of all recording downloads and exports together (0 = unlimited)
 	MoveToArchive    bool          `yaml:"move_to_archive"`   // Move old files instead of deleting
 	ArchivePath      string        `yaml:"archive_path"`      // Archive directory path
+	ColdPath         string        `yaml:"cold_path"`         // Second storage tier (slow disk, NFS) for older recordings
+	HotDays          int           `yaml:"hot_days"`          // Days recordings stay on base_path before moving to cold_path
+	ImportPaths      []ImportPath  `yaml:"import_paths"`      // Existing recordings listed read-only, never cleaned up
+	FilenamePatterns []FilenamePattern `yaml:"filename_patterns"` // Regexes reading stream and start time from paths of other tools
+	StreamSkipDirs   []string      `yaml:"stream_skip_dirs"`  // Directory names never taken as the stream name
+	ExportPath       string        `yaml:"export_path"`       // Directory for stored clip exports
+	ExportProfiles   map[string]ExportProfile `yaml:"export_profiles"` // Named presets of ?profile= for exports and downloads
+	ThumbnailPath    string        `yaml:"thumbnail_path"`    // Thumbnail cache directory (default {base_path}/.thumbs)
+	ThumbnailOffset  time.Duration `yaml:"thumbnail_offset"`  // Position of the thumbnail frame in the recording
+	PreviewFormat    string        `yaml:"preview_format"`    // Animated preview per recording: "gif", "webp" or "sprite" (empty disables)
+	PreviewFrames    int           `yaml:"preview_frames"`    // Frames sampled over the recording for the preview
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"` // Capture a JPEG of recorded streams this often (0 = disabled)
+	SnapshotRetentionDays int      `yaml:"snapshot_retention_days"` // Days to keep snapshots (0 = same as the stream's recordings)
+	MaxTranscodes    int           `yaml:"max_transcodes"`    // Concurrent ?transcode= downloads and playbacks (0 = disabled)
+	MaxJobs          int           `yaml:"max_jobs"`          // Background jobs (async exports, merges, ...) running at once
+	ListingCacheTTL  time.Duration `yaml:"listing_cache_ttl"` // How long a listing is answered from memory while nothing changed (0 = disabled)
+
+	// Recording process limits
+	MaxConcurrentStarts   int           `yaml:"max_concurrent_starts"`   // Recordings launching ffmpeg at once (0 = unlimited)
+	MaxRecordingProcesses int           `yaml:"max_recording_processes"` // Recordings running at once (0 = unlimited)
+	StartQueueTimeout     time.Duration `yaml:"start_queue_timeout"`     // How long a start waits for a free slot
+
+	// Integrity scan of recent files
+	IntegrityCheckInterval time.Duration `yaml:"integrity_check_interval"` // How often new files are probed (0 = disabled)
+	IntegrityCheckWindow   time.Duration `yaml:"integrity_check_window"`   // Only check files modified within this window
+	IntegrityRepair        bool          `yaml:"integrity_repair"`         // Remux the readable part of corrupt files
+	ProbeDurations         bool          `yaml:"probe_durations"`          // Probe finished files in the background for exact listing durations
 
 	// Health check settings
 	EnableHealthCheck    bool          `yaml:"enable_health_check"`    // Enable automatic health monitoring
@@ -88,6 +193,15 @@ type RecordingConfig struct {
 	StallThreshold          int           `yaml:"stall_threshold"`           // Consecutive stalls before recovery (default 3)
 	MaxRecoveryAttempts     int           `yaml:"max_recovery_attempts"`     // Max recovery attempts per stream (default 5)
 	RecoveryCooldown        time.Duration `yaml:"recovery_cooldown"`         // Time between recovery attempts (default 2m)
+	StallTimeout            time.Duration `yaml:"stall_timeout"`             // Restart a recording that wrote nothing for this long (0 disables)
+	StallCheckInterval      time.Duration `yaml:"stall_check_interval"`      // How often recording output is checked (default 15s)
+	AlertWebhook            string        `yaml:"alert_webhook"`             // URL that receives alerts (stalled recordings) as JSON
+
+	// Recording process resources
+	ResourceCheckInterval   time.Duration `yaml:"resource_check_interval"`   // How often CPU and memory of recording processes are read (0 disables)
+	FFmpegNice              int           `yaml:"ffmpeg_nice"`               // Scheduling priority of recording processes, 1 to 19 lowers it
+	MaxFFmpegCPU            float64       `yaml:"max_ffmpeg_cpu"`            // Kill a recording process above this CPU percent, 100 = one core (0 = no limit)
+	MaxFFmpegMemory         int64         `yaml:"max_ffmpeg_memory"`         // Kill a recording process above this resident memory in MB (0 = no limit)
 
 	// Minimum file protection (prevents cleanup from deleting all files)
 	MinimumFilesPerStream   int           `yaml:"minimum_files_per_stream"`  // Minimum files to keep per stream (default 5)
@@ -97,33 +211,107 @@ type RecordingConfig struct {
 	// Recording behavior
 	AutoStart        bool          `yaml:"auto_start"`        // Auto-start recording when stream available
 	AutoRecordCheckInterval time.Duration `yaml:"auto_record_check_interval"` // How often to check for new streams to record
+	AutoRecordMaxBackoff    time.Duration `yaml:"auto_record_max_backoff"`    // Longest wait between restarts of a failing stream
 	RestartOnError   bool          `yaml:"restart_on_error"`  // Restart if FFmpeg fails
+	ShutdownTimeout  time.Duration `yaml:"shutdown_timeout"`  // How long ffmpeg may take to finalize files on exit
 	BufferTime       time.Duration `yaml:"buffer_time"`       // Pre-recording buffer duration
+	BufferPath       string        `yaml:"buffer_path"`       // Pre-record buffer directory (default /dev/shm or temp dir)
 	PostRecordingTime time.Duration `yaml:"post_recording_time"` // Continue after stream ends
+	EventPostTime    time.Duration `yaml:"event_post_time"`   // Default post-roll for event recordings
+	MaxEventDuration time.Duration `yaml:"max_event_duration"` // Upper bound for a single event recording
 	
 	// Source settings
 	DirectSource     string        `yaml:"direct_source"`     // Global direct source template (e.g., "rtsp://camera-{stream}.local/stream1")
+	RTSPTransport    string        `yaml:"rtsp_transport"`    // "tcp" (default), "udp" or "auto" (ffmpeg tries UDP first)
+	InputTimeout     time.Duration `yaml:"input_timeout"`     // Give up on a source silent this long (0 = wait forever)
+	Reconnect        bool          `yaml:"reconnect"`         // Reconnect HTTP sources after errors
+	AnalyzeDuration  time.Duration `yaml:"analyze_duration"`  // How long ffmpeg analyzes the input (0 = ffmpeg default)
+	ProbeSize        int64         `yaml:"probe_size"`        // Bytes ffmpeg reads to detect the streams (0 = ffmpeg default)
 
 	// Quality and codec settings
 	DefaultVideo     string        `yaml:"default_video"`     // Default video codec
 	DefaultAudio     string        `yaml:"default_audio"`     // Default audio codec
 	BitrateLimit     string        `yaml:"bitrate_limit"`     // Bitrate limit for recordings
 
+	// MQTT broker for event triggers and state publishing
+	MQTT             RecordingMQTTConfig `yaml:"mqtt"`
+
+	// Offload finished segments to S3-compatible storage
+	Upload           RecordingUploadConfig `yaml:"upload"`
+
+	// Alert rules and the backends their alerts are delivered to
+	Alerts           []AlertRule           `yaml:"alerts"`            // Conditions such as not_recording or disk_low, with a cooldown
+	Notifiers        []AlertNotifierConfig `yaml:"notifiers" json:"-"` // email, telegram, slack, ntfy or webhook backends, with secrets
+
+	// Daily summary of the previous day
+	ReportTime       string   `yaml:"report_time"`      // Local time the report is written, e.g. "00:30" (empty disables)
+	ReportPath       string   `yaml:"report_path"`      // Directory of the JSON and HTML reports (default {base_path}/reports)
+	ReportNotifiers  []string `yaml:"report_notifiers"` // Notifiers the report is sent to, email notifiers get the HTML report
+
+	// Commands run with RECORDING_* environment variables
+	OnSegmentComplete   string        `yaml:"on_segment_complete"`   // Run for every finished file
+	OnRecordingComplete string        `yaml:"on_recording_complete"` // Run when a recording ends
+	HookTimeout         time.Duration `yaml:"hook_timeout"`          // Kill hook commands after this long
+
+	// Access to the recording API, redacted in config responses
+	APITokens []RecordingAPIToken `yaml:"api_tokens" json:"-"` // Bearer tokens with a role and stream scope
+	JWTSecret string              `yaml:"jwt_secret" json:"-"` // HS256 secret of JWTs with role and streams claims
+	AuditLog  string              `yaml:"audit_log"`           // Append-only log of recording access, e.g. "recordings/.audit.log" (empty disables)
+
+	// Encrypt finished recordings with AES-256-GCM, the key may also come from RECORDING_ENCRYPTION_KEY
+	EncryptionKey string `yaml:"encryption_key" json:"-"` // 32 bytes, base64 or hex encoded
+
 	// Monitoring
 	EnableMetrics    bool          `yaml:"enable_metrics"`    // Enable recording metrics
 	MetricsInterval  time.Duration `yaml:"metrics_interval"`  // Metrics collection interval
+
+	// Reload on SIGHUP or when the config file changes
+	ConfigWatchInterval time.Duration `yaml:"config_watch_interval"` // How often the config file is checked for changes (0 = only SIGHUP)
 	
 	// Per-stream configuration
-	Streams          map[string]StreamRecordingConfig `yaml:"streams"` // Per-stream recording settings
+	Streams          map[string]StreamRecordingConfig `yaml:"streams"` // Per-stream recording settings, keys may be globs ("camera_*") or "group:NAME"
+	StreamGroups     map[string][]string `yaml:"stream_groups"` // Named lists of streams (or globs) for "group:NAME" keys
+}
+
+// recordingConfig is the current recording config. Runtime changes and
+// reloads publish a new one instead of changing it in place, so a reader
+// that keeps the pointer of GetRecordingConfig sees one consistent config.
+var recordingConfig atomic.Pointer[RecordingConfig]
+
+func init() {
+	recordingConfig.Store(defaultRecordingConfig)
 }
 
-var GlobalRecordingConfig = &RecordingConfig{
+// GetRecordingConfig returns the current recording config, callers must not
+// change it
+func GetRecordingConfig() *RecordingConfig {
+	return recordingConfig.Load()
+}
+
+// setRecordingConfig makes cfg the current recording config
+func setRecordingConfig(cfg *RecordingConfig) {
+	recordingConfig.Store(cfg)
+}
+
+// defaultRecordingConfig holds the built-in settings
+var defaultRecordingConfig = &RecordingConfig{
 	// Default values
 	BasePath:          "recordings",
+	BasePaths:         StoragePaths{"recordings"},
+	StoragePolicy:     PoolFillFirst,
+	PoolMinFree:       10,            // Move on to the next pool below 10% free
 	PathTemplate:      "{stream}",
 	FilenameTemplate:  "{stream}_{timestamp}",
 	DefaultFormat:     "mp4",
+	Recorder:          "ffmpeg",
+	InputMode:         "rtsp",
+	Segmenter:         "ffmpeg",
 	CreateDirectories: true,
+	IndexInterval:     time.Minute,   // Reconcile index every minute
+	IndexWatch:        true,
+	IndexRescanInterval: time.Hour, // Full reconcile hourly while watching
+	HistoryRetention:  time.Hour * 24 * 30, // Remember recording sessions for 30 days
+	StreamSkipDirs:    []string{"recordings", "archive", "security", "indoor"},
 
 	SegmentDuration:   time.Minute * 10, // 10 minute segments by default
 	MaxFileSize:       1024,          // 1GB max file size
@@ -133,11 +321,28 @@ var GlobalRecordingConfig = &RecordingConfig{
 	RetentionHours:    0,             // 0 means use RetentionDays
 	MaxRecordings:     100,           // Max 100 recordings per stream
 	MaxTotalSize:      10240,         // 10GB total limit
+	QuotaMode:         QuotaCleanup,
+
+	DiskLowWatermark:  5,             // Pause new recordings below 5% free
+	DiskHighWatermark: 10,            // Emergency cleanup below 10% free
+	DiskCheckInterval: time.Second * 30,
 
 	EnableCleanup:     true,          // Enable cleanup by default
 	CleanupInterval:   time.Hour,     // Check every hour
 	MoveToArchive:     false,         // Delete by default
 	ArchivePath:       "archive",
+	ExportPath:        "exports",
+	ThumbnailOffset:   time.Second,   // Skip the first second to avoid black frames
+	PreviewFrames:     10,
+	MaxTranscodes:     2,             // Transcoding is CPU heavy
+	MaxJobs:           2,
+	ListingCacheTTL:   time.Second * 5,
+	StartQueueTimeout: time.Second * 30,
+	HookTimeout:       time.Minute * 5,
+
+	IntegrityCheckInterval: time.Hour,
+	IntegrityCheckWindow:   time.Hour * 24,
+	IntegrityRepair:        true,
 
 	EnableHealthCheck:    true,           // Enable health check by default
 	HealthCheckInterval:  time.Minute * 2,  // Check every 2 minutes (reduced from 10)
@@ -149,6 +354,8 @@ var GlobalRecordingConfig = &RecordingConfig{
 	StallThreshold:          3,                 // 3 consecutive stalls = stuck
 	MaxRecoveryAttempts:     5,                 // Max 5 recovery attempts
 	RecoveryCooldown:        time.Minute * 2,   // 2 minutes between recovery attempts
+	StallCheckInterval:      time.Second * 15,  // Check recording output every 15 seconds
+	ResourceCheckInterval:   time.Second * 10,  // Sample recording processes every 10 seconds
 
 	// Minimum file protection defaults
 	MinimumFilesPerStream:   5,                 // Keep at least 5 files per stream
@@ -157,10 +364,17 @@ var GlobalRecordingConfig = &RecordingConfig{
 
 	AutoStart:         false,         // Don't auto-start by default
 	AutoRecordCheckInterval: time.Second * 10, // Check every 10 seconds by default
+	AutoRecordMaxBackoff:    time.Minute * 5,  // Offline cameras are retried at least every 5 minutes
 	RestartOnError:    true,          // Restart on errors
+	ShutdownTimeout:   time.Second * 15, // Time for ffmpeg to write the moov atom on exit
 	BufferTime:        0,             // No buffer by default
 	PostRecordingTime: time.Second * 5, // 5 seconds after stream ends
+	EventPostTime:     time.Second * 30, // 30 seconds after the last trigger
+	MaxEventDuration:  time.Minute * 10, // Cap continuous motion at 10 minutes
 
+	RTSPTransport:     "tcp",         // No lost packets on busy networks
+	InputTimeout:      time.Second * 15, // A silent camera fails the recording instead of hanging it
+	
 	DefaultVideo:      "copy",        // Copy video codec by default
 	DefaultAudio:      "copy",        // Copy audio codec by default
 	BitrateLimit:      "",            // No limit by default
@@ -178,58 +392,125 @@ func LoadRecordingConfig() {
 	}
 
 	// Set defaults
-	cfg.Recording = *GlobalRecordingConfig
+	cfg.Recording = *GetRecordingConfig()
+	recordingDefaults = *GetRecordingConfig()
+	recordingDefaults.Streams = nil
 
 	// Load from YAML config
 	app.LoadConfig(&cfg)
 
-	// Update global config
-	*GlobalRecordingConfig = cfg.Recording
+	// Validate and fix config values, then make the config current
+	validateRecordingConfig(&cfg.Recording)
+	setRecordingConfig(&cfg.Recording)
 
-	// Validate and fix config values
-	validateRecordingConfig()
+	// Resolve the ffmpeg binaries and report a missing ffmpeg once, instead
+	// of with every recording start
+	checkFFmpeg()
 
 	// Start cleanup routine if enabled
-	if GlobalRecordingConfig.EnableCleanup {
+	if GetRecordingConfig().EnableCleanup {
 		go cleanupRoutine()
 	}
 
 	// Start health check routine if enabled
-	if GlobalRecordingConfig.EnableHealthCheck {
+	if GetRecordingConfig().EnableHealthCheck {
 		go healthCheckRoutine()
 	}
 
 	// Start watchdog routine if enabled
-	if GlobalRecordingConfig.WatchdogEnabled {
+	if GetRecordingConfig().WatchdogEnabled {
 		go StartWatchdog()
 	}
 
+	// Restart recordings whose output stopped growing
+	startAlertWebhook()
+	if GetRecordingConfig().StallTimeout > 0 {
+		go stallMonitorRoutine()
+	}
+
+	// Account CPU and memory of recording processes, kill runaway ones
+	if GetRecordingConfig().ResourceCheckInterval > 0 {
+		go resourceMonitorRoutine()
+	}
+
+	// Protect the recordings volume from filling up
+	if GetRecordingConfig().DiskLowWatermark > 0 || GetRecordingConfig().DiskHighWatermark > 0 {
+		go diskMonitorRoutine()
+	}
+
+	// Stop recordings over max_total_size with quota_mode enforce
+	go quotaMonitorRoutine()
+
+	// Evaluate alert rules and deliver alerts to the notifiers
+	startAlerting()
+
+	// Write a summary of every day
+	startReports()
+
+	// Clean up and resume recordings interrupted by the last shutdown
+	recoverRecordingState()
+
+	// Start MQTT/ONVIF listeners for event recordings
+	startEventTriggers()
+
+	// Publish recording state to MQTT if enabled
+	startMQTTPublisher()
+
+	// Upload finished segments to S3 if a bucket is configured
+	startRecordingUploader()
+
+	// Move the MP4 index of finished files to the front
+	startFaststartQueue()
+
+	// Generate hover previews of finished files
+	startPreviewQueue()
+
+	// Apply config changes without a restart
+	go configReloadRoutine()
+
+	// Capture periodic stills of recorded streams
+	startSnapshots()
+
+	// Find and repair files broken by crashes
+	if GetRecordingConfig().IntegrityCheckInterval > 0 {
+		go integrityCheckRoutine()
+	}
+
+	// Load the recording index and keep it in sync with disk
+	startListingCache()
+	go indexRoutine()
+
+	// Replace estimated durations in listings with probed ones
+	if GetRecordingConfig().ProbeDurations {
+		go durationProbeRoutine()
+	}
+
 	// Log configuration in a more readable format
 	log.Info().
-		Str("base_path", GlobalRecordingConfig.BasePath).
-		Str("default_format", GlobalRecordingConfig.DefaultFormat).
-		Str("default_video", GlobalRecordingConfig.DefaultVideo).
-		Str("default_audio", GlobalRecordingConfig.DefaultAudio).
-		Bool("enable_segments", GlobalRecordingConfig.EnableSegments).
-		Dur("segment_duration", GlobalRecordingConfig.SegmentDuration).
-		Int64("max_file_size_mb", GlobalRecordingConfig.MaxFileSize).
-		Int("retention_days", GlobalRecordingConfig.RetentionDays).
-		Bool("auto_start", GlobalRecordingConfig.AutoStart).
-		Dur("auto_record_check_interval", GlobalRecordingConfig.AutoRecordCheckInterval).
-		Bool("enable_cleanup", GlobalRecordingConfig.EnableCleanup).
-		Dur("cleanup_interval", GlobalRecordingConfig.CleanupInterval).
-		Bool("enable_health_check", GlobalRecordingConfig.EnableHealthCheck).
-		Dur("health_check_interval", GlobalRecordingConfig.HealthCheckInterval).
-		Bool("watchdog_enabled", GlobalRecordingConfig.WatchdogEnabled).
-		Dur("watchdog_interval", GlobalRecordingConfig.WatchdogInterval).
-		Int("stall_threshold", GlobalRecordingConfig.StallThreshold).
-		Int("min_files_per_stream", GlobalRecordingConfig.MinimumFilesPerStream).
-		Int("min_total_files", GlobalRecordingConfig.MinimumTotalFiles).
-		Int("stream_count", len(GlobalRecordingConfig.Streams)).
+		Strs("base_path", GetRecordingConfig().BasePaths).
+		Str("default_format", GetRecordingConfig().DefaultFormat).
+		Str("default_video", GetRecordingConfig().DefaultVideo).
+		Str("default_audio", GetRecordingConfig().DefaultAudio).
+		Bool("enable_segments", GetRecordingConfig().EnableSegments).
+		Dur("segment_duration", GetRecordingConfig().SegmentDuration).
+		Int64("max_file_size_mb", GetRecordingConfig().MaxFileSize).
+		Int("retention_days", GetRecordingConfig().RetentionDays).
+		Bool("auto_start", GetRecordingConfig().AutoStart).
+		Dur("auto_record_check_interval", GetRecordingConfig().AutoRecordCheckInterval).
+		Bool("enable_cleanup", GetRecordingConfig().EnableCleanup).
+		Dur("cleanup_interval", GetRecordingConfig().CleanupInterval).
+		Bool("enable_health_check", GetRecordingConfig().EnableHealthCheck).
+		Dur("health_check_interval", GetRecordingConfig().HealthCheckInterval).
+		Bool("watchdog_enabled", GetRecordingConfig().WatchdogEnabled).
+		Dur("watchdog_interval", GetRecordingConfig().WatchdogInterval).
+		Int("stall_threshold", GetRecordingConfig().StallThreshold).
+		Int("min_files_per_stream", GetRecordingConfig().MinimumFilesPerStream).
+		Int("min_total_files", GetRecordingConfig().MinimumTotalFiles).
+		Int("stream_count", len(GetRecordingConfig().Streams)).
 		Msg("[recording] config loaded")
 		
 	// Log per-stream configurations
-	for streamName, streamConfig := range GlobalRecordingConfig.Streams {
+	for streamName, streamConfig := range GetRecordingConfig().Streams {
 		log.Info().
 			Str("stream", streamName).
 			Interface("enabled", streamConfig.Enabled).
@@ -247,13 +528,107 @@ func LoadRecordingConfig() {
 	}
 }
 
-func validateRecordingConfig() {
-	cfg := GlobalRecordingConfig
+func validateRecordingConfig(cfg *RecordingConfig) {
+	// The first pool is the primary base path
+	var pools StoragePaths
+	for _, path := range cfg.BasePaths {
+		if path != "" {
+			pools = append(pools, filepath.Clean(path))
+		}
+	}
+	if len(pools) == 0 {
+		pools = StoragePaths{"recordings"}
+	}
+	cfg.BasePaths, cfg.BasePath = pools, pools[0]
+
+	if cfg.ColdPath != "" {
+		cfg.ColdPath = filepath.Clean(cfg.ColdPath)
+		if slices.Contains(cfg.BasePaths, cfg.ColdPath) {
+			log.Warn().Str("cold_path", cfg.ColdPath).Msg("[recording] cold_path is also a base_path, tiering disabled")
+			cfg.ColdPath = ""
+		}
+	}
+
+	if !validRTSPTransport(cfg.RTSPTransport) {
+		log.Warn().Str("rtsp_transport", cfg.RTSPTransport).Msg("[recording] unknown rtsp_transport, using tcp")
+		cfg.RTSPTransport = "tcp"
+	}
+	for name, profile := range cfg.ExportProfiles {
+		if _, err := profile.transcodeOptions(); err != nil {
+			log.Warn().Err(err).Str("profile", name).Msg("[recording] invalid export profile, ignored")
+			delete(cfg.ExportProfiles, name)
+		}
+	}
+	if cfg.QuotaMode != "" && cfg.QuotaMode != QuotaCleanup && cfg.QuotaMode != QuotaEnforce {
+		log.Warn().Str("quota_mode", cfg.QuotaMode).Msg("[recording] unknown quota_mode, using cleanup")
+		cfg.QuotaMode = QuotaCleanup
+	}
+	for name, streamConfig := range cfg.Streams {
+		if !validRTSPTransport(streamConfig.RTSPTransport) {
+			log.Warn().Str("stream", name).Str("rtsp_transport", streamConfig.RTSPTransport).Msg("[recording] unknown rtsp_transport, using the global one")
+			streamConfig.RTSPTransport = ""
+			cfg.Streams[name] = streamConfig
+		}
+	}
+
+	// Stream base paths are separate roots, e.g. on their own disks
+	for name, streamConfig := range cfg.Streams {
+		if streamConfig.BasePath == "" {
+			continue
+		}
+		streamConfig.BasePath = filepath.Clean(streamConfig.BasePath)
+		cfg.Streams[name] = streamConfig
+
+		for _, pool := range cfg.BasePaths {
+			if pool != streamConfig.BasePath && pathWithin(pool, streamConfig.BasePath) {
+				log.Warn().Str("stream", name).Str("base_path", streamConfig.BasePath).Str("pool", pool).
+					Msg("[recording] stream base_path contains a storage pool, recordings in the pool are scanned twice")
+			}
+		}
+	}
+
+	validateImportPaths(cfg)
+	cfg.FilenamePatterns = compileFilenamePatterns(cfg.FilenamePatterns, "filename_patterns")
 
-	// Ensure base path exists
+	switch cfg.StoragePolicy {
+	case PoolFillFirst, PoolRoundRobin:
+	default:
+		log.Warn().Str("storage_policy", cfg.StoragePolicy).Msg("[recording] unknown storage_policy, using fill_first")
+		cfg.StoragePolicy = PoolFillFirst
+	}
+
+	// Ensure base paths exist
 	if cfg.CreateDirectories {
-		if err := os.MkdirAll(cfg.BasePath, 0755); err != nil {
-			log.Error().Err(err).Str("path", cfg.BasePath).Msg("[recording] failed to create base directory")
+		for _, path := range storagePoolsOf(cfg) {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				log.Error().Err(err).Str("path", path).Msg("[recording] failed to create base directory")
+			}
+		}
+	}
+
+	for tag := range cfg.TagRetention {
+		if !tagRegexp.MatchString(tag) {
+			log.Warn().Str("tag", tag).Msg("[recording] tag_retention has a tag that can't be set, use lowercase letters, digits and _ : . -")
+		}
+	}
+
+	switch cfg.PreviewFormat {
+	case "", PreviewGIF, PreviewWebP, PreviewSprite:
+	default:
+		log.Warn().Str("preview_format", cfg.PreviewFormat).Msg("[recording] unknown preview_format, previews disabled")
+		cfg.PreviewFormat = ""
+	}
+	if cfg.PreviewFrames < 1 {
+		cfg.PreviewFrames = 10
+	}
+	if cfg.MaxJobs < 1 {
+		log.Warn().Int("max_jobs", cfg.MaxJobs).Msg("[recording] max_jobs must be at least 1, using 1")
+		cfg.MaxJobs = 1
+	}
+
+	if cfg.EncryptionKey != "" {
+		if _, err := parseEncryptionKey(cfg.EncryptionKey); err != nil {
+			log.Warn().Err(err).Msg("[encryption] invalid encryption_key, recordings are not encrypted")
 		}
 	}
 
@@ -282,60 +657,50 @@ func validateRecordingConfig() {
 		cfg.FilenameTemplate = "{stream}_{timestamp}"
 	}
 
-	// Create archive directory if needed
-	if cfg.MoveToArchive && cfg.ArchivePath != "" && cfg.CreateDirectories {
-		if err := os.MkdirAll(cfg.ArchivePath, 0755); err != nil {
-			log.Error().Err(err).Str("path", cfg.ArchivePath).Msg("[recording] failed to create archive directory")
+	for key := range cfg.Streams {
+		if group, ok := strings.CutPrefix(key, streamGroupPrefix); ok {
+			if _, ok = cfg.StreamGroups[group]; !ok {
+				log.Warn().Str("key", key).Msg("[recording] stream config for unknown group in stream_groups")
+			}
+		} else if _, err := path.Match(key, ""); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("[recording] invalid stream pattern, it matches nothing")
 		}
 	}
-}
 
-// GenerateRecordingPath creates the full path for a recording file
-func GenerateRecordingPath(streamName string, startTime time.Time, format string, segmentNum int) string {
-	cfg := GlobalRecordingConfig
-
-	// Process path template
-	pathTemplate := cfg.PathTemplate
-	pathTemplate = strings.ReplaceAll(pathTemplate, "{stream}", streamName)
-	pathTemplate = strings.ReplaceAll(pathTemplate, "{year}", startTime.Format("2006"))
-	pathTemplate = strings.ReplaceAll(pathTemplate, "{month}", startTime.Format("01"))
-	pathTemplate = strings.ReplaceAll(pathTemplate, "{day}", startTime.Format("02"))
-	pathTemplate = strings.ReplaceAll(pathTemplate, "{hour}", startTime.Format("15"))
-
-	// Process filename template
-	filenameTemplate := cfg.FilenameTemplate
-	filenameTemplate = strings.ReplaceAll(filenameTemplate, "{stream}", streamName)
-	filenameTemplate = strings.ReplaceAll(filenameTemplate, "{timestamp}", startTime.Format("2006-01-02_15-04-05"))
-	filenameTemplate = strings.ReplaceAll(filenameTemplate, "{date}", startTime.Format("2006-01-02"))
-	filenameTemplate = strings.ReplaceAll(filenameTemplate, "{time}", startTime.Format("15-04-05"))
-
-	// Note: No longer adding segment numbers to filenames for cleaner names
-
-	// Add format extension
-	if format == "" {
-		format = cfg.DefaultFormat
-	}
-	if !strings.HasPrefix(format, ".") {
-		format = "." + format
+	switch strings.ToLower(cfg.PathTimezone) {
+	case "", "local", "utc":
+	default:
+		if _, err := time.LoadLocation(cfg.PathTimezone); err != nil {
+			log.Warn().Err(err).Str("path_timezone", cfg.PathTimezone).Msg("[recording] unknown path_timezone, using local time")
+			cfg.PathTimezone = ""
+		}
 	}
 
-	filename := filenameTemplate + format
-	fullPath := filepath.Join(cfg.BasePath, pathTemplate, filename)
+	if cfg.CleanupWindow != "" {
+		if _, err := parseCleanupWindow(cfg.CleanupWindow); err != nil {
+			log.Warn().Err(err).Str("cleanup_window", cfg.CleanupWindow).Msg("[recording] invalid cleanup_window, cleanup runs at any time")
+			cfg.CleanupWindow = ""
+		}
+	}
 
-	// Create directory if needed
-	if cfg.CreateDirectories {
-		dir := filepath.Dir(fullPath)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			log.Error().Err(err).Str("dir", dir).Msg("[recording] failed to create recording directory")
+	if cfg.ReportTime != "" {
+		if _, err := parseClock(cfg.ReportTime); err != nil {
+			log.Warn().Err(err).Str("report_time", cfg.ReportTime).Msg("[recording] invalid report_time, daily reports disabled")
+			cfg.ReportTime = ""
 		}
 	}
 
-	return fullPath
+	// Create archive directory if needed
+	if cfg.MoveToArchive && cfg.ArchivePath != "" && cfg.CreateDirectories {
+		if err := os.MkdirAll(cfg.ArchivePath, 0755); err != nil {
+			log.Error().Err(err).Str("path", cfg.ArchivePath).Msg("[recording] failed to create archive directory")
+		}
+	}
 }
 
 // GetRetentionDuration returns the retention duration based on config
 func GetRetentionDuration() time.Duration {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	if cfg.RetentionHours > 0 {
 		return time.Duration(cfg.RetentionHours) * time.Hour
 	}
@@ -345,14 +710,35 @@ func GetRetentionDuration() time.Duration {
 	return 7 * 24 * time.Hour // Default to 7 days
 }
 
+// RetentionDuration returns how long recordings of the stream are kept,
+// retention_hours taking precedence over retention_days
+func (c StreamRecordingConfig) RetentionDuration() time.Duration {
+	if c.RetentionHours > 0 {
+		return time.Duration(c.RetentionHours) * time.Hour
+	}
+	if c.RetentionDays > 0 {
+		return time.Duration(c.RetentionDays) * 24 * time.Hour
+	}
+	return GetRetentionDuration()
+}
+
+// EventRetentionDuration returns how long event recordings of the stream are
+// kept, the continuous retention unless event_retention_days is set
+func (c StreamRecordingConfig) EventRetentionDuration() time.Duration {
+	if c.EventRetentionDays > 0 {
+		return time.Duration(c.EventRetentionDays) * 24 * time.Hour
+	}
+	return c.RetentionDuration()
+}
+
 // ShouldAutoStart returns true if recording should auto-start for the stream
 func ShouldAutoStart() bool {
-	return GlobalRecordingConfig.AutoStart
+	return GetRecordingConfig().AutoStart
 }
 
 // GetDefaultCodecs returns the default video and audio codecs
 func GetDefaultCodecs() (video, audio string) {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	video = cfg.DefaultVideo
 	audio = cfg.DefaultAudio
 	if video == "" {
@@ -366,10 +752,10 @@ func GetDefaultCodecs() (video, audio string) {
 
 // IsStreamRecordingEnabled checks if recording is enabled for a specific stream
 func IsStreamRecordingEnabled(streamName string) bool {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	
 	// Check if stream is explicitly configured for recording
-	if streamConfig, exists := cfg.Streams[streamName]; exists {
+	if streamConfig, exists := lookupStreamConfig(cfg, streamName); exists {
 		// If explicitly set for this stream, use that setting
 		if streamConfig.Enabled != nil {
 			log.Debug().
@@ -408,21 +794,38 @@ func IsStreamRecordingEnabled(streamName string) bool {
 
 // GetStreamRecordingConfig returns the effective configuration for a stream
 func GetStreamRecordingConfig(streamName string) StreamRecordingConfig {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	
 	// Start with defaults based on global config
 	streamConfig := StreamRecordingConfig{
 		Format:          cfg.DefaultFormat,
+		Recorder:        cfg.Recorder,
+		InputMode:       cfg.InputMode,
+		Segmenter:       cfg.Segmenter,
 		Video:           cfg.DefaultVideo,
 		Audio:           cfg.DefaultAudio,
 		BitrateLimit:    cfg.BitrateLimit,
+		RTSPTransport:   cfg.RTSPTransport,
+		InputTimeout:    cfg.InputTimeout,
+		AnalyzeDuration: cfg.AnalyzeDuration,
+		ProbeSize:       cfg.ProbeSize,
 		SegmentDuration: cfg.SegmentDuration,
 		MaxFileSize:     cfg.MaxFileSize,
 		RetentionDays:   cfg.RetentionDays,
 		RetentionHours:  cfg.RetentionHours,
+		EventRetentionDays: cfg.EventRetentionDays,
 		MaxRecordings:   cfg.MaxRecordings,
+		MaxTotalDuration: cfg.MaxTotalDuration,
+		ThinAfterDays:   cfg.ThinAfterDays,
+		ThinHourlyDays:  cfg.ThinHourlyDays,
 		PathTemplate:    cfg.PathTemplate,
 		FilenameTemplate: cfg.FilenameTemplate,
+		EventPreTime:    cfg.BufferTime,
+		EventPostTime:   cfg.EventPostTime,
+		OnSegmentComplete:   cfg.OnSegmentComplete,
+		OnRecordingComplete: cfg.OnRecordingComplete,
+		SnapshotInterval:      cfg.SnapshotInterval,
+		SnapshotRetentionDays: cfg.SnapshotRetentionDays,
 		// Source will be resolved after stream-specific overrides
 	}
 	
@@ -430,14 +833,22 @@ func GetStreamRecordingConfig(streamName string) StreamRecordingConfig {
 	enabled := cfg.AutoStart
 	enableSegments := cfg.EnableSegments
 	restartOnError := cfg.RestartOnError
+	faststart := cfg.Faststart
+	keyframeAlign := cfg.KeyframeAlign
+	verifySegments := cfg.VerifySegments
+	reconnect := cfg.Reconnect
 	
 	streamConfig.Enabled = &enabled
 	streamConfig.EnableSegments = &enableSegments
+	streamConfig.Faststart = &faststart
+	streamConfig.KeyframeAlign = &keyframeAlign
+	streamConfig.VerifySegments = &verifySegments
+	streamConfig.Reconnect = &reconnect
 	streamConfig.AutoStart = &enabled
 	streamConfig.RestartOnError = &restartOnError
 	
 	// Override with stream-specific settings if they exist
-	if specificConfig, exists := cfg.Streams[streamName]; exists {
+	if specificConfig, exists := lookupStreamConfig(cfg, streamName); exists {
 		if specificConfig.Enabled != nil {
 			streamConfig.Enabled = specificConfig.Enabled
 		}
@@ -447,6 +858,15 @@ func GetStreamRecordingConfig(streamName string) StreamRecordingConfig {
 		if specificConfig.Format != "" {
 			streamConfig.Format = specificConfig.Format
 		}
+		if specificConfig.Recorder != "" {
+			streamConfig.Recorder = specificConfig.Recorder
+		}
+		if specificConfig.InputMode != "" {
+			streamConfig.InputMode = specificConfig.InputMode
+		}
+		if specificConfig.Restream != "" {
+			streamConfig.Restream = specificConfig.Restream
+		}
 		if specificConfig.Video != "" {
 			streamConfig.Video = specificConfig.Video
 		}
@@ -456,6 +876,40 @@ func GetStreamRecordingConfig(streamName string) StreamRecordingConfig {
 		if specificConfig.BitrateLimit != "" {
 			streamConfig.BitrateLimit = specificConfig.BitrateLimit
 		}
+		if specificConfig.RTSPTransport != "" {
+			streamConfig.RTSPTransport = specificConfig.RTSPTransport
+		}
+		if specificConfig.InputTimeout > 0 {
+			streamConfig.InputTimeout = specificConfig.InputTimeout
+		}
+		if specificConfig.Reconnect != nil {
+			streamConfig.Reconnect = specificConfig.Reconnect
+		}
+		if specificConfig.AnalyzeDuration > 0 {
+			streamConfig.AnalyzeDuration = specificConfig.AnalyzeDuration
+		}
+		if specificConfig.ProbeSize > 0 {
+			streamConfig.ProbeSize = specificConfig.ProbeSize
+		}
+		if specificConfig.OnSegmentComplete != "" {
+			streamConfig.OnSegmentComplete = specificConfig.OnSegmentComplete
+		}
+		if specificConfig.OnRecordingComplete != "" {
+			streamConfig.OnRecordingComplete = specificConfig.OnRecordingComplete
+		}
+		if specificConfig.SnapshotInterval > 0 {
+			streamConfig.SnapshotInterval = specificConfig.SnapshotInterval
+		}
+		if specificConfig.SnapshotRetentionDays > 0 {
+			streamConfig.SnapshotRetentionDays = specificConfig.SnapshotRetentionDays
+		}
+		if specificConfig.FFmpegTemplate != "" {
+			streamConfig.FFmpegTemplate = specificConfig.FFmpegTemplate
+		}
+		if specificConfig.HWAccel != "" {
+			streamConfig.HWAccel = specificConfig.HWAccel
+			streamConfig.HWAccelDevice = specificConfig.HWAccelDevice
+		}
 		if specificConfig.SegmentDuration > 0 {
 			streamConfig.SegmentDuration = specificConfig.SegmentDuration
 		}
@@ -465,21 +919,48 @@ func GetStreamRecordingConfig(streamName string) StreamRecordingConfig {
 		if specificConfig.EnableSegments != nil {
 			streamConfig.EnableSegments = specificConfig.EnableSegments
 		}
-		if specificConfig.RetentionDays > 0 {
-			streamConfig.RetentionDays = specificConfig.RetentionDays
+		if specificConfig.Segmenter != "" {
+			streamConfig.Segmenter = specificConfig.Segmenter
+		}
+		if specificConfig.Faststart != nil {
+			streamConfig.Faststart = specificConfig.Faststart
+		}
+		if specificConfig.KeyframeAlign != nil {
+			streamConfig.KeyframeAlign = specificConfig.KeyframeAlign
+		}
+		if specificConfig.VerifySegments != nil {
+			streamConfig.VerifySegments = specificConfig.VerifySegments
 		}
-		if specificConfig.RetentionHours > 0 {
+		// Per-stream retention replaces the global one, whichever unit either uses
+		if specificConfig.RetentionDays > 0 || specificConfig.RetentionHours > 0 {
+			streamConfig.RetentionDays = specificConfig.RetentionDays
 			streamConfig.RetentionHours = specificConfig.RetentionHours
 		}
 		if specificConfig.MaxRecordings > 0 {
 			streamConfig.MaxRecordings = specificConfig.MaxRecordings
 		}
+		if specificConfig.EventRetentionDays > 0 {
+			streamConfig.EventRetentionDays = specificConfig.EventRetentionDays
+		}
+		if specificConfig.ThinAfterDays > 0 || specificConfig.ThinHourlyDays > 0 {
+			streamConfig.ThinAfterDays = specificConfig.ThinAfterDays
+			streamConfig.ThinHourlyDays = specificConfig.ThinHourlyDays
+		}
+		if specificConfig.MaxTotalSize > 0 {
+			streamConfig.MaxTotalSize = specificConfig.MaxTotalSize
+		}
+		if specificConfig.MaxTotalDuration > 0 {
+			streamConfig.MaxTotalDuration = specificConfig.MaxTotalDuration
+		}
 		if specificConfig.AutoStart != nil {
 			streamConfig.AutoStart = specificConfig.AutoStart
 		}
 		if specificConfig.RestartOnError != nil {
 			streamConfig.RestartOnError = specificConfig.RestartOnError
 		}
+		if specificConfig.Priority != 0 {
+			streamConfig.Priority = specificConfig.Priority
+		}
 		if specificConfig.PathTemplate != "" {
 			streamConfig.PathTemplate = specificConfig.PathTemplate
 		}
@@ -499,6 +980,14 @@ func GetStreamRecordingConfig(streamName string) StreamRecordingConfig {
 			streamConfig.Schedule = specificConfig.Schedule
 		}
 		streamConfig.RecordOnMotion = specificConfig.RecordOnMotion
+		streamConfig.MotionTopic = specificConfig.MotionTopic
+		streamConfig.MotionONVIF = specificConfig.MotionONVIF
+		if specificConfig.EventPreTime > 0 {
+			streamConfig.EventPreTime = specificConfig.EventPreTime
+		}
+		if specificConfig.EventPostTime > 0 {
+			streamConfig.EventPostTime = specificConfig.EventPostTime
+		}
 	}
 	
 	// Resolve direct source after all overrides (this ensures stream-specific sources take priority)
@@ -519,11 +1008,11 @@ func GetStreamRecordingConfig(streamName string) StreamRecordingConfig {
 
 // GetStreamsToAutoRecord returns a list of streams that should be auto-recorded
 func GetStreamsToAutoRecord() []string {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	var streamsToRecord []string
 	
 	// Check each configured stream
-	for streamName, streamConfig := range cfg.Streams {
+	for streamName, streamConfig := range configuredStreams(cfg) {
 		if streamConfig.Enabled != nil && *streamConfig.Enabled {
 			streamsToRecord = append(streamsToRecord, streamName)
 		} else if streamConfig.AutoStart != nil && *streamConfig.AutoStart {
@@ -544,10 +1033,10 @@ func ShouldAutoStartRecording(streamName string) bool {
 
 // ResolveDirectSource resolves the direct source URL for a stream
 func ResolveDirectSource(streamName string) string {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	
 	// Check if there's a stream-specific direct source
-	if streamConfig, exists := cfg.Streams[streamName]; exists && streamConfig.Source != "" {
+	if streamConfig, exists := lookupStreamConfig(cfg, streamName); exists && streamConfig.Source != "" {
 		log.Debug().
 			Str("stream", streamName).
 			Str("source", streamConfig.Source).
@@ -3,17 +3,59 @@ package ffmpeg
 import "github.com/AlexxIT/go2rtc/internal/detection"
 
 // onSegmentComplete is called when a recording segment file is finalised.
-// It queues the file for post-recording object detection analysis.
+// MP4s of streams with faststart are remuxed first.
 func onSegmentComplete(streamName, filePath string) {
-	detection.QueueFile(streamName, filePath)
+	if faststartJobs != nil && faststartJobs.enqueue(streamName, filePath) {
+		return // finished by the queue
+	}
+	finishSegment(streamName, filePath)
+}
+
+// finishSegment verifies the file with verify_segments, encrypts it if
+// encryption_key is set, registers it in the index, queues its preview,
+// announces it to subscribers, runs the on_segment_complete hook and queues
+// it for post-recording object detection analysis.
+func finishSegment(streamName, filePath string) {
+	// Probed before it is encrypted
+	var health *RecordingHealth
+	if verifySegments(streamName) {
+		health = verifySegment(streamName, filePath)
+	}
+
+	encrypted := false
+	if encryptionEnabled() {
+		if err := encryptRecordingFile(filePath); err != nil {
+			log.Error().Err(err).Str("file", filePath).Msg("[encryption] failed to encrypt recording, it stays unencrypted")
+		} else {
+			encrypted = true
+		}
+	}
+
+	recordingIndex.Update(filePath)
+	if health != nil {
+		recordingIndex.setHealth(filePath, health)
+		if health.Status == HealthCorrupt {
+			onCorruptSegment(streamName, filePath, health)
+		} else {
+			onVerifiedSegment(streamName)
+		}
+	}
+	queuePreview(filePath)
+	if recording := recordingIndex.GetByPath(filePath); recording != nil {
+		notify(NotifySegmentComplete, streamName, recording)
+		runSegmentHook(streamName, recording)
+	}
+	// Detection reads the file itself
+	if !encrypted {
+		detection.QueueFile(streamName, filePath)
+	}
 }
 
 // InitDetection wires the detection package's callbacks so it can read
 // per-stream config and the recording base path without circular imports.
 func InitDetection() {
-	detection.SetSidecarBasePath(func() string {
-		return GlobalRecordingConfig.BasePath
-	})
+	detection.SetSidecarBasePaths(storagePools)
+	detection.SetSidecarListener(recordingIndex.UpdateSidecar)
 
 	detection.SetStreamConfigReader(func(streamName string) detection.StreamDetectionOverride {
 		sc := GetStreamRecordingConfig(streamName)
@@ -0,0 +1,191 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DiskStatus describes free space on the recordings volume
+type DiskStatus struct {
+	Path        string    `json:"path"`
+	FreeBytes   uint64    `json:"free_bytes"`
+	TotalBytes  uint64    `json:"total_bytes"`
+	FreePercent float64   `json:"free_percent"`
+	Paused      bool      `json:"paused"` // new recordings refused below the low watermark
+	CheckedAt   time.Time `json:"checked_at"`
+	Error       string    `json:"error,omitempty"`
+
+	Pools []DiskStatus `json:"pools,omitempty"` // per pool with several base_path pools
+}
+
+// DiskMonitor watches free space on the recordings volume. Below the high
+// watermark the oldest recordings are removed, below the low watermark new
+// recordings are refused until space is available again.
+type DiskMonitor struct {
+	status DiskStatus
+	mu     sync.RWMutex
+}
+
+var diskMonitor = &DiskMonitor{}
+
+// errDiskPaused is returned for new recordings below the low watermark
+var errDiskPaused = errors.New("recording paused")
+
+// GetDiskMonitor returns the global disk monitor
+func GetDiskMonitor() *DiskMonitor {
+	return diskMonitor
+}
+
+// diskMonitorRoutine periodically checks free space on the recordings volume
+func diskMonitorRoutine() {
+	interval := GetRecordingConfig().DiskCheckInterval
+	if interval <= 0 {
+		interval = time.Second * 30
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		diskMonitor.check()
+		<-ticker.C
+	}
+}
+
+// Status returns the result of the last check
+func (m *DiskMonitor) Status() DiskStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// allowRecording returns an error while new recordings are paused
+func (m *DiskMonitor) allowRecording() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.status.Paused {
+		return fmt.Errorf("%w: only %.1f%% disk space free (low watermark %.1f%%)",
+			errDiskPaused, m.status.FreePercent, GetRecordingConfig().DiskLowWatermark)
+	}
+	return nil
+}
+
+func (m *DiskMonitor) check() {
+	cfg := GetRecordingConfig()
+
+	pools := storagePools()
+
+	var status DiskStatus
+	if len(pools) == 1 {
+		status = checkPool(pools[0], len(pools))
+	} else {
+		// With several pools new recordings only stop once every pool is full
+		status = DiskStatus{Path: cfg.BasePath, CheckedAt: time.Now(), Paused: true}
+		for _, pool := range pools {
+			poolStatus := checkPool(pool, len(pools))
+			status.FreeBytes += poolStatus.FreeBytes
+			status.TotalBytes += poolStatus.TotalBytes
+			if pool != cfg.ColdPath {
+				// Nothing is recorded to the cold tier
+				status.Paused = status.Paused && poolStatus.Paused
+			}
+			status.Pools = append(status.Pools, poolStatus)
+		}
+		if status.TotalBytes > 0 {
+			status.FreePercent = float64(status.FreeBytes) / float64(status.TotalBytes) * 100
+		}
+	}
+
+	m.mu.Lock()
+	wasPaused := m.status.Paused
+	m.status = status
+	m.mu.Unlock()
+
+	switch {
+	case status.Paused && !wasPaused:
+		log.Error().
+			Float64("free_percent", status.FreePercent).
+			Float64("low_watermark", cfg.DiskLowWatermark).
+			Msg("[disk] disk almost full, new recordings paused")
+		notify(NotifyDiskWarning, "", status)
+	case !status.Paused && wasPaused:
+		log.Info().
+			Float64("free_percent", status.FreePercent).
+			Msg("[disk] disk space recovered, recordings allowed again")
+		notify(NotifyDiskRecovered, "", status)
+	}
+}
+
+// checkPool reads free space of one storage pool and frees space on it below
+// the high watermark
+func checkPool(pool string, pools int) DiskStatus {
+	cfg := GetRecordingConfig()
+	status := DiskStatus{Path: pool, CheckedAt: time.Now()}
+
+	free, total, err := diskUsage(pool)
+	if err != nil || total == 0 {
+		if err != nil {
+			status.Error = err.Error()
+		}
+		log.Debug().Err(err).Str("path", pool).Msg("[disk] failed to read disk usage")
+		return status
+	}
+
+	status.FreeBytes, status.TotalBytes = free, total
+	status.FreePercent = float64(free) / float64(total) * 100
+
+	// Free space first, so recordings may not need to be paused at all
+	if cfg.DiskHighWatermark > 0 && status.FreePercent < cfg.DiskHighWatermark {
+		target := uint64(cfg.DiskHighWatermark / 100 * float64(total))
+		if pools == 1 {
+			pool = "" // no need to match every file against the pools
+		}
+		emergencyCleanup(pool, target-free)
+
+		if free, total, err = diskUsage(status.Path); err == nil && total > 0 {
+			status.FreeBytes, status.TotalBytes = free, total
+			status.FreePercent = float64(free) / float64(total) * 100
+		}
+	}
+
+	status.Paused = cfg.DiskLowWatermark > 0 && status.FreePercent < cfg.DiskLowWatermark
+
+	return status
+}
+
+// emergencyCleanup deletes the oldest recordings of the pool (all pools if
+// empty) until at least need bytes are freed. Retention rules are ignored,
+// protected files and files still being written are kept.
+func emergencyCleanup(pool string, need uint64) {
+	recordings := recordingIndex.Query("", "", 0) // newest first
+
+	var candidates []RecordingFile
+	var selected uint64
+	for i := len(recordings) - 1; i >= 0 && selected < need; i-- {
+		if recordings[i].Protected || recordings[i].Imported || retainedForever(recordings[i].Tags) {
+			continue
+		}
+		if pool != "" {
+			if recordingPool, _, ok := storagePoolRel(recordings[i].Path); !ok || recordingPool != pool {
+				continue
+			}
+		}
+		candidates = append(candidates, recordings[i])
+		selected += uint64(recordings[i].Size)
+	}
+	if len(candidates) == 0 {
+		log.Error().Str("pool", pool).Uint64("need_bytes", need).Msg("[disk] disk almost full and no recordings left to delete")
+		return
+	}
+
+	result := deleteRecordings(candidates, false)
+
+	log.Warn().
+		Int("deleted", len(result.Deleted)).
+		Int64("freed_bytes", result.FreedBytes).
+		Uint64("need_bytes", need).
+		Msg("[disk] emergency cleanup, disk below high watermark")
+}
@@ -0,0 +1,413 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Encrypted recordings keep their name and extension. The file is a header
+// (magic and a random nonce prefix) followed by the content in AES-256-GCM
+// sealed chunks, so it can be decrypted from any offset. Each chunk's nonce
+// is the prefix and the chunk number, its additional data the chunk number
+// and whether it's the last one, so chunks can't be reordered or cut off.
+const (
+	encryptionMagic      = "G2RENC1\x00"
+	encryptionPrefixSize = 8
+	encryptionHeaderSize = len(encryptionMagic) + encryptionPrefixSize
+	encryptionChunkSize  = 64 * 1024
+	encryptionTagSize    = 16
+)
+
+// encryptionKeyEnv is read when encryption_key isn't set
+const encryptionKeyEnv = "RECORDING_ENCRYPTION_KEY"
+
+// recordingEncryptionKey returns the 32 byte key from encryption_key or the
+// environment, base64 or hex encoded; nil if encryption is disabled
+func recordingEncryptionKey() ([]byte, error) {
+	value := GetRecordingConfig().EncryptionKey
+	if value == "" {
+		value = os.Getenv(encryptionKeyEnv)
+	}
+	if value == "" {
+		return nil, nil
+	}
+	return parseEncryptionKey(value)
+}
+
+func parseEncryptionKey(value string) ([]byte, error) {
+	if key, err := hex.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, errors.New("encryption key must be 32 bytes, base64 or hex encoded")
+}
+
+func encryptionEnabled() bool {
+	key, err := recordingEncryptionKey()
+	return err == nil && key != nil
+}
+
+func newRecordingAEAD() (cipher.AEAD, error) {
+	key, err := recordingEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errors.New("no encryption key configured")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, index uint64) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptionPrefixSize:], uint32(index))
+	return nonce
+}
+
+func chunkAdditionalData(index uint64, last bool) []byte {
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint64(data, index)
+	if last {
+		data[8] = 1
+	}
+	return data
+}
+
+// isEncryptedRecording reports whether the file starts with the header of
+// an encrypted recording
+func isEncryptedRecording(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(encryptionMagic))
+	if _, err = io.ReadFull(f, magic); err != nil {
+		return false
+	}
+	return string(magic) == encryptionMagic
+}
+
+// encryptRecordingFile replaces a finished recording with its encrypted
+// form, keeping the modification time recording times are derived from
+func encryptRecordingFile(path string) error {
+	if isEncryptedRecording(path) {
+		return nil
+	}
+
+	aead, err := newRecordingAEAD()
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".encrypting"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err = encryptStream(aead, bufio.NewReaderSize(src, encryptionChunkSize), dst); err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	_ = os.Chtimes(path, time.Now(), stat.ModTime())
+	return nil
+}
+
+func encryptStream(aead cipher.AEAD, src *bufio.Reader, dst io.Writer) error {
+	prefix := make([]byte, encryptionPrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	if _, err := dst.Write(append([]byte(encryptionMagic), prefix...)); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, encryptionChunkSize)
+	sealed := make([]byte, 0, encryptionChunkSize+encryptionTagSize)
+
+	for index := uint64(0); ; index++ {
+		n, err := io.ReadFull(src, chunk)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+
+		// The last chunk may be full or even empty
+		last := err != nil
+		if !last {
+			if _, peekErr := src.Peek(1); peekErr == io.EOF {
+				last = true
+			}
+		}
+
+		sealed = aead.Seal(sealed[:0], chunkNonce(prefix, index), chunk[:n], chunkAdditionalData(index, last))
+		if _, err = dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// decryptingReader reads an encrypted recording as its plain content
+type decryptingReader struct {
+	file   *os.File
+	aead   cipher.AEAD
+	prefix []byte
+	size   int64 // plain size
+	chunks int64
+	pos    int64
+	chunk  []byte
+	loaded int64 // index of the chunk in chunk, -1 if none
+}
+
+// openDecrypted opens an encrypted recording for reading and seeking
+func openDecrypted(path string) (*decryptingReader, error) {
+	aead, err := newRecordingAEAD()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, encryptionHeaderSize)
+	stat, err := file.Stat()
+	if err == nil {
+		_, err = io.ReadFull(file, header)
+	}
+	if err == nil && string(header[:len(encryptionMagic)]) != encryptionMagic {
+		err = errors.New("not an encrypted recording")
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	sealedSize := int64(encryptionChunkSize + encryptionTagSize)
+	body := stat.Size() - int64(encryptionHeaderSize)
+	chunks := (body + sealedSize - 1) / sealedSize
+	// Only an empty recording has an empty last chunk, anything shorter
+	// than a chunk's tag was cut off
+	rem := body % sealedSize
+	if chunks == 0 || rem > 0 && rem < encryptionTagSize || rem == encryptionTagSize && chunks > 1 {
+		_ = file.Close()
+		return nil, errors.New("encrypted recording is truncated")
+	}
+
+	reader := &decryptingReader{
+		file:   file,
+		aead:   aead,
+		prefix: header[len(encryptionMagic):],
+		size:   body - chunks*encryptionTagSize,
+		chunks: chunks,
+		loaded: -1,
+	}
+
+	// Reads of an empty recording never authenticate a chunk
+	if reader.size == 0 {
+		if err = reader.load(0); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+
+	return reader, nil
+}
+
+func (r *decryptingReader) Size() int64 {
+	return r.size
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	index := r.pos / encryptionChunkSize
+	if index != r.loaded {
+		if err := r.load(index); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.chunk[r.pos-index*encryptionChunkSize:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+func (r *decryptingReader) load(index int64) error {
+	sealedSize := int64(encryptionChunkSize + encryptionTagSize)
+
+	sealed := make([]byte, sealedSize)
+	n, err := r.file.ReadAt(sealed, int64(encryptionHeaderSize)+index*sealedSize)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	last := index == r.chunks-1
+	r.chunk, err = r.aead.Open(r.chunk[:0], chunkNonce(r.prefix, uint64(index)), sealed[:n], chunkAdditionalData(uint64(index), last))
+	if err != nil {
+		r.loaded = -1
+		return fmt.Errorf("recording chunk %d: %w", index, err)
+	}
+	r.loaded = index
+	return nil
+}
+
+func (r *decryptingReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+func (r *decryptingReader) Close() error {
+	return r.file.Close()
+}
+
+// openRecording opens a recording for reading its content, decrypting it
+// if it's encrypted
+func openRecording(path string) (io.ReadSeekCloser, error) {
+	if isEncryptedRecording(path) {
+		return openDecrypted(path)
+	}
+	return os.Open(path)
+}
+
+// ffmpeg and ffprobe read encrypted recordings from a loopback HTTP server,
+// so the content is never written to disk decrypted
+var decryptServer struct {
+	once   sync.Once
+	prefix string // http://127.0.0.1:PORT/SECRET
+	err    error
+}
+
+// recordingInput returns what ffmpeg should read a recording from: the file,
+// or a local URL serving its decrypted content
+func recordingInput(path string) string {
+	if !isEncryptedRecording(path) {
+		return path
+	}
+
+	decryptServer.once.Do(startDecryptServer)
+	if decryptServer.err != nil {
+		log.Error().Err(decryptServer.err).Msg("[encryption] decrypt server failed to start")
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return decryptServer.prefix + "?path=" + url.QueryEscape(abs)
+}
+
+func startDecryptServer() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		decryptServer.err = err
+		return
+	}
+
+	secret := make([]byte, 16)
+	_, _ = rand.Read(secret)
+	route := "/" + hex.EncodeToString(secret)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		if !isReadableRecordingPath(path) {
+			http.Error(w, "Access denied", http.StatusForbidden)
+			return
+		}
+
+		reader, err := openDecrypted(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		defer reader.Close()
+
+		var modTime time.Time
+		if stat, err := os.Stat(path); err == nil {
+			modTime = stat.ModTime()
+		}
+		http.ServeContent(w, r, filepath.Base(path), modTime, reader)
+	})
+
+	decryptServer.prefix = "http://" + ln.Addr().String() + route
+
+	go func() {
+		server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		_ = server.Serve(ln)
+	}()
+}
+
+// ffmpegProtocolArgs allows a concat list to name decrypt server URLs
+func ffmpegProtocolArgs(inputs ...string) []string {
+	for _, input := range inputs {
+		if strings.HasPrefix(input, "http://") {
+			return []string{"-protocol_whitelist", "file,http,tcp"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// encryptTestFile writes data to a recording and encrypts it
+func encryptTestFile(t *testing.T, data []byte) string {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{EncryptionKey: hex.EncodeToString(key)})
+
+	path := filepath.Join(t.TempDir(), "cam1_2024-01-01_12-00-00.mp4")
+	require.Nil(t, os.WriteFile(path, data, 0644))
+	require.Nil(t, encryptRecordingFile(path))
+	require.True(t, isEncryptedRecording(path))
+	return path
+}
+
+func readDecrypted(path string) ([]byte, error) {
+	reader, err := openDecrypted(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func TestRecordingEncryption(t *testing.T) {
+	sealedSize := encryptionChunkSize + encryptionTagSize
+
+	for _, size := range []int{0, 1, encryptionChunkSize - 1, encryptionChunkSize, 2 * encryptionChunkSize, 2*encryptionChunkSize + 17} {
+		data := make([]byte, size)
+		_, _ = rand.Read(data)
+		path := encryptTestFile(t, data)
+
+		stat, err := os.Stat(path)
+		require.Nil(t, err)
+		chunks := max((size+encryptionChunkSize-1)/encryptionChunkSize, 1) // an empty recording has one empty chunk
+		require.Equal(t, int64(encryptionHeaderSize+size+chunks*encryptionTagSize), stat.Size(), size)
+
+		plain, err := readDecrypted(path)
+		require.Nil(t, err, size)
+		require.True(t, bytes.Equal(data, plain), size)
+
+		// Encrypting again is a no-op
+		require.Nil(t, encryptRecordingFile(path))
+		plain, err = readDecrypted(path)
+		require.Nil(t, err, size)
+		require.True(t, bytes.Equal(data, plain), size)
+
+		if size == 0 {
+			continue
+		}
+
+		// Cut off after a whole chunk, mid chunk and inside a tag
+		sealed, err := os.ReadFile(path)
+		require.Nil(t, err)
+		for _, cut := range []int{encryptionHeaderSize, encryptionHeaderSize + sealedSize, len(sealed) - 1, len(sealed) - encryptionTagSize} {
+			if cut >= len(sealed) || cut < encryptionHeaderSize {
+				continue
+			}
+			truncated := filepath.Join(t.TempDir(), "truncated.mp4")
+			require.Nil(t, os.WriteFile(truncated, sealed[:cut], 0644))
+			_, err = readDecrypted(truncated)
+			require.Error(t, err, "size %d cut %d", size, cut)
+		}
+	}
+}
+
+func TestRecordingEncryptionSeek(t *testing.T) {
+	data := make([]byte, 3*encryptionChunkSize+100)
+	_, _ = rand.Read(data)
+	path := encryptTestFile(t, data)
+
+	reader, err := openDecrypted(path)
+	require.Nil(t, err)
+	defer reader.Close()
+	require.Equal(t, int64(len(data)), reader.Size())
+
+	for _, offset := range []int{2*encryptionChunkSize + 5, 10, encryptionChunkSize - 3, len(data) - 1, 0} {
+		pos, err := reader.Seek(int64(offset), io.SeekStart)
+		require.Nil(t, err)
+		require.Equal(t, int64(offset), pos)
+
+		// Reads across the chunk boundary
+		buf := make([]byte, 10)
+		n, err := io.ReadFull(reader, buf)
+		if offset+10 > len(data) {
+			require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		} else {
+			require.Nil(t, err)
+		}
+		require.Equal(t, data[offset:offset+n], buf[:n], offset)
+	}
+
+	pos, err := reader.Seek(-4, io.SeekEnd)
+	require.Nil(t, err)
+	require.Equal(t, int64(len(data)-4), pos)
+	rest, err := io.ReadAll(reader)
+	require.Nil(t, err)
+	require.Equal(t, data[len(data)-4:], rest)
+
+	_, err = reader.Seek(-1, io.SeekStart)
+	require.Error(t, err)
+}
+
+func TestRecordingEncryptionReordered(t *testing.T) {
+	data := make([]byte, 3*encryptionChunkSize)
+	_, _ = rand.Read(data)
+	path := encryptTestFile(t, data)
+
+	sealed, err := os.ReadFile(path)
+	require.Nil(t, err)
+
+	// Swap the first two chunks
+	sealedSize := encryptionChunkSize + encryptionTagSize
+	first := encryptionHeaderSize
+	second := first + sealedSize
+	swapped := append([]byte{}, sealed[:first]...)
+	swapped = append(swapped, sealed[second:second+sealedSize]...)
+	swapped = append(swapped, sealed[first:second]...)
+	swapped = append(swapped, sealed[second+sealedSize:]...)
+	require.Nil(t, os.WriteFile(path, swapped, 0644))
+
+	_, err = readDecrypted(path)
+	require.Error(t, err)
+
+	// A chunk before the last moved to the end
+	moved := append([]byte{}, sealed[:first]...)
+	moved = append(moved, sealed[first:second]...)
+	moved = append(moved, sealed[second+sealedSize:]...)
+	moved = append(moved, sealed[second:second+sealedSize]...)
+	require.Nil(t, os.WriteFile(path, moved, 0644))
+
+	reader, err := openDecrypted(path)
+	require.Nil(t, err)
+	defer reader.Close()
+	_, err = reader.Seek(2*encryptionChunkSize, io.SeekStart)
+	require.Nil(t, err)
+	_, err = reader.Read(make([]byte, 1))
+	require.Error(t, err)
+}
+
+func TestRecordingEncryptionEmptyTruncated(t *testing.T) {
+	path := encryptTestFile(t, nil)
+
+	sealed, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.Len(t, sealed, encryptionHeaderSize+encryptionTagSize)
+
+	// Without its empty chunk, or with a damaged one
+	require.Nil(t, os.WriteFile(path, sealed[:encryptionHeaderSize], 0644))
+	_, err = openDecrypted(path)
+	require.Error(t, err)
+
+	sealed[len(sealed)-1] ^= 1
+	require.Nil(t, os.WriteFile(path, sealed, 0644))
+	_, err = openDecrypted(path)
+	require.Error(t, err)
+}
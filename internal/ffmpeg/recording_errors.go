@@ -0,0 +1,99 @@
+package ffmpeg
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// Error codes of recording failures, stable for API clients, backoff and
+// alert rules
+const (
+	ErrorCodeUnreachable      = "camera_unreachable" // the source refused, timed out or has no route
+	ErrorCodeAuthFailed       = "auth_failed"        // the source rejected the credentials
+	ErrorCodeSourceNotFound   = "source_not_found"   // no such stream, path or file at the source
+	ErrorCodeCodecUnsupported = "codec_unsupported"  // a codec ffmpeg can't decode, encode or put in the container
+	ErrorCodeInvalidData      = "invalid_data"       // the source sends data ffmpeg can't parse
+	ErrorCodeDiskFull         = "disk_full"          // no space left, or paused below the low watermark
+	ErrorCodeQuotaExceeded    = "quota_exceeded"     // the stream is over max_total_size with quota_mode enforce
+	ErrorCodePermission       = "permission_denied"  // the output can't be written
+	ErrorCodeFFmpegMissing    = "ffmpeg_unavailable" // the ffmpeg binary can't be run
+	ErrorCodeResourceLimit    = "resource_limit"     // killed above max_ffmpeg_cpu or max_ffmpeg_memory
+	ErrorCodeFFmpegExit       = "ffmpeg_exit"        // ffmpeg failed otherwise, see exit_code
+)
+
+var errorCodes = []string{
+	ErrorCodeUnreachable, ErrorCodeAuthFailed, ErrorCodeSourceNotFound, ErrorCodeCodecUnsupported,
+	ErrorCodeInvalidData, ErrorCodeDiskFull, ErrorCodeQuotaExceeded, ErrorCodePermission, ErrorCodeFFmpegMissing,
+	ErrorCodeResourceLimit, ErrorCodeFFmpegExit,
+}
+
+// errorPatterns classify ffmpeg output and error messages, checked in order
+// and lowercase. Codec errors come first, they also say "not found".
+var errorPatterns = []struct {
+	code     string
+	patterns []string
+}{
+	{ErrorCodeCodecUnsupported, []string{
+		"encoder not found", "unknown encoder", "decoder not found", "not found for input stream", "codec not currently supported",
+		"could not find tag for codec", "not supported in container", "unsupported codec",
+		"incorrect codec parameters", "could not write header",
+	}},
+	{ErrorCodeDiskFull, []string{"no space left on device", "quota exceeded", "disk full"}},
+	{ErrorCodePermission, []string{"permission denied", "read-only file system", "operation not permitted"}},
+	{ErrorCodeAuthFailed, []string{"unauthorized", "403 forbidden", "authorization failed"}},
+	{ErrorCodeUnreachable, []string{
+		"connection refused", "host is unreachable", "no route to host", "network is unreachable",
+		"timed out", "i/o timeout", "connection reset",
+		"name or service not known", "could not resolve", "temporary failure in name resolution",
+	}},
+	{ErrorCodeSourceNotFound, []string{"404 not found", "no such file or directory", "stream not found", "source stream"}},
+	{ErrorCodeInvalidData, []string{"invalid data found"}},
+}
+
+// classifyRecordingError returns the error code of a failure message,
+// ErrorCodeFFmpegExit if it is none of the known failures
+func classifyRecordingError(msg string) string {
+	msg = strings.ToLower(msg)
+	for _, class := range errorPatterns {
+		for _, pattern := range class.patterns {
+			if strings.Contains(msg, pattern) {
+				return class.code
+			}
+		}
+	}
+	return ErrorCodeFFmpegExit
+}
+
+// newStreamError describes a recording that failed to start or run
+func newStreamError(recordingID string, err error) StreamError {
+	streamErr := StreamError{Error: err.Error(), RecordingID: recordingID}
+	switch {
+	case errors.Is(err, errFFmpegUnavailable):
+		streamErr.Code = ErrorCodeFFmpegMissing
+	case errors.Is(err, errDiskPaused):
+		streamErr.Code = ErrorCodeDiskFull
+	case errors.Is(err, errQuotaExceeded):
+		streamErr.Code = ErrorCodeQuotaExceeded
+	default:
+		streamErr.Code = classifyRecordingError(streamErr.Error)
+	}
+	return streamErr
+}
+
+// ffmpegStreamError describes an ffmpeg process that exited with an error,
+// classified by its whole output
+func ffmpegStreamError(recordingID, stderr string, waitErr error) StreamError {
+	streamErr := StreamError{RecordingID: recordingID, Code: classifyRecordingError(stderr)}
+	if stderr != "" {
+		streamErr.Error = extractFFmpegError(stderr)
+	} else if waitErr != nil {
+		streamErr.Error = waitErr.Error()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		streamErr.ExitCode = exitErr.ExitCode()
+	}
+	return streamErr
+}
@@ -0,0 +1,53 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyRecordingError(t *testing.T) {
+	tests := map[string]string{
+		"[tcp @ 0x55d] Connection to tcp://10.0.0.5:554 failed: Connection refused": ErrorCodeUnreachable,
+		"method DESCRIBE failed: 401 Unauthorized":                                  ErrorCodeAuthFailed,
+		"method DESCRIBE failed: 404 Not Found":                                     ErrorCodeSourceNotFound,
+		"Unknown encoder 'libx265'":                                                 ErrorCodeCodecUnsupported,
+		"Decoder (codec hevc) not found for input stream #0:0":                      ErrorCodeCodecUnsupported,
+		"av_interleaved_write_frame(): No space left on device":                     ErrorCodeDiskFull,
+		"/recordings/cam1/cam1.mp4: Permission denied":                              ErrorCodePermission,
+		"rtsp://cam1: Invalid data found when processing input":                     ErrorCodeInvalidData,
+		"Conversion failed!":                                                        ErrorCodeFFmpegExit,
+	}
+	for msg, code := range tests {
+		require.Equal(t, code, classifyRecordingError(msg), msg)
+	}
+
+	require.Equal(t, ErrorCodeDiskFull, newStreamError("", fmt.Errorf("%w: disk space low", errDiskPaused)).Code)
+	require.Equal(t, ErrorCodeFFmpegMissing, newStreamError("", fmt.Errorf("%w: not found", errFFmpegUnavailable)).Code)
+	require.Equal(t, ErrorCodeUnreachable, newStreamError("", errors.New("dial tcp: i/o timeout")).Code)
+}
+
+func TestFailureBackoff(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{AutoRecordCheckInterval: time.Second * 10, AutoRecordMaxBackoff: time.Minute * 5})
+
+	require.Equal(t, time.Second*10, failureBackoff(ErrorCodeUnreachable, 1))
+	require.Equal(t, time.Second*40, failureBackoff(ErrorCodeUnreachable, 3))
+	require.Equal(t, time.Minute*5, failureBackoff(ErrorCodeFFmpegExit, 10))
+
+	// Waiting longer doesn't help a full disk, and retrying fast doesn't fix credentials
+	require.Equal(t, time.Second*10, failureBackoff(ErrorCodeDiskFull, 10))
+	require.Equal(t, time.Minute*5, failureBackoff(ErrorCodeAuthFailed, 1))
+}
+
+func TestStreamErrorAlertRule(t *testing.T) {
+	rule := AlertRule{Condition: AlertStreamError, Codes: []string{ErrorCodeAuthFailed}}
+	require.Nil(t, rule.normalize(nil))
+	require.Equal(t, time.Minute*2, rule.For)
+
+	require.NotNil(t, (&AlertRule{Condition: AlertStreamError, Codes: []string{"unknown"}}).normalize(nil))
+}
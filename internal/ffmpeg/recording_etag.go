@@ -0,0 +1,93 @@
+package ffmpeg
+
+import (
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// respondJSONTagged writes v as JSON with an ETag of its content, or only
+// 304 Not Modified if the client already has it. Polling UIs and proxies
+// revalidate instead of transferring an unchanged listing again.
+func respondJSONTagged(w http.ResponseWriter, r *http.Request, v any) {
+	respondTagged(w, r, func(out io.Writer) error {
+		return json.NewEncoder(out).Encode(v)
+	})
+}
+
+// respondTagged writes the JSON response of write like respondJSONTagged,
+// gzipped if the client accepts it. write is called twice, once for the
+// ETag, so large responses are never held in memory.
+func respondTagged(w http.ResponseWriter, r *http.Request, write func(io.Writer) error) {
+	hash := sha1.New()
+	if err := write(hash); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Weak, the same content is sent gzipped or not
+	etag := `W/"` + hex.EncodeToString(hash.Sum(nil)[:10]) + `"`
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", "no-cache") // may be stored, but is checked every time
+	header.Add("Vary", "Accept-Encoding")
+
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", "application/json")
+
+	var err error
+	if acceptsGzip(r) {
+		header.Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		if err = write(gz); err == nil {
+			err = gz.Close()
+		}
+	} else {
+		err = write(w)
+	}
+	if err != nil {
+		// Mostly a client that went away, the status is sent already
+		log.Debug().Err(err).Str("url", r.URL.String()).Msg("[api] failed to write response")
+	}
+}
+
+// acceptsGzip reports whether the client takes gzip responses
+func acceptsGzip(r *http.Request) bool {
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+		if strings.TrimSpace(coding) == "gzip" {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// setFileETag sets the ETag of a file's current version, for
+// http.ServeContent to answer If-None-Match and If-Range with
+func setFileETag(w http.ResponseWriter, info os.FileInfo) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+}
+
+// etagMatch reports whether an If-None-Match header lists the ETag, with
+// the weak comparison of RFC 9110
+func etagMatch(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package ffmpeg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondJSONTagged(t *testing.T) {
+	respond := func(v any, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/recordings", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		respondJSONTagged(w, r, v)
+		return w
+	}
+
+	w := respond(map[string]any{"count": 1}, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "{\"count\":1}\n", w.Body.String())
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	w = respond(map[string]any{"count": 1}, `"other", `+strings.TrimPrefix(etag, "W/"))
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Empty(t, w.Body.String())
+	require.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = respond(map[string]any{"count": 2}, etag)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+func TestFileETag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cam1.mp4")
+	require.Nil(t, os.WriteFile(path, []byte("recording"), 0644))
+
+	serve := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		file, err := os.Open(path)
+		require.Nil(t, err)
+		defer file.Close()
+		info, err := file.Stat()
+		require.Nil(t, err)
+
+		r := httptest.NewRequest("GET", "/api/recordings?download=x", nil)
+		r.Header.Set("If-None-Match", ifNoneMatch)
+		w := httptest.NewRecorder()
+		setFileETag(w, info)
+		http.ServeContent(w, r, "cam1.mp4", info.ModTime(), file)
+		return w
+	}
+
+	w := serve("")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, http.StatusNotModified, serve(w.Header().Get("ETag")).Code)
+
+	// A file that grew is sent again
+	require.Nil(t, os.WriteFile(path, []byte("recording continued"), 0644))
+	require.Equal(t, http.StatusOK, serve(w.Header().Get("ETag")).Code)
+}
@@ -0,0 +1,305 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+// eventRecording tracks an in-progress event recording and when it should stop
+type eventRecording struct {
+	ID       string        `json:"id"`
+	Stream   string        `json:"stream"`
+	Filename string        `json:"filename"`
+	Source   string        `json:"source"`
+	Started  time.Time     `json:"started"`
+	Deadline time.Time     `json:"deadline"`
+	PreRoll  time.Duration `json:"pre_roll"`
+}
+
+// EventRecordingManager manages bounded recordings started by motion/event triggers
+type EventRecordingManager struct {
+	events map[string]*eventRecording // stream name -> active event recording
+	mu     sync.Mutex
+}
+
+var eventRecordingManager = &EventRecordingManager{
+	events: make(map[string]*eventRecording),
+}
+
+// TriggerEventRecording starts an event recording for the stream, or extends
+// the post-roll of the event recording already running. Negative pre/post
+// values use the configured defaults for the stream.
+func TriggerEventRecording(streamName string, pre, post time.Duration, source string) (*eventRecording, error) {
+	cfg := GetRecordingConfig()
+	streamConfig := GetStreamRecordingConfig(streamName)
+
+	if pre < 0 {
+		pre = streamConfig.EventPreTime
+	}
+	if post < 0 {
+		post = streamConfig.EventPostTime
+	}
+
+	em := eventRecordingManager
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	now := time.Now()
+
+	// Re-trigger while recording only pushes the stop deadline forward
+	if event, ok := em.events[streamName]; ok {
+		deadline := now.Add(post)
+		if limit := event.Started.Add(cfg.MaxEventDuration); cfg.MaxEventDuration > 0 && deadline.After(limit) {
+			deadline = limit
+		}
+		if deadline.After(event.Deadline) {
+			event.Deadline = deadline
+		}
+		log.Debug().
+			Str("stream", streamName).
+			Str("source", source).
+			Time("deadline", event.Deadline).
+			Msg("[event] extended event recording")
+		copied := *event
+		return &copied, nil
+	}
+
+	if streams.Get(streamName) == nil && streamConfig.Source == "" {
+		return nil, fmt.Errorf("stream '%s' not found", streamName)
+	}
+
+	format := streamConfig.Format
+	if format == "" {
+		format = cfg.DefaultFormat
+	}
+
+	id := fmt.Sprintf("event_%s_%d", streamName, now.Unix())
+	config := RecordConfig{
+		Filename: eventRecordingPath(streamName, now, format),
+		Format:   format,
+		Video:    streamConfig.Video,
+		Audio:    streamConfig.Audio,
+		Event:    true,
+		PreRoll:  pre,
+	}
+
+	if err := GetRecordingManager().StartRecording(id, streamName, config); err != nil {
+		return nil, err
+	}
+
+	event := &eventRecording{
+		ID:       id,
+		Stream:   streamName,
+		Filename: config.Filename,
+		Source:   source,
+		Started:  now,
+		Deadline: now.Add(post),
+		PreRoll:  pre,
+	}
+	em.events[streamName] = event
+
+	log.Info().
+		Str("stream", streamName).
+		Str("recording_id", id).
+		Str("source", source).
+		Dur("post", post).
+		Msg("[event] started event recording")
+
+	go em.waitAndStop(event)
+
+	copied := *event
+	return &copied, nil
+}
+
+// waitAndStop stops the event recording once its deadline has passed
+func (em *EventRecordingManager) waitAndStop(event *eventRecording) {
+	for {
+		em.mu.Lock()
+		remaining := time.Until(event.Deadline)
+		if remaining <= 0 {
+			delete(em.events, event.Stream)
+			em.mu.Unlock()
+			break
+		}
+		em.mu.Unlock()
+
+		// Stop tracking if ffmpeg exited on its own
+		if rec := GetRecordingManager().GetRecording(event.ID); rec == nil || rec.finished() {
+			em.mu.Lock()
+			delete(em.events, event.Stream)
+			em.mu.Unlock()
+			log.Warn().Str("stream", event.Stream).Str("recording_id", event.ID).Msg("[event] event recording ended early")
+			return
+		}
+
+		if remaining > time.Second {
+			remaining = time.Second
+		}
+		time.Sleep(remaining)
+	}
+
+	if err := GetRecordingManager().StopRecording(event.ID, ExitEventEnd); err != nil {
+		log.Debug().Err(err).Str("recording_id", event.ID).Msg("[event] event recording already stopped")
+	}
+
+	log.Info().
+		Str("stream", event.Stream).
+		Str("recording_id", event.ID).
+		Dur("duration", time.Since(event.Started)).
+		Msg("[event] finished event recording")
+}
+
+// ListEventRecordings returns the currently running event recordings
+func (em *EventRecordingManager) ListEventRecordings() []eventRecording {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	events := make([]eventRecording, 0, len(em.events))
+	for _, event := range em.events {
+		events = append(events, *event)
+	}
+	return events
+}
+
+// eventRecordingPath generates the output path for an event recording,
+// tagging the filename with an "_event" suffix
+func eventRecordingPath(streamName string, startTime time.Time, format string) string {
+	path := streamPathTemplate(streamName).Render(streamName, startTime, format, 0)
+	ext := filepath.Ext(path)
+	return newRecordingPath(strings.TrimSuffix(path, ext) + "_event" + ext)
+}
+
+// isEventRecordingFile reports whether the file was written by an event recording
+func isEventRecordingFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasSuffix(strings.TrimSuffix(name, filepath.Ext(name)), "_event")
+}
+
+// startEventTriggers starts the MQTT and ONVIF listeners configured for event recording
+func startEventTriggers() {
+	cfg := GetRecordingConfig()
+
+	// Keep the last seconds of event streams around for pre-roll
+	startPreBuffers()
+
+	topicStreams := make(map[string]string)
+	for streamName, streamConfig := range configuredStreams(cfg) {
+		if streamConfig.MotionTopic != "" {
+			topicStreams[streamConfig.MotionTopic] = streamName
+		}
+		if streamConfig.MotionONVIF != "" {
+			go watchONVIFMotion(streamName, streamConfig.MotionONVIF)
+		}
+	}
+
+	if len(topicStreams) == 0 {
+		return
+	}
+	if cfg.MQTT.URL == "" {
+		log.Warn().Msg("[event] motion_topic configured but recording mqtt url is empty")
+		return
+	}
+
+	topics := make([]string, 0, len(topicStreams))
+	for topic := range topicStreams {
+		topics = append(topics, topic)
+	}
+
+	go mqttSubscribe(topics, func(topic string, payload []byte) {
+		if !isMotionPayload(payload) {
+			return
+		}
+		for filter, streamName := range topicStreams {
+			if !mqttTopicMatch(filter, topic) {
+				continue
+			}
+			if _, err := TriggerEventRecording(streamName, -1, -1, "mqtt"); err != nil {
+				log.Warn().Err(err).Str("stream", streamName).Str("topic", topic).Msg("[event] failed to start mqtt event recording")
+			}
+		}
+	})
+}
+
+// isMotionPayload reports whether an MQTT payload signals motion start
+func isMotionPayload(payload []byte) bool {
+	switch strings.ToLower(strings.TrimSpace(string(payload))) {
+	case "", "off", "0", "false", "no", "clear", "idle":
+		return false
+	}
+	return true
+}
+
+// apiRecordingEvent triggers an event recording, suitable as a webhook target
+// for cameras and automation systems
+func apiRecordingEvent(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		if r.URL.Query().Get("src") == "" {
+			api.ResponseJSON(w, eventRecordingManager.ListEventRecordings())
+			return
+		}
+	case "POST":
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	streamName := query.Get("src")
+	if streamName == "" {
+		http.Error(w, "Missing 'src' parameter (stream name)", http.StatusBadRequest)
+		return
+	}
+
+	pre, err := parseDurationParam(query.Get("pre"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'pre' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	post, err := parseDurationParam(query.Get("post"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'post' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	source := query.Get("source")
+	if source == "" {
+		source = "webhook"
+	}
+
+	event, err := TriggerEventRecording(streamName, pre, post, source)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to start event recording: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	api.ResponseJSON(w, event)
+}
+
+// parseDurationParam parses durations like "30s" or plain seconds,
+// returning -1 for an empty value so defaults apply
+func parseDurationParam(s string) (time.Duration, error) {
+	if s == "" {
+		return -1, nil
+	}
+	if seconds, err := strconv.Atoi(s); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("negative duration")
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil && d < 0 {
+		return 0, fmt.Errorf("negative duration")
+	}
+	return d, err
+}
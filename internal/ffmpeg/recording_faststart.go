@@ -0,0 +1,232 @@
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FaststartStatus reports the faststart remux queue
+type FaststartStatus struct {
+	Pending   int    `json:"pending"`
+	Remuxed   int64  `json:"remuxed"`
+	Skipped   int64  `json:"skipped"` // already had the moov atom first
+	Failed    int64  `json:"failed"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type faststartJob struct {
+	stream string
+	path   string
+}
+
+// faststartQueue remuxes finished MP4s one at a time so their moov atom comes
+// before the media data and players can start and seek without downloading
+// the whole file. The segment is announced once the remux is done.
+type faststartQueue struct {
+	queue  []faststartJob
+	status FaststartStatus
+	wake   chan struct{}
+	mu     sync.Mutex
+}
+
+var faststartJobs *faststartQueue
+
+// startFaststartQueue starts the remux worker if any stream uses faststart
+func startFaststartQueue() {
+	enabled := GetRecordingConfig().Faststart
+	for _, streamConfig := range GetRecordingConfig().Streams {
+		if streamConfig.Faststart != nil && *streamConfig.Faststart {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return
+	}
+
+	faststartJobs = &faststartQueue{wake: make(chan struct{}, 1)}
+	go faststartJobs.run()
+
+	log.Info().Msg("[recording] remuxing finished MP4 files with faststart")
+}
+
+// enqueue queues an MP4 of a faststart stream and reports whether it did
+func (q *faststartQueue) enqueue(streamName, filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".mp4", ".m4v", ".mov":
+	default:
+		return false
+	}
+
+	if faststart := GetStreamRecordingConfig(streamName).Faststart; faststart == nil || !*faststart {
+		return false
+	}
+
+	q.mu.Lock()
+	q.queue = append(q.queue, faststartJob{stream: streamName, path: filePath})
+	q.status.Pending = len(q.queue)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (q *faststartQueue) run() {
+	for range q.wake {
+		for {
+			q.mu.Lock()
+			if len(q.queue) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			job := q.queue[0]
+			q.queue = q.queue[1:]
+			q.mu.Unlock()
+
+			remuxed, err := remuxFaststart(job.path)
+
+			q.mu.Lock()
+			q.status.Pending = len(q.queue)
+			switch {
+			case err != nil:
+				q.status.Failed++
+				q.status.LastError = err.Error()
+			case remuxed:
+				q.status.Remuxed++
+			default:
+				q.status.Skipped++
+			}
+			q.mu.Unlock()
+
+			if err != nil {
+				// The original file is untouched and still playable
+				log.Warn().Err(err).Str("stream", job.stream).Str("path", job.path).Msg("[recording] faststart remux failed")
+			}
+
+			finishSegment(job.stream, job.path)
+		}
+	}
+}
+
+// Status returns the queue length and results so far
+func (q *faststartQueue) Status() FaststartStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.status
+}
+
+// remuxFaststart rewrites a file with -movflags +faststart unless its moov
+// atom already comes first, e.g. fragmented MP4 from the native recorder
+func remuxFaststart(path string) (bool, error) {
+	first, err := moovFirst(path)
+	if err != nil || first {
+		return false, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	// Not a recording extension, so the index and cleanup ignore it
+	tmp := path + ".faststart.tmp"
+
+	format := "mp4"
+	if strings.EqualFold(filepath.Ext(path), ".mov") {
+		format = "mov"
+	}
+
+	cmd := exec.Command(ffmpegBin(),
+		"-hide_banner", "-v", "error",
+		"-i", path,
+		"-map", "0", "-c", "copy",
+		"-movflags", "+faststart",
+		"-f", format, "-y", tmp,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(tmp)
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return false, errors.New(msg)
+		}
+		return false, err
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return false, err
+	}
+
+	// Recording times are derived from the file name and modification time
+	_ = os.Chtimes(path, time.Now(), info.ModTime())
+
+	return true, nil
+}
+
+// moovFirst reports whether the moov atom precedes the mdat atom
+func moovFirst(path string) (bool, error) {
+	var first string
+	err := walkMP4Atoms(path, func(typ string) bool {
+		if typ == "moov" || typ == "mdat" {
+			first = typ
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return false, err
+	}
+	if first == "" {
+		return false, errors.New("no moov or mdat atom")
+	}
+	return first == "moov", nil
+}
+
+// walkMP4Atoms calls visit with the type of every top-level atom until it
+// returns true or the file ends
+func walkMP4Atoms(path string, visit func(typ string) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var offset int64
+	header := make([]byte, 16)
+
+	for {
+		if _, err = f.ReadAt(header[:8], offset); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header))
+		if visit(string(header[4:8])) {
+			return nil
+		}
+
+		switch size {
+		case 0: // last atom, runs to the end of the file
+			return nil
+		case 1: // 64-bit size follows the type
+			if _, err = f.ReadAt(header[8:16], offset+8); err != nil {
+				return err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if size < 8 {
+			return errors.New("invalid atom size")
+		}
+		offset += size
+	}
+}
@@ -0,0 +1,140 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// FilenamePattern parses recordings written by other tools. The regex is
+// matched against the path relative to its storage or import directory,
+// with / separators, and names its parts with the groups stream, timestamp
+// and optionally end.
+type FilenamePattern struct {
+	Regex      string `yaml:"regex" json:"regex"`
+	TimeFormat string `yaml:"time_format" json:"time_format"` // Go layout of timestamp and end, or "unix" / "unix_ms"
+
+	re *regexp.Regexp
+}
+
+// compileFilenamePatterns compiles the patterns and drops invalid ones
+func compileFilenamePatterns(patterns []FilenamePattern, key string) []FilenamePattern {
+	var result []FilenamePattern
+	for _, pattern := range patterns {
+		if err := pattern.compile(); err != nil {
+			log.Warn().Err(err).Str("regex", pattern.Regex).Msgf("[recording] invalid %s entry, ignored", key)
+			continue
+		}
+		result = append(result, pattern)
+	}
+	return result
+}
+
+func (p *FilenamePattern) compile() error {
+	re, err := regexp.Compile(p.Regex)
+	if err != nil {
+		return err
+	}
+	if re.SubexpIndex("stream") < 0 && re.SubexpIndex("timestamp") < 0 {
+		return fmt.Errorf("needs a (?P<stream>...) or (?P<timestamp>...) group")
+	}
+	if re.SubexpIndex("timestamp") >= 0 && p.TimeFormat == "" {
+		return fmt.Errorf("time_format is required with a timestamp group")
+	}
+	p.re = re
+	return nil
+}
+
+// parseTime reads a timestamp or end group
+func (p *FilenamePattern) parseTime(value string) (time.Time, error) {
+	switch p.TimeFormat {
+	case "unix":
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		return time.Unix(seconds, 0), err
+	case "unix_ms":
+		ms, err := strconv.ParseInt(value, 10, 64)
+		return time.UnixMilli(ms), err
+	}
+	return time.ParseInLocation(p.TimeFormat, value, templateLocation())
+}
+
+// parsedName is what a filename pattern read from a path, zero values for
+// groups it doesn't have
+type parsedName struct {
+	stream     string
+	start, end time.Time
+}
+
+// matchFilenamePatterns returns the result of the first pattern matching
+// the relative path
+func matchFilenamePatterns(patterns []FilenamePattern, relativePath string) (*parsedName, bool) {
+	relativePath = filepath.ToSlash(relativePath)
+
+	for i := range patterns {
+		p := &patterns[i]
+		if p.re == nil {
+			continue
+		}
+		m := p.re.FindStringSubmatch(relativePath)
+		if m == nil {
+			continue
+		}
+
+		group := func(name string) string {
+			if i := p.re.SubexpIndex(name); i >= 0 {
+				return m[i]
+			}
+			return ""
+		}
+
+		var parsed parsedName
+		parsed.stream = group("stream")
+		if value := group("timestamp"); value != "" {
+			start, err := p.parseTime(value)
+			if err != nil {
+				continue // e.g. a file that only looks alike
+			}
+			parsed.start = start
+		}
+		if value := group("end"); value != "" && !parsed.start.IsZero() {
+			if end, err := p.parseTime(value); err == nil && end.After(parsed.start) {
+				parsed.end = end
+			}
+		}
+		return &parsed, true
+	}
+
+	return nil, false
+}
+
+// recordingFilenamePatterns returns the patterns for a file: those of its
+// import path before the global filename_patterns
+func recordingFilenamePatterns(filePath string) []FilenamePattern {
+	patterns := GetRecordingConfig().FilenamePatterns
+	if imp, _, ok := importPathRel(filePath); ok && len(imp.Patterns) > 0 {
+		patterns = append(append([]FilenamePattern(nil), imp.Patterns...), patterns...)
+	}
+	return patterns
+}
+
+// recordingStreamName returns the stream a file in the storage or import
+// directories belongs to, as listings show it
+func recordingStreamName(filePath string) string {
+	if imp, _, ok := importPathRel(filePath); ok && imp.Stream != "" {
+		return imp.Stream
+	}
+
+	rel, ok := "", false
+	if _, rel, ok = storagePoolRel(filePath); !ok {
+		_, rel, ok = importPathRel(filePath)
+	}
+	if ok {
+		if parsed, ok := matchFilenamePatterns(recordingFilenamePatterns(filePath), rel); ok && parsed.stream != "" {
+			return parsed.stream
+		}
+	}
+
+	return extractStreamName(filePath, filepath.Base(filePath))
+}
@@ -0,0 +1,55 @@
+package ffmpeg
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilenamePatterns(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "recordings")
+	nvr := filepath.Join(dir, "nvr")
+
+	cfg := GetRecordingConfig()
+	setRecordingConfig(&RecordingConfig{
+		BasePath:  main,
+		BasePaths: StoragePaths{main},
+		FilenamePatterns: []FilenamePattern{
+			{Regex: `^(?P<stream>[^/]+)/(?P<timestamp>\d{14})\.mkv$`, TimeFormat: "20060102150405"},
+			{Regex: `(?P<timestamp>\d+`}, // invalid, dropped
+		},
+		ImportPaths: []ImportPath{{
+			Path: nvr,
+			Patterns: []FilenamePattern{{
+				Regex:      `^ch(?P<stream>\d+)_(?P<timestamp>\d+)_(?P<end>\d+)\.mp4$`,
+				TimeFormat: "unix",
+			}},
+		}},
+		StreamSkipDirs: []string{"recordings"},
+	})
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	validateRecordingConfig(GetRecordingConfig())
+	require.Len(t, GetRecordingConfig().FilenamePatterns, 1)
+
+	modTime := time.Now().Add(-time.Hour)
+
+	path := filepath.Join(main, "garage", "20250115143000.mkv")
+	file := buildRecordingFile("a", path, filepath.Join("garage", "20250115143000.mkv"), 10<<20, modTime)
+	require.Equal(t, "garage", file.StreamName)
+	require.Equal(t, time.Date(2025, 1, 15, 14, 30, 0, 0, templateLocation()), file.StartTime)
+	require.Equal(t, "garage", recordingStreamName(path))
+
+	// Import path patterns are tried first
+	path = filepath.Join(nvr, "ch2_1736951400_1736952000.mp4")
+	file = buildRecordingFile("b", path, "ch2_1736951400_1736952000.mp4", 10<<20, modTime)
+	require.Equal(t, "2", file.StreamName)
+	require.Equal(t, time.Unix(1736951400, 0), file.StartTime)
+	require.Equal(t, time.Unix(1736952000, 0), file.EndTime)
+
+	// Paths no pattern matches fall back to the built-in parsing
+	path = filepath.Join(main, "porch", "porch_2025-01-15_14-30-00.mp4")
+	require.Equal(t, "porch", recordingStreamName(path))
+}
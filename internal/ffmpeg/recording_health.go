@@ -0,0 +1,241 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Health levels of the recording system, from best to worst
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded" // recording works with problems
+	healthCritical = "critical" // nothing can be recorded
+)
+
+var healthLevels = map[string]int{healthOK: 0, healthDegraded: 1, healthCritical: 2}
+
+// ComponentHealth is the health of one part of the recording system
+type ComponentHealth struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// SystemHealth summarizes the recording system for liveness and
+// readiness probes, the status is the worst status of the checks
+type SystemHealth struct {
+	Status    string                     `json:"status"`
+	Checks    map[string]ComponentHealth `json:"checks"`
+	CheckedAt time.Time                  `json:"checked_at"`
+}
+
+// worseHealth returns the worse of two health levels
+func worseHealth(a, b string) string {
+	if healthLevels[b] > healthLevels[a] {
+		return b
+	}
+	return a
+}
+
+// systemHealth checks the recorders, the scheduler, the disk, failed
+// streams, the last cleanup and ffmpeg. It only reads state the background
+// routines keep, so it is cheap enough for frequent probes.
+func systemHealth() SystemHealth {
+	expected := getStreamsToRecordForHealthCheck()
+	failures := GetAutoRecordFailures()
+
+	health := SystemHealth{
+		Status: healthOK,
+		Checks: map[string]ComponentHealth{
+			"recorders":      recordersHealth(len(expected)),
+			"scheduler":      schedulerHealth(),
+			"disk":           diskHealth(),
+			"failed_streams": failedStreamsHealth(failures, len(expected)),
+			"last_cleanup":   cleanupHealth(),
+			"ffmpeg":         ffmpegHealth(),
+		},
+		CheckedAt: time.Now(),
+	}
+	for _, check := range health.Checks {
+		health.Status = worseHealth(health.Status, check.Status)
+	}
+	return health
+}
+
+func recordersHealth(expected int) ComponentHealth {
+	var recordings, segmented int
+	for _, recording := range GetRecordingManager().ListRecordings() {
+		if recording.Active {
+			recordings++
+		}
+	}
+	for _, recording := range GetSegmentedRecordingManager().ListSegmentedRecordings() {
+		if recording.Active {
+			segmented++
+		}
+	}
+
+	autoRecordingManager.mu.Lock()
+	autoStart := autoRecordingManager.started
+	autoRecordingManager.mu.Unlock()
+
+	check := ComponentHealth{
+		Status: healthOK,
+		Details: map[string]any{
+			"recordings": recordings,
+			"segmented":  segmented,
+			"expected":   expected,
+			"auto_start": autoStart,
+		},
+	}
+	if active := recordings + segmented; active < expected {
+		check.Status = healthDegraded
+		check.Message = fmt.Sprintf("%d of %d expected recordings running", active, expected)
+	}
+	return check
+}
+
+func schedulerHealth() ComponentHealth {
+	schedules := len(GetSchedules())
+	running := schedulerRunning()
+
+	check := ComponentHealth{
+		Status: healthOK,
+		Details: map[string]any{
+			"running":   running,
+			"schedules": schedules,
+		},
+	}
+	if schedules > 0 && !running {
+		check.Status = healthDegraded
+		check.Message = "scheduler stopped, schedules don't run"
+	}
+	return check
+}
+
+// currentDiskStatus returns the last check of the disk monitor, or reads the
+// recordings volume without watermarks, when there is no monitor to ask
+func currentDiskStatus() DiskStatus {
+	status := diskMonitor.Status()
+	if !status.CheckedAt.IsZero() {
+		return status
+	}
+
+	path := GetRecordingConfig().BasePath
+	status = DiskStatus{Path: path, CheckedAt: time.Now()}
+	if free, total, err := diskUsage(path); err != nil {
+		status.Error = err.Error()
+	} else if total > 0 {
+		status.FreeBytes, status.TotalBytes = free, total
+		status.FreePercent = float64(free) / float64(total) * 100
+	}
+	return status
+}
+
+func diskHealth() ComponentHealth {
+	cfg := GetRecordingConfig()
+	status := currentDiskStatus()
+
+	check := ComponentHealth{
+		Status: healthOK,
+		Details: map[string]any{
+			"path":         status.Path,
+			"free_bytes":   status.FreeBytes,
+			"total_bytes":  status.TotalBytes,
+			"free_percent": status.FreePercent,
+			"paused":       status.Paused,
+		},
+	}
+	switch {
+	case status.Error != "":
+		check.Status = healthDegraded
+		check.Message = status.Error
+	case status.Paused:
+		check.Status = healthCritical
+		check.Message = "disk almost full, new recordings paused"
+	case status.TotalBytes > 0 && status.FreeBytes == 0:
+		check.Status = healthCritical
+		check.Message = "disk full"
+	case cfg.DiskHighWatermark > 0 && status.FreePercent < cfg.DiskHighWatermark:
+		check.Status = healthDegraded
+		check.Message = fmt.Sprintf("%.1f%% free, below the high watermark", status.FreePercent)
+	}
+	return check
+}
+
+func failedStreamsHealth(failures map[string]StreamFailure, expected int) ComponentHealth {
+	streams := make([]string, 0, len(failures))
+	codes := make(map[string]string, len(failures))
+	for streamName, failure := range failures {
+		streams = append(streams, streamName)
+		codes[streamName] = failure.Code
+	}
+	sort.Strings(streams)
+
+	check := ComponentHealth{
+		Status:  healthOK,
+		Details: map[string]any{"count": len(failures), "streams": streams, "codes": codes},
+	}
+	switch {
+	case expected > 0 && len(failures) >= expected:
+		check.Status = healthCritical
+		check.Message = "every stream fails to record"
+	case len(failures) > 0:
+		check.Status = healthDegraded
+		check.Message = fmt.Sprintf("%d streams fail to record", len(failures))
+	}
+	return check
+}
+
+func cleanupHealth() ComponentHealth {
+	cfg := GetRecordingConfig()
+	if !cfg.EnableCleanup {
+		return ComponentHealth{Status: healthOK, Message: "cleanup disabled"}
+	}
+
+	run := LastCleanup()
+	check := ComponentHealth{Status: healthOK, Details: map[string]any{}}
+	if run.Time.IsZero() {
+		check.Message = "no cleanup ran yet"
+		return check
+	}
+
+	check.Details["time"] = run.Time
+	check.Details["age"] = time.Since(run.Time).Round(time.Second).String()
+	switch {
+	case run.Error != "":
+		check.Status = healthDegraded
+		check.Message = run.Error
+	case cfg.CleanupWindow == "" && cfg.CleanupInterval > 0 && time.Since(run.Time) > 2*cfg.CleanupInterval:
+		// Outside a cleanup window nothing runs, so only overdue without one
+		check.Status = healthDegraded
+		check.Message = "cleanup overdue"
+	}
+	return check
+}
+
+func ffmpegHealth() ComponentHealth {
+	status := ffmpegStatus()
+
+	check := ComponentHealth{
+		Status: healthOK,
+		Details: map[string]any{
+			"path":          status.Path,
+			"version":       status.Version,
+			"ffprobe_found": status.FFprobeFound,
+		},
+	}
+	switch {
+	case status.Error != "":
+		check.Status = healthCritical
+		check.Message = status.Error
+	case len(status.MissingMuxers) > 0:
+		check.Status = healthDegraded
+		check.Message = fmt.Sprintf("missing muxers: %v", status.MissingMuxers)
+	case !status.FFprobeFound:
+		check.Status = healthDegraded
+		check.Message = "ffprobe not found"
+	}
+	return check
+}
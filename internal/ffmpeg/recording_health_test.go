@@ -0,0 +1,23 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemHealthStatus(t *testing.T) {
+	require.Equal(t, healthDegraded, worseHealth(healthOK, healthDegraded))
+	require.Equal(t, healthCritical, worseHealth(healthCritical, healthDegraded))
+	require.Equal(t, healthOK, worseHealth(healthOK, healthOK))
+
+	failures := map[string]StreamFailure{"cam2": {Failures: 3}, "cam1": {Failures: 1}}
+
+	check := failedStreamsHealth(failures, 3)
+	require.Equal(t, healthDegraded, check.Status)
+	require.Equal(t, []string{"cam1", "cam2"}, check.Details["streams"])
+
+	// Nothing records when every expected stream fails
+	require.Equal(t, healthCritical, failedStreamsHealth(failures, 2).Status)
+	require.Equal(t, healthOK, failedStreamsHealth(nil, 2).Status)
+}
@@ -0,0 +1,369 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Why a recording ended, the reason of its history entry
+const (
+	ExitStopped       = "stopped"        // stopped through the API
+	ExitDuration      = "duration"       // its duration limit was reached
+	ExitSchedule      = "schedule"       // its schedule ended or was removed
+	ExitEventEnd      = "event_end"      // the event and its post time are over
+	ExitDisabled      = "disabled"       // recording of the stream was disabled
+	ExitShutdown      = "shutdown"       // go2rtc was stopped
+	ExitRecovery      = "recovery"       // stopped by the watchdog or recovery to start over
+	ExitResourceLimit = "resource_limit" // killed above max_ffmpeg_cpu or max_ffmpeg_memory
+	ExitQuota         = "quota"          // its stream went over max_total_size with quota_mode enforce
+	ExitFailed        = "failed"         // the recorder exited with an error
+	ExitEnded         = "ended"          // the recorder exited on its own, e.g. the source ended
+	ExitStartFailed   = "start_failed"   // the recorder could not be started
+	ExitInterrupted   = "interrupted"    // go2rtc exited without stopping it, e.g. a crash
+)
+
+// RecordingSession is an ended recording in the history
+type RecordingSession struct {
+	ID        string    `json:"id"`
+	Stream    string    `json:"stream"`
+	Segmented bool      `json:"segmented,omitempty"`
+	Event     bool      `json:"event,omitempty"`
+	Files     []string  `json:"files"`
+	Start     time.Time `json:"start"`
+	Stop      time.Time `json:"stop"`
+	Duration  float64   `json:"duration"` // seconds recorded, pauses excluded
+	State     string    `json:"state"`    // finalized or failed
+	Reason    string    `json:"reason"`
+	ErrorCode string    `json:"error_code,omitempty"` // one of the ErrorCode constants
+	Error     string    `json:"error,omitempty"`
+}
+
+var history struct {
+	trimmed time.Time
+	mu      sync.Mutex
+}
+
+// getHistoryPath returns the location of the recording history
+func getHistoryPath() string {
+	if GetRecordingConfig().HistoryPath != "" {
+		return GetRecordingConfig().HistoryPath
+	}
+	return filepath.Join(GetRecordingConfig().BasePath, ".recordings.history")
+}
+
+// addHistory appends a session as one JSON line. Once a day sessions older
+// than history_retention are dropped first.
+func addHistory(session RecordingSession) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return
+	}
+
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	path := getHistoryPath()
+	if time.Since(history.trimmed) > time.Hour*24 {
+		history.trimmed = time.Now()
+		if err = trimHistory(path); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("[history] failed to trim recording history")
+		}
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		var f *os.File
+		if f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			_, err = f.Write(append(data, '\n'))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("[history] failed to write recording history")
+	}
+}
+
+// trimHistory rewrites the history without the sessions that stopped before
+// history_retention. Must be called with history.mu held.
+func trimHistory(path string) error {
+	retention := GetRecordingConfig().HistoryRetention
+	if retention <= 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	cutoff := time.Now().Add(-retention)
+
+	var kept []byte
+	var dropped int
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		var session RecordingSession
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if json.Unmarshal(line, &session) != nil || session.Stop.Before(cutoff) {
+			dropped++
+			continue
+		}
+		kept = append(kept, line...)
+	}
+	if dropped == 0 {
+		return nil
+	}
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, kept, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// HistoryFilter selects sessions of the history, zero fields match everything
+type HistoryFilter struct {
+	Since  time.Time // sessions running at or after
+	Until  time.Time // sessions running before
+	Stream string
+	ID     string
+	Reason string
+}
+
+func (f HistoryFilter) matches(session *RecordingSession) bool {
+	return (f.Since.IsZero() || !session.Stop.Before(f.Since)) &&
+		(f.Until.IsZero() || session.Start.Before(f.Until)) &&
+		(f.Stream == "" || session.Stream == f.Stream) &&
+		(f.ID == "" || session.ID == f.ID) &&
+		(f.Reason == "" || session.Reason == f.Reason)
+}
+
+// readHistory returns the matching sessions, the latest to end first, at
+// most limit of them (all if limit is 0)
+func readHistory(filter HistoryFilter, limit int) ([]RecordingSession, error) {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	f, err := os.Open(getHistoryPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var sessions []RecordingSession
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var session RecordingSession
+		if json.Unmarshal(scanner.Bytes(), &session) != nil || !filter.matches(&session) {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// The file is in the order the sessions ended
+	for i, j := 0, len(sessions)-1; i < j; i, j = i+1, j-1 {
+		sessions[i], sessions[j] = sessions[j], sessions[i]
+	}
+	if limit > 0 && len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+	return sessions, nil
+}
+
+// addFailedStartHistory records a recording that could not be started
+func addFailedStartHistory(id, streamName string, config RecordConfig, segmented bool, streamErr StreamError) {
+	now := time.Now()
+	addHistory(RecordingSession{
+		ID:        id,
+		Stream:    streamName,
+		Segmented: segmented,
+		Event:     config.Event,
+		Files:     []string{},
+		Start:     now,
+		Stop:      now,
+		State:     StateFailed,
+		Reason:    ExitStartFailed,
+		ErrorCode: streamErr.Code,
+		Error:     streamErr.Error,
+	})
+}
+
+// setStopReason records why the recording is stopped, the first reason wins
+func (r *Recording) setStopReason(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopReason == "" {
+		r.stopReason = reason
+	}
+}
+
+// exitReason returns why the recording ended. Must be called with r.mu held.
+func (r *Recording) exitReason() string {
+	switch {
+	case r.stopReason != "":
+		return r.stopReason
+	case r.State == StateFailed:
+		return ExitFailed
+	default:
+		return ExitEnded
+	}
+}
+
+// addToHistory records the ended recording once its last file is final. The
+// manager and the shutdown may both get here, only the first one adds it.
+func (r *Recording) addToHistory() {
+	r.mu.Lock()
+	added := r.inHistory
+	r.inHistory = true
+	r.mu.Unlock()
+
+	if added {
+		return
+	}
+	_ = r.waitExited()
+
+	r.mu.Lock()
+	session := RecordingSession{
+		ID:        r.ID,
+		Stream:    r.Stream,
+		Event:     r.Config.Event,
+		Files:     append([]string{}, r.files...),
+		Start:     r.started,
+		Stop:      r.ended,
+		Duration:  r.Duration.Seconds(),
+		State:     r.State,
+		Reason:    r.exitReason(),
+		ErrorCode: r.lastError.Code,
+		Error:     r.lastError.Error,
+	}
+	r.mu.Unlock()
+
+	if session.Stop.IsZero() {
+		session.Stop = time.Now()
+	}
+	addHistory(session)
+}
+
+// addToHistory records the ended segmented recording once, after its last
+// segment is final. A failed segment gives the reason if nobody stopped it.
+func (sr *SegmentedRecording) addToHistory() {
+	sr.mu.Lock()
+	if sr.inHistory {
+		sr.mu.Unlock()
+		return
+	}
+	sr.inHistory = true
+	sr.compactSegments()
+	files := append([]SegmentInfo(nil), sr.segments...)
+	recordings := append([]*Recording(nil), sr.completed...)
+	if sr.currentRecording != nil {
+		recordings = append(recordings, sr.currentRecording)
+	}
+	session := RecordingSession{
+		ID:        sr.ID,
+		Stream:    sr.Stream,
+		Segmented: true,
+		Event:     sr.Config.Event,
+		Files:     []string{},
+		Start:     sr.StartTime,
+		State:     sr.State,
+		Reason:    sr.stopReason,
+	}
+	failed := sr.currentRecording
+	sr.mu.Unlock()
+
+	for _, recording := range recordings {
+		_ = recording.waitExited()
+		files = append(files, recording.completedSegments()...)
+	}
+
+	for _, file := range files {
+		session.Files = append(session.Files, file.Path)
+		session.Duration += file.EndTime.Sub(file.StartTime).Seconds()
+		if file.EndTime.After(session.Stop) {
+			session.Stop = file.EndTime
+		}
+	}
+	if session.Stop.IsZero() {
+		session.Stop = time.Now()
+	}
+
+	if failed != nil {
+		failed.mu.Lock()
+		if session.Reason == "" {
+			session.Reason = failed.exitReason()
+		}
+		session.ErrorCode, session.Error = failed.lastError.Code, failed.lastError.Error
+		failed.mu.Unlock()
+	}
+	if session.Reason == "" {
+		session.Reason = ExitEnded
+	}
+
+	addHistory(session)
+}
+
+// addInterruptedHistory records recordings the previous run didn't stop,
+// unless they are in the history already. The last write to the file is the
+// closest there is to the time they stopped.
+func addInterruptedHistory(recordings []*persistedRecording) {
+	var since time.Time
+	for _, rec := range recordings {
+		if since.IsZero() || rec.StartTime.Before(since) {
+			since = rec.StartTime
+		}
+	}
+
+	sessions, err := readHistory(HistoryFilter{Since: since}, 0)
+	if err != nil {
+		log.Warn().Err(err).Msg("[history] failed to read recording history")
+		return
+	}
+	recorded := make(map[string]bool, len(sessions))
+	for _, session := range sessions {
+		recorded[session.ID] = true
+	}
+
+	for _, rec := range recordings {
+		id, segmented := segmentedRecordingID(rec.ID)
+		if recorded[id] {
+			continue
+		}
+		recorded[id] = true
+
+		stop := rec.StartTime
+		if info, err := os.Stat(rec.Config.Filename); err == nil && info.ModTime().After(stop) {
+			stop = info.ModTime()
+		}
+		addHistory(RecordingSession{
+			ID:        id,
+			Stream:    rec.Stream,
+			Segmented: segmented,
+			Event:     rec.Config.Event,
+			Files:     []string{rec.Config.Filename},
+			Start:     rec.StartTime,
+			Stop:      stop,
+			Duration:  stop.Sub(rec.StartTime).Seconds(),
+			State:     StateFailed,
+			Reason:    ExitInterrupted,
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingHistory(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() {
+		setRecordingConfig(cfg)
+		history.trimmed = time.Time{}
+	})
+	setRecordingConfig(&RecordingConfig{
+		HistoryPath:      filepath.Join(t.TempDir(), "history"),
+		HistoryRetention: time.Hour * 24,
+	})
+	history.trimmed = time.Time{}
+
+	now := time.Now()
+	night := now.Add(-time.Hour * 12)
+
+	addHistory(RecordingSession{ID: "old", Stream: "cam1", Start: now.Add(-time.Hour * 72), Stop: now.Add(-time.Hour * 48), Reason: ExitStopped})
+	addHistory(RecordingSession{ID: "a", Stream: "cam1", Start: night, Stop: night.Add(time.Hour * 5), State: StateFailed, Reason: ExitFailed, Error: "Connection refused"})
+	addHistory(RecordingSession{ID: "b", Stream: "cam2", Start: night, Stop: now.Add(-time.Hour), Reason: ExitSchedule})
+	addFailedStartHistory("c", "cam1", RecordConfig{}, false, newStreamError("c", fmt.Errorf("%w: not found", errFFmpegUnavailable)))
+
+	sessions, err := readHistory(HistoryFilter{}, 0)
+	require.Nil(t, err)
+	require.Len(t, sessions, 4)
+	require.Equal(t, "c", sessions[0].ID)
+	require.Equal(t, ExitStartFailed, sessions[0].Reason)
+	require.Equal(t, ErrorCodeFFmpegMissing, sessions[0].ErrorCode)
+
+	sessions, err = readHistory(HistoryFilter{Stream: "cam1", Since: night.Add(time.Hour), Until: night.Add(time.Hour * 2)}, 0)
+	require.Nil(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, "Connection refused", sessions[0].Error)
+
+	sessions, err = readHistory(HistoryFilter{Reason: ExitSchedule}, 0)
+	require.Nil(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, "cam2", sessions[0].Stream)
+
+	sessions, err = readHistory(HistoryFilter{}, 2)
+	require.Nil(t, err)
+	require.Len(t, sessions, 2)
+
+	// The next day the session older than the retention is dropped
+	history.trimmed = time.Time{}
+	addHistory(RecordingSession{ID: "d", Stream: "cam1", Start: now, Stop: now, Reason: ExitEnded})
+	sessions, err = readHistory(HistoryFilter{ID: "old"}, 0)
+	require.Nil(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestRecordingExitReason(t *testing.T) {
+	r := NewRecording("rec", "cam1", RecordConfig{})
+
+	r.State = StateFailed
+	require.Equal(t, ExitFailed, r.exitReason())
+
+	r.State = StateFinalized
+	require.Equal(t, ExitEnded, r.exitReason())
+
+	// The first reason given wins, a kill after a stop doesn't replace it
+	r.setStopReason(ExitSchedule)
+	r.setStopReason(ExitRecovery)
+	require.Equal(t, ExitSchedule, r.exitReason())
+
+	id, segmented := segmentedRecordingID("manual_cam1_seg12")
+	require.Equal(t, "manual_cam1", id)
+	require.True(t, segmented)
+	id, segmented = segmentedRecordingID("auto_cam1_1736978400")
+	require.Equal(t, "auto_cam1_1736978400", id)
+	require.False(t, segmented)
+}
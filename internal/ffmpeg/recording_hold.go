@@ -0,0 +1,32 @@
+package ffmpeg
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// holdPath returns the hidden sidecar that marks a recording under legal
+// hold. The index keeps the hold too, the sidecar survives a lost or
+// rebuilt index and an unmounted disk the index dropped.
+func holdPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".hold")
+}
+
+// hasHold reports whether the recording at path has a hold sidecar
+func hasHold(path string) bool {
+	_, err := os.Stat(holdPath(path))
+	return err == nil
+}
+
+// writeHold creates or removes the hold sidecar of a recording
+func writeHold(path string, protected bool) error {
+	if protected {
+		return os.WriteFile(holdPath(path), nil, 0644)
+	}
+	if err := os.Remove(holdPath(path)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingHold(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+
+	local, usb := t.TempDir(), filepath.Join(t.TempDir(), "usb")
+	setRecordingConfig(&RecordingConfig{BasePaths: []string{local, usb}})
+
+	newIndex := func() *RecordingIndex {
+		idx := &RecordingIndex{
+			entries: make(map[string]*indexEntry),
+			byPath:  make(map[string]string),
+			aliases: make(map[string]string),
+		}
+		idx.once.Do(func() {}) // loaded
+		return idx
+	}
+
+	var paths []string
+	for _, root := range []string{local, usb} {
+		path := filepath.Join(root, "cam1", "cam1_2024-01-01_12-00-00.mp4")
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.Nil(t, os.WriteFile(path, []byte("recording"), 0644))
+		paths = append(paths, path)
+	}
+
+	idx := newIndex()
+	idx.Reconcile()
+	require.Equal(t, 2, idx.Len())
+
+	held := idx.GetByPath(paths[1])
+	require.NotNil(t, idx.setProtected(held.ID, true))
+	require.True(t, hasHold(paths[1]))
+
+	// An unmounted pool keeps its recordings
+	require.Nil(t, os.Rename(usb, usb+".unmounted"))
+	idx.Reconcile()
+	require.Equal(t, 2, idx.Len())
+	require.True(t, idx.isProtected(paths[1]))
+
+	// A rebuilt index gets the hold back from the sidecar
+	require.Nil(t, os.Rename(usb+".unmounted", usb))
+	idx = newIndex()
+	idx.Reconcile()
+	require.Equal(t, 2, idx.Len())
+	require.True(t, idx.isProtected(paths[1]))
+	require.False(t, idx.isProtected(paths[0]))
+	require.True(t, idx.GetByPath(paths[1]).Protected)
+
+	// The hold moves with the recording
+	moved := filepath.Join(local, "cam1", "cam1_2024-01-01_13-00-00.mp4")
+	require.Nil(t, moveRecordingFile(paths[1], moved))
+	require.True(t, hasHold(moved))
+	require.False(t, hasHold(paths[1]))
+
+	idx.Update(moved)
+	require.NotNil(t, idx.setProtected(idx.GetByPath(moved).ID, false))
+	require.False(t, hasHold(moved))
+	require.False(t, idx.isProtected(moved))
+}
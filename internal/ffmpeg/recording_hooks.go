@@ -0,0 +1,139 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hook names, passed to the command as RECORDING_HOOK
+const (
+	hookSegmentComplete   = "on_segment_complete"
+	hookRecordingComplete = "on_recording_complete"
+)
+
+// runSegmentHook runs the stream's on_segment_complete command for a finished file
+func runSegmentHook(streamName string, recording *RecordingFile) {
+	command := GetStreamRecordingConfig(streamName).OnSegmentComplete
+	if command == "" {
+		return
+	}
+
+	end := recording.EndTime
+	if end.IsZero() {
+		// Just closed files still count as active by their mtime
+		end = time.Now()
+	}
+
+	go runHook(hookSegmentComplete, command, map[string]string{
+		"RECORDING_ID":       recording.ID,
+		"RECORDING_STREAM":   streamName,
+		"RECORDING_PATH":     recording.Path,
+		"RECORDING_SIZE":     strconv.FormatInt(recording.Size, 10),
+		"RECORDING_DURATION": formatHookSeconds(end.Sub(recording.StartTime)),
+		"RECORDING_START":    recording.StartTime.Format(time.RFC3339),
+		"RECORDING_END":      end.Format(time.RFC3339),
+	})
+}
+
+// runRecordingHook runs the stream's on_recording_complete command once a
+// recording ended. Segmented recordings pass their directory as path and all
+// files in RECORDING_FILES.
+func runRecordingHook(r *Recording) {
+	command := GetStreamRecordingConfig(r.Stream).OnRecordingComplete
+	if command == "" {
+		return
+	}
+
+	var segments []SegmentInfo
+	var files []string
+	var size int64
+	for _, segment := range r.completedSegments() {
+		if info, err := os.Stat(segment.Path); err == nil {
+			segments = append(segments, segment)
+			files = append(files, segment.Path)
+			size += info.Size()
+		}
+	}
+	if len(segments) == 0 {
+		return // failed before writing anything
+	}
+
+	path := files[0]
+	if len(files) > 1 {
+		path = filepath.Dir(path)
+	}
+
+	start, end := segments[0].StartTime, segments[len(segments)-1].EndTime
+
+	go runHook(hookRecordingComplete, command, map[string]string{
+		"RECORDING_ID":       r.ID,
+		"RECORDING_STREAM":   r.Stream,
+		"RECORDING_PATH":     path,
+		"RECORDING_FILES":    strings.Join(files, string(os.PathListSeparator)),
+		"RECORDING_SIZE":     strconv.FormatInt(size, 10),
+		"RECORDING_DURATION": formatHookSeconds(end.Sub(start)),
+		"RECORDING_START":    start.Format(time.RFC3339),
+		"RECORDING_END":      end.Format(time.RFC3339),
+	})
+}
+
+// runHook runs a hook command through the shell with the recording details
+// in its environment and kills it after hook_timeout
+func runHook(hook, command string, env map[string]string) {
+	timeout := GetRecordingConfig().HookTimeout
+	if timeout <= 0 {
+		timeout = time.Minute * 5
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	cmd.Env = append(os.Environ(), "RECORDING_HOOK="+hook)
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	started := time.Now()
+	output, err := cmd.CombinedOutput()
+
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = ctx.Err()
+		}
+		log.Error().
+			Err(err).
+			Str("hook", hook).
+			Str("stream", env["RECORDING_STREAM"]).
+			Str("path", env["RECORDING_PATH"]).
+			Str("output", strings.TrimSpace(string(output))).
+			Msg("[recording] hook command failed")
+		return
+	}
+
+	log.Debug().
+		Str("hook", hook).
+		Str("stream", env["RECORDING_STREAM"]).
+		Str("path", env["RECORDING_PATH"]).
+		Dur("took", time.Since(started)).
+		Msg("[recording] hook command finished")
+}
+
+func formatHookSeconds(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return strconv.FormatFloat(d.Seconds(), 'f', 1, 64)
+}
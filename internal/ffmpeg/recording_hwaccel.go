@@ -0,0 +1,95 @@
+package ffmpeg
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/AlexxIT/go2rtc/internal/ffmpeg/hardware"
+	"github.com/AlexxIT/go2rtc/pkg/ffmpeg"
+)
+
+// hwaccelEngines maps the hwaccel names of the recording config to the
+// engines of go2rtc's transcoding templates
+var hwaccelEngines = map[string]string{
+	"auto":         "", // probe like #hardware without a value
+	"vaapi":        hardware.EngineVAAPI,
+	"nvenc":        hardware.EngineCUDA,
+	"cuda":         hardware.EngineCUDA,
+	"qsv":          hardware.EngineDXVA2,
+	"dxva2":        hardware.EngineDXVA2,
+	"v4l2m2m":      hardware.EngineV4L2M2M,
+	"rkmpp":        hardware.EngineRKMPP,
+	"videotoolbox": hardware.EngineVideoToolbox,
+}
+
+// recordingVideoArgs returns the input options, video codec options and
+// filters for a recording. Transcodes are moved to the GPU selected by the
+// stream's hwaccel the same way #hardware does for go2rtc's own transcodes.
+func recordingVideoArgs(video string, streamConfig StreamRecordingConfig) (input, codec, filters []string) {
+	if video == "copy" {
+		return nil, []string{"-c:v", "copy"}, nil
+	}
+
+	// The templates consist of options without spaces
+	encoder := defaults[video]
+	if encoder == "" {
+		encoder = "-c:v " + video
+	}
+
+	// Scaling goes through MakeHardware so it becomes scale_vaapi, scale_cuda...
+	if filter := scaleFilter(streamConfig); filter != "" {
+		filters = append(filters, filter)
+	}
+	output := outputQualityArgs(streamConfig)
+
+	name := strings.ToLower(streamConfig.HWAccel)
+	if name == "" {
+		return nil, append(strings.Fields(encoder), output...), filters
+	}
+
+	engine, ok := hwaccelEngines[name]
+	if !ok {
+		log.Warn().Str("hwaccel", streamConfig.HWAccel).Msg("[recording] unknown hwaccel, transcoding in software")
+		return nil, append(strings.Fields(encoder), output...), filters
+	}
+
+	args := &ffmpeg.Args{Bin: defaults["bin"], Codecs: []string{encoder}, Filters: filters}
+	hardware.MakeHardware(args, engine, defaults)
+
+	if args.Codecs[0] == encoder {
+		// Only libx264, libx265 and mjpeg templates have hardware versions
+		log.Warn().Str("hwaccel", name).Str("video", video).Msg("[recording] codec has no hardware encoder, transcoding in software")
+		return nil, append(strings.Fields(encoder), output...), filters
+	}
+
+	hwInput := args.Input
+
+	// Intel QSV without DXVA2 (Linux) decodes on the QSV device directly
+	if name == "qsv" && runtime.GOOS != "windows" {
+		hwInput = strings.Replace(hwInput, "-hwaccel dxva2 -hwaccel_output_format dxva2_vld", "-hwaccel qsv -hwaccel_output_format qsv", 1)
+		filters = nil
+		for _, filter := range args.Filters {
+			if filter != "hwmap=derive_device=qsv,format=qsv" {
+				filters = append(filters, filter)
+			}
+		}
+	} else {
+		filters = args.Filters
+	}
+
+	if device := streamConfig.HWAccelDevice; device != "" {
+		switch engine {
+		case hardware.EngineVAAPI, hardware.EngineCUDA:
+			input = []string{"-hwaccel_device", device}
+		case hardware.EngineDXVA2:
+			if runtime.GOOS != "windows" {
+				input = []string{"-qsv_device", device}
+			} else {
+				input = []string{"-hwaccel_device", device}
+			}
+		}
+	}
+
+	input = append(input, strings.Fields(hwInput)...)
+	return input, append(strings.Fields(args.Codecs[0]), output...), filters
+}
@@ -0,0 +1,91 @@
+package ffmpeg
+
+import (
+	"errors"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ImportPath is a directory of existing recordings, e.g. from an old NVR.
+// Its files are indexed and served like recordings, but never modified,
+// moved or deleted.
+type ImportPath struct {
+	Path     string            `yaml:"path" json:"path"`
+	Stream   string            `yaml:"stream" json:"stream,omitempty"`     // every file belongs to this stream, parsed from the path otherwise
+	Patterns []FilenamePattern `yaml:"patterns" json:"patterns,omitempty"` // tried before filename_patterns
+}
+
+// UnmarshalYAML accepts a plain directory or an object with options
+func (p *ImportPath) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*p = ImportPath{Path: node.Value}
+		return nil
+	case yaml.MappingNode:
+		type plain ImportPath
+		return node.Decode((*plain)(p))
+	}
+	return errors.New("import_paths entries must be a directory or {path, stream}")
+}
+
+// importRoots returns the import_paths directories
+func importRoots() []string {
+	roots := make([]string, 0, len(GetRecordingConfig().ImportPaths))
+	for _, imp := range GetRecordingConfig().ImportPaths {
+		roots = append(roots, imp.Path)
+	}
+	return roots
+}
+
+// importPathRel returns the import path containing path and the path
+// relative to it
+func importPathRel(path string) (*ImportPath, string, bool) {
+	for i := range GetRecordingConfig().ImportPaths {
+		imp := &GetRecordingConfig().ImportPaths[i]
+		if rel, ok := relWithin(path, imp.Path); ok {
+			return imp, rel, true
+		}
+	}
+	return nil, "", false
+}
+
+// isImportedRecording reports whether path is in one of the import_paths
+func isImportedRecording(path string) bool {
+	_, _, ok := importPathRel(path)
+	return ok
+}
+
+// isReadableRecordingPath reports whether path may be served, a recording
+// in the storage directories or the import paths
+func isReadableRecordingPath(path string) bool {
+	return isWithinBasePath(path) || isImportedRecording(path)
+}
+
+// validateImportPaths drops import paths that overlap the storage
+// directories, cleanup would delete their files
+func validateImportPaths(cfg *RecordingConfig) {
+	pools := storagePoolsOf(cfg)
+
+	var paths []ImportPath
+	for _, imp := range cfg.ImportPaths {
+		if imp.Path == "" {
+			continue
+		}
+		imp.Path = filepath.Clean(imp.Path)
+		imp.Patterns = compileFilenamePatterns(imp.Patterns, "import_paths patterns")
+
+		overlaps := false
+		for _, pool := range pools {
+			if pathWithin(imp.Path, pool) || pathWithin(pool, imp.Path) {
+				log.Warn().Str("import_path", imp.Path).Str("pool", pool).Msg("[recording] import path overlaps a storage directory, ignored")
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			paths = append(paths, imp)
+		}
+	}
+	cfg.ImportPaths = paths
+}
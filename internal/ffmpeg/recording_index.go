@@ -0,0 +1,1002 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// indexEntry is the persisted form of a recording file in the index
+type indexEntry struct {
+	ID        string           `json:"id"`
+	Path      string           `json:"path"`
+	Size      int64            `json:"size"`
+	ModTime   time.Time        `json:"mod_time"`
+	Probe     *RecordingInfo   `json:"probe,omitempty"`     // cached ffprobe result for this size/mtime
+	Upload    *UploadStatus    `json:"upload,omitempty"`    // offload to S3, reset when the file changes
+	Protected bool             `json:"protected,omitempty"` // legal hold, never deleted by cleanup
+	Start     *time.Time       `json:"start,omitempty"`     // exact span reported by the segment muxer
+	End       *time.Time       `json:"end,omitempty"`
+	LegacyID  string           `json:"legacy_id,omitempty"`  // ID before IDs were derived from the path
+	Health    *RecordingHealth `json:"health,omitempty"`     // integrity check result, reset when the file changes
+	Notes     []Annotation     `json:"notes,omitempty"`      // bookmarks and notes, kept when the file changes
+	Tags      []string         `json:"tags,omitempty"`       // labels set through the API, sorted
+	Labels    []string         `json:"labels,omitempty"`     // from the detection sidecar
+	LabelsMod int64            `json:"labels_mod,omitempty"` // mod time of the sidecar read in ns, zero without one
+
+	name     *recordingName   // parsed path, nil if outside the recordings directories
+	namedFor *RecordingConfig // config the path was parsed with
+}
+
+// RecordingIndex keeps an in-memory view of all recording files on disk so the
+// API can answer listings and ID lookups without walking the filesystem. The
+// index is persisted to IndexPath and kept up to date by the recorder managers,
+// the watcher of the storage pools and a periodic reconciliation routine.
+type RecordingIndex struct {
+	entries map[string]*indexEntry // recording ID -> entry
+	byPath  map[string]string      // file path -> recording ID
+	aliases map[string]string      // legacy ID -> recording ID, keeps old links working
+	dirty   bool
+	tracked bool             // count new data in metrics, off while loading existing files
+	named   *RecordingConfig // config all entries were parsed with
+	once    sync.Once
+	mu      sync.RWMutex
+	saveMu  sync.Mutex // one save at a time, the last snapshot is written last
+}
+
+var recordingIndex = &RecordingIndex{
+	entries: make(map[string]*indexEntry),
+	byPath:  make(map[string]string),
+	aliases: make(map[string]string),
+}
+
+// GetRecordingIndex returns the global recording index
+func GetRecordingIndex() *RecordingIndex {
+	return recordingIndex
+}
+
+// getIndexPath returns the location of the persisted index file
+func getIndexPath() string {
+	if GetRecordingConfig().IndexPath != "" {
+		return GetRecordingConfig().IndexPath
+	}
+	return filepath.Join(GetRecordingConfig().BasePath, ".recordings.index")
+}
+
+// indexRoutine loads the index and periodically reconciles it with the
+// filesystem, only every index_rescan_interval while the pools are watched
+func indexRoutine() {
+	// Watch first, changes during the initial load are applied after it
+	if GetRecordingConfig().IndexWatch {
+		indexWatch.start(append(storagePools(), importRoots()...))
+	}
+	recordingIndex.ensureLoaded()
+
+	interval := GetRecordingConfig().IndexInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastScan := time.Now()
+	for range ticker.C {
+		if !indexWatch.Active() || time.Since(lastScan) >= GetRecordingConfig().IndexRescanInterval {
+			recordingIndex.Reconcile()
+			lastScan = time.Now()
+		}
+		recordingIndex.Save()
+	}
+}
+
+// ensureLoaded loads the persisted index on first use, rebuilding it from
+// the filesystem if no index file exists yet
+func (idx *RecordingIndex) ensureLoaded() {
+	idx.once.Do(func() {
+		if err := idx.Load(); err != nil {
+			log.Info().Err(err).Str("path", getIndexPath()).Msg("[index] no usable index file, rebuilding")
+		}
+
+		// Pick up anything that changed while we were not running
+		idx.Reconcile()
+		idx.Save()
+
+		idx.mu.Lock()
+		idx.tracked = true
+		idx.mu.Unlock()
+	})
+}
+
+// Load reads the persisted index file
+func (idx *RecordingIndex) Load() error {
+	data, err := os.ReadFile(getIndexPath())
+	if err != nil {
+		return err
+	}
+
+	var entries []*indexEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.entries = make(map[string]*indexEntry, len(entries))
+	idx.byPath = make(map[string]string, len(entries))
+	idx.aliases = make(map[string]string)
+	idx.named = nil
+
+	var migrated int
+	for _, entry := range entries {
+		// Entries from older versions used time based IDs, switch them to
+		// path based ones and remember the old ID
+		if !strings.HasPrefix(entry.ID, generateRecordingID(entry.Path)) {
+			if entry.LegacyID == "" {
+				entry.LegacyID = entry.ID
+			}
+			entry.ID = ""
+			migrated++
+		}
+		if entry.ID == "" || idx.entries[entry.ID] != nil {
+			entry.ID = idx.newID(entry.Path)
+		}
+		idx.entries[entry.ID] = entry
+		idx.byPath[entry.Path] = entry.ID
+		if entry.LegacyID != "" {
+			idx.aliases[entry.LegacyID] = entry.ID
+		}
+	}
+	if migrated > 0 {
+		idx.markChanged()
+		log.Info().Int("entries", migrated).Msg("[index] migrated recording IDs to path based IDs")
+	}
+
+	log.Debug().Int("entries", len(entries)).Msg("[index] loaded recording index")
+	return nil
+}
+
+// markChanged marks the index for the next save and drops the cached
+// listings. Must be called with idx.mu held.
+func (idx *RecordingIndex) markChanged() {
+	idx.dirty = true
+	recordingListings.invalidate()
+}
+
+// Save writes the index to disk if it has changed since the last save
+func (idx *RecordingIndex) Save() {
+	idx.saveMu.Lock()
+	defer idx.saveMu.Unlock()
+
+	// Encode copies, the entries are changed in place under idx.mu
+	idx.mu.Lock()
+	if !idx.dirty {
+		idx.mu.Unlock()
+		return
+	}
+	entries := make([]indexEntry, 0, len(idx.entries))
+	for _, entry := range idx.entries {
+		entries = append(entries, *entry)
+	}
+	idx.dirty = false
+	idx.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		idx.saveFailed()
+		log.Error().Err(err).Msg("[index] failed to encode recording index")
+		return
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated index
+	path := getIndexPath()
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		idx.saveFailed()
+		log.Error().Err(err).Str("path", path).Msg("[index] failed to save recording index")
+	}
+}
+
+// saveFailed keeps the changes of a failed save for the next one
+func (idx *RecordingIndex) saveFailed() {
+	idx.mu.Lock()
+	idx.dirty = true
+	idx.mu.Unlock()
+}
+
+// Reconcile walks the storage pools and brings the index in line with the files on disk
+func (idx *RecordingIndex) Reconcile() {
+	seen := make(map[string]bool)
+	sidecars := make(map[string]time.Time) // path without extension -> mod time
+
+	var added, updated int
+	var unreadable []string
+
+	for _, basePath := range append(storagePools(), importRoots()...) {
+		// An unmounted or unreadable disk looks empty, its recordings must
+		// not be dropped together with their holds, tags and notes
+		if err := readableDir(basePath); err != nil {
+			log.Warn().Err(err).Str("path", basePath).Msg("[index] can't read storage path, keeping its recordings")
+			unreadable = append(unreadable, basePath)
+			continue
+		}
+
+		_ = filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil // Continue on errors
+			}
+			ext := strings.ToLower(filepath.Ext(path))
+			if ext == ".json" {
+				sidecars[strings.TrimSuffix(path, filepath.Ext(path))] = info.ModTime()
+				return nil
+			}
+			if !isVideoFile(ext) {
+				return nil
+			}
+
+			seen[path] = true
+			switch idx.put(path, info) {
+			case 1:
+				added++
+			case 2:
+				updated++
+			}
+			return nil
+		})
+	}
+
+	// Only sidecars written or removed since the last time are read
+	for path := range seen {
+		idx.updateLabels(path, sidecars[strings.TrimSuffix(path, filepath.Ext(path))])
+	}
+
+	idx.mu.Lock()
+	var removed int
+	for path, id := range idx.byPath {
+		if !seen[path] && !slices.ContainsFunc(unreadable, func(root string) bool { return pathWithin(path, root) }) {
+			delete(idx.entries, id)
+			delete(idx.byPath, path)
+			removed++
+		}
+	}
+	if removed > 0 {
+		idx.markChanged()
+	}
+	total := len(idx.entries)
+	idx.mu.Unlock()
+
+	if added > 0 || updated > 0 || removed > 0 {
+		log.Debug().
+			Int("added", added).
+			Int("updated", updated).
+			Int("removed", removed).
+			Int("total", total).
+			Msg("[index] reconciled recording index")
+	}
+}
+
+// readableDir returns an error if dir can't be listed
+func readableDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = f.Readdirnames(1); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// put inserts or refreshes a file in the index. Returns 0 if nothing
+// changed, 1 if the file was added and 2 if an existing entry was updated.
+func (idx *RecordingIndex) put(path string, info os.FileInfo) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if id, ok := idx.byPath[path]; ok {
+		entry := idx.entries[id]
+		if entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+			return 0
+		}
+		if idx.tracked {
+			recordingMetrics.addWritten(path, info.Size()-entry.Size, false)
+		}
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime()
+		entry.Probe = nil // file changed, probe again
+		entry.Upload = nil
+		entry.Health = nil
+		entry.parseName()
+		idx.markChanged()
+		return 2
+	}
+
+	entry := &indexEntry{
+		ID:        idx.newID(path),
+		Path:      path,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		Protected: hasHold(path),
+	}
+	entry.parseName()
+	idx.entries[entry.ID] = entry
+	idx.byPath[path] = entry.ID
+	idx.markChanged()
+	if idx.tracked {
+		recordingMetrics.addWritten(path, info.Size(), true)
+	}
+	return 1
+}
+
+// newID returns the ID for a new entry. Must be called with idx.mu held.
+func (idx *RecordingIndex) newID(path string) string {
+	id := generateRecordingID(path)
+	// A hash collision is very unlikely, but must not merge two recordings
+	for i := 2; idx.entries[id] != nil; i++ {
+		id = fmt.Sprintf("%s_%d", generateRecordingID(path), i)
+	}
+	return id
+}
+
+// Update refreshes a single file in the index, removing it if it no longer exists
+func (idx *RecordingIndex) Update(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		idx.Remove(path)
+		return
+	}
+	if info.IsDir() || !isVideoFile(strings.ToLower(filepath.Ext(path))) {
+		return
+	}
+	idx.put(path, info)
+	idx.updateLabels(path, sidecarModTime(path))
+}
+
+// UpdateSidecar reads the labels of a detection sidecar that was written or
+// removed into the entry of its recording
+func (idx *RecordingIndex) UpdateSidecar(sidecar string) {
+	base := strings.TrimSuffix(sidecar, filepath.Ext(sidecar))
+
+	idx.mu.RLock()
+	var path string
+	for ext := range videoExtensions {
+		for _, candidate := range []string{base + ext, base + strings.ToUpper(ext)} {
+			if _, ok := idx.byPath[candidate]; ok {
+				path = candidate
+			}
+		}
+	}
+	idx.mu.RUnlock()
+
+	if path != "" {
+		idx.updateLabels(path, sidecarModTime(path))
+	}
+}
+
+// updateLabels reads the detection labels of the recording at path if its
+// sidecar changed since they were read. modTime is the mod time of the
+// sidecar, zero if there is none.
+func (idx *RecordingIndex) updateLabels(path string, modTime time.Time) {
+	var mod int64
+	if !modTime.IsZero() {
+		mod = modTime.UnixNano()
+	}
+
+	idx.mu.RLock()
+	id, ok := idx.byPath[path]
+	if ok {
+		ok = idx.entries[id].LabelsMod != mod
+	}
+	idx.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	var labels []string
+	if mod != 0 {
+		labels = loadDetectionLabels(path)
+	}
+
+	idx.mu.Lock()
+	if entry, ok := idx.entries[id]; ok {
+		entry.Labels = labels
+		entry.LabelsMod = mod
+		idx.markChanged()
+	}
+	idx.mu.Unlock()
+}
+
+// UpdateDir refreshes all recording files in a single directory (non-recursive).
+// Used when a recorder exits, since segmented output names are only known to ffmpeg.
+func (idx *RecordingIndex) UpdateDir(dir string) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			idx.Update(filepath.Join(dir, file.Name()))
+		}
+	}
+}
+
+// move re-keys an entry after its file was moved, keeping its ID and state
+func (idx *RecordingIndex) move(oldPath, newPath string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	id, ok := idx.byPath[oldPath]
+	if !ok {
+		return
+	}
+	delete(idx.byPath, oldPath)
+	idx.byPath[newPath] = id
+	idx.entries[id].Path = newPath
+	idx.markChanged()
+}
+
+// Remove drops files from the index
+func (idx *RecordingIndex) Remove(paths ...string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, path := range paths {
+		if id, ok := idx.byPath[path]; ok {
+			if legacy := idx.entries[id].LegacyID; legacy != "" {
+				delete(idx.aliases, legacy)
+			}
+			delete(idx.entries, id)
+			delete(idx.byPath, path)
+			idx.markChanged()
+		}
+	}
+}
+
+// RemoveDir drops the files below a removed directory from the index
+func (idx *RecordingIndex) RemoveDir(dir string) {
+	prefix := dir + string(filepath.Separator)
+
+	idx.mu.RLock()
+	var paths []string
+	for path := range idx.byPath {
+		if strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+		}
+	}
+	idx.mu.RUnlock()
+
+	idx.Remove(paths...)
+}
+
+// entriesUnder returns copies of the entries of the files below dir
+func (idx *RecordingIndex) entriesUnder(dir string) []indexEntry {
+	idx.ensureLoaded()
+
+	prefix := dir + string(filepath.Separator)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var entries []indexEntry
+	for path, id := range idx.byPath {
+		if strings.HasPrefix(path, prefix) {
+			entries = append(entries, *idx.entries[id])
+		}
+	}
+	return entries
+}
+
+// Get returns the recording with the given ID, or nil if it is not indexed
+func (idx *RecordingIndex) Get(id string) *RecordingFile {
+	idx.ensureLoaded()
+
+	idx.mu.RLock()
+	entry, ok := idx.entries[idx.resolve(id)]
+	if ok {
+		copied := *entry
+		entry = &copied
+	}
+	idx.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+	return entry.recordingFile()
+}
+
+// resolve maps a legacy ID to the current one. Must be called with idx.mu held.
+func (idx *RecordingIndex) resolve(id string) string {
+	if current, ok := idx.aliases[id]; ok && idx.entries[id] == nil {
+		return current
+	}
+	return id
+}
+
+// cachedProbe returns the cached ffprobe result for the file, or nil if the
+// file is not indexed, was never probed or has changed since
+func (idx *RecordingIndex) cachedProbe(path string, size int64, modTime time.Time) *RecordingInfo {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	id, ok := idx.byPath[path]
+	if !ok {
+		return nil
+	}
+	entry := idx.entries[id]
+	if entry.Probe == nil || entry.Size != size || !entry.ModTime.Equal(modTime) {
+		return nil
+	}
+	return entry.Probe
+}
+
+// storeProbe caches an ffprobe result if the indexed file still matches size and mtime
+func (idx *RecordingIndex) storeProbe(path string, size int64, modTime time.Time, info *RecordingInfo) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	id, ok := idx.byPath[path]
+	if !ok {
+		return
+	}
+	if entry := idx.entries[id]; entry.Size == size && entry.ModTime.Equal(modTime) {
+		entry.Probe = info
+		idx.markChanged()
+	}
+}
+
+// setUpload stores the upload status of an indexed file
+func (idx *RecordingIndex) setUpload(path string, status *UploadStatus) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if id, ok := idx.byPath[path]; ok {
+		idx.entries[id].Upload = status
+		idx.markChanged()
+	}
+}
+
+// setHealth stores the integrity check result of an indexed file
+func (idx *RecordingIndex) setHealth(path string, health *RecordingHealth) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if id, ok := idx.byPath[path]; ok {
+		idx.entries[id].Health = health
+		idx.markChanged()
+	}
+}
+
+// uncheckedEntries returns copies of the entries modified since the given
+// time that have no integrity check result yet
+func (idx *RecordingIndex) uncheckedEntries(since time.Time) []indexEntry {
+	idx.ensureLoaded()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var entries []indexEntry
+	for _, entry := range idx.entries {
+		if entry.Health == nil && entry.ModTime.After(since) {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+// unprobedEntries returns copies of the entries without a cached probe or
+// exact span
+func (idx *RecordingIndex) unprobedEntries() []indexEntry {
+	idx.ensureLoaded()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var entries []indexEntry
+	for _, entry := range idx.entries {
+		if entry.Probe == nil && entry.Start == nil {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+// setSpan stores the exact start and end time of an indexed file
+func (idx *RecordingIndex) setSpan(path string, start, end time.Time) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if id, ok := idx.byPath[path]; ok {
+		entry := idx.entries[id]
+		entry.Start, entry.End = &start, &end
+		idx.markChanged()
+	}
+}
+
+// setProtected places or lifts a legal hold. Returns the updated recording,
+// or nil if it is not indexed.
+func (idx *RecordingIndex) setProtected(id string, protected bool) *RecordingFile {
+	idx.ensureLoaded()
+
+	idx.mu.Lock()
+	entry, ok := idx.entries[idx.resolve(id)]
+	var path string
+	if ok {
+		path = entry.Path
+		if entry.Protected != protected {
+			entry.Protected = protected
+			idx.markChanged()
+		}
+	}
+	idx.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	if err := writeHold(path, protected); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("[index] can't write legal hold")
+	}
+	return idx.Get(id)
+}
+
+// addAnnotation attaches a bookmark or note. Returns the updated recording,
+// or nil if it is not indexed.
+func (idx *RecordingIndex) addAnnotation(id string, annotation Annotation) *RecordingFile {
+	idx.ensureLoaded()
+
+	idx.mu.Lock()
+	entry, ok := idx.entries[idx.resolve(id)]
+	if ok {
+		// Sorted in a copy, copies handed out share the old backing array
+		notes := append(entry.Notes[:len(entry.Notes):len(entry.Notes)], annotation)
+		sort.SliceStable(notes, func(i, j int) bool {
+			return notes[i].Offset < notes[j].Offset
+		})
+		entry.Notes = notes
+		idx.markChanged()
+	}
+	idx.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return idx.Get(id)
+}
+
+// removeAnnotation deletes a bookmark or note and reports whether the
+// recording and the annotation were found
+func (idx *RecordingIndex) removeAnnotation(id, annotationID string) (recordingFound, found bool) {
+	idx.ensureLoaded()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entry, ok := idx.entries[idx.resolve(id)]
+	if !ok {
+		return false, false
+	}
+	for i, annotation := range entry.Notes {
+		if annotation.ID == annotationID {
+			entry.Notes = append(entry.Notes[:i:i], entry.Notes[i+1:]...)
+			idx.markChanged()
+			return true, true
+		}
+	}
+	return true, false
+}
+
+// setTags adds and removes tags. Returns the updated recording, or nil if it
+// is not indexed.
+func (idx *RecordingIndex) setTags(id string, add, remove []string) *RecordingFile {
+	idx.ensureLoaded()
+
+	idx.mu.Lock()
+	entry, ok := idx.entries[idx.resolve(id)]
+	if ok {
+		tags := make(map[string]bool, len(entry.Tags)+len(add))
+		for _, tag := range entry.Tags {
+			tags[tag] = true
+		}
+		for _, tag := range add {
+			tags[tag] = true
+		}
+		for _, tag := range remove {
+			delete(tags, tag)
+		}
+
+		// A new slice, copies handed out share the old backing array
+		var sorted []string
+		for tag := range tags {
+			sorted = append(sorted, tag)
+		}
+		sort.Strings(sorted)
+		entry.Tags = sorted
+		idx.markChanged()
+	}
+	idx.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return idx.Get(id)
+}
+
+// tagCounts returns how many recordings have each tag
+func (idx *RecordingIndex) tagCounts() map[string]int {
+	idx.ensureLoaded()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, entry := range idx.entries {
+		for _, tag := range entry.Tags {
+			counts[tag]++
+		}
+	}
+	return counts
+}
+
+// tagsOf returns the tags of the file at path
+func (idx *RecordingIndex) tagsOf(path string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if id, ok := idx.byPath[path]; ok {
+		return idx.entries[id].Tags
+	}
+	return nil
+}
+
+// isProtected reports whether the file at path is under legal hold or has a
+// tag that tag_retention keeps forever
+func (idx *RecordingIndex) isProtected(path string) bool {
+	idx.ensureLoaded()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if id, ok := idx.byPath[path]; ok {
+		entry := idx.entries[id]
+		return entry.Protected || retainedForever(entry.Tags)
+	}
+	return hasHold(path)
+}
+
+// uploadEntries returns copies of all entries with an upload status
+func (idx *RecordingIndex) uploadEntries() []indexEntry {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var entries []indexEntry
+	for _, entry := range idx.entries {
+		if entry.Upload != nil {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+// GetByPath returns the recording stored at path, or nil if it is not indexed
+func (idx *RecordingIndex) GetByPath(path string) *RecordingFile {
+	idx.mu.RLock()
+	id, ok := idx.byPath[path]
+	idx.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+	return idx.Get(id)
+}
+
+// Query returns indexed recordings matching the stream and date filters,
+// newest first, limited to limit results
+func (idx *RecordingIndex) Query(streamFilter, dateFilter string, limit int) []RecordingFile {
+	recordings, _ := idx.Find(RecordingQuery{Stream: streamFilter, Date: dateFilter, Limit: limit})
+	return recordings
+}
+
+// RecordingQuery filters, sorts and pages the recordings list
+type RecordingQuery struct {
+	Stream    string
+	Date      string    // YYYY-MM-DD of the start time
+	From      time.Time // recordings overlapping [From, To]
+	To        time.Time
+	Note      string   // text in one of the recording's annotations, case-insensitive
+	Tags      []string // all of these tags
+	Sort      string   // start_time (default), size or duration
+	Ascending bool     // default newest/largest/longest first
+	Offset    int
+	Limit     int
+}
+
+// Find returns one page of matching recordings and the number of matches,
+// from the listing cache if the same query was answered recently
+func (idx *RecordingIndex) Find(q RecordingQuery) ([]RecordingFile, int) {
+	idx.ensureLoaded()
+
+	now := time.Now()
+	key := listingKey(q)
+	if recordings, total, ok := recordingListings.get(key, now); ok {
+		return recordings, total
+	}
+
+	idx.parseNames()
+
+	idx.mu.RLock()
+	generation := recordingListings.currentGeneration()
+	entries := make([]indexEntry, 0, len(idx.entries))
+	for _, entry := range idx.entries {
+		entries = append(entries, *entry)
+	}
+	idx.mu.RUnlock()
+
+	// Filter on the parsed entries, only matches are turned into recordings
+	recordings := make([]RecordingFile, 0, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		name := entry.currentName()
+		if name == nil {
+			continue
+		}
+		if q.Stream != "" && name.stream != q.Stream {
+			continue
+		}
+		start, end := entry.span(name)
+		if q.Date != "" && start.Format("2006-01-02") != q.Date {
+			continue
+		}
+		if len(q.Tags) > 0 && !hasTags(entry.Tags, q.Tags) {
+			continue
+		}
+		if q.Note != "" && !hasAnnotation(entry.Notes, q.Note) {
+			continue
+		}
+		if !q.To.IsZero() && start.After(q.To) {
+			continue
+		}
+		if !q.From.IsZero() {
+			if end.IsZero() {
+				end = time.Now() // still being written
+			}
+			if end.Before(q.From) {
+				continue
+			}
+		}
+		recordings = append(recordings, *entry.recordingFile())
+	}
+
+	var less func(a, b *RecordingFile) bool
+	switch q.Sort {
+	case "size":
+		less = func(a, b *RecordingFile) bool { return a.Size < b.Size }
+	case "duration":
+		less = func(a, b *RecordingFile) bool { return a.DurationSeconds < b.DurationSeconds }
+	default:
+		less = func(a, b *RecordingFile) bool { return a.StartTime.Before(b.StartTime) }
+	}
+
+	// Ties by start time, so pages stay stable between requests
+	sort.SliceStable(recordings, func(i, j int) bool {
+		a, b := &recordings[i], &recordings[j]
+		if q.Ascending {
+			a, b = b, a
+		}
+		if less(b, a) {
+			return true
+		}
+		if less(a, b) {
+			return false
+		}
+		return b.StartTime.Before(a.StartTime) || b.StartTime.Equal(a.StartTime) && b.ID < a.ID
+	})
+
+	total := len(recordings)
+
+	switch {
+	case q.Offset >= len(recordings) && q.Offset > 0:
+		recordings = []RecordingFile{}
+	case q.Offset > 0:
+		recordings = recordings[q.Offset:]
+	}
+	if q.Limit > 0 && len(recordings) > q.Limit {
+		recordings = recordings[:q.Limit]
+	}
+
+	recordingListings.put(key, generation, recordings, total, now)
+
+	return recordings, total
+}
+
+// parseNames parses the paths of all entries again after a config change,
+// like new stream_skip_dirs or filename_patterns
+func (idx *RecordingIndex) parseNames() {
+	cfg := GetRecordingConfig()
+
+	idx.mu.RLock()
+	named := idx.named == cfg
+	idx.mu.RUnlock()
+	if named {
+		return
+	}
+
+	idx.mu.Lock()
+	for _, entry := range idx.entries {
+		if entry.namedFor != cfg {
+			entry.parseName()
+		}
+	}
+	idx.named = cfg
+	idx.mu.Unlock()
+}
+
+// Len returns the number of indexed recordings
+func (idx *RecordingIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.entries)
+}
+
+// recordingFile converts an index entry to the API representation
+func (e *indexEntry) recordingFile() *RecordingFile {
+	name := e.currentName()
+	if name == nil {
+		return nil
+	}
+	recording := name.recordingFile(e.ID, e.Path, e.Size, e.ModTime)
+	recording.DetectionLabels = e.Labels
+	recording.Upload = e.Upload
+	recording.Protected = e.Protected
+	recording.Health = e.Health
+	recording.Annotations = e.Notes
+	recording.Tags = e.Tags
+	if e.Start != nil && e.End != nil {
+		recording.StartTime, recording.EndTime = *e.Start, *e.End
+		recording.DurationSeconds = e.End.Sub(*e.Start).Seconds()
+	} else if e.Probe != nil {
+		// Probed by ?exact=true, probe_durations or an info request
+		setProbedDuration(recording, e.Probe.Duration)
+	} else if e.Health != nil {
+		setProbedDuration(recording, e.Health.Duration)
+	}
+	return recording
+}
+
+// parseName parses the path of the entry with the current config
+func (e *indexEntry) parseName() {
+	e.namedFor = GetRecordingConfig()
+	e.name, _ = nameRecording(e.Path, e.ModTime)
+}
+
+// currentName returns the parsed path, parsing it again if the config changed
+func (e *indexEntry) currentName() *recordingName {
+	if e.namedFor == GetRecordingConfig() {
+		return e.name
+	}
+	name, _ := nameRecording(e.Path, e.ModTime)
+	return name
+}
+
+// span returns the start and end of the recording, preferring the exact span
+// reported by the segment muxer
+func (e *indexEntry) span(name *recordingName) (time.Time, time.Time) {
+	if e.Start != nil && e.End != nil {
+		return *e.Start, *e.End
+	}
+	return name.span(e.Size, e.ModTime)
+}
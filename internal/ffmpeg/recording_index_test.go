@@ -0,0 +1,163 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestIndex returns an empty index with one recording
+func newTestIndex(t *testing.T) (*RecordingIndex, string) {
+	idx := &RecordingIndex{
+		entries: make(map[string]*indexEntry),
+		byPath:  make(map[string]string),
+		aliases: make(map[string]string),
+	}
+	idx.once.Do(func() {}) // loaded
+
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	dir := t.TempDir()
+	setRecordingConfig(&RecordingConfig{BasePath: dir})
+
+	path := filepath.Join(dir, "cam1", "cam1_2024-01-01_12-00-00.mp4")
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.Nil(t, os.WriteFile(path, []byte("recording"), 0644))
+	idx.Update(path)
+	return idx, idx.GetByPath(path).ID
+}
+
+func TestRecordingIndexTagsCopy(t *testing.T) {
+	idx, id := newTestIndex(t)
+
+	before := idx.setTags(id, []string{"person", "vehicle"}, nil)
+	require.Equal(t, []string{"person", "vehicle"}, before.Tags)
+
+	after := idx.setTags(id, []string{"car"}, []string{"vehicle"})
+	require.Equal(t, []string{"car", "person"}, after.Tags)
+	require.Equal(t, []string{"person", "vehicle"}, before.Tags) // not changed in place
+
+	require.Nil(t, idx.setTags(id, nil, []string{"car", "person"}).Tags)
+	require.Equal(t, []string{"car", "person"}, after.Tags)
+}
+
+func TestRecordingIndexAnnotationsCopy(t *testing.T) {
+	idx, id := newTestIndex(t)
+
+	idx.addAnnotation(id, Annotation{ID: "b", Offset: 20})
+	before := idx.addAnnotation(id, Annotation{ID: "c", Offset: 30}).Annotations
+	require.Len(t, before, 2)
+
+	after := idx.addAnnotation(id, Annotation{ID: "a", Offset: 10}).Annotations
+	require.Equal(t, []string{"a", "b", "c"}, []string{after[0].ID, after[1].ID, after[2].ID})
+	require.Equal(t, []string{"b", "c"}, []string{before[0].ID, before[1].ID}) // not sorted in place
+
+	idx.addAnnotation(id, Annotation{ID: "0", Offset: 0})
+	require.Equal(t, []string{"a", "b", "c"}, []string{after[0].ID, after[1].ID, after[2].ID})
+
+	_, found := idx.removeAnnotation(id, "a")
+	require.True(t, found)
+	require.Equal(t, "a", after[0].ID)
+}
+
+func TestRecordingIndexFind(t *testing.T) {
+	idx, id := newTestIndex(t)
+	path := idx.Get(id).Path
+	dir := filepath.Dir(filepath.Dir(path))
+
+	other := filepath.Join(dir, "cam2", "cam2_2024-01-02_12-00-00.mp4")
+	require.Nil(t, os.MkdirAll(filepath.Dir(other), 0755))
+	require.Nil(t, os.WriteFile(other, []byte("recording"), 0644))
+	idx.Update(other)
+
+	find := func(q RecordingQuery) []string {
+		recordings, _ := idx.Find(q)
+		var ids []string
+		for _, recording := range recordings {
+			ids = append(ids, recording.StreamName+" "+recording.DateGroup+" "+strings.Join(recording.DetectionLabels, ","))
+		}
+		return ids
+	}
+
+	require.Equal(t, []string{"cam2 2024-01-02 ", "cam1 2024-01-01 "}, find(RecordingQuery{}))
+	require.Equal(t, []string{"cam1 2024-01-01 "}, find(RecordingQuery{Stream: "cam1"}))
+	require.Equal(t, []string{"cam2 2024-01-02 "}, find(RecordingQuery{Date: "2024-01-02"}))
+
+	// Labels are read once into the index, not for every listing
+	sidecar := detectionSidecar(path)
+	require.Nil(t, os.WriteFile(sidecar, []byte(`{"labels": ["person"]}`), 0644))
+	idx.UpdateSidecar(sidecar)
+	require.Equal(t, []string{"cam1 2024-01-01 person"}, find(RecordingQuery{Stream: "cam1"}))
+
+	require.Nil(t, os.WriteFile(sidecar, []byte(`{"labels": ["car"]}`), 0644))
+	require.Equal(t, []string{"cam1 2024-01-01 person"}, find(RecordingQuery{Stream: "cam1"}))
+	require.Nil(t, os.Chtimes(sidecar, time.Now(), time.Now().Add(time.Minute)))
+	idx.Reconcile()
+	require.Equal(t, []string{"cam1 2024-01-01 car"}, find(RecordingQuery{Stream: "cam1"}))
+
+	require.Nil(t, os.Remove(sidecar))
+	idx.Update(path)
+	require.Equal(t, []string{"cam1 2024-01-01 "}, find(RecordingQuery{Stream: "cam1"}))
+
+	// Paths are parsed again after a config change
+	setRecordingConfig(&RecordingConfig{BasePath: dir, StreamSkipDirs: []string{"cam1", "cam2"}})
+	require.Empty(t, find(RecordingQuery{Stream: "cam1"}))
+	require.Len(t, find(RecordingQuery{Stream: filepath.Base(dir)}), 2)
+}
+
+func TestRecordingIndexSaveConcurrent(t *testing.T) {
+	idx, id := newTestIndex(t)
+	path := idx.Get(id).Path
+
+	// Saves run until all changes are made
+	done := make(chan struct{})
+	var saves sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		saves.Add(1)
+		go func() {
+			defer saves.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					idx.Save()
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				tag := fmt.Sprintf("tag%d", i)
+				idx.setTags(id, []string{tag}, nil)
+				idx.addAnnotation(id, Annotation{ID: fmt.Sprintf("%d-%d", i, j), Offset: float64(j)})
+				idx.setSpan(path, time.Now(), time.Now())
+				idx.setTags(id, nil, []string{tag})
+				runtime.Gosched()
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(done)
+	saves.Wait()
+	idx.Save()
+
+	// The last save has every change
+	loaded := &RecordingIndex{}
+	require.Nil(t, loaded.Load())
+	require.Len(t, loaded.Get(id).Annotations, 200)
+	require.Empty(t, loaded.Get(id).Tags)
+}
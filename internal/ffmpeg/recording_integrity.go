@@ -0,0 +1,186 @@
+package ffmpeg
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// Integrity check results
+const (
+	HealthOK       = "ok"
+	HealthCorrupt  = "corrupt"
+	HealthRepaired = "repaired"
+)
+
+// RecordingHealth is the result of the last integrity check of a file
+type RecordingHealth struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`    // what was wrong, also kept after a repair
+	Duration  float64   `json:"duration,omitempty"` // probed duration in seconds
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// integritySettle is how long a file must be unchanged before it is checked,
+// so files still being written are left alone
+const integritySettle = time.Minute * 2
+
+// integrityCheckRoutine probes new files every integrity_check_interval
+func integrityCheckRoutine() {
+	interval := GetRecordingConfig().IntegrityCheckInterval
+
+	log.Info().
+		Dur("interval", interval).
+		Dur("window", GetRecordingConfig().IntegrityCheckWindow).
+		Bool("repair", GetRecordingConfig().IntegrityRepair).
+		Msg("[integrity] checking recent recordings")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		runIntegrityCheck()
+		<-ticker.C
+	}
+}
+
+// runIntegrityCheck checks every recent file without a result. Zero-duration
+// and unreadable files (typical after a crash or power loss) are flagged and,
+// with integrity_repair, remuxed.
+func runIntegrityCheck() {
+	since := time.Time{}
+	if window := GetRecordingConfig().IntegrityCheckWindow; window > 0 {
+		since = time.Now().Add(-window)
+	}
+
+	var checked, corrupt, repaired int
+
+	for _, entry := range recordingIndex.uncheckedEntries(since) {
+		if time.Since(entry.ModTime) < integritySettle {
+			continue
+		}
+
+		var health *RecordingHealth
+		var err error
+		if GetRecordingConfig().IntegrityRepair && !entry.Protected && !isImportedRecording(entry.Path) {
+			health, err = repairCorruptRecording(entry.Path)
+		} else {
+			health, err = checkRecordingIntegrity(entry.Path)
+			if err == nil {
+				recordingIndex.setHealth(entry.Path, health)
+			}
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("[integrity] ffprobe not available, skipping integrity check")
+			return
+		}
+
+		checked++
+		switch health.Status {
+		case HealthCorrupt:
+			corrupt++
+			log.Warn().Str("file", entry.Path).Str("error", health.Error).Msg("[integrity] corrupt recording")
+		case HealthRepaired:
+			repaired++
+			log.Info().Str("file", entry.Path).Str("error", health.Error).Msg("[integrity] repaired recording")
+		}
+	}
+
+	if checked > 0 {
+		log.Debug().
+			Int("checked", checked).
+			Int("corrupt", corrupt).
+			Int("repaired", repaired).
+			Msg("[integrity] integrity check finished")
+	}
+}
+
+// checkRecordingIntegrity probes a file. The error is only set when ffprobe
+// itself can't run.
+func checkRecordingIntegrity(path string) (*RecordingHealth, error) {
+	health := &RecordingHealth{Status: HealthOK, CheckedAt: time.Now()}
+
+	info, err := getRecordingDetailedInfo(&RecordingFile{Path: path})
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return nil, err
+	case err != nil:
+		health.Status, health.Error = HealthCorrupt, "unreadable"
+	case info.Duration <= 0:
+		health.Status, health.Error = HealthCorrupt, "zero duration"
+	default:
+		health.Duration = info.Duration
+	}
+
+	return health, nil
+}
+
+// repairCorruptRecording checks a file and remuxes its readable part if it is
+// corrupt. The result is stored in the index.
+func repairCorruptRecording(path string) (*RecordingHealth, error) {
+	health, err := checkRecordingIntegrity(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if health.Status == HealthCorrupt {
+		if err = remuxReadable(path); err != nil {
+			health.Error += ", repair failed: " + err.Error()
+		} else {
+			recordingIndex.Update(path) // size and mtime changed
+
+			problem := health.Error
+			if health, err = checkRecordingIntegrity(path); err != nil {
+				return nil, err
+			}
+			if health.Status == HealthOK {
+				health.Status = HealthRepaired
+			}
+			health.Error = problem
+		}
+	}
+
+	recordingIndex.setHealth(path, health)
+	return health, nil
+}
+
+// handleRepairRecording checks a recording and repairs it if it is corrupt:
+//
+//	POST /api/recordings?repair=ID
+func handleRepairRecording(w http.ResponseWriter, query map[string][]string) {
+	id := getQueryParam(query, "repair")
+
+	recording := recordingIndex.Get(id)
+	if recording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+	if recording.Protected {
+		http.Error(w, "Recording is protected", http.StatusConflict)
+		return
+	}
+	if recording.Imported {
+		http.Error(w, "Imported recordings are read-only", http.StatusForbidden)
+		return
+	}
+	if stat, err := os.Stat(recording.Path); err != nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	} else if time.Since(stat.ModTime()) < integritySettle {
+		http.Error(w, "Recording is still being written", http.StatusConflict)
+		return
+	}
+
+	if _, err := repairCorruptRecording(recording.Path); err != nil {
+		http.Error(w, "ffprobe not available: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Info().Str("recording_id", id).Msg("[api] recording integrity checked")
+
+	api.ResponseJSON(w, recordingIndex.GetByPath(recording.Path))
+}
@@ -0,0 +1,255 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job types
+const (
+	JobExport     = "export"     // clip stored in export_path, ?async=true of the export API
+	JobMerge      = "merge"      // ?async=true of the merge API
+	JobRestore    = "restore"    // recording moved back from archive_path
+	JobThumbnails = "thumbnails" // thumbnails of the recordings of a stream
+)
+
+const (
+	maxQueuedJobs   = 100 // jobs waiting for a worker, more are refused
+	maxFinishedJobs = 100 // finished jobs kept for the API
+)
+
+// Job is a heavy operation that runs in the background instead of in the
+// HTTP request that started it
+type Job struct {
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Stream   string    `json:"stream,omitempty"`
+	State    string    `json:"state"`
+	Progress float64   `json:"progress"` // 0 to 1
+	Created  time.Time `json:"created"`
+	Started  time.Time `json:"started,omitempty"`
+	Finished time.Time `json:"finished,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Result   any       `json:"result,omitempty"` // the response the request would have had
+
+	run    jobFunc
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// jobFunc does the work of a job, reporting the part done from 0 to 1
+type jobFunc func(ctx context.Context, progress func(float64)) (any, error)
+
+// jobQueue runs at most max_jobs jobs at once, the others wait in order
+type jobQueue struct {
+	jobs    map[string]*Job
+	queue   []*Job
+	running int
+	mu      sync.Mutex
+}
+
+var jobs = &jobQueue{jobs: make(map[string]*Job)}
+
+var (
+	errJobQueueFull = errors.New("too many jobs queued, try again later")
+	errJobNotFound  = errors.New("job not found")
+	errJobFinished  = errors.New("job already finished")
+)
+
+// submit queues a job and starts it if a worker is free
+func (q *jobQueue) submit(typ, streamName string, run jobFunc) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.queue) >= maxQueuedJobs {
+		return Job{}, errJobQueueFull
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:      typ + "_" + strconv.FormatInt(now.UnixNano(), 36),
+		Type:    typ,
+		Stream:  streamName,
+		State:   JobQueued,
+		Created: now,
+		run:     run,
+	}
+	job.ctx, job.cancel = context.WithCancel(context.Background())
+
+	q.jobs[job.ID] = job
+	q.queue = append(q.queue, job)
+	q.startLocked()
+
+	log.Debug().Str("job", job.ID).Str("stream", streamName).Msg("[jobs] job queued")
+
+	return *job, nil
+}
+
+// startLocked starts queued jobs while workers are free. max_jobs is read
+// every time, so a change applies to the next job.
+func (q *jobQueue) startLocked() {
+	for len(q.queue) > 0 && q.running < max(GetRecordingConfig().MaxJobs, 1) {
+		job := q.queue[0]
+		q.queue = q.queue[1:]
+
+		job.State = JobRunning
+		job.Started = time.Now()
+		q.running++
+
+		go q.run(job)
+	}
+}
+
+func (q *jobQueue) run(job *Job) {
+	result, err := job.run(job.ctx, func(progress float64) {
+		q.mu.Lock()
+		job.Progress = min(max(progress, 0), 1)
+		q.mu.Unlock()
+	})
+	cancelled := job.ctx.Err() != nil
+	job.cancel()
+
+	q.mu.Lock()
+	job.Finished = time.Now()
+	switch {
+	case err != nil && cancelled:
+		job.State = JobCancelled
+	case err != nil:
+		job.State, job.Error = JobFailed, err.Error()
+	default:
+		job.State, job.Progress, job.Result = JobDone, 1, result
+	}
+	finished := *job
+
+	q.running--
+	q.trimLocked()
+	q.startLocked()
+	q.mu.Unlock()
+
+	if finished.State == JobFailed {
+		log.Warn().Str("job", finished.ID).Str("stream", finished.Stream).Str("error", finished.Error).Msg("[jobs] job failed")
+	} else {
+		log.Info().Str("job", finished.ID).Str("stream", finished.Stream).Str("state", finished.State).
+			Dur("took", finished.Finished.Sub(finished.Started)).Msg("[jobs] job finished")
+	}
+
+	notify(NotifyJobFinished, finished.Stream, finished)
+}
+
+// trimLocked forgets the oldest finished jobs over maxFinishedJobs
+func (q *jobQueue) trimLocked() {
+	var finished []*Job
+	for _, job := range q.jobs {
+		if !job.Finished.IsZero() {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].Finished.Before(finished[j].Finished)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(q.jobs, job.ID)
+	}
+}
+
+// Cancel removes a queued job or stops a running one, which then ends as
+// cancelled once its ffmpeg exited
+func (q *jobQueue) Cancel(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job := q.jobs[id]
+	switch {
+	case job == nil:
+		return Job{}, errJobNotFound
+	case !job.Finished.IsZero():
+		return *job, errJobFinished
+	}
+
+	job.cancel()
+
+	if job.State == JobQueued {
+		for i, queued := range q.queue {
+			if queued == job {
+				q.queue = append(q.queue[:i], q.queue[i+1:]...)
+				break
+			}
+		}
+		job.State = JobCancelled
+		job.Finished = time.Now()
+	}
+
+	log.Info().Str("job", id).Msg("[jobs] job cancelled")
+
+	return *job, nil
+}
+
+// Get returns a job by its ID
+func (q *jobQueue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if job := q.jobs[id]; job != nil {
+		return *job, true
+	}
+	return Job{}, false
+}
+
+// List returns the jobs, newest first
+func (q *jobQueue) List() []Job {
+	q.mu.Lock()
+	list := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		list = append(list, *job)
+	}
+	q.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.After(list[j].Created)
+	})
+	return list
+}
+
+// CancelAll cancels the queued and running jobs on shutdown
+func (q *jobQueue) CancelAll() {
+	for _, job := range q.List() {
+		if job.Finished.IsZero() {
+			_, _ = q.Cancel(job.ID)
+		}
+	}
+}
+
+// waitTranscodeSlot waits for a max_transcodes slot, jobs queue behind
+// downloads and playbacks instead of failing
+func waitTranscodeSlot(ctx context.Context) (release func(), err error) {
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+
+	for {
+		if release, ok := acquireTranscodeDownload(); ok {
+			return release, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobQueue(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{MaxJobs: 1})
+
+	q := &jobQueue{jobs: make(map[string]*Job)}
+
+	waitState := func(id, state string) Job {
+		var job Job
+		require.Eventually(t, func() bool {
+			job, _ = q.Get(id)
+			return job.State == state
+		}, time.Second, time.Millisecond*10)
+		return job
+	}
+
+	release := make(chan struct{})
+	first, err := q.submit(JobExport, "cam1", func(ctx context.Context, progress func(float64)) (any, error) {
+		progress(0.5)
+		<-release
+		return "clip.mp4", nil
+	})
+	require.Nil(t, err)
+
+	// max_jobs 1, the second job waits for the first
+	second, err := q.submit(JobMerge, "cam2", func(ctx context.Context, progress func(float64)) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.Nil(t, err)
+	third, err := q.submit(JobThumbnails, "cam1", func(ctx context.Context, progress func(float64)) (any, error) {
+		return nil, errors.New("no ffmpeg")
+	})
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		job, _ := q.Get(first.ID)
+		return job.Progress == 0.5
+	}, time.Second, time.Millisecond*10)
+	job, _ := q.Get(second.ID)
+	require.Equal(t, JobQueued, job.State)
+
+	// A queued job is cancelled right away and never runs
+	job, err = q.Cancel(third.ID)
+	require.Nil(t, err)
+	require.Equal(t, JobCancelled, job.State)
+
+	close(release)
+	job = waitState(first.ID, JobDone)
+	require.Equal(t, "clip.mp4", job.Result)
+	require.Equal(t, 1.0, job.Progress)
+
+	// A running job ends as cancelled once its work returns
+	waitState(second.ID, JobRunning)
+	_, err = q.Cancel(second.ID)
+	require.Nil(t, err)
+	waitState(second.ID, JobCancelled)
+
+	_, err = q.Cancel(first.ID)
+	require.ErrorIs(t, err, errJobFinished)
+	_, err = q.Cancel("missing")
+	require.ErrorIs(t, err, errJobNotFound)
+
+	failed, err := q.submit(JobRestore, "cam1", func(ctx context.Context, progress func(float64)) (any, error) {
+		return nil, errors.New("archive unavailable")
+	})
+	require.Nil(t, err)
+	job = waitState(failed.ID, JobFailed)
+	require.Equal(t, "archive unavailable", job.Error)
+
+	require.Len(t, q.List(), 4)
+	require.Equal(t, failed.ID, q.List()[0].ID)
+}
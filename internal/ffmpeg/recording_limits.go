@@ -0,0 +1,164 @@
+package ffmpeg
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recordingStartSettle is how long a start slot is held, ffmpeg connects to
+// the camera and probes the input in its first seconds
+const recordingStartSettle = time.Second * 5
+
+// errNoRecordingSlot is returned when a recording waited start_queue_timeout
+// for max_recording_processes or max_concurrent_starts
+var errNoRecordingSlot = errors.New("recording limit reached, no free slot")
+
+// recordingLimiter is a counting semaphore. Waiters are served by stream
+// priority, highest first, and in arrival order otherwise. The limit is read
+// on every change, so config reloads apply right away.
+type recordingLimiter struct {
+	limit   func() int // 0 = unlimited
+	active  int
+	waiters []*slotWaiter
+	mu      sync.Mutex
+}
+
+type slotWaiter struct {
+	stream   string
+	priority int
+	ready    chan struct{}
+}
+
+var (
+	recordingProcesses = &recordingLimiter{limit: func() int { return GetRecordingConfig().MaxRecordingProcesses }}
+	recordingStarts    = &recordingLimiter{limit: func() int { return GetRecordingConfig().MaxConcurrentStarts }}
+)
+
+// acquireRecordingSlots waits for a process and a start slot for a new
+// recording of a stream. The start slot frees itself once ffmpeg has
+// settled, release frees the process slot when the recording has finished.
+func acquireRecordingSlots(streamName string) (release func(), err error) {
+	streamConfig := GetStreamRecordingConfig(streamName)
+	if isNativeRecorder(streamConfig) {
+		return func() {}, nil // no ffmpeg process
+	}
+
+	deadline := time.Now().Add(GetRecordingConfig().StartQueueTimeout)
+
+	if err = recordingProcesses.acquire(streamName, streamConfig.Priority, time.Until(deadline)); err != nil {
+		return nil, err
+	}
+	if err = recordingStarts.acquire(streamName, streamConfig.Priority, time.Until(deadline)); err != nil {
+		recordingProcesses.release()
+		return nil, err
+	}
+	time.AfterFunc(recordingStartSettle, recordingStarts.release)
+
+	var once sync.Once
+	return func() { once.Do(recordingProcesses.release) }, nil
+}
+
+// acquire takes a slot, waiting up to timeout behind the streams queued
+// with the same or a higher priority
+func (l *recordingLimiter) acquire(streamName string, priority int, timeout time.Duration) error {
+	l.mu.Lock()
+	l.grant()
+	if limit := l.limit(); limit <= 0 || (l.active < limit && len(l.waiters) == 0) {
+		l.active++
+		l.mu.Unlock()
+		return nil
+	}
+	if timeout <= 0 {
+		l.mu.Unlock()
+		return errNoRecordingSlot
+	}
+
+	waiter := &slotWaiter{stream: streamName, priority: priority, ready: make(chan struct{})}
+	i := sort.Search(len(l.waiters), func(i int) bool { return l.waiters[i].priority < priority })
+	l.waiters = append(l.waiters[:i], append([]*slotWaiter{waiter}, l.waiters[i:]...)...)
+	queued := len(l.waiters)
+	l.mu.Unlock()
+
+	log.Debug().
+		Str("stream", streamName).
+		Int("priority", priority).
+		Int("queued", queued).
+		Msg("[recording] waiting for a recording slot")
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-timer.C:
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil // granted while timing out
+	default:
+	}
+	for i, w := range l.waiters {
+		if w == waiter {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			break
+		}
+	}
+	return errNoRecordingSlot
+}
+
+// available reports whether a slot is free without waiting, so periodic
+// checks don't queue
+func (l *recordingLimiter) available() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limit()
+	return limit <= 0 || (l.active < limit && len(l.waiters) == 0)
+}
+
+func (l *recordingLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active > 0 {
+		l.active--
+	}
+	l.grant()
+}
+
+// grant hands free slots to the waiters in order. Must be called with l.mu
+// held.
+func (l *recordingLimiter) grant() {
+	for len(l.waiters) > 0 {
+		if limit := l.limit(); limit > 0 && l.active >= limit {
+			return
+		}
+		waiter := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.active++
+		close(waiter.ready)
+	}
+}
+
+// status returns the slots in use and the streams waiting for one
+func (l *recordingLimiter) status() map[string]any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	queued := make([]string, 0, len(l.waiters))
+	for _, waiter := range l.waiters {
+		queued = append(queued, waiter.stream)
+	}
+	return map[string]any{
+		"active": l.active,
+		"limit":  l.limit(),
+		"queued": queued,
+	}
+}
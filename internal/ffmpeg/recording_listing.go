@@ -0,0 +1,128 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// recordingListing is the response of a recordings listing. It is written
+// field by field and one recording at a time, and the grouped block refers
+// to the recordings instead of copying them, so memory stays flat however
+// many recordings a page has.
+type recordingListing struct {
+	recordings []RecordingFile
+	grouped    bool           // add the recordings grouped by date
+	fields     map[string]any // the other fields, count, total, ...
+}
+
+// respondListing writes a listing, without the grouped block for
+// ?grouped=false
+func respondListing(w http.ResponseWriter, r *http.Request, recordings []RecordingFile, fields map[string]any) {
+	listing := &recordingListing{
+		recordings: recordings,
+		grouped:    r.URL.Query().Get("grouped") != "false",
+		fields:     fields,
+	}
+	respondTagged(w, r, listing.write)
+}
+
+// write encodes the listing as one JSON object with the fields sorted like
+// encoding/json sorts a map
+func (l *recordingListing) write(out io.Writer) error {
+	w := bufio.NewWriterSize(out, 32*1024)
+	enc := json.NewEncoder(w)
+
+	names := make([]string, 0, len(l.fields)+2)
+	for name := range l.fields {
+		names = append(names, name)
+	}
+	names = append(names, "recordings")
+	if l.grouped {
+		names = append(names, "grouped")
+	}
+	sort.Strings(names)
+
+	_ = w.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			_ = w.WriteByte(',')
+		}
+		if err := enc.Encode(name); err != nil {
+			return err
+		}
+		_ = w.WriteByte(':')
+
+		var err error
+		switch name {
+		case "recordings":
+			err = l.writeRecordings(w, enc, nil)
+		case "grouped":
+			err = l.writeGrouped(w, enc)
+		default:
+			err = enc.Encode(l.fields[name])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, _ = w.WriteString("}\n")
+
+	return w.Flush()
+}
+
+// writeRecordings writes the recordings at the indexes as an array, all
+// recordings for nil indexes
+func (l *recordingListing) writeRecordings(w *bufio.Writer, enc *json.Encoder, indexes []int) error {
+	count := len(l.recordings)
+	if indexes != nil {
+		count = len(indexes)
+	}
+
+	_ = w.WriteByte('[')
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			_ = w.WriteByte(',')
+		}
+		recording := i
+		if indexes != nil {
+			recording = indexes[i]
+		}
+		if err := enc.Encode(&l.recordings[recording]); err != nil {
+			return err
+		}
+	}
+	return w.WriteByte(']')
+}
+
+// writeGrouped writes the recordings grouped by date, the dates in order
+func (l *recordingListing) writeGrouped(w *bufio.Writer, enc *json.Encoder) error {
+	groups := map[string][]int{}
+	for i := range l.recordings {
+		date := l.recordings[i].DateGroup
+		groups[date] = append(groups[date], i)
+	}
+
+	dates := make([]string, 0, len(groups))
+	for date := range groups {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	_ = w.WriteByte('{')
+	for i, date := range dates {
+		if i > 0 {
+			_ = w.WriteByte(',')
+		}
+		if err := enc.Encode(date); err != nil {
+			return err
+		}
+		_ = w.WriteByte(':')
+		if err := l.writeRecordings(w, enc, groups[date]); err != nil {
+			return err
+		}
+	}
+	return w.WriteByte('}')
+}
@@ -0,0 +1,65 @@
+package ffmpeg
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingListing(t *testing.T) {
+	start := time.Date(2025, 1, 15, 23, 0, 0, 0, time.UTC)
+	var recordings []RecordingFile
+	for i := 0; i < 3; i++ {
+		recordings = append(recordings, RecordingFile{
+			ID:        string(rune('a' + i)),
+			StartTime: start.Add(time.Hour * time.Duration(i)),
+			DateGroup: start.Add(time.Hour * time.Duration(i)).Format("2006-01-02"),
+		})
+	}
+	fields := map[string]any{"count": 3, "total": 10, "next_offset": 3}
+
+	list := func(url, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		respondListing(w, r, recordings, fields)
+		return w
+	}
+
+	// The same document the listing was before it was streamed
+	expected, err := json.Marshal(map[string]any{
+		"count":       3,
+		"total":       10,
+		"next_offset": 3,
+		"recordings":  recordings,
+		"grouped": map[string][]RecordingFile{
+			"2025-01-15": recordings[:1],
+			"2025-01-16": recordings[1:],
+		},
+	})
+	require.Nil(t, err)
+
+	w := list("/api/recordings", "")
+	require.Empty(t, w.Header().Get("Content-Encoding"))
+	require.JSONEq(t, string(expected), w.Body.String())
+
+	w = list("/api/recordings", "br, gzip;q=0.8")
+	require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	gz, err := gzip.NewReader(w.Body)
+	require.Nil(t, err)
+	body, err := io.ReadAll(gz)
+	require.Nil(t, err)
+	require.JSONEq(t, string(expected), string(body))
+
+	var response map[string]any
+	w = list("/api/recordings?grouped=false", "gzip;q=0")
+	require.Empty(t, w.Header().Get("Content-Encoding"))
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotContains(t, response, "grouped")
+	require.Len(t, response["recordings"], 3)
+}
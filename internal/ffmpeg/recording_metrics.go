@@ -0,0 +1,171 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordingMetrics holds the counters exported on /api/recordings/metrics.
+// Gauges such as active recordings are computed at scrape time, storage
+// totals are refreshed from the recording index every MetricsInterval.
+type RecordingMetrics struct {
+	bytesWritten   map[string]int64 // stream -> bytes observed being written
+	segments       map[string]int64 // stream -> new recording files
+	failedStarts   map[string]int64 // stream -> recordings that failed to start
+	cleanupDeleted int64
+	cleanupArchive int64
+
+	storageBytes map[string]int64 // stream -> bytes on disk
+	storageFiles map[string]int64 // stream -> files on disk
+	storageAt    time.Time
+
+	mu sync.Mutex
+}
+
+var recordingMetrics = &RecordingMetrics{
+	bytesWritten: make(map[string]int64),
+	segments:     make(map[string]int64),
+	failedStarts: make(map[string]int64),
+	storageBytes: make(map[string]int64),
+	storageFiles: make(map[string]int64),
+}
+
+// metricsStreamName returns the stream a recording file belongs to
+func metricsStreamName(path string) string {
+	return recordingStreamName(path)
+}
+
+// addWritten records growth of a recording file seen by the index
+func (m *RecordingMetrics) addWritten(path string, delta int64, isNew bool) {
+	stream := metricsStreamName(path)
+
+	m.mu.Lock()
+	if delta > 0 {
+		m.bytesWritten[stream] += delta
+	}
+	if isNew {
+		m.segments[stream]++
+	}
+	m.mu.Unlock()
+}
+
+// addFailedStart counts a recording that could not be started
+func (m *RecordingMetrics) addFailedStart(stream string) {
+	m.mu.Lock()
+	m.failedStarts[stream]++
+	m.mu.Unlock()
+
+	countReportFailure(stream)
+}
+
+// addCleanup counts files removed by a cleanup run
+func (m *RecordingMetrics) addCleanup(result *CleanupResult) {
+	m.mu.Lock()
+	m.cleanupDeleted += int64(result.FilesDeleted)
+	m.cleanupArchive += int64(result.FilesArchived)
+	m.mu.Unlock()
+}
+
+// refreshStorage recalculates storage totals from the recording index
+func (m *RecordingMetrics) refreshStorage() {
+	storageBytes := make(map[string]int64)
+	storageFiles := make(map[string]int64)
+
+	for _, recording := range recordingIndex.Query("", "", 0) {
+		storageBytes[recording.StreamName] += recording.Size
+		storageFiles[recording.StreamName]++
+	}
+
+	m.mu.Lock()
+	m.storageBytes = storageBytes
+	m.storageFiles = storageFiles
+	m.storageAt = time.Now()
+	m.mu.Unlock()
+}
+
+// apiRecordingMetrics exposes recording metrics in the Prometheus text format
+func apiRecordingMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := GetRecordingConfig()
+	if !cfg.EnableMetrics {
+		http.Error(w, "Recording metrics are disabled (enable_metrics: false)", http.StatusNotFound)
+		return
+	}
+
+	m := recordingMetrics
+
+	m.mu.Lock()
+	stale := time.Since(m.storageAt) > cfg.MetricsInterval
+	m.mu.Unlock()
+	if stale {
+		m.refreshStorage()
+	}
+
+	active := make(map[string]int64)
+	for _, recording := range GetRecordingManager().ListRecordings() {
+		recording.mu.Lock()
+		if recording.Active {
+			active[recording.Stream]++
+		}
+		recording.mu.Unlock()
+	}
+
+	cpu, memory := streamResources()
+
+	var sb strings.Builder
+
+	m.mu.Lock()
+	writeMetric(&sb, "go2file_recordings_active", "gauge", "Recordings currently running", active)
+	writeMetric(&sb, "go2file_recording_cpu_percent", "gauge", "CPU use of recording processes, 100 = one core", cpu)
+	writeMetric(&sb, "go2file_recording_memory_bytes", "gauge", "Resident memory of recording processes", memory)
+	writeMetric(&sb, "go2file_recording_bytes_written_total", "counter", "Bytes written to recording files", m.bytesWritten)
+	writeMetric(&sb, "go2file_recording_segments_total", "counter", "Recording files created", m.segments)
+	writeMetric(&sb, "go2file_recording_failed_starts_total", "counter", "Recordings that failed to start", m.failedStarts)
+	writeMetric(&sb, "go2file_recording_storage_bytes", "gauge", "Size of recordings on disk", m.storageBytes)
+	writeMetric(&sb, "go2file_recording_storage_files", "gauge", "Number of recordings on disk", m.storageFiles)
+	writeMetric(&sb, "go2file_cleanup_deleted_files_total", "counter", "Recordings deleted by cleanup", m.cleanupDeleted)
+	writeMetric(&sb, "go2file_cleanup_archived_files_total", "counter", "Recordings archived by cleanup", m.cleanupArchive)
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(sb.String()))
+}
+
+// labelEscaper escapes label values as required by the Prometheus text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetric appends a metric family; per-stream maps get a stream label
+func writeMetric(sb *strings.Builder, name, kind, help string, value any) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+
+	switch v := value.(type) {
+	case int64:
+		fmt.Fprintf(sb, "%s %d\n", name, v)
+	case map[string]int64:
+		streams := make([]string, 0, len(v))
+		for stream := range v {
+			streams = append(streams, stream)
+		}
+		sort.Strings(streams)
+		for _, stream := range streams {
+			fmt.Fprintf(sb, "%s{stream=\"%s\"} %d\n", name, labelEscaper.Replace(stream), v[stream])
+		}
+	case map[string]float64:
+		streams := make([]string, 0, len(v))
+		for stream := range v {
+			streams = append(streams, stream)
+		}
+		sort.Strings(streams)
+		for _, stream := range streams {
+			fmt.Fprintf(sb, "%s{stream=\"%s\"} %g\n", name, labelEscaper.Replace(stream), v[stream])
+		}
+	}
+}
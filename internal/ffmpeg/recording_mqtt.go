@@ -0,0 +1,276 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/mqtt"
+)
+
+// RecordingMQTTConfig holds the broker settings shared by the MQTT integrations
+type RecordingMQTTConfig struct {
+	URL      string `yaml:"url"`       // Broker address, e.g. "mqtt://192.168.1.10:1883"
+	Username string `yaml:"username"`  // Optional, can also be set in the URL
+	Password string `yaml:"password"`  // Optional, can also be set in the URL
+	ClientID string `yaml:"client_id"` // Defaults to go2rtc-<hostname>
+
+	Publish     bool   `yaml:"publish"`      // Publish recording state as retained messages
+	TopicPrefix string `yaml:"topic_prefix"` // Defaults to "go2file"
+}
+
+// mqttDial opens a new authenticated connection to the configured broker
+func mqttDial(suffix string) (*mqtt.Client, error) {
+	cfg := GetRecordingConfig().MQTT
+	if cfg.URL == "" {
+		return nil, errors.New("mqtt: broker url not configured")
+	}
+
+	address := cfg.URL
+	username, password := cfg.Username, cfg.Password
+
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, err
+		}
+		address = u.Host
+		if u.User != nil && username == "" {
+			username = u.User.Username()
+			password, _ = u.User.Password()
+		}
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address += ":1883"
+	}
+
+	conn, err := net.DialTimeout("tcp", address, mqtt.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID := cfg.ClientID
+	if clientID == "" {
+		hostname, _ := os.Hostname()
+		clientID = "go2rtc-" + hostname
+	}
+
+	client := mqtt.NewClient(conn)
+	if err = client.Connect(clientID+suffix, username, password); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// mqttSubscribe listens to the given topic filters and calls handler for each
+// message, reconnecting with a delay whenever the connection drops
+func mqttSubscribe(topics []string, handler func(topic string, payload []byte)) {
+	for {
+		if err := mqttSubscribeOnce(topics, handler); err != nil {
+			log.Warn().Err(err).Strs("topics", topics).Msg("[mqtt] subscription lost, reconnecting")
+		}
+		time.Sleep(time.Second * 10)
+	}
+}
+
+func mqttSubscribeOnce(topics []string, handler func(topic string, payload []byte)) error {
+	client, err := mqttDial("-sub")
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	for _, topic := range topics {
+		if err = client.Subscribe(topic); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Strs("topics", topics).Msg("[mqtt] subscribed")
+
+	lastPing := time.Now()
+	for {
+		topic, payload, err := client.Read()
+		if err != nil {
+			// Read timeouts are expected while the broker is idle
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				return err
+			}
+		} else if topic != "" {
+			handler(topic, payload)
+		}
+
+		// Keep the connection alive, the broker drops clients after 1.5x keepalive
+		if time.Since(lastPing) > time.Second*15 {
+			if err = client.Ping(); err != nil {
+				return err
+			}
+			lastPing = time.Now()
+		}
+	}
+}
+
+// mqttTopicMatch reports whether topic matches the filter, supporting + and # wildcards
+func mqttTopicMatch(filter, topic string) bool {
+	filterParts := strings.Split(filter, "/")
+	topicParts := strings.Split(topic, "/")
+
+	for i, part := range filterParts {
+		if part == "#" {
+			return true
+		}
+		if i >= len(topicParts) {
+			return false
+		}
+		if part != "+" && part != topicParts[i] {
+			return false
+		}
+	}
+
+	return len(filterParts) == len(topicParts)
+}
+
+// mqttPublisher publishes recording state as retained messages. Only the
+// latest payload per topic is queued, so a broker outage never builds a backlog.
+type mqttPublisher struct {
+	prefix  string
+	pending map[string][]byte // topic -> payload
+	streams map[string]bool   // streams whose on/off state must be refreshed
+	wake    chan struct{}
+	mu      sync.Mutex
+}
+
+// startMQTTPublisher publishes recording notifications if enabled:
+//
+//	<prefix>/<stream>/recording    on | off
+//	<prefix>/<stream>/last_segment JSON metadata of the last finished file
+//	<prefix>/cleanup/result        JSON result of the last cleanup run
+func startMQTTPublisher() {
+	cfg := GetRecordingConfig().MQTT
+	if !cfg.Publish {
+		return
+	}
+	if cfg.URL == "" {
+		log.Warn().Msg("[mqtt] publish enabled but recording mqtt url is empty")
+		return
+	}
+
+	p := &mqttPublisher{
+		prefix:  cfg.TopicPrefix,
+		pending: make(map[string][]byte),
+		streams: make(map[string]bool),
+		wake:    make(chan struct{}, 1),
+	}
+	if p.prefix == "" {
+		p.prefix = "go2file"
+	}
+
+	subscribeNotifications(p.handle)
+	go p.run()
+}
+
+func (p *mqttPublisher) handle(n RecordingNotification) {
+	p.mu.Lock()
+	switch n.Type {
+	case NotifyRecordingStarted, NotifyRecordingStopped:
+		// Resolved on flush, several recordings may share a stream
+		p.streams[n.Stream] = true
+	case NotifySegmentComplete:
+		if b, err := json.Marshal(n.Data); err == nil {
+			p.pending[p.prefix+"/"+n.Stream+"/last_segment"] = b
+		}
+	case NotifyCleanupResult:
+		if b, err := json.Marshal(n.Data); err == nil {
+			p.pending[p.prefix+"/cleanup/result"] = b
+		}
+	}
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run keeps the publisher connected, reconnecting with a delay on errors
+func (p *mqttPublisher) run() {
+	for {
+		if err := p.runOnce(); err != nil {
+			log.Warn().Err(err).Msg("[mqtt] publisher disconnected, reconnecting")
+		}
+		time.Sleep(time.Second * 10)
+	}
+}
+
+func (p *mqttPublisher) runOnce() error {
+	client, err := mqttDial("-pub")
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	log.Info().Str("prefix", p.prefix).Msg("[mqtt] publishing recording state")
+
+	ticker := time.NewTicker(time.Second * 15)
+	defer ticker.Stop()
+
+	for {
+		if err = p.flush(client); err != nil {
+			return err
+		}
+
+		select {
+		case <-p.wake:
+		case <-ticker.C:
+			if err = client.Ping(); err != nil {
+				return err
+			}
+			// Consume PINGRESP so unread responses don't pile up
+			if _, _, err = client.Read(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// flush publishes all queued messages, requeueing them if the connection fails
+func (p *mqttPublisher) flush(client *mqtt.Client) error {
+	p.mu.Lock()
+	pending, streams := p.pending, p.streams
+	p.pending = make(map[string][]byte)
+	p.streams = make(map[string]bool)
+	p.mu.Unlock()
+
+	for stream := range streams {
+		state := "off"
+		if GetRecordingManager().IsStreamRecording(stream) {
+			state = "on"
+		}
+		pending[p.prefix+"/"+stream+"/recording"] = []byte(state)
+	}
+
+	for topic, payload := range pending {
+		if err := client.PublishRetain(topic, payload); err != nil {
+			p.mu.Lock()
+			for topic, payload := range pending {
+				// Keep anything newer queued while we were publishing
+				if _, ok := p.pending[topic]; !ok {
+					p.pending[topic] = payload
+				}
+			}
+			p.mu.Unlock()
+			return err
+		}
+		delete(pending, topic)
+	}
+
+	return nil
+}
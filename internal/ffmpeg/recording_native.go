@@ -0,0 +1,197 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/mp4"
+	"github.com/AlexxIT/go2rtc/pkg/mpegts"
+)
+
+// nativeConsumer is a go2rtc muxer consumer that can be written to a file
+type nativeConsumer interface {
+	core.Consumer
+	io.WriterTo
+}
+
+// isNativeRecorder reports whether the stream should be recorded with the
+// built-in Go muxers instead of an external ffmpeg process
+func isNativeRecorder(streamConfig StreamRecordingConfig) bool {
+	return streamConfig.Recorder == "native"
+}
+
+// newNativeConsumer returns a consumer for the container matching the file extension.
+// The built-in muxers cover fragmented MP4 and MPEG-TS.
+func newNativeConsumer(filename string) (nativeConsumer, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".mp4", ".m4v":
+		cons := mp4.NewConsumer(nil)
+		cons.FormatName = "mp4"
+		cons.Protocol = "file"
+		return cons, nil
+	case ".ts":
+		cons := mpegts.NewConsumer()
+		cons.Protocol = "file"
+		return cons, nil
+	}
+	return nil, fmt.Errorf("native recorder supports mp4 and ts output, got %s", filepath.Ext(filename))
+}
+
+// startNative attaches a muxer consumer to the running internal stream and
+// writes it directly to disk. Must be called with r.mu held.
+func (r *Recording) startNative(streamConfig StreamRecordingConfig) error {
+	stream := streams.Get(r.Stream)
+	if stream == nil {
+		return fmt.Errorf("internal source stream '%s' not found", r.Stream)
+	}
+
+	// Validate the container before marking the recording active
+	if _, err := newNativeConsumer(r.Config.Filename); err != nil {
+		return err
+	}
+
+	var segmentDuration time.Duration
+	if recorderSegments(streamConfig, r.Config) {
+		segmentDuration = streamConfig.SegmentDuration
+	}
+
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	r.State = StateRecording
+	r.Active = true
+	r.StartTime = time.Now()
+	clearStreamError(r.Stream)
+	recordingState.add(r)
+
+	log.Info().
+		Str("recording_id", r.ID).
+		Str("stream", r.Stream).
+		Str("output_file", r.Config.Filename).
+		Dur("segment_duration", segmentDuration).
+		Msg("[recording] native recorder attached to stream")
+
+	go r.runNative(stream, segmentDuration, r.stop, r.done)
+
+	return nil
+}
+
+// runNative writes segments until the recording is stopped
+func (r *Recording) runNative(stream *streams.Stream, segmentDuration time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	filename := r.Config.Filename
+	var failure error
+
+	for {
+		stopped, err := r.writeNativeSegment(stream, filename, segmentDuration, stop)
+		if err != nil {
+			failure = err
+			streamErr := newStreamError(r.ID, err)
+			setStreamError(r.Stream, streamErr)
+			log.Error().Err(err).Str("recording_id", r.ID).Str("stream", r.Stream).Str("code", streamErr.Code).
+				Msg("[recording] native recorder failed")
+			stopped = true
+
+			r.mu.Lock()
+			r.lastError = streamErr
+			r.mu.Unlock()
+		}
+
+		go onSegmentComplete(r.Stream, filename)
+
+		r.mu.Lock()
+		r.files = append(r.files, filename)
+		r.mu.Unlock()
+
+		if stopped {
+			break
+		}
+
+		// Next segment gets a fresh timestamped name
+		format := strings.TrimPrefix(filepath.Ext(filename), ".")
+		filename = GenerateRecordingPath(r.Stream, time.Now(), format, 0)
+
+		r.mu.Lock()
+		r.Config.Filename = filename
+		r.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	r.Active = false
+	r.exited(failure)
+	r.mu.Unlock()
+
+	recordingState.remove(r.ID)
+}
+
+// writeNativeSegment writes one file, returning true when the recording was stopped
+func (r *Recording) writeNativeSegment(stream *streams.Stream, filename string, segmentDuration time.Duration, stop chan struct{}) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return true, err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return true, err
+	}
+	defer file.Close()
+
+	cons, err := newNativeConsumer(filename)
+	if err != nil {
+		return true, err
+	}
+
+	if err = stream.AddConsumer(cons); err != nil {
+		return true, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := cons.WriteTo(file)
+		done <- err
+	}()
+
+	var timer <-chan time.Time
+	if segmentDuration > 0 {
+		timer = time.After(segmentDuration)
+	}
+
+	stopped, finished := false, false
+	select {
+	case <-stop:
+		stopped = true
+	case <-timer:
+	case err = <-done:
+		// Producer went away, the stream will be picked up again by auto-recording
+		stopped, finished = true, true
+	}
+
+	_ = cons.Stop()
+	stream.RemoveConsumer(cons)
+
+	// Wait for the muxer to flush before the file is closed
+	if !finished {
+		<-done
+	}
+
+	return stopped, err
+}
+
+// countActiveNativeRecordings returns the number of recordings running without ffmpeg
+func countActiveNativeRecordings() int {
+	var count int
+	for _, recording := range GetRecordingManager().ListRecordings() {
+		recording.mu.Lock()
+		if recording.Active && recording.stop != nil {
+			count++
+		}
+		recording.mu.Unlock()
+	}
+	return count
+}
@@ -0,0 +1,273 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AlertNotifierConfig is a backend alerts are delivered to
+type AlertNotifierConfig struct {
+	Name     string   `yaml:"name"`     // referenced by the notifiers of alert rules
+	Type     string   `yaml:"type"`     // email, telegram, slack, ntfy or webhook
+	URL      string   `yaml:"url"`      // webhook URL, ntfy topic URL or mail server "smtp://HOST:587" / "smtps://HOST:465"
+	Token    string   `yaml:"token"`    // telegram bot token, ntfy access token
+	ChatID   string   `yaml:"chat_id"`  // telegram chat
+	Username string   `yaml:"username"` // mail server login
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"` // mail sender
+	To       []string `yaml:"to"`   // mail recipients
+}
+
+// alertNotifier delivers alerts to a backend
+type alertNotifier interface {
+	send(alert Alert) error
+}
+
+// htmlNotifier is a notifier that can also deliver HTML documents, such as
+// the daily report
+type htmlNotifier interface {
+	sendHTML(subject string, html []byte) error
+}
+
+type alertNotifierFunc func(alert Alert) error
+
+func (f alertNotifierFunc) send(alert Alert) error {
+	return f(alert)
+}
+
+// alertNotifierTypes creates the notifiers by type, new backends only need
+// an entry here
+var alertNotifierTypes = map[string]func(cfg AlertNotifierConfig) (alertNotifier, error){
+	"email":    newEmailNotifier,
+	"telegram": newTelegramNotifier,
+	"slack":    newSlackNotifier,
+	"ntfy":     newNtfyNotifier,
+	"webhook":  newWebhookNotifier,
+}
+
+var notifierClient = &http.Client{Timeout: time.Second * 10}
+
+func newAlertNotifier(cfg AlertNotifierConfig) (alertNotifier, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("notifier without name")
+	}
+	newNotifier, ok := alertNotifierTypes[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown notifier type %q", cfg.Type)
+	}
+	return newNotifier(cfg)
+}
+
+// newWebhookNotifier posts alerts as JSON
+func newWebhookNotifier(cfg AlertNotifierConfig) (alertNotifier, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("webhook notifier needs url")
+	}
+	return alertNotifierFunc(func(alert Alert) error {
+		return postNotification(cfg.URL, alert)
+	}), nil
+}
+
+// newSlackNotifier posts alerts to a Slack incoming webhook, Mattermost and
+// Discord ("/slack" webhook URLs) accept the same message
+func newSlackNotifier(cfg AlertNotifierConfig) (alertNotifier, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("slack notifier needs url")
+	}
+	return alertNotifierFunc(func(alert Alert) error {
+		return postNotification(cfg.URL, map[string]string{"text": alert.text()})
+	}), nil
+}
+
+// newTelegramNotifier sends alerts as messages of a Telegram bot
+func newTelegramNotifier(cfg AlertNotifierConfig) (alertNotifier, error) {
+	if cfg.Token == "" || cfg.ChatID == "" {
+		return nil, errors.New("telegram notifier needs token and chat_id")
+	}
+	endpoint := cmp.Or(cfg.URL, "https://api.telegram.org") + "/bot" + cfg.Token + "/sendMessage"
+
+	return alertNotifierFunc(func(alert Alert) error {
+		return postNotification(endpoint, map[string]string{"chat_id": cfg.ChatID, "text": alert.text()})
+	}), nil
+}
+
+// newNtfyNotifier publishes alerts to an ntfy topic
+func newNtfyNotifier(cfg AlertNotifierConfig) (alertNotifier, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("ntfy notifier needs the topic url")
+	}
+	return alertNotifierFunc(func(alert Alert) error {
+		req, err := http.NewRequest("POST", cfg.URL, strings.NewReader(alert.Message))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Title", alert.title())
+		switch alert.State {
+		case AlertResolved:
+			req.Header.Set("Tags", "white_check_mark")
+		case AlertReport:
+			req.Header.Set("Tags", "bar_chart")
+		default:
+			req.Header.Set("Tags", "warning")
+			req.Header.Set("Priority", "high")
+		}
+		if cfg.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+cfg.Token)
+		}
+		return doNotification(req)
+	}), nil
+}
+
+// emailNotifier mails alerts through an SMTP server, with STARTTLS when the
+// server offers it
+type emailNotifier struct {
+	server *url.URL
+	auth   smtp.Auth
+	from   string
+	to     []string
+}
+
+func newEmailNotifier(cfg AlertNotifierConfig) (alertNotifier, error) {
+	server, err := url.Parse(cfg.URL)
+	if err != nil || (server.Scheme != "smtp" && server.Scheme != "smtps") || server.Hostname() == "" {
+		return nil, errors.New("email notifier needs url smtp://HOST:PORT or smtps://HOST:PORT")
+	}
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return nil, errors.New("email notifier needs from and to")
+	}
+
+	n := &emailNotifier{server: server, from: cfg.From, to: cfg.To}
+	if cfg.Username != "" {
+		n.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, server.Hostname())
+	}
+	return n, nil
+}
+
+func (n *emailNotifier) send(alert Alert) error {
+	body := fmt.Sprintf("%s\r\n\r\nSince: %s\r\n", alert.Message, alert.Since.Format(time.RFC3339))
+	return n.mail(alert.title(), "text/plain", []byte(body))
+}
+
+func (n *emailNotifier) sendHTML(subject string, html []byte) error {
+	return n.mail(subject, "text/html", html)
+}
+
+func (n *emailNotifier) mail(subject, contentType string, body []byte) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\nContent-Type: %s; charset=utf-8\r\n\r\n", contentType)
+	msg.Write(body)
+
+	return sendMail(n.server, n.auth, n.from, n.to, msg.Bytes())
+}
+
+func sendMail(server *url.URL, auth smtp.Auth, from string, to []string, msg []byte) error {
+	host := server.Hostname()
+	addr := server.Host
+	if server.Port() == "" {
+		if server.Scheme == "smtps" {
+			addr = net.JoinHostPort(host, "465")
+		} else {
+			addr = net.JoinHostPort(host, "25")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: time.Second * 10}
+	var conn net.Conn
+	var err error
+	if server.Scheme == "smtps" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(time.Second * 30))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && server.Scheme == "smtp" {
+		if err = client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err = client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// postNotification posts v as JSON
+func postNotification(endpoint string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doNotification(req)
+}
+
+func doNotification(req *http.Request) error {
+	res, err := notifierClient.Do(req)
+	if err != nil {
+		// The telegram URL contains the bot token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 256))
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package ffmpeg
+
+import (
+	"sync"
+	"time"
+)
+
+// Notification types published by the recording subsystem
+const (
+	NotifyRecordingStarted = "recording_started"
+	NotifyRecordingStopped = "recording_stopped"
+	NotifySegmentComplete  = "segment_complete"
+	NotifyCleanupResult    = "cleanup_result"
+	NotifyRecordingStalled = "recording_stalled"
+	NotifyDiskWarning      = "disk_warning"
+	NotifyDiskRecovered    = "disk_recovered"
+	NotifyResourceLimit    = "resource_limit"
+	NotifyQuotaExceeded    = "quota_exceeded"
+	NotifyQuotaRecovered   = "quota_recovered"
+	NotifySegmentCorrupt   = "segment_corrupt"
+	NotifyJobFinished      = "job_finished"
+)
+
+// RecordingNotification describes a state change in the recording subsystem.
+// Integrations (MQTT, webhooks, ...) subscribe to these instead of polling.
+type RecordingNotification struct {
+	Type   string    `json:"type"`
+	Stream string    `json:"stream,omitempty"`
+	Time   time.Time `json:"time"`
+	Data   any       `json:"data,omitempty"`
+}
+
+type notifyHandler struct {
+	id     int
+	handle func(RecordingNotification)
+}
+
+var (
+	notifyHandlers []notifyHandler
+	notifyNextID   int
+	notifyMu       sync.RWMutex
+)
+
+// subscribeNotifications registers a handler for all recording notifications.
+// Handlers run on the notifying goroutine, possibly with recorder locks held,
+// so they must not block or call back into the recording manager. The returned
+// function removes the handler again.
+func subscribeNotifications(handler func(RecordingNotification)) (unsubscribe func()) {
+	notifyMu.Lock()
+	notifyNextID++
+	id := notifyNextID
+	notifyHandlers = append(notifyHandlers, notifyHandler{id: id, handle: handler})
+	notifyMu.Unlock()
+
+	return func() {
+		notifyMu.Lock()
+		defer notifyMu.Unlock()
+
+		// Copy, notify may still be iterating the old slice
+		handlers := make([]notifyHandler, 0, len(notifyHandlers))
+		for _, h := range notifyHandlers {
+			if h.id != id {
+				handlers = append(handlers, h)
+			}
+		}
+		notifyHandlers = handlers
+	}
+}
+
+// notify delivers a notification to all subscribers
+func notify(typ, stream string, data any) {
+	n := RecordingNotification{Type: typ, Stream: stream, Time: time.Now(), Data: data}
+
+	notifyMu.RLock()
+	handlers := notifyHandlers
+	notifyMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler.handle(n)
+	}
+}
@@ -0,0 +1,154 @@
+package ffmpeg
+
+import (
+	"maps"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/onvif"
+)
+
+// motionStateRe matches the SimpleItem carrying the motion state in ONVIF
+// notifications, e.g. tns1:RuleEngine/CellMotionDetector/Motion (IsMotion)
+// or tns1:VideoSource/MotionAlarm (State)
+var motionStateRe = regexp.MustCompile(`Name="(?:IsMotion|State)"\s+Value="(true|false)"`)
+
+// The pull-point subscription terminates a minute after it was created or
+// last renewed, it is renewed every onvifRenewInterval
+const (
+	onvifTermination    = time.Minute
+	onvifPullTimeout    = time.Second * 10
+	onvifUnsubscribeMax = time.Second * 2 // how long shutdown waits for cameras
+)
+
+var (
+	onvifRenewInterval = time.Second * 30
+	onvifRetryInterval = time.Second * 30
+
+	onvifSubscriptions = map[string]*onvif.Client{} // subscription address -> client
+	onvifStopped       bool
+	onvifMu            sync.Mutex
+)
+
+// watchONVIFMotion subscribes to the camera's ONVIF event service and
+// triggers event recordings whenever motion is reported
+func watchONVIFMotion(streamName, rawURL string) {
+	for {
+		pulled, err := watchONVIFMotionOnce(streamName, rawURL)
+		if onvifStopping() {
+			return
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("stream", streamName).Msg("[event] onvif motion subscription lost, retrying")
+		}
+		// A subscription the camera ended is replaced right away, a camera
+		// that can't be subscribed to is tried again later
+		if !pulled {
+			time.Sleep(onvifRetryInterval)
+		}
+	}
+}
+
+// watchONVIFMotionOnce pulls the events of one subscription until it fails,
+// pulled is true if the subscription delivered at least once
+func watchONVIFMotionOnce(streamName, rawURL string) (pulled bool, err error) {
+	rawURL = strings.Replace(rawURL, "onvif://", "http://", 1)
+
+	client, err := onvif.NewClient(rawURL)
+	if err != nil {
+		return false, err
+	}
+
+	address, err := client.CreatePullPointSubscription()
+	if err != nil {
+		return false, err
+	}
+
+	onvifMu.Lock()
+	stopped := onvifStopped
+	if !stopped {
+		onvifSubscriptions[address] = client
+	}
+	onvifMu.Unlock()
+
+	defer func() {
+		onvifMu.Lock()
+		delete(onvifSubscriptions, address)
+		onvifMu.Unlock()
+		// Most likely gone after a fault, but a camera with a limit of
+		// subscriptions must not wait for it to terminate
+		go func() { _ = client.Unsubscribe(address) }()
+	}()
+
+	if stopped {
+		return false, nil
+	}
+
+	log.Info().Str("stream", streamName).Str("subscription", address).Msg("[event] subscribed to onvif motion events")
+
+	renewed := time.Now()
+	for ; ; pulled = true {
+		if time.Since(renewed) >= onvifRenewInterval {
+			if err = client.Renew(address, onvifTermination); err != nil {
+				return pulled, err
+			}
+			renewed = time.Now()
+		}
+
+		b, err := client.PullMessages(address, onvifPullTimeout)
+		if err != nil {
+			return pulled, err
+		}
+
+		for _, m := range motionStateRe.FindAllSubmatch(b, -1) {
+			if string(m[1]) == "true" {
+				if _, err = TriggerEventRecording(streamName, -1, -1, "onvif"); err != nil {
+					log.Warn().Err(err).Str("stream", streamName).Msg("[event] failed to start onvif event recording")
+				}
+				break
+			}
+		}
+	}
+}
+
+func onvifStopping() bool {
+	onvifMu.Lock()
+	defer onvifMu.Unlock()
+	return onvifStopped
+}
+
+// unsubscribeONVIF ends all subscriptions on shutdown, waiting at most
+// onvifUnsubscribeMax for the cameras
+func unsubscribeONVIF() {
+	onvifMu.Lock()
+	onvifStopped = true
+	subscriptions := maps.Clone(onvifSubscriptions)
+	onvifMu.Unlock()
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for address, client := range subscriptions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Unsubscribe(address); err != nil {
+				log.Debug().Err(err).Str("subscription", address).Msg("[event] failed to unsubscribe from onvif events")
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(onvifUnsubscribeMax):
+	}
+}
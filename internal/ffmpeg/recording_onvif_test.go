@@ -0,0 +1,118 @@
+package ffmpeg
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeONVIFCamera answers the event service requests of a camera, the pulls
+// of the first subscription fail after three
+type fakeONVIFCamera struct {
+	requests []string
+	ended    map[string]bool
+	mu       sync.Mutex
+}
+
+func (c *fakeONVIFCamera) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	base := "http://" + r.Host
+	var operation string
+	for _, name := range []string{"GetCapabilities", "CreatePullPointSubscription", "PullMessages", "Renew", "Unsubscribe"} {
+		if strings.Contains(string(body), ":"+name) {
+			operation = name
+			break
+		}
+	}
+	c.requests = append(c.requests, operation+" "+r.URL.Path)
+
+	switch operation {
+	case "GetCapabilities":
+		_, _ = io.WriteString(w, `<tt:Events><tt:XAddr>`+base+`/events</tt:XAddr></tt:Events>`)
+	case "CreatePullPointSubscription":
+		subscription := "/subscription1"
+		if c.count("CreatePullPointSubscription") > 1 {
+			subscription = "/subscription2"
+		}
+		_, _ = io.WriteString(w, `<tev:SubscriptionReference><wsa:Address>`+base+subscription+`</wsa:Address></tev:SubscriptionReference>`)
+	case "Unsubscribe":
+		c.ended[r.URL.Path] = true
+	case "PullMessages":
+		if c.ended[r.URL.Path] || r.URL.Path == "/subscription1" && c.count("PullMessages /subscription1") > 3 {
+			http.Error(w, "ResourceUnknownFault", http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// count must be called with c.mu held
+func (c *fakeONVIFCamera) count(prefix string) (n int) {
+	for _, request := range c.requests {
+		if strings.HasPrefix(request, prefix) {
+			n++
+		}
+	}
+	return
+}
+
+func (c *fakeONVIFCamera) counts(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count(prefix)
+}
+
+func TestWatchONVIFMotion(t *testing.T) {
+	renewInterval, retryInterval := onvifRenewInterval, onvifRetryInterval
+	t.Cleanup(func() {
+		onvifRenewInterval, onvifRetryInterval = renewInterval, retryInterval
+		onvifMu.Lock()
+		onvifStopped = false
+		onvifMu.Unlock()
+	})
+	onvifRenewInterval = time.Millisecond * 20
+	onvifRetryInterval = time.Hour // a failed subscribe would hang the test
+
+	camera := &fakeONVIFCamera{ended: map[string]bool{}}
+	server := httptest.NewServer(camera)
+	defer server.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watchONVIFMotion("cam1", strings.Replace(server.URL, "http://", "onvif://", 1))
+	}()
+
+	// The first subscription fails and is replaced right away
+	require.Eventually(t, func() bool {
+		return camera.counts("PullMessages /subscription2") > 0
+	}, time.Second*5, time.Millisecond*10)
+	require.Eventually(t, func() bool {
+		return camera.counts("Unsubscribe /subscription1") == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	// Renewed before it terminates
+	require.Eventually(t, func() bool {
+		return camera.counts("Renew /subscription2") > 0
+	}, time.Second*5, time.Millisecond*10)
+
+	// Unsubscribed on shutdown
+	unsubscribeONVIF()
+	require.Equal(t, 1, camera.counts("Unsubscribe /subscription2"))
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("watcher didn't stop")
+	}
+	require.Equal(t, 2, camera.counts("CreatePullPointSubscription"))
+}
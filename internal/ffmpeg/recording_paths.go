@@ -0,0 +1,200 @@
+package ffmpeg
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordingPathTemplate is everything a recording path is generated from, so
+// generating one doesn't depend on (or change) the global config
+type RecordingPathTemplate struct {
+	BasePath string         // storage pool the recording is written to
+	Path     string         // directory template under BasePath
+	Filename string         // filename template without extension
+	Format   string         // extension used when the recording has no format
+	Alias    string         // value of {stream_alias}, the stream name if empty
+	Location *time.Location // timezone of the timestamps, local time if nil
+}
+
+// streamPathTemplate returns the path template for a new recording of the
+// stream, with the stream's own templates and storage pool
+func streamPathTemplate(streamName string) RecordingPathTemplate {
+	cfg := GetRecordingConfig()
+	streamConfig := GetStreamRecordingConfig(streamName)
+
+	template := RecordingPathTemplate{
+		BasePath: selectStoragePool(streamName),
+		Path:     streamConfig.PathTemplate,
+		Filename: streamConfig.FilenameTemplate,
+		Format:   cfg.DefaultFormat,
+		Location: templateLocation(),
+	}
+	if specific, ok := lookupStreamConfig(cfg, streamName); ok {
+		template.Alias = specific.Alias
+	}
+	return template
+}
+
+// Render returns the path of a recording started at startTime
+func (t RecordingPathTemplate) Render(streamName string, startTime time.Time, format string, segmentNum int) string {
+	if format == "" {
+		format = t.Format
+	}
+	if !strings.HasPrefix(format, ".") {
+		format = "." + format
+	}
+
+	// Both templates support every placeholder
+	replacer := t.replacer(streamName, startTime, format, segmentNum)
+
+	return filepath.Join(t.BasePath, replacer.Replace(t.Path), replacer.Replace(t.Filename)+format)
+}
+
+// replacer returns the placeholders of path and filename templates
+func (t RecordingPathTemplate) replacer(streamName string, startTime time.Time, format string, segmentNum int) *strings.Replacer {
+	loc := t.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	ts := startTime.In(loc)
+
+	alias := t.Alias
+	if alias == "" {
+		alias = streamName
+	}
+
+	return strings.NewReplacer(
+		"{stream_alias}", alias,
+		"{stream}", streamName,
+		"{year}", ts.Format("2006"),
+		"{month}", ts.Format("01"),
+		"{day}", ts.Format("02"),
+		"{hour}", ts.Format("15"),
+		"{weekday}", strings.ToLower(ts.Weekday().String()),
+		"{timestamp}", ts.Format("2006-01-02_15-04-05"),
+		"{date}", ts.Format("2006-01-02"),
+		"{time}", ts.Format("15-04-05"),
+		"{unix}", strconv.FormatInt(ts.Unix(), 10),
+		"{uuid}", newUUID(),
+		"{hostname}", templateHostname(),
+		"{segment}", fmt.Sprintf("%03d", segmentNum),
+		"{format}", strings.TrimPrefix(format, "."),
+	)
+}
+
+// GenerateRecordingPath creates the full path for a recording file of the
+// stream. Every recorder (auto-start, scheduler, events, segments) gets its
+// file names from here.
+func GenerateRecordingPath(streamName string, startTime time.Time, format string, segmentNum int) string {
+	return newRecordingPath(streamPathTemplate(streamName).Render(streamName, startTime, format, segmentNum))
+}
+
+// newRecordingPath makes a generated path unique and creates its directory
+func newRecordingPath(path string) string {
+	path = uniqueRecordingPath(path)
+
+	// Create directory if needed
+	if GetRecordingConfig().CreateDirectories {
+		dir := filepath.Dir(path)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Error().Err(err).Str("dir", dir).Msg("[recording] failed to create recording directory")
+		}
+	}
+
+	return path
+}
+
+// pathReservationTTL is how long a generated path stays taken without a file,
+// ffmpeg may not have created it yet when the next recording starts
+const pathReservationTTL = time.Minute
+
+var (
+	pathReservations   = map[string]time.Time{}
+	pathReservationsMu sync.Mutex
+)
+
+// uniqueRecordingPath returns path, or path with a _1, _2... suffix if a file
+// or another recording started within the same second already uses it. The
+// recorders write with -y, so a collision would clobber the earlier file.
+func uniqueRecordingPath(path string) string {
+	pathReservationsMu.Lock()
+	defer pathReservationsMu.Unlock()
+
+	now := time.Now()
+	for reserved, at := range pathReservations {
+		if now.Sub(at) > pathReservationTTL {
+			delete(pathReservations, reserved)
+		}
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	unique := path
+	for i := 1; recordingPathTaken(unique); i++ {
+		unique = base + "_" + strconv.Itoa(i) + ext
+	}
+	if unique != path {
+		log.Debug().Str("path", path).Str("unique", unique).Msg("[recording] recording path already in use, adding a suffix")
+	}
+
+	pathReservations[unique] = now
+	return unique
+}
+
+func recordingPathTaken(path string) bool {
+	if _, ok := pathReservations[path]; ok {
+		return true
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// templateLocation returns the timezone of timestamps in recording paths
+func templateLocation() *time.Location {
+	switch tz := GetRecordingConfig().PathTimezone; strings.ToLower(tz) {
+	case "", "local":
+		return time.Local
+	case "utc":
+		return time.UTC
+	default:
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+		return time.Local
+	}
+}
+
+var hostname = sync.OnceValue(func() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return name
+})
+
+// templateHostname returns the host name without characters that aren't
+// safe in a path
+func templateHostname() string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, hostname())
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,88 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRecordingPathRapidRestarts(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := GetRecordingConfig()
+	setRecordingConfig(&RecordingConfig{
+		BasePath:          dir,
+		BasePaths:         StoragePaths{dir},
+		PathTemplate:      "{stream}",
+		FilenameTemplate:  "{stream}_{timestamp}",
+		DefaultFormat:     "mp4",
+		CreateDirectories: true,
+	})
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+
+	start := time.Date(2025, 1, 15, 14, 30, 25, 0, time.Local)
+
+	// A file from an earlier run already exists
+	first := filepath.Join(dir, "cam1", "cam1_2025-01-15_14-30-25.mp4")
+	require.Nil(t, os.MkdirAll(filepath.Dir(first), 0755))
+	require.Nil(t, os.WriteFile(first, nil, 0644))
+
+	// The recorder restarts several times within the same second, before
+	// ffmpeg created any of the files
+	seen := map[string]bool{first: true}
+	for i := 1; i <= 3; i++ {
+		path := GenerateRecordingPath("cam1", start, "mp4", 0)
+		require.False(t, seen[path], "path %s generated twice", path)
+		seen[path] = true
+	}
+
+	require.True(t, seen[filepath.Join(dir, "cam1", "cam1_2025-01-15_14-30-25_1.mp4")])
+	require.True(t, seen[filepath.Join(dir, "cam1", "cam1_2025-01-15_14-30-25_3.mp4")])
+
+	// Other streams and other seconds keep their plain names
+	require.Equal(t,
+		filepath.Join(dir, "cam2", "cam2_2025-01-15_14-30-25.mp4"),
+		GenerateRecordingPath("cam2", start, "mp4", 0),
+	)
+	require.Equal(t,
+		filepath.Join(dir, "cam1", "cam1_2025-01-15_14-30-26.mp4"),
+		GenerateRecordingPath("cam1", start.Add(time.Second), "mp4", 0),
+	)
+}
+
+func TestStreamBasePathRoots(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "recordings")
+	disk2 := filepath.Join(dir, "disk2")
+
+	cfg := GetRecordingConfig()
+	setRecordingConfig(&RecordingConfig{
+		BasePath:  main,
+		BasePaths: StoragePaths{main},
+		Streams: map[string]StreamRecordingConfig{
+			"garage": {BasePath: disk2 + "/"},
+			"porch":  {BasePath: filepath.Join(main, "porch")},
+		},
+	})
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	validateRecordingConfig(GetRecordingConfig())
+
+	// The nested root is covered by the pool it's in
+	require.Equal(t, []string{main, disk2}, storagePools())
+
+	pool, rel, ok := storagePoolRel(filepath.Join(disk2, "garage", "a.mp4"))
+	require.True(t, ok)
+	require.Equal(t, disk2, pool)
+	require.Equal(t, filepath.Join("garage", "a.mp4"), rel)
+
+	pool, rel, ok = storagePoolRel(filepath.Join(main, "porch", "porch", "b.mp4"))
+	require.True(t, ok)
+	require.Equal(t, main, pool)
+	require.Equal(t, filepath.Join("porch", "porch", "b.mp4"), rel)
+
+	require.False(t, isWithinBasePath(filepath.Join(dir, "other", "c.mp4")))
+	require.False(t, isWithinBasePath(filepath.Join(disk2, "..", "c.mp4")))
+}
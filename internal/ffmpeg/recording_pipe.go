@@ -0,0 +1,62 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/mpegts"
+)
+
+// pipeInput feeds ffmpeg's stdin with MPEG-TS from a consumer of the running
+// internal stream, so recording shares the upstream connection with viewers
+// instead of opening a second RTSP session to 127.0.0.1
+type pipeInput struct {
+	stream *streams.Stream
+	cons   *mpegts.Consumer
+	stdin  io.WriteCloser
+}
+
+// isPipeInput reports whether the stream should be recorded through stdin
+func isPipeInput(streamConfig StreamRecordingConfig) bool {
+	return streamConfig.InputMode == "pipe"
+}
+
+// newPipeInput attaches a consumer to the stream and connects it to the command's
+// stdin. Must be called before cmd.Start.
+func newPipeInput(streamName string, cmd *exec.Cmd) (*pipeInput, error) {
+	stream := streams.Get(streamName)
+	if stream == nil {
+		return nil, fmt.Errorf("internal source stream '%s' not found", streamName)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	cons := mpegts.NewConsumer()
+	cons.Protocol = "pipe"
+
+	if err = stream.AddConsumer(cons); err != nil {
+		_ = stdin.Close()
+		return nil, err
+	}
+
+	return &pipeInput{stream: stream, cons: cons, stdin: stdin}, nil
+}
+
+// Run starts copying stream data into ffmpeg, call after cmd.Start
+func (p *pipeInput) Run() {
+	go func() {
+		_, _ = p.cons.WriteTo(p.stdin)
+	}()
+}
+
+// Close detaches the consumer from the stream
+func (p *pipeInput) Close() {
+	_ = p.cons.Stop()
+	p.stream.RemoveConsumer(p.cons)
+	_ = p.stdin.Close()
+}
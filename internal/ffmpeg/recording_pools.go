@@ -0,0 +1,169 @@
+package ffmpeg
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Placement policies for several base_path pools
+const (
+	PoolFillFirst  = "fill_first"  // fill the pools in order
+	PoolRoundRobin = "round_robin" // rotate new recordings over the pools
+)
+
+// StoragePaths is base_path: a single directory or a list of storage pools
+type StoragePaths []string
+
+func (p *StoragePaths) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*p = StoragePaths{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		var paths []string
+		if err := node.Decode(&paths); err != nil {
+			return err
+		}
+		*p = paths
+		return nil
+	}
+	return errors.New("base_path must be a directory or a list of directories")
+}
+
+var (
+	poolNext int // next round robin pool
+	poolMu   sync.Mutex
+)
+
+// storagePools returns every directory recordings are stored in: the
+// base_path pools, the base paths pinned to single streams and the cold tier
+func storagePools() []string {
+	return storagePoolsOf(GetRecordingConfig())
+}
+
+func storagePoolsOf(cfg *RecordingConfig) []string {
+	pools := hotPoolsOf(cfg)
+	if path := cfg.ColdPath; path != "" && !slices.Contains(pools, path) {
+		pools = append(pools, path)
+	}
+	return pools
+}
+
+// hotPools returns the directories new recordings are written to: the
+// base_path pools followed by the base paths pinned to single streams
+func hotPools() []string {
+	return hotPoolsOf(GetRecordingConfig())
+}
+
+func hotPoolsOf(cfg *RecordingConfig) []string {
+	pools := append([]string(nil), cfg.BasePaths...)
+	if len(pools) == 0 {
+		pools = []string{cfg.BasePath}
+	}
+
+	var pinned []string
+	for _, streamConfig := range cfg.Streams {
+		if path := streamConfig.BasePath; path != "" && !slices.Contains(pools, path) && !slices.Contains(pinned, path) {
+			pinned = append(pinned, path)
+		}
+	}
+	sort.Strings(pinned)
+
+	// A root inside another one is already listed and cleaned with it,
+	// walking both would see its recordings twice
+	for _, path := range pinned {
+		if !slices.ContainsFunc(pools, func(pool string) bool { return pathWithin(path, pool) }) {
+			pools = append(pools, path)
+		}
+	}
+	return pools
+}
+
+// pathWithin reports whether path is root or inside it
+func pathWithin(path, root string) bool {
+	_, ok := relWithin(path, root)
+	return ok
+}
+
+// relWithin returns path relative to root, ok is false if it's outside
+func relWithin(path, root string) (string, bool) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// selectStoragePool returns the base path for a new recording of the stream
+func selectStoragePool(streamName string) string {
+	cfg := GetRecordingConfig()
+
+	if streamConfig, ok := lookupStreamConfig(cfg, streamName); ok && streamConfig.BasePath != "" {
+		return streamConfig.BasePath
+	}
+
+	pools := cfg.BasePaths
+	if len(pools) <= 1 {
+		return cfg.BasePath
+	}
+
+	start := 0
+	if cfg.StoragePolicy == PoolRoundRobin {
+		poolMu.Lock()
+		start = poolNext
+		poolNext = (poolNext + 1) % len(pools)
+		poolMu.Unlock()
+	}
+
+	for i := range pools {
+		pool := pools[(start+i)%len(pools)]
+		if free, ok := poolFreePercent(pool); !ok || free >= cfg.PoolMinFree {
+			return pool
+		}
+	}
+
+	// Every pool is full, use the one with the most space left
+	best, bestFree := pools[0], -1.0
+	for _, pool := range pools {
+		if free, ok := poolFreePercent(pool); ok && free > bestFree {
+			best, bestFree = pool, free
+		}
+	}
+
+	log.Warn().Str("pool", best).Float64("free_percent", bestFree).Msg("[disk] all storage pools are below pool_min_free")
+	return best
+}
+
+// poolFreePercent returns the free space of the pool's volume, ok is false if
+// it can't be read
+func poolFreePercent(pool string) (float64, bool) {
+	free, total, err := diskUsage(pool)
+	if err != nil || total == 0 {
+		return 0, false
+	}
+	return float64(free) / float64(total) * 100, true
+}
+
+// storagePoolRel returns the pool containing path and the path relative to it
+func storagePoolRel(path string) (pool, rel string, ok bool) {
+	for _, pool = range storagePools() {
+		if rel, ok = relWithin(path, pool); ok {
+			return pool, rel, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,295 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Preview formats, set with preview_format
+const (
+	PreviewGIF    = "gif"    // animated GIF of the sampled frames
+	PreviewWebP   = "webp"   // animated WebP, smaller but needs ffmpeg with libwebp
+	PreviewSprite = "sprite" // JPEG sprite sheet with a WebVTT file for hover scrubbing
+)
+
+const (
+	previewWidth       = 240 // animated previews
+	previewSpriteWidth = 160 // sprite tiles
+	previewSpriteCols  = 5
+	previewFrameRate   = 2 // frames per second of animated previews
+)
+
+func previewsEnabled() bool {
+	return GetRecordingConfig().PreviewFormat != ""
+}
+
+// previewPath is where the preview asset of a recording is cached, next to
+// its thumbnails
+func previewPath(recording *RecordingFile, format string) string {
+	name := recording.ID + "_preview." + format
+	if format == PreviewSprite {
+		name = recording.ID + "_sprite.jpg"
+	}
+	return filepath.Join(getThumbnailDir(), name)
+}
+
+// previewVTTPath is the WebVTT file mapping times to sprite tiles
+func previewVTTPath(recording *RecordingFile) string {
+	return filepath.Join(getThumbnailDir(), recording.ID+"_sprite.vtt")
+}
+
+// previewContentType returns the MIME type of a preview asset
+func previewContentType(format string) string {
+	switch format {
+	case PreviewGIF:
+		return "image/gif"
+	case PreviewWebP:
+		return "image/webp"
+	}
+	return "image/jpeg"
+}
+
+// handleRecordingPreview serves the animated preview or sprite sheet of a
+// recording, generating it if the background job hasn't yet:
+//
+//	GET /api/recordings?preview=ID
+//	GET /api/recordings?preview=ID&vtt=true
+func handleRecordingPreview(w http.ResponseWriter, r *http.Request, query map[string][]string) {
+	recordingID := getQueryParam(query, "preview")
+
+	format := GetRecordingConfig().PreviewFormat
+	if format == "" {
+		http.Error(w, "Previews are disabled, set 'preview_format'", http.StatusNotFound)
+		return
+	}
+
+	recording := recordingIndex.Get(recordingID)
+	if recording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	vtt := getQueryParam(query, "vtt") == "true"
+	if vtt && format != PreviewSprite {
+		http.Error(w, "A WebVTT file exists only with preview_format: sprite", http.StatusBadRequest)
+		return
+	}
+
+	path, err := getRecordingPreview(recording, format)
+	if err != nil {
+		log.Warn().Err(err).Str("recording", recordingID).Msg("[recording] failed to generate preview")
+		http.Error(w, fmt.Sprintf("Failed to generate preview: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := previewContentType(format)
+	if vtt {
+		path, contentType = previewVTTPath(recording), "text/vtt"
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to open preview: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get preview info: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	setFileETag(w, info)
+	http.ServeContent(w, r, filepath.Base(path), info.ModTime(), file)
+}
+
+// getRecordingPreview returns the path of the cached preview, generating it
+// if it's missing or older than the recording
+func getRecordingPreview(recording *RecordingFile, format string) (string, error) {
+	path := previewPath(recording, format)
+
+	if previewInfo, err := os.Stat(path); err == nil {
+		if fileInfo, err := os.Stat(recording.Path); err == nil && !fileInfo.ModTime().After(previewInfo.ModTime()) {
+			return path, nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	if err := generatePreview(recording, format, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// generatePreview samples preview_frames frames spread over the recording.
+// Only keyframes are decoded, so a long segment takes about as long as a
+// short one.
+func generatePreview(recording *RecordingFile, format, output string) error {
+	frames := GetRecordingConfig().PreviewFrames
+
+	var duration time.Duration
+	width, height := 16, 9
+	if info, err := getRecordingInfo(recording); err == nil {
+		duration = time.Duration(info.Duration * float64(time.Second))
+		if info.Width > 0 && info.Height > 0 {
+			width, height = info.Width, info.Height
+		}
+	} else if !recording.EndTime.IsZero() {
+		duration = recording.EndTime.Sub(recording.StartTime)
+	}
+	if duration <= 0 {
+		return errors.New("unknown recording duration")
+	}
+	interval := duration / time.Duration(frames)
+
+	filter := fmt.Sprintf("fps=1/%s", formatSeconds(interval))
+
+	var args []string
+	switch format {
+	case PreviewSprite:
+		rows := (frames + previewSpriteCols - 1) / previewSpriteCols
+		cols := min(frames, previewSpriteCols)
+		filter += fmt.Sprintf(",scale=%d:-2,tile=%dx%d", previewSpriteWidth, cols, rows)
+		args = []string{"-frames:v", "1", "-q:v", "5"}
+	case PreviewGIF:
+		filter += fmt.Sprintf(",scale=%d:-2,setpts=N/%d/TB,split[a][b];[a]palettegen[p];[b][p]paletteuse", previewWidth, previewFrameRate)
+		args = []string{"-frames:v", strconv.Itoa(frames), "-loop", "0"}
+	case PreviewWebP:
+		filter += fmt.Sprintf(",scale=%d:-2,setpts=N/%d/TB", previewWidth, previewFrameRate)
+		args = []string{"-frames:v", strconv.Itoa(frames), "-c:v", "libwebp", "-loop", "0", "-q:v", "60"}
+	default:
+		return fmt.Errorf("unknown preview format %q", format)
+	}
+
+	input := recordingInput(recording.Path)
+
+	cmdArgs := []string{"-hide_banner", "-v", "error"}
+	cmdArgs = append(cmdArgs, ffmpegProtocolArgs(input)...)
+	cmdArgs = append(cmdArgs, "-skip_frame", "nokey", "-i", input, "-an", "-vf", filter)
+	cmdArgs = append(cmdArgs, args...)
+
+	// Written next to the output and renamed, requests never see half a file
+	ext := filepath.Ext(output)
+	tmp := strings.TrimSuffix(output, ext) + ".tmp" + ext
+	cmdArgs = append(cmdArgs, "-y", tmp)
+
+	cmd := exec.Command(ffmpegBin(), cmdArgs...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%w: %s", err, extractFFmpegError(string(out)))
+	}
+	if info, err := os.Stat(tmp); err != nil || info.Size() == 0 {
+		_ = os.Remove(tmp)
+		return errors.New("ffmpeg produced no preview")
+	}
+
+	if format == PreviewSprite {
+		tileHeight := previewSpriteWidth * height / width &^ 1
+		vtt := spriteVTT(recording, frames, interval, tileHeight)
+		if err := os.WriteFile(previewVTTPath(recording), []byte(vtt), 0644); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+
+	return os.Rename(tmp, output)
+}
+
+// spriteVTT maps each interval of the recording to its tile of the sprite
+// sheet. Image URLs are relative to the WebVTT URL.
+func spriteVTT(recording *RecordingFile, frames int, interval time.Duration, tileHeight int) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+
+	for i := 0; i < frames; i++ {
+		x := i % previewSpriteCols * previewSpriteWidth
+		y := i / previewSpriteCols * tileHeight
+		fmt.Fprintf(&sb, "\n%s --> %s\nrecordings?preview=%s#xywh=%d,%d,%d,%d\n",
+			formatVTTTime(time.Duration(i)*interval), formatVTTTime(time.Duration(i+1)*interval),
+			recording.ID, x, y, previewSpriteWidth, tileHeight)
+	}
+
+	return sb.String()
+}
+
+func formatVTTTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// previewQueue generates the previews of finished files one at a time, so
+// they are ready before anyone hovers over the recording
+type previewQueue struct {
+	queue []string
+	wake  chan struct{}
+	mu    sync.Mutex
+}
+
+var previewJobs *previewQueue
+
+// startPreviewQueue starts the preview worker if preview_format is set
+func startPreviewQueue() {
+	if !previewsEnabled() {
+		return
+	}
+
+	previewJobs = &previewQueue{wake: make(chan struct{}, 1)}
+	go previewJobs.run()
+
+	log.Info().Str("format", GetRecordingConfig().PreviewFormat).Msg("[recording] generating previews of finished recordings")
+}
+
+// queuePreview schedules the preview of a finished file
+func queuePreview(filePath string) {
+	q := previewJobs
+	if q == nil || !previewsEnabled() {
+		return
+	}
+
+	q.mu.Lock()
+	q.queue = append(q.queue, filePath)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *previewQueue) run() {
+	for range q.wake {
+		for {
+			q.mu.Lock()
+			if len(q.queue) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			path := q.queue[0]
+			q.queue = q.queue[1:]
+			q.mu.Unlock()
+
+			recording := recordingIndex.GetByPath(path)
+			if recording == nil {
+				continue // deleted in the meantime
+			}
+
+			if _, err := getRecordingPreview(recording, GetRecordingConfig().PreviewFormat); err != nil {
+				log.Warn().Err(err).Str("path", path).Msg("[recording] preview generation failed")
+			}
+		}
+	}
+}
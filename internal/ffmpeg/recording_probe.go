@@ -0,0 +1,131 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// getRecordingInfo returns ffprobe details for the recording, served from the
+// index cache while the file's size and mtime are unchanged
+func getRecordingInfo(recording *RecordingFile) (*RecordingInfo, error) {
+	stat, err := os.Stat(recording.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached := recordingIndex.cachedProbe(recording.Path, stat.Size(), stat.ModTime()); cached != nil {
+		info := *cached
+		info.RecordingFile = recording
+		return &info, nil
+	}
+
+	info, err := getRecordingDetailedInfo(recording)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files still being written change on every probe, don't bother caching
+	if !isActiveRecording(stat.Size(), stat.ModTime()) {
+		cached := *info
+		cached.RecordingFile = nil
+		recordingIndex.storeProbe(recording.Path, stat.Size(), stat.ModTime(), &cached)
+	}
+
+	return info, nil
+}
+
+// applyExactDuration replaces the filename based duration estimate of a
+// finished recording with the probed container duration
+func applyExactDuration(recording *RecordingFile) {
+	if recording.EndTime.IsZero() {
+		return // still recording
+	}
+
+	info, err := getRecordingInfo(recording)
+	if err != nil || info.Duration <= 0 {
+		return
+	}
+
+	setProbedDuration(recording, info.Duration)
+}
+
+// setProbedDuration replaces the estimated end time and duration of a
+// finished recording with a probed duration in seconds
+func setProbedDuration(recording *RecordingFile, seconds float64) {
+	if recording.EndTime.IsZero() || seconds <= 0 {
+		return
+	}
+
+	duration := time.Duration(seconds * float64(time.Second))
+	recording.EndTime = recording.StartTime.Add(duration)
+	recording.DurationSeconds = seconds
+	if duration < time.Minute {
+		recording.Duration = fmt.Sprintf("%.0fs", duration.Seconds())
+	} else {
+		recording.Duration = fmt.Sprintf("%.0fm", duration.Minutes())
+	}
+}
+
+// durationProbeRoutine probes finished recordings after every index
+// reconcile, so listings show exact durations without ?exact=true
+func durationProbeRoutine() {
+	interval := GetRecordingConfig().IndexInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Files ffprobe can't read, by mtime, not retried until they change
+	failed := map[string]time.Time{}
+
+	for {
+		if !runDurationProbe(failed) {
+			return
+		}
+		<-ticker.C
+	}
+}
+
+// runDurationProbe probes the finished files without a cached result, one
+// at a time. Returns false if ffprobe is not available.
+func runDurationProbe(failed map[string]time.Time) bool {
+	var probed int
+
+	for _, entry := range recordingIndex.unprobedEntries() {
+		if time.Since(entry.ModTime) < integritySettle {
+			continue // still being written
+		}
+		if entry.Health != nil {
+			continue // the integrity check already probed it
+		}
+		if failed[entry.Path].Equal(entry.ModTime) {
+			continue
+		}
+		recording := entry.recordingFile()
+		if recording == nil {
+			continue
+		}
+
+		_, err := getRecordingInfo(recording)
+		switch {
+		case errors.Is(err, exec.ErrNotFound):
+			log.Warn().Err(err).Msg("[index] ffprobe not available, durations stay estimated")
+			return false
+		case err != nil:
+			failed[entry.Path] = entry.ModTime
+		default:
+			delete(failed, entry.Path)
+			probed++
+		}
+	}
+
+	if probed > 0 {
+		log.Debug().Int("probed", probed).Msg("[index] probed recording durations")
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package ffmpeg
+
+import (
+	"os"
+	"time"
+)
+
+// stopTimeout returns how long ffmpeg may take to finalize a file after
+// SIGINT before the process is killed
+func stopTimeout() time.Duration {
+	if timeout := GetRecordingConfig().ShutdownTimeout; timeout > 0 {
+		return timeout
+	}
+	return time.Second * 15
+}
+
+// processAlive reports whether the ffmpeg child of the recording is still
+// running. The reaper closes done once Wait returns, so this works the same
+// on every platform and never matches processes we didn't start.
+func (r *Recording) processAlive() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.processAliveLocked()
+}
+
+func (r *Recording) processAliveLocked() bool {
+	if r.cmd == nil || r.cmd.Process == nil || r.done == nil {
+		return false // native recordings have no process
+	}
+	select {
+	case <-r.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// kill terminates the ffmpeg child without waiting for it to finalize the
+// file, the reason is kept in the history of the recording
+func (r *Recording) kill(reason string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.processAliveLocked() {
+		return false
+	}
+	if r.stopReason == "" {
+		r.stopReason = reason
+	}
+	if err := r.cmd.Process.Kill(); err != nil {
+		log.Warn().Err(err).Int("pid", r.PID).Str("recording_id", r.ID).Msg("[recording] failed to kill ffmpeg process")
+		return false
+	}
+	return true
+}
+
+// escalateStop kills the process if it is still running stopTimeout after
+// it was asked to stop
+func escalateStop(id string, process *os.Process, done chan struct{}) {
+	if process == nil || done == nil {
+		return
+	}
+
+	go func() {
+		timer := time.NewTimer(stopTimeout())
+		defer timer.Stop()
+
+		select {
+		case <-done:
+		case <-timer.C:
+			log.Warn().Int("pid", process.Pid).Str("recording_id", id).Msg("[recording] ffmpeg ignored interrupt, killing")
+			_ = process.Kill()
+		}
+	}()
+}
+
+// trackedRecordings returns every recording started by this process for a
+// stream (all streams if empty), including current segments of segmented
+// recordings
+func trackedRecordings(streamName string) []*Recording {
+	var recordings []*Recording
+	for _, recording := range GetRecordingManager().ListRecordings() {
+		if streamName == "" || recording.Stream == streamName {
+			recordings = append(recordings, recording)
+		}
+	}
+	for _, segmented := range GetSegmentedRecordingManager().ListSegmentedRecordings() {
+		segmented.mu.Lock()
+		if current := segmented.currentRecording; current != nil && (streamName == "" || current.Stream == streamName) {
+			recordings = append(recordings, current)
+		}
+		segmented.mu.Unlock()
+	}
+	return recordings
+}
+
+// trackedFFmpegPIDs returns the PIDs of running ffmpeg children for a stream
+// (all streams if empty)
+func trackedFFmpegPIDs(streamName string) []int {
+	var pids []int
+	for _, recording := range trackedRecordings(streamName) {
+		recording.mu.Lock()
+		if recording.processAliveLocked() {
+			pids = append(pids, recording.PID)
+		}
+		recording.mu.Unlock()
+	}
+	return pids
+}
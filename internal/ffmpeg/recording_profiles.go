@@ -0,0 +1,121 @@
+package ffmpeg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ExportProfile is a named preset of the ?profile= parameter of exports,
+// downloads and playback
+type ExportProfile struct {
+	Transcode string `yaml:"transcode" json:"transcode,omitempty"` // "h264" re-encodes, "copy" remuxes, empty keeps the file
+	Height    int    `yaml:"height" json:"height,omitempty"`       // scale to this height, 0 keeps it
+	Bitrate   string `yaml:"bitrate" json:"bitrate,omitempty"`     // cap the video bitrate, e.g. "8M"
+	Format    string `yaml:"format" json:"format,omitempty"`       // container of exported clips, default the recording's
+	Checksum  bool   `yaml:"checksum" json:"checksum,omitempty"`   // SHA-256 of exported clips and a manifest of stored ones
+}
+
+// builtinExportProfiles are available without config, export_profiles may
+// replace them
+var builtinExportProfiles = map[string]ExportProfile{
+	"whatsapp": {Transcode: "h264", Height: 720, Bitrate: "8M", Format: "mp4"},
+	"evidence": {Checksum: true},
+}
+
+// lookupExportProfile returns the profile of the name, the zero profile for
+// an empty name
+func lookupExportProfile(name string) (ExportProfile, error) {
+	if name == "" {
+		return ExportProfile{}, nil
+	}
+	if profile, ok := GetRecordingConfig().ExportProfiles[name]; ok {
+		return profile, nil
+	}
+	if profile, ok := builtinExportProfiles[name]; ok {
+		return profile, nil
+	}
+	return ExportProfile{}, fmt.Errorf("unknown profile %q", name)
+}
+
+// transcodeOptions returns the transcoding of the profile, nil if it keeps
+// the recording as it is
+func (p ExportProfile) transcodeOptions() (*transcodeOptions, error) {
+	query := map[string][]string{}
+	if p.Transcode != "" {
+		query["transcode"] = []string{p.Transcode}
+	}
+	if p.Height != 0 {
+		query["height"] = []string{strconv.Itoa(p.Height)}
+	}
+	if p.Bitrate != "" {
+		query["bitrate"] = []string{p.Bitrate}
+	}
+	return parseTranscodeOptions(query)
+}
+
+// ExportManifest lists the checksums of a stored clip and the recordings it
+// was cut from
+type ExportManifest struct {
+	File    string           `json:"file"`
+	SHA256  string           `json:"sha256"`
+	Size    int64            `json:"size"`
+	Stream  string           `json:"stream"`
+	Start   time.Time        `json:"start"`
+	End     time.Time        `json:"end"`
+	Profile string           `json:"profile,omitempty"`
+	Created time.Time        `json:"created"`
+	Sources []ManifestSource `json:"sources"`
+}
+
+// ManifestSource is a recording an exported clip was cut from, checksummed
+// as stored on disk
+type ManifestSource struct {
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// fileSHA256 returns the hex SHA-256 and the size of a file
+func fileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// writeExportManifest writes {clip}.manifest.json next to a stored clip and
+// returns its path
+func writeExportManifest(manifest *ExportManifest, clip string, segments []RecordingFile) (string, error) {
+	for _, segment := range segments {
+		sum, size, err := fileSHA256(segment.Path)
+		if err != nil {
+			return "", err
+		}
+		manifest.Sources = append(manifest.Sources, ManifestSource{File: segment.Path, SHA256: sum, Size: size})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := clip + ".manifest.json"
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
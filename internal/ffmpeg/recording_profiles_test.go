@@ -0,0 +1,37 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportProfiles(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{
+		ExportProfiles: map[string]ExportProfile{
+			"mobile":   {Transcode: "h264", Height: 480, Bitrate: "1M"},
+			"whatsapp": {Transcode: "h264", Height: 480},
+		},
+	})
+
+	opts, err := parseTranscodeOptions(map[string][]string{"profile": {"mobile"}})
+	require.Nil(t, err)
+	require.Equal(t, "h264_480_1M", opts.name())
+
+	// The config replaces a built-in profile of the same name
+	opts, err = parseTranscodeOptions(map[string][]string{"profile": {"whatsapp"}})
+	require.Nil(t, err)
+	require.Equal(t, "h264_480", opts.name())
+
+	// Evidence keeps the recording as it is
+	opts, err = parseTranscodeOptions(map[string][]string{"profile": {"evidence"}})
+	require.Nil(t, err)
+	require.Nil(t, opts)
+
+	_, err = parseTranscodeOptions(map[string][]string{"profile": {"unknown"}})
+	require.NotNil(t, err)
+	_, err = parseTranscodeOptions(map[string][]string{"profile": {"mobile"}, "height": {"720"}})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,120 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FFmpegProgress is the latest -progress report of a recording's ffmpeg
+type FFmpegProgress struct {
+	Frame          int64     `json:"frame"`
+	FPS            float64   `json:"fps"`
+	BitrateKbps    float64   `json:"bitrate_kbps"`
+	TotalSize      int64     `json:"total_size"`
+	OutTimeSeconds float64   `json:"out_time_seconds"`
+	DupFrames      int64     `json:"dup_frames"`
+	DropFrames     int64     `json:"drop_frames"`
+	Speed          float64   `json:"speed"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// progressArgs makes ffmpeg write key=value progress blocks to stdout instead
+// of the stats line on stderr
+var progressArgs = []string{"-progress", "pipe:1", "-nostats"}
+
+// readProgress parses the -progress output until EOF and calls update after
+// every complete block
+func readProgress(r io.Reader, update func(FFmpegProgress)) {
+	var progress FFmpegProgress
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "frame":
+			progress.Frame, _ = strconv.ParseInt(value, 10, 64)
+		case "fps":
+			progress.FPS, _ = strconv.ParseFloat(value, 64)
+		case "bitrate":
+			// "1024.5kbits/s" or "N/A" before the first packet
+			progress.BitrateKbps, _ = strconv.ParseFloat(strings.TrimSuffix(value, "kbits/s"), 64)
+		case "total_size":
+			progress.TotalSize, _ = strconv.ParseInt(value, 10, 64)
+		case "out_time_us", "out_time_ms": // both are microseconds
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil {
+				progress.OutTimeSeconds = float64(us) / 1e6
+			}
+		case "dup_frames":
+			progress.DupFrames, _ = strconv.ParseInt(value, 10, 64)
+		case "drop_frames":
+			progress.DropFrames, _ = strconv.ParseInt(value, 10, 64)
+		case "speed":
+			progress.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+		case "progress":
+			progress.UpdatedAt = time.Now()
+			update(progress)
+		}
+	}
+}
+
+// runFFmpeg runs ffmpeg to completion, the error has ffmpeg's message. With
+// a progress func and the expected output duration it reports the part of
+// the output written so far.
+func runFFmpeg(ctx context.Context, args []string, duration time.Duration, progress func(float64)) error {
+	if progress == nil || duration <= 0 {
+		out, err := exec.CommandContext(ctx, ffmpegBin(), args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, extractFFmpegError(string(out)))
+		}
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpegBin(), append(slices.Clone(progressArgs), args...)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	readProgress(stdout, func(p FFmpegProgress) {
+		progress(p.OutTimeSeconds / duration.Seconds())
+	})
+
+	if err = cmd.Wait(); err != nil {
+		return fmt.Errorf("%w: %s", err, extractFFmpegError(stderr.String()))
+	}
+	return nil
+}
+
+// setProgress stores the latest progress report
+func (r *Recording) setProgress(progress FFmpegProgress) {
+	r.mu.Lock()
+	r.Progress = &progress
+	r.mu.Unlock()
+}
+
+// hasFrames reports whether ffmpeg has written a frame of the current file
+func (r *Recording) hasFrames() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Progress != nil && r.Progress.Frame > 0
+}
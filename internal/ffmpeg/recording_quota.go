@@ -0,0 +1,163 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Quota modes of max_total_size
+const (
+	QuotaCleanup = "cleanup" // the cleanup deletes the oldest recordings over the quota
+	QuotaEnforce = "enforce" // recordings over the quota are also refused and stopped
+)
+
+// QuotaStatus is the storage a stream uses over its max_total_size, the
+// global max_total_size for the empty stream
+type QuotaStatus struct {
+	Stream  string    `json:"stream,omitempty"`
+	UsedMB  int64     `json:"used_mb"`
+	LimitMB int64     `json:"limit_mb"`
+	Since   time.Time `json:"since"`
+}
+
+// QuotaMonitor keeps the streams over their quota with quota_mode enforce.
+// Their recordings are stopped and new ones refused until the cleanup brings
+// them below it again.
+type QuotaMonitor struct {
+	exceeded map[string]QuotaStatus
+	mu       sync.RWMutex
+}
+
+var quotaMonitor = &QuotaMonitor{}
+
+// errQuotaExceeded is returned for new recordings of streams over their quota
+var errQuotaExceeded = errors.New("storage quota exceeded")
+
+// quotaMonitorRoutine checks the quotas with the disk check interval
+func quotaMonitorRoutine() {
+	interval := GetRecordingConfig().DiskCheckInterval
+	if interval <= 0 {
+		interval = time.Second * 30
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		quotaMonitor.check()
+		<-ticker.C
+	}
+}
+
+// Exceeded returns the streams over their quota, "" for the global quota
+func (m *QuotaMonitor) Exceeded() map[string]QuotaStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	exceeded := make(map[string]QuotaStatus, len(m.exceeded))
+	for name, status := range m.exceeded {
+		exceeded[name] = status
+	}
+	return exceeded
+}
+
+// allowRecording returns an error while the stream or all recordings are over
+// their quota
+func (m *QuotaMonitor) allowRecording(streamName string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if status, ok := m.exceeded[streamName]; ok {
+		return fmt.Errorf("%w: stream %s uses %d MB of its max_total_size of %d MB",
+			errQuotaExceeded, streamName, status.UsedMB, status.LimitMB)
+	}
+	if status, ok := m.exceeded[""]; ok {
+		return fmt.Errorf("%w: recordings use %d MB of the max_total_size of %d MB",
+			errQuotaExceeded, status.UsedMB, status.LimitMB)
+	}
+	return nil
+}
+
+func (m *QuotaMonitor) check() {
+	var exceeded map[string]QuotaStatus
+	if GetRecordingConfig().QuotaMode == QuotaEnforce {
+		exceeded = overQuota(time.Now())
+	}
+
+	m.mu.Lock()
+	previous := m.exceeded
+	for name, status := range exceeded {
+		if prev, ok := previous[name]; ok {
+			status.Since = prev.Since
+			exceeded[name] = status
+		}
+	}
+	m.exceeded = exceeded
+	m.mu.Unlock()
+
+	for name, status := range exceeded {
+		if _, ok := previous[name]; !ok {
+			log.Error().
+				Str("stream", name).
+				Int64("used_mb", status.UsedMB).
+				Int64("limit_mb", status.LimitMB).
+				Msg("[quota] storage quota exceeded, recordings stopped")
+			notify(NotifyQuotaExceeded, name, status)
+		}
+		// Also stops recordings started through the API in the meantime
+		stopRecordingsOverQuota(name)
+	}
+	for name, status := range previous {
+		if _, ok := exceeded[name]; !ok {
+			log.Info().Str("stream", name).Msg("[quota] storage below quota, recordings allowed again")
+			notify(NotifyQuotaRecovered, name, status)
+		}
+	}
+}
+
+// overQuota sums the indexed recordings of each stream, the file being
+// written included, and returns the streams over their max_total_size
+func overQuota(now time.Time) map[string]QuotaStatus {
+	sizes := map[string]int64{}
+	var total int64
+	for _, recording := range recordingIndex.Query("", "", 0) {
+		if recording.Imported {
+			continue // not ours to count, never cleaned up
+		}
+		sizes[recording.StreamName] += recording.Size
+		total += recording.Size
+	}
+
+	exceeded := map[string]QuotaStatus{}
+	if limit := GetRecordingConfig().MaxTotalSize; limit > 0 && total > limit*1024*1024 {
+		exceeded[""] = QuotaStatus{UsedMB: total / 1024 / 1024, LimitMB: limit, Since: now}
+	}
+	for name, size := range sizes {
+		if limit := GetStreamRecordingConfig(name).MaxTotalSize; limit > 0 && size > limit*1024*1024 {
+			exceeded[name] = QuotaStatus{Stream: name, UsedMB: size / 1024 / 1024, LimitMB: limit, Since: now}
+		}
+	}
+	return exceeded
+}
+
+// stopRecordingsOverQuota stops the recordings of a stream, of all streams
+// for the global quota
+func stopRecordingsOverQuota(streamName string) {
+	for id, recording := range GetRecordingManager().ListRecordings() {
+		if recording.Active && (streamName == "" || recording.Stream == streamName) {
+			if err := GetRecordingManager().StopRecording(id, ExitQuota); err != nil {
+				log.Error().Err(err).Str("id", id).Msg("[quota] failed to stop recording")
+			}
+		}
+	}
+
+	for id, recording := range GetSegmentedRecordingManager().ListSegmentedRecordings() {
+		if recording.Active && (streamName == "" || recording.Stream == streamName) {
+			if err := GetSegmentedRecordingManager().StopSegmentedRecording(id, ExitQuota); err != nil {
+				log.Error().Err(err).Str("id", id).Msg("[quota] failed to stop segmented recording")
+			}
+		}
+	}
+}
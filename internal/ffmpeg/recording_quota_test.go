@@ -0,0 +1,23 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaMonitor(t *testing.T) {
+	m := &QuotaMonitor{}
+	require.Nil(t, m.allowRecording("cam1"))
+
+	m.exceeded = map[string]QuotaStatus{"cam1": {Stream: "cam1", UsedMB: 1100, LimitMB: 1024}}
+	err := m.allowRecording("cam1")
+	require.ErrorIs(t, err, errQuotaExceeded)
+	require.Equal(t, ErrorCodeQuotaExceeded, newStreamError("", err).Code)
+	require.True(t, isInsufficientStorage(err))
+	require.Nil(t, m.allowRecording("cam2"))
+
+	// The global quota stops every stream
+	m.exceeded[""] = QuotaStatus{UsedMB: 10300, LimitMB: 10240}
+	require.ErrorIs(t, m.allowRecording("cam2"), errQuotaExceeded)
+}
@@ -0,0 +1,123 @@
+package ffmpeg
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/pkg/shell"
+	"gopkg.in/yaml.v3"
+)
+
+// recordingDefaults are the built-in settings a reloaded config starts from
+var recordingDefaults RecordingConfig
+
+// configReloadRoutine reloads the recording config on SIGHUP and, with
+// config_watch_interval, whenever the config file changes
+func configReloadRoutine() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	if interval := GetRecordingConfig().ConfigWatchInterval; interval > 0 && app.ConfigPath != "" {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	modTime := configModTime()
+
+	for {
+		select {
+		case <-hup:
+			reloadRecordingConfig("SIGHUP")
+			modTime = configModTime()
+		case <-tick:
+			if t := configModTime(); !t.Equal(modTime) {
+				modTime = t
+				reloadRecordingConfig("config file changed")
+			}
+		}
+	}
+}
+
+func configModTime() time.Time {
+	if info, err := os.Stat(app.ConfigPath); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+// reloadRecordingConfig reads the recording section of the config file again
+// and applies it. Streams that were disabled stop, newly enabled ones start
+// and all other recordings keep running.
+func reloadRecordingConfig(reason string) {
+	if app.ConfigPath == "" {
+		log.Warn().Str("reason", reason).Msg("[config] no config file to reload")
+		return
+	}
+
+	data, err := os.ReadFile(app.ConfigPath)
+	if err != nil {
+		log.Error().Err(err).Msg("[config] failed to read config file, keeping the current recording config")
+		return
+	}
+
+	var cfg struct {
+		Recording RecordingConfig `yaml:"recording"`
+	}
+	cfg.Recording = recordingDefaults
+
+	if err = yaml.Unmarshal([]byte(shell.ReplaceEnvVars(string(data))), &cfg); err != nil {
+		log.Error().Err(err).Msg("[config] invalid config file, keeping the current recording config")
+		return
+	}
+
+	validateRecordingConfig(&cfg.Recording)
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	if settings := restartOnlyChanges(GetRecordingConfig(), &cfg.Recording); len(settings) > 0 {
+		log.Warn().Strs("settings", settings).Msg("[config] changed settings only fully apply after a restart")
+	}
+
+	applyRecordingConfig(&cfg.Recording)
+
+	log.Info().
+		Str("reason", reason).
+		Int("streams", len(cfg.Recording.Streams)).
+		Msg("[config] recording config reloaded")
+}
+
+// restartOnlyChanges returns the changed global settings that are only read
+// when recording starts up, e.g. the cleanup interval or the MQTT broker
+func restartOnlyChanges(old, updated *RecordingConfig) []string {
+	before, after := yamlSettings(old), yamlSettings(updated)
+
+	var changed []string
+	for key, value := range after {
+		if key == "streams" || slices.Contains(runtimeGlobalSettings, key) {
+			continue
+		}
+		if !reflect.DeepEqual(before[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// yamlSettings returns a config struct as a map with its YAML keys
+func yamlSettings(cfg any) map[string]any {
+	settings := map[string]any{}
+	if data, err := yaml.Marshal(cfg); err == nil {
+		_ = yaml.Unmarshal(data, &settings)
+	}
+	return settings
+}
@@ -0,0 +1,144 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+// replayPrefix is the RTSP path of recording replays, e.g.
+// rtsp://HOST:8554/replay/cam1?start=2025-01-15T14:30:00
+const replayPrefix = "replay/"
+
+// replayListTTL is how long the concat list of a replay is kept, ffmpeg
+// reads it once when it starts
+const replayListTTL = time.Minute
+
+// replayStream creates the stream of an RTSP replay, the recordings of a
+// stream played in real time from start, continuing across segments until
+// end or the last recording. Every connection gets its own stream, so
+// clients scrubbing the same camera don't move each other.
+func replayStream(name string, query url.Values, remoteAddr string) (*streams.Stream, error) {
+	streamName := strings.TrimPrefix(name, replayPrefix)
+	if streamName == "" {
+		return nil, errors.New("missing stream name, use replay/STREAM?start=TIME")
+	}
+
+	if err := authorizeReplay(streamName, query, remoteAddr); err != nil {
+		return nil, err
+	}
+
+	start, err := parseTimeParam(query.Get("start"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'start' parameter: %w", err)
+	}
+
+	end := time.Now()
+	if value := query.Get("end"); value != "" {
+		if end, err = parseTimeParam(value); err != nil {
+			return nil, fmt.Errorf("invalid 'end' parameter: %w", err)
+		}
+		if !end.After(start) {
+			return nil, errors.New("'end' must be after 'start'")
+		}
+	}
+
+	segments := findRecordingsInRange(streamName, start, end)
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no recordings of %s after %s", streamName, start.Format(time.RFC3339))
+	}
+
+	offset := start.Sub(segments[0].StartTime)
+	if offset < 0 {
+		offset = 0 // start falls in a gap, play from the next recording
+	}
+
+	// A concat list even for one segment, paths with spaces would split the
+	// exec command
+	list, err := os.CreateTemp("", "go2rtc-replay-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	var inputs []string
+	for _, segment := range segments {
+		path, err := filepath.Abs(segment.Path)
+		if err != nil {
+			_ = list.Close()
+			_ = os.Remove(list.Name())
+			return nil, err
+		}
+		path = recordingInput(path)
+		inputs = append(inputs, path)
+		_, _ = list.WriteString("file '" + strings.ReplaceAll(path, "'", `'\''`) + "'\n")
+	}
+	if err = list.Close(); err != nil {
+		_ = os.Remove(list.Name())
+		return nil, err
+	}
+	time.AfterFunc(replayListTTL, func() {
+		_ = os.Remove(list.Name())
+	})
+
+	args := []string{"-hide_banner", "-re"}
+	args = append(args, ffmpegProtocolArgs(inputs...)...)
+	args = append(args, "-f", "concat", "-safe", "0", "-ss", formatSeconds(offset))
+	if query.Get("end") != "" {
+		args = append(args, "-t", formatSeconds(end.Sub(start)))
+	}
+	args = append(args, "-i", list.Name(), "-c", "copy", "-f", "rtsp", "{output}")
+
+	quoted, err := execArgs(append([]string{ffmpegBin()}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	source := "exec:" + quoted
+
+	log.Info().
+		Str("stream_name", streamName).
+		Time("start", start).
+		Int("segments", len(segments)).
+		Msg("[recording] replaying recordings over RTSP")
+
+	return streams.NewStream(source), nil
+}
+
+// authorizeReplay checks a replay like a play request of the recording API,
+// RTSP clients pass their token as ?token=, and writes it to the audit log
+func authorizeReplay(streamName string, query url.Values, remoteAddr string) error {
+	params := url.Values{}
+	for key, values := range query {
+		params[key] = values
+	}
+	params.Set("stream", streamName)
+
+	r := &http.Request{
+		Method:     "GET",
+		URL:        &url.URL{Path: "/" + replayPrefix + streamName, RawQuery: params.Encode()},
+		Header:     http.Header{},
+		RemoteAddr: remoteAddr,
+	}
+
+	principal, err := checkRecordingAccess(r, permView)
+
+	if auditEnabled() {
+		entry := newAuditEntry(r, "replay")
+		entry.User = auditUser(r, principal)
+		entry.Status = http.StatusOK
+		if err != nil {
+			entry.Status = err.status
+		}
+		writeAuditEntry(*entry)
+	}
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
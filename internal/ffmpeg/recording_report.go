@@ -0,0 +1,373 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reportGapTolerance merges segments closer than this, like the timeline
+const reportGapTolerance = 5 * time.Second
+
+// StreamReport summarises one day of a stream
+type StreamReport struct {
+	Stream        string          `json:"stream"`
+	RecordedHours float64         `json:"recorded_hours"`
+	Coverage      float64         `json:"coverage_percent"`
+	Gaps          []TimelineRange `json:"gaps"`
+	GapHours      float64         `json:"gap_hours"`
+	Recordings    int             `json:"recordings"` // files started that day
+	Events        int             `json:"events"`     // event recordings started that day
+	Size          int64           `json:"size"`
+	SizeHuman     string          `json:"size_human"`
+	Failures      int             `json:"failures"` // failed starts, stalls and killed processes
+}
+
+// DailyReport summarises one day of all streams
+type DailyReport struct {
+	Date          string         `json:"date"`
+	GeneratedAt   time.Time      `json:"generated_at"`
+	Streams       []StreamReport `json:"streams"`
+	RecordedHours float64        `json:"recorded_hours"`
+	Recordings    int            `json:"recordings"`
+	Events        int            `json:"events"`
+	Size          int64          `json:"size"`
+	SizeHuman     string         `json:"size_human"`
+	Failures      int            `json:"failures"`
+}
+
+// reportFailures counts failures by day and stream since the start, the
+// recordings themselves don't show them
+var reportFailures = struct {
+	days map[string]map[string]int
+	mu   sync.Mutex
+}{days: make(map[string]map[string]int)}
+
+// countReportFailure counts a failure of a stream for today's report
+func countReportFailure(streamName string) {
+	date := time.Now().Format("2006-01-02")
+
+	reportFailures.mu.Lock()
+	defer reportFailures.mu.Unlock()
+
+	if reportFailures.days[date] == nil {
+		reportFailures.days[date] = make(map[string]int)
+		// Reports are written the next day, a week is plenty
+		for day := range reportFailures.days {
+			if day < time.Now().AddDate(0, 0, -7).Format("2006-01-02") {
+				delete(reportFailures.days, day)
+			}
+		}
+	}
+	reportFailures.days[date][streamName]++
+}
+
+func reportFailureCounts(date string) map[string]int {
+	reportFailures.mu.Lock()
+	defer reportFailures.mu.Unlock()
+
+	counts := make(map[string]int, len(reportFailures.days[date]))
+	for streamName, count := range reportFailures.days[date] {
+		counts[streamName] = count
+	}
+	return counts
+}
+
+// reportPath returns the directory of the daily reports
+func reportPath() string {
+	cfg := GetRecordingConfig()
+	return cmp.Or(cfg.ReportPath, filepath.Join(cfg.BasePath, "reports"))
+}
+
+// buildDailyReport summarises the day starting at day, for one stream or
+// all streams with recordings, a recording config or failures that day
+func buildDailyReport(day time.Time, streamName string) *DailyReport {
+	date := day.Format("2006-01-02")
+	failures := reportFailureCounts(date)
+
+	names := map[string]bool{}
+	if streamName != "" {
+		names[streamName] = true
+	} else {
+		for _, recording := range recordingIndex.Query("", date, 0) {
+			names[recording.StreamName] = true
+		}
+		for name := range configuredStreams(GetRecordingConfig()) {
+			names[name] = true
+		}
+		for name := range failures {
+			names[name] = true
+		}
+	}
+
+	report := &DailyReport{
+		Date:        date,
+		GeneratedAt: time.Now(),
+		Streams:     []StreamReport{},
+	}
+
+	dayEnd := day.AddDate(0, 0, 1)
+	for name := range names {
+		recordings := findRecordingsInRange(name, day, dayEnd)
+		timeline := buildTimelineFrom(recordings, name, day, dayEnd, reportGapTolerance, estimatedRecordingDuration)
+
+		stream := StreamReport{
+			Stream:        name,
+			RecordedHours: timeline.RecordedSeconds / 3600,
+			Coverage:      timeline.Coverage,
+			Gaps:          timeline.Gaps,
+			GapHours:      timeline.GapSeconds / 3600,
+			Failures:      failures[name],
+		}
+		for _, recording := range recordings {
+			if recording.StartTime.Before(day) || !recording.StartTime.Before(dayEnd) {
+				continue
+			}
+			stream.Recordings++
+			stream.Size += recording.Size
+			if recording.Event {
+				stream.Events++
+			}
+		}
+		stream.SizeHuman = formatFileSize(stream.Size)
+
+		report.Streams = append(report.Streams, stream)
+	}
+
+	sort.Slice(report.Streams, func(i, j int) bool {
+		return report.Streams[i].Stream < report.Streams[j].Stream
+	})
+	report.total()
+
+	return report
+}
+
+// total sums up the streams of the report
+func (r *DailyReport) total() {
+	r.RecordedHours, r.Recordings, r.Events, r.Size, r.Failures = 0, 0, 0, 0, 0
+	for _, stream := range r.Streams {
+		r.RecordedHours += stream.RecordedHours
+		r.Recordings += stream.Recordings
+		r.Events += stream.Events
+		r.Size += stream.Size
+		r.Failures += stream.Failures
+	}
+	r.SizeHuman = formatFileSize(r.Size)
+}
+
+// only returns the report limited to one stream, nil if it has no such stream
+func (r *DailyReport) only(streamName string) *DailyReport {
+	for _, stream := range r.Streams {
+		if stream.Stream == streamName {
+			report := *r
+			report.Streams = []StreamReport{stream}
+			report.total()
+			return &report
+		}
+	}
+	return nil
+}
+
+// loadDailyReport reads a written report, nil if there is none
+func loadDailyReport(date string) *DailyReport {
+	data, err := os.ReadFile(filepath.Join(reportPath(), date+".json"))
+	if err != nil {
+		return nil
+	}
+	var report DailyReport
+	if err = json.Unmarshal(data, &report); err != nil {
+		return nil
+	}
+	return &report
+}
+
+// writeDailyReport writes the JSON and HTML report of a day and sends it to
+// report_notifiers
+func writeDailyReport(day time.Time) error {
+	report := buildDailyReport(day, "")
+
+	dir := reportPath()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(dir, report.Date+".json"), data, 0644); err != nil {
+		return err
+	}
+
+	html, err := report.html()
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(dir, report.Date+".html"), html, 0644); err != nil {
+		return err
+	}
+
+	log.Info().Str("date", report.Date).Int("streams", len(report.Streams)).
+		Float64("recorded_hours", report.RecordedHours).Int("failures", report.Failures).
+		Msg("[report] daily report written")
+
+	sendDailyReport(report, html)
+	return nil
+}
+
+// sendDailyReport delivers the report to report_notifiers, as HTML where the
+// notifier supports it and as a text summary otherwise
+func sendDailyReport(report *DailyReport, html []byte) {
+	names := GetRecordingConfig().ReportNotifiers
+	if len(names) == 0 {
+		return
+	}
+	if alerts == nil {
+		log.Warn().Strs("notifiers", names).Msg("[report] no notifiers configured, report not sent")
+		return
+	}
+
+	subject := "Recording report " + report.Date
+	for _, name := range names {
+		notifier := alerts.notifiers[name]
+		if notifier == nil {
+			log.Warn().Str("notifier", name).Msg("[report] unknown report notifier")
+			continue
+		}
+
+		go func() {
+			var err error
+			if n, ok := notifier.(htmlNotifier); ok {
+				err = n.sendHTML(subject, html)
+			} else {
+				err = notifier.send(Alert{
+					Rule:    "daily report " + report.Date,
+					State:   AlertReport,
+					Message: report.summary(),
+					Since:   report.GeneratedAt,
+					Time:    report.GeneratedAt,
+					Data:    report,
+				})
+			}
+			if err != nil {
+				log.Warn().Err(err).Str("notifier", name).Msg("[report] failed to send daily report")
+			}
+		}()
+	}
+}
+
+// summary is the text form of the report for chat notifiers
+func (r *DailyReport) summary() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%.1f h recorded, %d recordings, %d events, %s, %d failures",
+		r.RecordedHours, r.Recordings, r.Events, r.SizeHuman, r.Failures)
+	for _, stream := range r.Streams {
+		fmt.Fprintf(&sb, "\n%s: %.1f h (%.1f%%), %d gaps, %d events, %s, %d failures",
+			stream.Stream, stream.RecordedHours, stream.Coverage, len(stream.Gaps), stream.Events, stream.SizeHuman, stream.Failures)
+	}
+	return sb.String()
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Recording report {{.Date}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.warn { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Recording report {{.Date}}</h1>
+<p>{{printf "%.1f" .RecordedHours}} h recorded, {{.Recordings}} recordings, {{.Events}} events, {{.SizeHuman}}, {{.Failures}} failures</p>
+<table>
+<tr><th>Stream</th><th>Recorded</th><th>Coverage</th><th>Gaps</th><th>Recordings</th><th>Events</th><th>Storage</th><th>Failures</th></tr>
+{{range .Streams}}<tr>
+<td>{{.Stream}}</td>
+<td>{{printf "%.1f" .RecordedHours}} h</td>
+<td{{if lt .Coverage 95.0}} class="warn"{{end}}>{{printf "%.1f" .Coverage}}%</td>
+<td>{{len .Gaps}} ({{printf "%.1f" .GapHours}} h)</td>
+<td>{{.Recordings}}</td>
+<td>{{.Events}}</td>
+<td>{{.SizeHuman}}</td>
+<td{{if .Failures}} class="warn"{{end}}>{{.Failures}}</td>
+</tr>
+{{end}}</table>
+{{range .Streams}}{{if .Gaps}}
+<h2>Gaps of {{.Stream}}</h2>
+<ul>
+{{range .Gaps}}<li>{{.Start.Format "15:04:05"}} – {{.End.Format "15:04:05"}}</li>
+{{end}}</ul>
+{{end}}{{end}}
+<p><small>Generated {{.GeneratedAt.Format "2006-01-02 15:04:05"}}</small></p>
+</body>
+</html>
+`))
+
+func (r *DailyReport) html() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// startReports counts failures for the reports and writes them daily with
+// report_time
+func startReports() {
+	subscribeNotifications(func(n RecordingNotification) {
+		if n.Type == NotifyRecordingStalled || n.Type == NotifyResourceLimit {
+			countReportFailure(n.Stream)
+		}
+	})
+
+	if GetRecordingConfig().ReportTime != "" {
+		go reportRoutine()
+	}
+}
+
+// reportRoutine writes the report of the previous day at report_time, and
+// right away if yesterday's report was missed while go2rtc was down
+func reportRoutine() {
+	at, err := parseClock(GetRecordingConfig().ReportTime)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	yesterday := today.AddDate(0, 0, -1)
+	if now.After(today.Add(at)) && loadDailyReport(yesterday.Format("2006-01-02")) == nil {
+		if err := writeDailyReport(yesterday); err != nil {
+			log.Error().Err(err).Msg("[report] failed to write daily report")
+		}
+	}
+
+	for {
+		now = time.Now()
+		today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		next := today.Add(at)
+		if !next.After(now) {
+			next = today.AddDate(0, 0, 1).Add(at)
+		}
+		time.Sleep(time.Until(next))
+
+		// The day before the one report_time is in
+		day := time.Date(next.Year(), next.Month(), next.Day()-1, 0, 0, 0, 0, time.Local)
+		if err := writeDailyReport(day); err != nil {
+			log.Error().Err(err).Msg("[report] failed to write daily report")
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyReport(t *testing.T) {
+	day := time.Date(2025, 1, 15, 0, 0, 0, 0, time.Local)
+	report := &DailyReport{
+		Date: "2025-01-15",
+		Streams: []StreamReport{
+			{Stream: "cam1", RecordedHours: 23.5, Coverage: 97.9, Recordings: 144, Events: 2, Size: 3 << 30, Failures: 1,
+				Gaps: []TimelineRange{{Start: day.Add(time.Hour * 3), End: day.Add(time.Hour*3 + time.Minute*30)}}},
+			{Stream: "cam2", RecordedHours: 12, Coverage: 50, Recordings: 72, Size: 1 << 30},
+		},
+	}
+	report.total()
+	require.Equal(t, 35.5, report.RecordedHours)
+	require.Equal(t, 216, report.Recordings)
+	require.Equal(t, int64(4<<30), report.Size)
+	require.Equal(t, 1, report.Failures)
+
+	cam2 := report.only("cam2")
+	require.Len(t, cam2.Streams, 1)
+	require.Equal(t, 12.0, cam2.RecordedHours)
+	require.Equal(t, 0, cam2.Failures)
+	require.Nil(t, report.only("cam3"))
+	require.Len(t, report.Streams, 2)
+
+	html, err := report.html()
+	require.Nil(t, err)
+	require.Contains(t, string(html), "<td>cam1</td>")
+	require.Contains(t, string(html), "03:00:00 – 03:30:00")
+
+	require.Contains(t, report.summary(), "cam1: 23.5 h (97.9%), 1 gaps, 2 events")
+}
@@ -0,0 +1,142 @@
+package ffmpeg
+
+import (
+	"time"
+)
+
+// resourceLimitSamples is how many checks in a row a process must exceed
+// max_ffmpeg_cpu or max_ffmpeg_memory before it is killed, a keyframe burst
+// shouldn't count
+const resourceLimitSamples = 3
+
+// ProcessUsage is the CPU and memory use of a recording's ffmpeg process
+type ProcessUsage struct {
+	PID        int       `json:"pid"`
+	CPUPercent float64   `json:"cpu_percent"` // since the last check, 100 = one core
+	MemoryMB   float64   `json:"memory_mb"`   // resident
+	SampledAt  time.Time `json:"sampled_at"`
+}
+
+// ResourceLimitExceeded describes a recording process killed for its usage
+type ResourceLimitExceeded struct {
+	RecordingID string        `json:"recording_id"`
+	Stream      string        `json:"stream"`
+	Usage       *ProcessUsage `json:"usage"`
+	Limit       string        `json:"limit"` // max_ffmpeg_cpu or max_ffmpeg_memory
+}
+
+// processSample is the CPU time of a process at a check
+type processSample struct {
+	pid int
+	cpu float64 // seconds
+	at  time.Time
+}
+
+// resourceMonitorRoutine samples the recording processes every
+// resource_check_interval
+func resourceMonitorRoutine() {
+	interval := GetRecordingConfig().ResourceCheckInterval
+
+	log.Info().
+		Dur("interval", interval).
+		Float64("max_cpu", GetRecordingConfig().MaxFFmpegCPU).
+		Int64("max_memory_mb", GetRecordingConfig().MaxFFmpegMemory).
+		Msg("[resources] monitoring recording processes")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, recording := range trackedRecordings("") {
+			recording.sampleResources()
+		}
+	}
+}
+
+// sampleResources reads the usage of the ffmpeg process and kills it after
+// it exceeded a limit for resourceLimitSamples checks
+func (r *Recording) sampleResources() {
+	r.mu.Lock()
+	if !r.processAliveLocked() {
+		r.Resources = nil
+		r.mu.Unlock()
+		return
+	}
+	pid := r.PID
+	r.mu.Unlock()
+
+	cpu, rss, err := readProcessUsage(pid)
+	if err != nil {
+		log.Trace().Err(err).Int("pid", pid).Msg("[resources] failed to read process usage")
+		return
+	}
+	now := time.Now()
+
+	usage := &ProcessUsage{PID: pid, MemoryMB: round1(float64(rss) / 1024 / 1024), SampledAt: now}
+
+	r.mu.Lock()
+	// A rotated recording runs a new process, its first check has no CPU rate
+	if last := r.cpuSample; last.pid == pid && now.After(last.at) {
+		usage.CPUPercent = round1((cpu - last.cpu) / now.Sub(last.at).Seconds() * 100)
+	}
+	r.cpuSample = processSample{pid: pid, cpu: cpu, at: now}
+	r.Resources = usage
+
+	limit := exceededResourceLimit(usage)
+	if limit != "" {
+		r.overLimit++
+	} else {
+		r.overLimit = 0
+	}
+	kill := r.overLimit >= resourceLimitSamples
+	if kill {
+		r.overLimit = 0
+	}
+	r.mu.Unlock()
+
+	if !kill {
+		return
+	}
+
+	exceeded := ResourceLimitExceeded{RecordingID: r.ID, Stream: r.Stream, Usage: usage, Limit: limit}
+
+	log.Error().
+		Str("recording_id", r.ID).
+		Str("stream", r.Stream).
+		Int("pid", pid).
+		Float64("cpu_percent", usage.CPUPercent).
+		Float64("memory_mb", usage.MemoryMB).
+		Str("limit", limit).
+		Msg("[resources] recording process over its limit, killing")
+
+	notify(NotifyResourceLimit, r.Stream, exceeded)
+	r.kill(ExitResourceLimit)
+}
+
+// exceededResourceLimit returns the setting the usage is above, empty if none
+func exceededResourceLimit(usage *ProcessUsage) string {
+	cfg := GetRecordingConfig()
+	if cfg.MaxFFmpegCPU > 0 && usage.CPUPercent > cfg.MaxFFmpegCPU {
+		return "max_ffmpeg_cpu"
+	}
+	if cfg.MaxFFmpegMemory > 0 && usage.MemoryMB > float64(cfg.MaxFFmpegMemory) {
+		return "max_ffmpeg_memory"
+	}
+	return ""
+}
+
+// streamResources sums the last sampled usage of the recording processes by
+// stream
+func streamResources() (cpu map[string]float64, memory map[string]int64) {
+	cpu, memory = make(map[string]float64), make(map[string]int64)
+
+	for _, recording := range trackedRecordings("") {
+		recording.mu.Lock()
+		if usage := recording.Resources; usage != nil {
+			cpu[recording.Stream] += usage.CPUPercent
+			memory[recording.Stream] += int64(usage.MemoryMB * 1024 * 1024)
+		}
+		recording.mu.Unlock()
+	}
+	return
+}
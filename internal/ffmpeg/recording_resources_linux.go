@@ -0,0 +1,46 @@
+//go:build linux
+
+package ffmpeg
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// clockTicks is USER_HZ, the unit of CPU times in /proc. It is 100 on every
+// architecture Linux supports.
+const clockTicks = 100
+
+// readProcessUsage returns the CPU time in seconds and the resident memory
+// in bytes of a process
+func readProcessUsage(pid int) (cpu float64, rss uint64, err error) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// The command name may contain spaces and brackets, fields follow the last one
+	i := strings.LastIndexByte(string(data), ')')
+	if i < 0 {
+		return 0, 0, errors.New("invalid /proc stat")
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 22 {
+		return 0, 0, errors.New("invalid /proc stat")
+	}
+
+	// utime, stime and rss are fields 14, 15 and 24, counted from the pid
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	pages, _ := strconv.ParseUint(fields[21], 10, 64)
+
+	return float64(utime+stime) / clockTicks, pages * uint64(os.Getpagesize()), nil
+}
+
+// setProcessNice changes the scheduling priority of a process
+func setProcessNice(pid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package ffmpeg
+
+import "errors"
+
+var errNoProcessAccounting = errors.New("process usage not supported on this platform")
+
+func readProcessUsage(pid int) (cpu float64, rss uint64, err error) {
+	return 0, 0, errNoProcessAccounting
+}
+
+func setProcessNice(pid, nice int) error {
+	return errNoProcessAccounting
+}
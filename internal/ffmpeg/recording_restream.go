@@ -0,0 +1,67 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AlexxIT/go2rtc/internal/rtsp"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+// muxerOption is an option of the output muxer, e.g. segment_time
+type muxerOption struct {
+	key, value string
+}
+
+// ffmpeg unescapes tee outputs twice, once when splitting the outputs at |
+// and once more when splitting the options of an output at :
+var (
+	teeOutputEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `|`, `\|`)
+	teeOptionEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`)
+)
+
+// outputArgs returns the output of a recording: the muxer with its options
+// writing path, or with a restream a tee muxer that also publishes the same
+// packets to the go2rtc stream, so live viewers don't need a second ffmpeg
+// transcoding the camera
+func outputArgs(format string, options []muxerOption, path, restream string) []string {
+	if restream == "" {
+		args := []string{"-f", format}
+		for _, option := range options {
+			args = append(args, "-"+option.key, option.value)
+		}
+		return append(args, "-y", path)
+	}
+
+	slave := "f=" + format
+	for _, option := range options {
+		slave += ":" + option.key + "=" + teeOptionEscaper.Replace(option.value)
+	}
+
+	// The tee muxer has no default streams, and a failing live output must
+	// not stop the recording
+	return []string{
+		"-map", "0:v:0?", "-map", "0:a:0?", "-y", "-f", "tee",
+		teeOutputEscaper.Replace("["+slave+"]"+path) + "|" +
+			teeOutputEscaper.Replace("[f=rtsp:rtsp_transport=tcp:onfail=ignore]"+restreamURL(restream)),
+	}
+}
+
+// restreamName returns the go2rtc stream a recording publishes to, empty
+// without restream
+func restreamName(streamName string, streamConfig StreamRecordingConfig) string {
+	return strings.ReplaceAll(streamConfig.Restream, "{stream}", streamName)
+}
+
+func restreamURL(name string) string {
+	return fmt.Sprintf("rtsp://127.0.0.1:%s/%s", rtsp.Port, name)
+}
+
+// ensureRestream creates the restream target, the RTSP server only accepts
+// publishing to existing streams
+func ensureRestream(name string) {
+	if streams.Get(name) == nil {
+		streams.New(name)
+		log.Info().Str("stream", name).Msg("[recording] created restream target")
+	}
+}
@@ -0,0 +1,217 @@
+package ffmpeg
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Client uploads objects to S3-compatible storage (AWS, MinIO, ...) using
+// plain net/http with AWS Signature Version 4
+type s3Client struct {
+	endpoint  *url.URL
+	region    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+}
+
+func newS3Client(cfg RecordingUploadConfig) (*s3Client, error) {
+	endpoint := cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, errors.New("s3: endpoint has no host")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &s3Client{
+		endpoint:  u,
+		region:    region,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		pathStyle: cfg.PathStyle,
+		client:    &http.Client{Timeout: time.Hour},
+	}, nil
+}
+
+// objectURL returns the URL of an object, bucket in the path for MinIO-style
+// endpoints and in the host name for virtual-hosted AWS buckets
+func (c *s3Client) objectURL(bucket, key string) *url.URL {
+	u := *c.endpoint
+	path := "/" + strings.TrimPrefix(key, "/")
+	if c.pathStyle {
+		path = "/" + bucket + path
+	} else {
+		u.Host = bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawPath = s3Escape(u.Path)
+	return &u
+}
+
+// putFile uploads a local file and verifies the stored checksum. Returns the
+// ETag of the new object.
+func (c *s3Client) putFile(bucket, key, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	// Hash in one pass: MD5 for integrity checks, SHA-256 for the signature
+	md5sum, sha := md5.New(), sha256.New()
+	if _, err = io.Copy(io.MultiWriter(md5sum, sha), f); err != nil {
+		return "", err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	checksum := md5sum.Sum(nil)
+
+	req, err := http.NewRequest("PUT", c.objectURL(bucket, key).String(), f)
+	if err != nil {
+		return "", err
+	}
+	if req.ContentLength = info.Size(); req.ContentLength == 0 {
+		req.Body = http.NoBody // otherwise sent chunked, which S3 rejects
+	}
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(checksum))
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.sign(req, hex.EncodeToString(sha.Sum(nil)), time.Now())
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return "", fmt.Errorf("s3: %s: %s", res.Status, s3ErrorMessage(body))
+	}
+
+	// S3 already rejects bodies not matching Content-MD5, the ETag check also
+	// catches proxies and gateways that don't. Multipart and KMS-encrypted
+	// objects have ETags that are not an MD5 of the content.
+	etag := strings.Trim(res.Header.Get("ETag"), `"`)
+	if len(etag) == 32 && etag != hex.EncodeToString(checksum) {
+		return "", fmt.Errorf("s3: checksum mismatch, local %x remote %s", checksum, etag)
+	}
+
+	return etag, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header
+func (c *s3Client) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + c.region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-md5" || name == "content-type" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), now.Format("20060102"))
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape percent-encodes a path the way SigV4 expects: everything except
+// unreserved characters and the path separator
+func s3Escape(path string) string {
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+var s3MessageRe = regexp.MustCompile(`<Message>([^<]*)</Message>`)
+
+// s3ErrorMessage extracts the message from an S3 XML error response
+func s3ErrorMessage(body []byte) string {
+	if m := s3MessageRe.FindSubmatch(body); m != nil {
+		return string(m[1])
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,208 @@
+package ffmpeg
+
+import (
+	"sort"
+	"time"
+)
+
+// schedulePreviewMaxRuns caps the runs of one cron expression in a preview,
+// e.g. "@every 1s" over a week
+const schedulePreviewMaxRuns = 10000
+
+// ScheduleInterval is a stretch of time a stream is recorded by its schedule
+type ScheduleInterval struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration string    `json:"duration"`
+}
+
+// ScheduleConflict is a scheduled recording that overlaps another recording
+// of the same stream
+type ScheduleConflict struct {
+	Type      string    `json:"type"` // "auto_recording" or "schedule"
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Schedules []string  `json:"schedules,omitempty"` // the overlapping entries
+}
+
+// SchedulePreview is the recording timeline of a stream's schedule
+type SchedulePreview struct {
+	StreamName string             `json:"stream_name"`
+	Timezone   string             `json:"timezone"`
+	Timeline   []ScheduleInterval `json:"timeline"`
+	Recorded   string             `json:"recorded"`
+	Conflicts  []ScheduleConflict `json:"conflicts"`
+}
+
+// entryInterval is a run of one schedule entry
+type entryInterval struct {
+	start, end time.Time
+	entry      int
+}
+
+// previewSchedule returns the times the schedule records between from and
+// to: all entries combined, minus the blackout windows. autoRecorded marks
+// a stream that is also recorded continuously.
+func previewSchedule(s *StreamSchedule, from, to time.Time, autoRecorded bool) SchedulePreview {
+	preview := SchedulePreview{
+		StreamName: s.StreamName,
+		Timezone:   s.location.String(),
+		Timeline:   []ScheduleInterval{},
+		Conflicts:  []ScheduleConflict{},
+	}
+
+	var runs []entryInterval
+	for i, entry := range s.entries {
+		runs = append(runs, s.entryIntervals(i, entry, from, to)...)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].start.Before(runs[j].start)
+	})
+
+	var blackouts []entryInterval
+	for _, window := range s.blackouts {
+		blackouts = append(blackouts, windowIntervals(window, -1, from, to, s.location)...)
+	}
+
+	// Entries that record at the same time, a single recording covers both
+	for i := range runs {
+		for j := i + 1; j < len(runs) && runs[j].start.Before(runs[i].end); j++ {
+			if runs[i].entry == runs[j].entry {
+				continue
+			}
+			preview.Conflicts = append(preview.Conflicts, ScheduleConflict{
+				Type:      "schedule",
+				Start:     maxTime(runs[j].start, from),
+				End:       minTime(minTime(runs[i].end, runs[j].end), to),
+				Schedules: []string{s.Schedules[runs[i].entry], s.Schedules[runs[j].entry]},
+			})
+		}
+	}
+
+	var recorded time.Duration
+	for _, interval := range subtractIntervals(mergeIntervals(runs), blackouts) {
+		start, end := maxTime(interval.start, from), minTime(interval.end, to)
+		if !end.After(start) {
+			continue
+		}
+		preview.Timeline = append(preview.Timeline, ScheduleInterval{
+			Start:    start,
+			End:      end,
+			Duration: end.Sub(start).String(),
+		})
+		recorded += end.Sub(start)
+
+		if autoRecorded {
+			preview.Conflicts = append(preview.Conflicts, ScheduleConflict{
+				Type:  "auto_recording",
+				Start: start,
+				End:   end,
+			})
+		}
+	}
+	preview.Recorded = recorded.String()
+
+	return preview
+}
+
+// entryIntervals returns the runs of a schedule entry that overlap from-to
+func (s *StreamSchedule) entryIntervals(index int, entry *scheduleEntry, from, to time.Time) []entryInterval {
+	if entry.window != nil {
+		return windowIntervals(entry.window, index, from, to, s.location)
+	}
+
+	var runs []entryInterval
+
+	// A run that started earlier may still be going
+	if !entry.lastRun.IsZero() {
+		if end := s.runEnd(entry.lastRun); end.After(from) {
+			runs = append(runs, entryInterval{start: entry.lastRun, end: end, entry: index})
+		}
+	}
+
+	start := from
+	for i := 0; i < schedulePreviewMaxRuns; i++ {
+		start = calculateNextRun(entry.cron, start, s.location)
+		if !start.Before(to) {
+			break
+		}
+		runs = append(runs, entryInterval{start: start, end: s.runEnd(start), entry: index})
+	}
+
+	return runs
+}
+
+// windowIntervals returns the occurrences of a window that overlap from-to
+func windowIntervals(window *scheduleWindow, index int, from, to time.Time, location *time.Location) []entryInterval {
+	local := from.In(location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+
+	var runs []entryInterval
+	for ; ; day = day.AddDate(0, 0, 1) {
+		start, end, ok := window.occurrence(day, location)
+		if !ok {
+			if clockOnDay(day, 0, location).After(to) {
+				break
+			}
+			continue
+		}
+		if !start.Before(to) {
+			break
+		}
+		if end.After(from) {
+			runs = append(runs, entryInterval{start: start, end: end, entry: index})
+		}
+	}
+	return runs
+}
+
+// mergeIntervals joins overlapping intervals sorted by start
+func mergeIntervals(intervals []entryInterval) []entryInterval {
+	var merged []entryInterval
+	for _, interval := range intervals {
+		if n := len(merged); n > 0 && !interval.start.After(merged[n-1].end) {
+			merged[n-1].end = maxTime(merged[n-1].end, interval.end)
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// subtractIntervals removes the parts of intervals covered by any of cut
+func subtractIntervals(intervals, cut []entryInterval) []entryInterval {
+	for _, c := range cut {
+		var result []entryInterval
+		for _, interval := range intervals {
+			if !c.start.Before(interval.end) || !c.end.After(interval.start) {
+				result = append(result, interval)
+				continue
+			}
+			if c.start.After(interval.start) {
+				result = append(result, entryInterval{start: interval.start, end: c.start, entry: interval.entry})
+			}
+			if c.end.Before(interval.end) {
+				result = append(result, entryInterval{start: c.end, end: interval.end, entry: interval.entry})
+			}
+		}
+		intervals = result
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+	return intervals
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
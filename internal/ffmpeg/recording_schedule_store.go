@@ -0,0 +1,112 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// persistedSchedule is the on-disk form of a schedule added via the API
+type persistedSchedule struct {
+	Stream    string   `json:"stream"`
+	Schedules []string `json:"schedules"`
+	Blackouts []string `json:"blackouts,omitempty"`
+	Stop      string   `json:"stop,omitempty"`
+	Duration  string   `json:"duration"`
+	Timezone  string   `json:"timezone,omitempty"`
+}
+
+// getSchedulesPath returns the file API schedules are kept in, next to the
+// recording state
+func getSchedulesPath() string {
+	return filepath.Join(filepath.Dir(getStatePath()), ".schedules.json")
+}
+
+// persistSchedule marks a schedule as added via the API and saves it, so it
+// is loaded again after a restart
+func persistSchedule(streamName string) {
+	scheduleManager.mu.Lock()
+	if schedule, ok := scheduleManager.schedules[streamName]; ok {
+		schedule.persisted = true
+	}
+	scheduleManager.mu.Unlock()
+	saveSchedules()
+}
+
+// saveSchedules writes the schedules added via the API
+func saveSchedules() {
+	schedules := []persistedSchedule{}
+	scheduleManager.mu.Lock()
+	for _, schedule := range scheduleManager.schedules {
+		if !schedule.persisted {
+			continue
+		}
+		schedules = append(schedules, persistedSchedule{
+			Stream:    schedule.StreamName,
+			Schedules: schedule.Schedules,
+			Blackouts: schedule.Blackouts,
+			Stop:      schedule.Stop,
+			Duration:  schedule.Duration.String(),
+			Timezone:  schedule.Timezone,
+		})
+	}
+	scheduleManager.mu.Unlock()
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].Stream < schedules[j].Stream
+	})
+
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return
+	}
+
+	path := getSchedulesPath()
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("[scheduler] failed to save schedules")
+	}
+}
+
+// LoadPersistedSchedules adds the schedules saved by the API. They replace
+// config schedules of the same stream, like they did when they were added.
+func LoadPersistedSchedules() {
+	path := getSchedulesPath()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("path", path).Msg("[scheduler] failed to read saved schedules")
+		}
+		return
+	}
+
+	var schedules []persistedSchedule
+	if err = json.Unmarshal(data, &schedules); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("[scheduler] failed to parse saved schedules")
+		return
+	}
+
+	for _, saved := range schedules {
+		duration, err := time.ParseDuration(saved.Duration)
+		if err != nil {
+			duration = time.Hour
+		}
+		if err = AddSchedule(saved.Stream, saved.Schedules, saved.Blackouts, saved.Stop, duration, saved.Timezone); err != nil {
+			log.Error().Err(err).Str("stream", saved.Stream).Msg("[scheduler] failed to add saved schedule")
+			continue
+		}
+		scheduleManager.mu.Lock()
+		scheduleManager.schedules[saved.Stream].persisted = true
+		scheduleManager.mu.Unlock()
+	}
+
+	log.Info().Int("count", len(schedules)).Msg("[scheduler] loaded saved schedules")
+}
@@ -0,0 +1,137 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// scheduleWindow is a weekly recurring time range such as "mon-fri 08:00-18:00".
+// A window crossing midnight belongs to the day it starts on.
+type scheduleWindow struct {
+	days  [7]bool // by time.Weekday
+	clock *cleanupWindow
+}
+
+// isScheduleWindow tells time windows apart from cron expressions, which
+// never contain a colon
+func isScheduleWindow(value string) bool {
+	return strings.Contains(value, ":")
+}
+
+// parseScheduleWindow parses "[DAYS] HH:MM-HH:MM", DAYS being a list of
+// weekdays and ranges like "mon-fri" or "sat,sun" (every day if omitted)
+func parseScheduleWindow(value string) (*scheduleWindow, error) {
+	fields := strings.Fields(value)
+
+	var days, clock string
+	switch len(fields) {
+	case 1:
+		days, clock = "*", fields[0]
+	case 2:
+		days, clock = fields[0], fields[1]
+	default:
+		return nil, errors.New("expected [DAYS] HH:MM-HH:MM")
+	}
+
+	window := &scheduleWindow{}
+
+	var err error
+	if window.clock, err = parseCleanupWindow(clock); err != nil {
+		return nil, err
+	}
+
+	if days == "*" {
+		for i := range window.days {
+			window.days[i] = true
+		}
+		return window, nil
+	}
+
+	for _, part := range strings.Split(strings.ToLower(days), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := parseWeekday(from)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseWeekday(to); err != nil {
+				return nil, err
+			}
+		}
+		// Ranges may wrap around the week, e.g. "fri-mon"
+		for day := first; ; day = (day + 1) % 7 {
+			window.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+
+	return window, nil
+}
+
+func parseWeekday(name string) (int, error) {
+	for i, weekday := range weekdayNames {
+		if strings.HasPrefix(name, weekday) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// occurrence returns the window starting on a calendar day (a UTC midnight),
+// ok is false if the window doesn't run that day
+func (w *scheduleWindow) occurrence(day time.Time, location *time.Location) (start, end time.Time, ok bool) {
+	if !w.days[day.Weekday()] {
+		return
+	}
+
+	endDay := day
+	if w.clock.end <= w.clock.start {
+		endDay = day.AddDate(0, 0, 1)
+	}
+
+	start = clockOnDay(day, w.clock.start, location)
+	end = clockOnDay(endDay, w.clock.end, location)
+	return start, end, true
+}
+
+// activeEnd returns when the occurrence of the window containing t ends,
+// zero if t is outside the window
+func (w *scheduleWindow) activeEnd(t time.Time, location *time.Location) time.Time {
+	local := t.In(location)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Yesterday's window may still run past midnight
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+		if start, end, ok := w.occurrence(day, location); ok && !t.Before(start) && t.Before(end) {
+			return end
+		}
+	}
+	return time.Time{}
+}
+
+// nextStart returns the first start of the window after t
+func (w *scheduleWindow) nextStart(t time.Time, location *time.Location) time.Time {
+	local := t.In(location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 8; i++ {
+		if start, _, ok := w.occurrence(day, location); ok && start.After(t) {
+			return start
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// clockOnDay returns the time of day on a calendar day in location
+func clockOnDay(day time.Time, clock time.Duration, location *time.Location) time.Time {
+	year, month, date := day.Date()
+	return wallClockTime(year, month, date, int(clock/time.Hour), int(clock%time.Hour/time.Minute), location)
+}
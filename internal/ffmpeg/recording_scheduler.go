@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -11,35 +12,71 @@ import (
 type ScheduleManager struct {
 	schedules map[string]*StreamSchedule
 	running   bool
+	mu        sync.Mutex // guards schedules, their state and running
 }
 
 // StreamSchedule represents a recording schedule for a stream
 type StreamSchedule struct {
-	StreamName   string
-	Schedule     string
-	Duration     time.Duration
-	Config       RecordConfig
-	NextRun      time.Time
-	ActiveID     string // ID of currently active scheduled recording
-	parsedSchedule *ParsedSchedule
+	StreamName string
+	Schedules  []string      // cron expressions and time windows the stream is recorded in
+	Blackouts  []string      // time windows the stream is never recorded in
+	Stop       string        // cron expression ending recordings started by cron expressions
+	Timezone   string        // IANA zone the schedule is evaluated in, empty for local time
+	Duration   time.Duration // length of recordings started by cron expressions without Stop
+	Config     RecordConfig
+	NextRun    time.Time
+	ActiveID   string // ID of currently active scheduled recording
+	entries    []*scheduleEntry
+	blackouts  []*scheduleWindow
+	stop       *ParsedSchedule
+	location   *time.Location
+	persisted  bool // added via the API, kept in the schedules file
+}
+
+// scheduleEntry is one cron expression or time window of a schedule
+type scheduleEntry struct {
+	cron    *ParsedSchedule
+	window  *scheduleWindow
+	lastRun time.Time // last start of a cron expression
+	nextRun time.Time // next start of a cron expression
 }
 
 // ParsedSchedule represents parsed cron-like schedule
 type ParsedSchedule struct {
+	Seconds    []int  // 0-59, -1 for *, 0 without a seconds field
 	Minutes    []int  // 0-59, -1 for *
 	Hours      []int  // 0-23, -1 for *
 	Days       []int  // 1-31, -1 for *
 	Months     []int  // 1-12, -1 for *
 	Weekdays   []int  // 0-6 (Sunday=0), -1 for *
+	Every      time.Duration // interval of an @every schedule, the fields are unused
 	Raw        string
 }
 
+// scheduleShortcuts are the predefined schedules of crontab
+var scheduleShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// scheduleCheckInterval is how often schedules are checked, often enough
+// for schedules with a seconds field
+const scheduleCheckInterval = time.Second
+
 var scheduleManager = &ScheduleManager{
 	schedules: make(map[string]*StreamSchedule),
 }
 
 // StartScheduler begins the recording scheduler
 func StartScheduler() {
+	scheduleManager.mu.Lock()
+	defer scheduleManager.mu.Unlock()
+
 	if scheduleManager.running {
 		return
 	}
@@ -51,6 +88,9 @@ func StartScheduler() {
 
 // StopScheduler stops the recording scheduler
 func StopScheduler() {
+	scheduleManager.mu.Lock()
+	defer scheduleManager.mu.Unlock()
+
 	if !scheduleManager.running {
 		return
 	}
@@ -60,7 +100,7 @@ func StopScheduler() {
 	// Stop all active scheduled recordings
 	for _, schedule := range scheduleManager.schedules {
 		if schedule.ActiveID != "" {
-			GetRecordingManager().StopRecording(schedule.ActiveID)
+			GetRecordingManager().StopRecording(schedule.ActiveID, ExitSchedule)
 			schedule.ActiveID = ""
 		}
 	}
@@ -68,37 +108,86 @@ func StopScheduler() {
 	log.Info().Msg("[scheduler] recording scheduler stopped")
 }
 
-// AddSchedule adds a recording schedule for a stream
-func AddSchedule(streamName, scheduleStr string, duration time.Duration) error {
-	parsedSchedule, err := parseSchedule(scheduleStr)
-	if err != nil {
-		return fmt.Errorf("invalid schedule format: %v", err)
+// AddSchedule adds a recording schedule for a stream, evaluated in the
+// timezone (local time if empty). The stream is recorded while any of the
+// schedules (cron expressions or time windows) is active and none of the
+// blackout windows is. A cron expression starts a recording that lasts until
+// the next time of the stop expression, or for duration if stop is empty.
+func AddSchedule(streamName string, schedules, blackouts []string, stop string, duration time.Duration, timezone string) error {
+	if len(schedules) == 0 {
+		return fmt.Errorf("at least one schedule is required")
+	}
+
+	schedule := &StreamSchedule{
+		StreamName: streamName,
+		Schedules:  schedules,
+		Blackouts:  blackouts,
+		Stop:       stop,
+		Timezone:   timezone,
+		Duration:   duration,
+	}
+
+	var err error
+	if schedule.location, err = scheduleLocation(timezone); err != nil {
+		return err
+	}
+
+	if stop != "" {
+		if schedule.stop, err = parseSchedule(stop); err != nil {
+			return fmt.Errorf("invalid stop schedule: %v", err)
+		}
+	}
+
+	now := time.Now()
+
+	for _, scheduleStr := range schedules {
+		entry := &scheduleEntry{}
+		if isScheduleWindow(scheduleStr) {
+			if entry.window, err = parseScheduleWindow(scheduleStr); err != nil {
+				return fmt.Errorf("invalid schedule window %q: %v", scheduleStr, err)
+			}
+		} else {
+			if entry.cron, err = parseSchedule(scheduleStr); err != nil {
+				return fmt.Errorf("invalid schedule format: %v", err)
+			}
+			entry.nextRun = calculateNextRun(entry.cron, now, schedule.location)
+		}
+		schedule.entries = append(schedule.entries, entry)
+	}
+
+	for _, blackoutStr := range blackouts {
+		window, err := parseScheduleWindow(blackoutStr)
+		if err != nil {
+			return fmt.Errorf("invalid blackout window %q: %v", blackoutStr, err)
+		}
+		schedule.blackouts = append(schedule.blackouts, window)
 	}
 
 	streamConfig := GetStreamRecordingConfig(streamName)
-	config := RecordConfig{
+	schedule.Config = RecordConfig{
 		Video:    streamConfig.Video,
 		Audio:    streamConfig.Audio,
 		Format:   streamConfig.Format,
 		Duration: duration,
 	}
 
-	schedule := &StreamSchedule{
-		StreamName:     streamName,
-		Schedule:       scheduleStr,
-		Duration:       duration,
-		Config:         config,
-		parsedSchedule: parsedSchedule,
-	}
+	schedule.NextRun = schedule.nextRun(now)
+	nextRun := schedule.NextRun
 
-	schedule.NextRun = calculateNextRun(parsedSchedule, time.Now())
+	// Replacing a schedule stops the recording it started
+	scheduleManager.mu.Lock()
+	removeSchedule(streamName)
 	scheduleManager.schedules[streamName] = schedule
+	scheduleManager.mu.Unlock()
 
 	log.Info().
 		Str("stream", streamName).
-		Str("schedule", scheduleStr).
+		Strs("schedules", schedules).
+		Strs("blackouts", blackouts).
+		Str("stop", stop).
+		Str("timezone", schedule.location.String()).
 		Dur("duration", duration).
-		Time("next_run", schedule.NextRun).
+		Time("next_run", nextRun).
 		Msg("[scheduler] schedule added")
 
 	return nil
@@ -106,10 +195,17 @@ func AddSchedule(streamName, scheduleStr string, duration time.Duration) error {
 
 // RemoveSchedule removes a recording schedule
 func RemoveSchedule(streamName string) {
+	scheduleManager.mu.Lock()
+	removeSchedule(streamName)
+	scheduleManager.mu.Unlock()
+}
+
+// removeSchedule must be called with scheduleManager.mu held
+func removeSchedule(streamName string) {
 	if schedule, exists := scheduleManager.schedules[streamName]; exists {
 		// Stop active recording if any
 		if schedule.ActiveID != "" {
-			GetRecordingManager().StopRecording(schedule.ActiveID)
+			GetRecordingManager().StopRecording(schedule.ActiveID, ExitSchedule)
 		}
 		delete(scheduleManager.schedules, streamName)
 		log.Info().Str("stream", streamName).Msg("[scheduler] schedule removed")
@@ -118,110 +214,260 @@ func RemoveSchedule(streamName string) {
 
 // LoadSchedulesFromConfig loads schedules from stream configurations
 func LoadSchedulesFromConfig() {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	
-	for streamName, streamConfig := range cfg.Streams {
+	for streamName, streamConfig := range configuredStreams(cfg) {
+		var schedules []string
 		if streamConfig.Schedule != "" {
-			// Default duration if not specified in config
-			duration := time.Hour
-			if streamConfig.SegmentDuration > 0 {
-				duration = streamConfig.SegmentDuration
-			}
-			
-			if err := AddSchedule(streamName, streamConfig.Schedule, duration); err != nil {
-				log.Error().
-					Err(err).
-					Str("stream", streamName).
-					Str("schedule", streamConfig.Schedule).
-					Msg("[scheduler] failed to add schedule from config")
-			}
+			schedules = append(schedules, streamConfig.Schedule)
+		}
+		schedules = append(schedules, streamConfig.Schedules...)
+
+		if len(schedules) == 0 {
+			continue
+		}
+
+		// Default duration if not specified in config
+		duration := time.Hour
+		if streamConfig.SegmentDuration > 0 {
+			duration = streamConfig.SegmentDuration
+		}
+		
+		if err := AddSchedule(streamName, schedules, streamConfig.Blackouts, streamConfig.ScheduleStop, duration, streamConfig.ScheduleTimezone); err != nil {
+			log.Error().
+				Err(err).
+				Str("stream", streamName).
+				Strs("schedules", schedules).
+				Msg("[scheduler] failed to add schedule from config")
 		}
 	}
 }
 
 // scheduleRoutine is the main scheduler loop
 func scheduleRoutine() {
-	ticker := time.NewTicker(time.Minute) // Check every minute
+	ticker := time.NewTicker(scheduleCheckInterval)
 	defer ticker.Stop()
 
-	for scheduleManager.running {
-		select {
-		case now := <-ticker.C:
-			checkAndExecuteSchedules(now)
+	for now := range ticker.C {
+		if !checkAndExecuteSchedules(now) {
+			return
 		}
 	}
 }
 
-// checkAndExecuteSchedules checks if any schedules should be executed
-func checkAndExecuteSchedules(now time.Time) {
-	for streamName, schedule := range scheduleManager.schedules {
-		// Check if it's time to start a recording
-		if now.After(schedule.NextRun) || now.Equal(schedule.NextRun) {
-			if schedule.ActiveID == "" { // Only start if not already recording
-				if err := startScheduledRecording(schedule); err != nil {
-					log.Error().
-						Err(err).
-						Str("stream", streamName).
-						Msg("[scheduler] failed to start scheduled recording")
-				} else {
-					log.Info().
-						Str("stream", streamName).
-						Str("schedule", schedule.Schedule).
-						Dur("duration", schedule.Duration).
-						Msg("[scheduler] started scheduled recording")
-				}
-			}
-			
-			// Calculate next run time
-			schedule.NextRun = calculateNextRun(schedule.parsedSchedule, now.Add(time.Minute))
+// scheduledStart is a recording a schedule check found due, started after
+// the check released the lock
+type scheduledStart struct {
+	schedule *StreamSchedule
+	config   RecordConfig
+	duration time.Duration
+}
+
+// checkAndExecuteSchedules starts a recording when a schedule becomes active
+// and stops it when the schedule ends or a blackout window begins, whatever
+// duration the recording was started with. Returns false once the scheduler
+// was stopped.
+func checkAndExecuteSchedules(now time.Time) bool {
+	starts, ok := dueSchedules(now)
+	if !ok {
+		return false
+	}
+
+	// A start may wait start_queue_timeout for a recording slot, the other
+	// schedules and the schedule API don't wait for it
+	for _, start := range starts {
+		streamName := start.schedule.StreamName
+		recordingID, err := startScheduledRecording(streamName, start.config, start.duration)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("stream", streamName).
+				Msg("[scheduler] failed to start scheduled recording")
+			continue
 		}
-		
-		// Check if scheduled recording should stop
+
+		scheduleManager.mu.Lock()
+		current := scheduleManager.running && scheduleManager.schedules[streamName] == start.schedule
+		if current {
+			start.schedule.ActiveID = recordingID
+		}
+		scheduleManager.mu.Unlock()
+
+		if !current {
+			// Removed or replaced while the recording started
+			_ = GetRecordingManager().StopRecording(recordingID, ExitSchedule)
+			continue
+		}
+
+		log.Info().
+			Str("stream", streamName).
+			Strs("schedules", start.schedule.Schedules).
+			Dur("duration", start.duration).
+			Msg("[scheduler] started scheduled recording")
+	}
+	return true
+}
+
+// dueSchedules stops the recordings of schedules that ended and returns the
+// recordings to start. ok is false once the scheduler was stopped.
+func dueSchedules(now time.Time) (starts []scheduledStart, ok bool) {
+	scheduleManager.mu.Lock()
+	defer scheduleManager.mu.Unlock()
+
+	if !scheduleManager.running {
+		return nil, false
+	}
+
+	for streamName, schedule := range scheduleManager.schedules {
+		// Forget recordings that ended on their own
 		if schedule.ActiveID != "" {
 			recording := GetRecordingManager().GetRecording(schedule.ActiveID)
-			if recording == nil || !recording.Active {
+			if recording == nil || recording.finished() {
 				schedule.ActiveID = ""
 			}
 		}
+
+		until, blackout := schedule.activeUntil(now)
+
+		switch {
+		case until.IsZero() && schedule.ActiveID != "":
+			GetRecordingManager().StopRecording(schedule.ActiveID, ExitSchedule)
+			schedule.ActiveID = ""
+			log.Info().
+				Str("stream", streamName).
+				Bool("blackout", blackout).
+				Msg("[scheduler] stopped scheduled recording at the end of its window")
+
+		case !until.IsZero() && schedule.ActiveID == "":
+			starts = append(starts, scheduledStart{schedule: schedule, config: schedule.Config, duration: until.Sub(now)})
+		}
+
+		schedule.NextRun = schedule.nextRun(now)
+	}
+	return starts, true
+}
+
+// activeUntil returns when the schedule stops being active, zero if it isn't
+// active at now. blackout is true if a blackout window covers now.
+func (s *StreamSchedule) activeUntil(now time.Time) (until time.Time, blackout bool) {
+	for _, entry := range s.entries {
+		var end time.Time
+		if entry.cron != nil {
+			if !now.Before(entry.nextRun) {
+				entry.lastRun = entry.nextRun
+				entry.nextRun = calculateNextRun(entry.cron, now, s.location)
+			}
+			if !entry.lastRun.IsZero() {
+				end = s.runEnd(entry.lastRun)
+			}
+		} else {
+			end = entry.window.activeEnd(now, s.location)
+		}
+		if end.After(now) && end.After(until) {
+			until = end
+		}
+	}
+
+	for _, window := range s.blackouts {
+		if !window.activeEnd(now, s.location).IsZero() {
+			return time.Time{}, true
+		}
 	}
+
+	return until, false
 }
 
-// startScheduledRecording starts a scheduled recording
-func startScheduledRecording(schedule *StreamSchedule) error {
-	recordingID := fmt.Sprintf("sched_%s_%d", schedule.StreamName, time.Now().Unix())
+// runEnd returns when a recording started by a cron expression at start
+// ends: at the next stop time, or after Duration without a stop expression
+func (s *StreamSchedule) runEnd(start time.Time) time.Time {
+	if s.stop != nil {
+		return calculateNextRun(s.stop, start, s.location)
+	}
+	return start.Add(s.Duration)
+}
+
+// nextRun returns the next start of any of the schedule's entries after now
+func (s *StreamSchedule) nextRun(now time.Time) time.Time {
+	var next time.Time
+	for _, entry := range s.entries {
+		start := entry.nextRun
+		if entry.window != nil {
+			start = entry.window.nextStart(now, s.location)
+		}
+		if !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next
+}
+
+// startScheduledRecording starts a scheduled recording of the given length
+// and returns its ID
+func startScheduledRecording(streamName string, config RecordConfig, duration time.Duration) (string, error) {
+	recordingID := fmt.Sprintf("sched_%s_%d", streamName, time.Now().Unix())
 	
 	// Generate filename
-	schedule.Config.Filename = GenerateRecordingPath(
-		schedule.StreamName, 
+	config.Filename = GenerateRecordingPath(
+		streamName, 
 		time.Now(), 
-		schedule.Config.Format, 
+		config.Format, 
 		0,
 	)
+	config.Duration = duration
 	
-	if err := GetRecordingManager().StartRecording(recordingID, schedule.StreamName, schedule.Config); err != nil {
-		return err
+	if err := GetRecordingManager().StartRecording(recordingID, streamName, config); err != nil {
+		return "", err
 	}
-	
-	schedule.ActiveID = recordingID
-	return nil
+	return recordingID, nil
 }
 
 // parseSchedule parses cron-like schedule string
-// Format: "minute hour day month weekday"
+// Format: "[second] minute hour day month weekday"
 // Examples:
 //   "0 9 * * 1-5"     = 9:00 AM, Monday through Friday
 //   "0 22 * * *"      = 10:00 PM every day
 //   "*/15 9-17 * * *" = Every 15 minutes from 9 AM to 5 PM
 //   "0 8,20 * * *"    = 8:00 AM and 8:00 PM every day
+//   "30 0 9 * * *"    = 9:00:30 AM every day
+//   "@daily"          = midnight every day, also @hourly, @weekly, @monthly, @yearly
+//   "@every 30m"      = every 30 minutes
 func parseSchedule(scheduleStr string) (*ParsedSchedule, error) {
-	parts := strings.Fields(scheduleStr)
-	if len(parts) != 5 {
-		return nil, fmt.Errorf("schedule must have 5 fields: minute hour day month weekday")
+	if interval, ok := strings.CutPrefix(scheduleStr, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil {
+			return nil, fmt.Errorf("invalid @every interval: %v", err)
+		}
+		if every < time.Second {
+			return nil, fmt.Errorf("@every interval must be at least 1s")
+		}
+		return &ParsedSchedule{Every: every, Raw: scheduleStr}, nil
 	}
 
-	parsed := &ParsedSchedule{Raw: scheduleStr}
-	
+	expr := scheduleStr
+	if strings.HasPrefix(expr, "@") {
+		var ok bool
+		if expr, ok = scheduleShortcuts[strings.ToLower(expr)]; !ok {
+			return nil, fmt.Errorf("unknown schedule shortcut: %s", scheduleStr)
+		}
+	}
+
+	parts := strings.Fields(expr)
+
+	parsed := &ParsedSchedule{Seconds: []int{0}, Raw: scheduleStr}
+
 	var err error
+	switch len(parts) {
+	case 5:
+	case 6:
+		parsed.Seconds, err = parseField(parts[0], 0, 59)
+		if err != nil {
+			return nil, fmt.Errorf("invalid second field: %v", err)
+		}
+		parts = parts[1:]
+	default:
+		return nil, fmt.Errorf("schedule must have 5 fields: minute hour day month weekday (or 6 with seconds first)")
+	}
+	
 	parsed.Minutes, err = parseField(parts[0], 0, 59)
 	if err != nil {
 		return nil, fmt.Errorf("invalid minute field: %v", err)
@@ -269,7 +515,7 @@ func parseField(field string, min, max int) ([]int, error) {
 			}
 			
 			step, err := strconv.Atoi(stepParts[1])
-			if err != nil {
+			if err != nil || step <= 0 {
 				return nil, fmt.Errorf("invalid step value: %s", stepParts[1])
 			}
 			
@@ -342,30 +588,109 @@ func parseField(field string, min, max int) ([]int, error) {
 	return result, nil
 }
 
-// calculateNextRun calculates the next time a schedule should run
-func calculateNextRun(schedule *ParsedSchedule, from time.Time) time.Time {
-	// Start from the next minute to avoid immediate re-triggering
-	current := time.Date(from.Year(), from.Month(), from.Day(), from.Hour(), from.Minute()+1, 0, 0, from.Location())
-	
-	// Look ahead up to 2 years to find next match
-	for i := 0; i < 366*24*60*2; i++ {
-		if matchesSchedule(schedule, current) {
-			return current
+// scheduleLocation returns the timezone a schedule is evaluated in
+func scheduleLocation(timezone string) (*time.Location, error) {
+	switch strings.ToLower(timezone) {
+	case "", "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", timezone, err)
+	}
+	return location, nil
+}
+
+// calculateNextRun returns the first time after from that matches the
+// schedule in the given timezone. Days are walked on the calendar and only
+// matching hours and minutes are tried, so DST changes don't shift runs:
+// a time skipped by a change runs when the clock jumps past it, a time
+// repeated by one runs only the first time. @every schedules run on
+// multiples of their interval.
+func calculateNextRun(schedule *ParsedSchedule, from time.Time, location *time.Location) time.Time {
+	if schedule.Every > 0 {
+		return from.Truncate(schedule.Every).Add(schedule.Every)
+	}
+
+	local := from.In(location)
+
+	// Calendar days, in UTC so adding a day is always 24 hours
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Look ahead up to 5 years, e.g. for February 29
+	for i := 0; i < 366*5; i++ {
+		year, month, date := day.Date()
+		if matchesField(schedule.Days, date) &&
+			matchesField(schedule.Months, int(month)) &&
+			matchesField(schedule.Weekdays, int(day.Weekday())) {
+
+			var next time.Time
+			for hour := 0; hour < 24; hour++ {
+				if !matchesField(schedule.Hours, hour) {
+					continue
+				}
+				for minute := 0; minute < 60; minute++ {
+					if !matchesField(schedule.Minutes, minute) {
+						continue
+					}
+					base := wallClockTime(year, month, date, hour, minute, location)
+					for second := 0; second < 60; second++ {
+						if !matchesField(schedule.Seconds, second) {
+							continue
+						}
+						t := base.Add(time.Duration(second) * time.Second)
+						if t.After(from) {
+							if next.IsZero() || t.Before(next) {
+								next = t
+							}
+							break // later seconds of the minute are later
+						}
+					}
+				}
+			}
+			if !next.IsZero() {
+				return next
+			}
 		}
-		current = current.Add(time.Minute)
+		day = day.AddDate(0, 0, 1)
 	}
-	
+
 	// Fallback - should never happen with valid schedules
 	return from.Add(24 * time.Hour)
 }
 
-// matchesSchedule checks if a time matches a schedule
-func matchesSchedule(schedule *ParsedSchedule, t time.Time) bool {
-	return matchesField(schedule.Minutes, t.Minute()) &&
-		matchesField(schedule.Hours, t.Hour()) &&
-		matchesField(schedule.Days, t.Day()) &&
-		matchesField(schedule.Months, int(t.Month())) &&
-		matchesField(schedule.Weekdays, int(t.Weekday()))
+// wallClockTime returns the first instant the clock in location shows the
+// date and time. A time that doesn't exist because the clock jumped forward
+// maps to the same distance after the jump.
+func wallClockTime(year int, month time.Month, day, hour, minute int, location *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, minute, 0, 0, location)
+	naive := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+
+	// The offsets in effect before and after a possible change on that day
+	_, before := t.Add(-time.Hour * 12).Zone()
+	_, after := t.Add(time.Hour * 12).Zone()
+	if before == after {
+		return t
+	}
+
+	var first time.Time
+	for _, offset := range []int{before, after} {
+		candidate := naive.Add(-time.Duration(offset) * time.Second)
+		in := candidate.In(location)
+		if in.Hour() != hour || in.Minute() != minute || in.Day() != day {
+			continue
+		}
+		if first.IsZero() || candidate.Before(first) {
+			first = candidate
+		}
+	}
+	if first.IsZero() {
+		// Skipped by the change, read the time with the offset before it
+		first = naive.Add(-time.Duration(before) * time.Second)
+	}
+	return first.In(location)
 }
 
 // matchesField checks if a time field matches a schedule field
@@ -383,11 +708,28 @@ func matchesField(scheduleField []int, timeValue int) bool {
 	return false
 }
 
-// GetSchedules returns all active schedules
+// GetSchedules returns copies of all active schedules, the scheduler keeps
+// changing the originals
 func GetSchedules() map[string]*StreamSchedule {
+	scheduleManager.mu.Lock()
+	defer scheduleManager.mu.Unlock()
+
 	result := make(map[string]*StreamSchedule)
 	for k, v := range scheduleManager.schedules {
-		result[k] = v
+		schedule := *v
+		schedule.entries = make([]*scheduleEntry, len(v.entries))
+		for i, entry := range v.entries {
+			copied := *entry
+			schedule.entries[i] = &copied
+		}
+		result[k] = &schedule
 	}
 	return result
+}
+
+// schedulerRunning reports whether the scheduler is started
+func schedulerRunning() bool {
+	scheduleManager.mu.Lock()
+	defer scheduleManager.mu.Unlock()
+	return scheduleManager.running
 }
\ No newline at end of file
@@ -0,0 +1,137 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2025, 1, 15, 10, 20, 30, 0, time.UTC)
+	next := func(expr string) time.Time {
+		schedule, err := parseSchedule(expr)
+		require.Nil(t, err, expr)
+		return calculateNextRun(schedule, from, time.UTC)
+	}
+
+	// Shortcuts
+	require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), next("@yearly"))
+	require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), next("@annually"))
+	require.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), next("@monthly"))
+	require.Equal(t, time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC), next("@weekly"))
+	require.Equal(t, time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC), next("@daily"))
+	require.Equal(t, time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC), next("@MIDNIGHT"))
+	require.Equal(t, time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC), next("@hourly"))
+	_, err := parseSchedule("@sometimes")
+	require.Error(t, err)
+
+	// @every
+	require.Equal(t, time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC), next("@every 15m"))
+	require.Equal(t, time.Date(2025, 1, 15, 10, 20, 40, 0, time.UTC), next("@every 10s"))
+	for _, expr := range []string{"@every 500ms", "@every 0s", "@every -1m", "@every often"} {
+		_, err = parseSchedule(expr)
+		require.Error(t, err, expr)
+	}
+
+	// Seconds field
+	require.Equal(t, time.Date(2025, 1, 15, 10, 20, 45, 0, time.UTC), next("45 * * * * *"))
+	require.Equal(t, time.Date(2025, 1, 15, 10, 20, 40, 0, time.UTC), next("*/20 * * * * *"))
+	require.Equal(t, time.Date(2025, 1, 16, 9, 0, 15, 0, time.UTC), next("15 0 9 * * *"))
+	require.Equal(t, time.Date(2025, 1, 15, 10, 21, 0, 0, time.UTC), next("* * * * *"))
+	_, err = parseSchedule("60 * * * * *")
+	require.Error(t, err)
+
+	// Steps
+	require.Equal(t, time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC), next("*/15 9-17 * * *"))
+	require.Equal(t, time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC), next("0 9-17/2 * * *"))
+	for _, expr := range []string{"*/0 * * * *", "*/-5 * * * *", "0 9-17/0 * * *", "*/0 * * * * *", "0 0 */x * *"} {
+		_, err = parseSchedule(expr)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestScheduleManagerConcurrency(t *testing.T) {
+	scheduleManager.mu.Lock()
+	running := scheduleManager.running
+	scheduleManager.running = true
+	scheduleManager.mu.Unlock()
+	t.Cleanup(func() {
+		scheduleManager.mu.Lock()
+		scheduleManager.running = running
+		scheduleManager.mu.Unlock()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			require.True(t, checkAndExecuteSchedules(time.Now()))
+			for _, schedule := range GetSchedules() {
+				_, _ = schedule.activeUntil(time.Now())
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		streamName := fmt.Sprintf("scheduler_test_%d", i%5)
+		require.Nil(t, AddSchedule(streamName, []string{"0 0 1 1 *"}, nil, "", time.Hour, "UTC"))
+		if i%2 == 0 {
+			RemoveSchedule(streamName)
+		}
+	}
+	<-done
+
+	for i := 0; i < 5; i++ {
+		RemoveSchedule(fmt.Sprintf("scheduler_test_%d", i))
+	}
+}
+
+func TestScheduleStartWaitsUnlocked(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{
+		BasePath:              t.TempDir(),
+		MaxRecordingProcesses: 1,
+		StartQueueTimeout:     time.Millisecond * 500,
+	})
+
+	// Every slot is taken, the scheduled start waits start_queue_timeout
+	require.Nil(t, recordingProcesses.acquire("other", 0, 0))
+	t.Cleanup(recordingProcesses.release)
+
+	scheduleManager.mu.Lock()
+	running := scheduleManager.running
+	scheduleManager.running = true
+	scheduleManager.mu.Unlock()
+	t.Cleanup(func() {
+		scheduleManager.mu.Lock()
+		scheduleManager.running = running
+		scheduleManager.mu.Unlock()
+		RemoveSchedule("scheduler_test_wait")
+	})
+
+	require.Nil(t, AddSchedule("scheduler_test_wait", []string{"* * * * * *"}, nil, "", time.Hour, "UTC"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.True(t, checkAndExecuteSchedules(time.Now().Add(time.Second*2)))
+	}()
+
+	time.Sleep(time.Millisecond * 100)
+	start := time.Now()
+	require.NotEmpty(t, GetSchedules())
+	require.Nil(t, AddSchedule("scheduler_test_other", []string{"0 0 1 1 *"}, nil, "", time.Hour, "UTC"))
+	RemoveSchedule("scheduler_test_other")
+	require.Less(t, time.Since(start), time.Millisecond*200)
+
+	select {
+	case <-done:
+		t.Fatal("start didn't wait for a slot")
+	default:
+	}
+	<-done
+	require.Empty(t, GetSchedules()["scheduler_test_wait"].ActiveID)
+}
@@ -0,0 +1,186 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SegmentInfo describes a segment closed by ffmpeg's segment muxer
+type SegmentInfo struct {
+	Path      string    `json:"path"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// segmentList follows the CSV list ffmpeg's segment muxer appends a line to
+// whenever it closes a segment, so every segment is registered as soon as it
+// is complete instead of being guessed from file names afterwards
+type segmentList struct {
+	path    string
+	dir     string
+	stream  string
+	started time.Time
+
+	offset   int64
+	partial  []byte
+	segments []SegmentInfo
+
+	quit   chan struct{}
+	exited chan struct{}
+	mu     sync.Mutex
+}
+
+func newSegmentList(r *Recording, dir string) *segmentList {
+	l := &segmentList{
+		path:   filepath.Join(dir, "."+r.ID+".segments.csv"),
+		dir:    dir,
+		stream: r.Stream,
+		quit:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	// Left behind by a crash, ffmpeg truncates it but we could read it first
+	_ = os.Remove(l.path)
+	return l
+}
+
+// follow polls the list until close is called
+func (l *segmentList) follow(started time.Time) {
+	l.mu.Lock()
+	l.started = started
+	l.mu.Unlock()
+
+	defer close(l.exited)
+
+	ticker := time.NewTicker(time.Second * 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.poll()
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// close stops following and registers the segments ffmpeg listed on exit
+func (l *segmentList) close() {
+	close(l.quit)
+	<-l.exited
+	l.poll()
+	_ = os.Remove(l.path)
+}
+
+// Segments returns the segments completed so far
+func (l *segmentList) Segments() []SegmentInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]SegmentInfo(nil), l.segments...)
+}
+
+// completedSegments returns the files written by the recording so far. With
+// the segment muxer these are the listed segments, otherwise the single output
+// file, which runs until now while still being written.
+func (r *Recording) completedSegments() []SegmentInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.segments != nil {
+		return r.segments.Segments()
+	}
+
+	end := r.StartTime.Add(r.Duration)
+	if r.Active {
+		end = time.Now()
+	}
+	return []SegmentInfo{{Path: r.Config.Filename, StartTime: r.StartTime, EndTime: end}}
+}
+
+// poll reads lines appended since the last poll
+func (l *segmentList) poll() {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return // not written yet
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err != nil || info.Size() < l.offset {
+		l.offset, l.partial = 0, nil // recreated
+	}
+	if _, err = f.Seek(l.offset, io.SeekStart); err != nil {
+		return
+	}
+	data, err := io.ReadAll(f)
+	if err != nil || len(data) == 0 {
+		return
+	}
+	l.offset += int64(len(data))
+
+	// Keep an incomplete last line for the next poll
+	data = append(l.partial, data...)
+	i := bytes.LastIndexByte(data, '\n')
+	if i < 0 {
+		l.partial = data
+		return
+	}
+	l.partial = append([]byte(nil), data[i+1:]...)
+
+	reader := csv.NewReader(bytes.NewReader(data[:i+1]))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		log.Warn().Err(err).Str("path", l.path).Msg("[segments] failed to parse segment list")
+		return
+	}
+
+	for _, record := range records {
+		if len(record) >= 3 {
+			l.add(record[0], record[1], record[2])
+		}
+	}
+}
+
+// add registers a completed segment: name,start,end with times in seconds
+// from the start of the recording
+func (l *segmentList) add(name, start, end string) {
+	startSec, err := strconv.ParseFloat(start, 64)
+	if err != nil {
+		return
+	}
+	endSec, err := strconv.ParseFloat(end, 64)
+	if err != nil || endSec < startSec {
+		return
+	}
+
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.dir, filepath.Base(name))
+	}
+
+	// The strftime name has the wall clock at the segment start, the list
+	// gives the exact length
+	l.mu.Lock()
+	segmentStart, _ := extractTimeFromFilename(filepath.Base(path), l.started.Add(seconds(startSec)))
+	segment := SegmentInfo{
+		Path:      path,
+		StartTime: segmentStart,
+		EndTime:   segmentStart.Add(seconds(endSec - startSec)),
+	}
+	l.segments = append(l.segments, segment)
+	l.mu.Unlock()
+
+	recordingIndex.Update(path)
+	recordingIndex.setSpan(path, segment.StartTime, segment.EndTime)
+	onSegmentComplete(l.stream, path)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
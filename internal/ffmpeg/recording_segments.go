@@ -4,23 +4,40 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 	"sync"
 	"time"
 )
 
+// isManagedSegmentation reports whether SegmentedRecording splits the
+// stream's recordings by starting a new recorder per segment. Otherwise the
+// recorder splits its own output with ffmpeg's segment muxer, or the native
+// recorder's rotation, and only one of them may cut files.
+func isManagedSegmentation(streamConfig StreamRecordingConfig) bool {
+	return streamConfig.Segmenter == "manager"
+}
+
+// recorderSegments reports whether a recording splits its output itself
+func recorderSegments(streamConfig StreamRecordingConfig, config RecordConfig) bool {
+	return !config.Event && streamConfig.EnableSegments != nil && *streamConfig.EnableSegments &&
+		!isManagedSegmentation(streamConfig)
+}
+
 // SegmentedRecording manages a recording that splits into multiple segments
 type SegmentedRecording struct {
 	ID               string
 	Config           RecordConfig
 	Stream           string
 	StartTime        time.Time
-	Active           bool
+	State            string
+	Active           bool // true while paused, the recording continues on resume
 	
 	currentSegment   int
 	currentRecording *Recording
+	completed        []*Recording  // stopped segments, until their files are final
+	segments         []SegmentInfo // files of finished segments
 	segmentStartTime time.Time
+	stopReason       string
+	inHistory        bool
 	
 	mu sync.Mutex
 }
@@ -31,6 +48,7 @@ func NewSegmentedRecording(id, streamName string, config RecordConfig) *Segmente
 		Config:           config,
 		Stream:           streamName,
 		StartTime:        time.Now(),
+		State:            StateStarting,
 		Active:           false,
 		currentSegment:   0,
 	}
@@ -56,9 +74,11 @@ func (sr *SegmentedRecording) Start() error {
 			Str("recording_id", sr.ID).
 			Str("stream", sr.Stream).
 			Msg("[segments] failed to start first segment")
+		sr.State = StateFailed
 		return err
 	}
 
+	sr.State = StateRecording
 	sr.Active = true
 
 	// Start segment management routine
@@ -75,23 +95,105 @@ func (sr *SegmentedRecording) Stop() error {
 		return nil
 	}
 
-	// Stop current segment
-	if sr.currentRecording != nil {
-		completedFile := sr.currentRecording.Config.Filename
-		sr.currentRecording.Stop()
-		sr.currentRecording = nil
-		// Queue final segment for detection
-		if completedFile != "" {
-			go onSegmentComplete(sr.Stream, completedFile)
-		}
+	if sr.stopReason == "" {
+		sr.stopReason = ExitStopped
 	}
-
+	sr.stopCurrentSegment()
+	sr.State = StateFinalized
 	sr.Active = false
 	return nil
 }
 
+// setStopReason records why the recording is stopped, the first reason wins
+func (sr *SegmentedRecording) setStopReason(reason string) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if sr.stopReason == "" {
+		sr.stopReason = reason
+	}
+}
+
+// Pause stops the current segment, Resume starts the next one
+func (sr *SegmentedRecording) Pause() error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if sr.State != StateRecording {
+		return fmt.Errorf("%w: recording is %s", errInvalidTransition, sr.State)
+	}
+
+	sr.stopCurrentSegment()
+	sr.State = StatePaused
+
+	log.Info().Str("recording_id", sr.ID).Str("stream", sr.Stream).Msg("[segments] segmented recording paused")
+	return nil
+}
+
+func (sr *SegmentedRecording) Resume() error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if sr.State != StatePaused {
+		return fmt.Errorf("%w: recording is %s", errInvalidTransition, sr.State)
+	}
+
+	if err := sr.startNextSegment(); err != nil {
+		return err
+	}
+	sr.State = StateRecording
+
+	log.Info().Str("recording_id", sr.ID).Str("stream", sr.Stream).Msg("[segments] segmented recording resumed")
+	return nil
+}
+
+// Rotate starts the next segment right away and returns the path of the
+// finalized one
+func (sr *SegmentedRecording) Rotate() (string, error) {
+	sr.mu.Lock()
+	if sr.State != StateRecording || sr.currentRecording == nil {
+		state := sr.State
+		sr.mu.Unlock()
+		return "", fmt.Errorf("%w: recording is %s", errInvalidTransition, state)
+	}
+	previous := sr.currentRecording
+	err := sr.startNextSegment()
+	sr.mu.Unlock()
+
+	if err != nil {
+		return "", err
+	}
+	// A keyframe handover stops the previous segment a little later
+	for deadline := time.Now().Add(keyframeHandoverTimeout); previous.Active && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond * 200)
+	}
+	if err = previous.waitExited(); err != nil {
+		return "", err
+	}
+
+	var closed string
+	if segments := previous.completedSegments(); len(segments) > 0 {
+		closed = segments[len(segments)-1].Path
+	}
+	return closed, nil
+}
+
+// stopCurrentSegment stops the running segment. Must be called with sr.mu held.
+func (sr *SegmentedRecording) stopCurrentSegment() {
+	if sr.currentRecording == nil {
+		return
+	}
+	completedFile := sr.currentRecording.Config.Filename
+	sr.currentRecording.Stop()
+	sr.completed = append(sr.completed, sr.currentRecording)
+	sr.currentRecording = nil
+	// Queue final segment for detection
+	if completedFile != "" {
+		go onSegmentComplete(sr.Stream, completedFile)
+	}
+}
+
 func (sr *SegmentedRecording) startNextSegment() error {
-	cfg := GlobalRecordingConfig
+	cfg := GetRecordingConfig()
 	
 	sr.currentSegment++
 	
@@ -101,14 +203,21 @@ func (sr *SegmentedRecording) startNextSegment() error {
 		Int("segment", sr.currentSegment).
 		Msg("[segments] starting new segment")
 	
+	// With keyframe_align the previous segment keeps recording until the next
+	// one has its first keyframe, so the switch doesn't lose a GOP
+	previous := sr.currentRecording
+	handover := previous != nil && previous.Active && keyframeAligned(GetStreamRecordingConfig(sr.Stream))
+	
 	// Stop current segment if running
-	if sr.currentRecording != nil && sr.currentRecording.Active {
+	if sr.currentRecording != nil && sr.currentRecording.Active && !handover {
 		log.Debug().
 			Str("recording_id", sr.ID).
 			Int("prev_segment", sr.currentSegment-1).
 			Msg("[segments] stopping previous segment")
 		completedFile := sr.currentRecording.Config.Filename
 		sr.currentRecording.Stop()
+		sr.compactSegments()
+		sr.completed = append(sr.completed, sr.currentRecording)
 		// Queue completed segment for post-recording detection analysis
 		if completedFile != "" {
 			go onSegmentComplete(sr.Stream, completedFile)
@@ -142,6 +251,11 @@ func (sr *SegmentedRecording) startNextSegment() error {
 		return fmt.Errorf("failed to start segment %d: %w", sr.currentSegment, err)
 	}
 
+	if handover {
+		sr.completed = append(sr.completed, previous)
+		go sr.handover(previous, recording)
+	}
+	
 	sr.currentRecording = recording
 	sr.segmentStartTime = now
 	sr.currentSegment++
@@ -158,6 +272,7 @@ func (sr *SegmentedRecording) startNextSegment() error {
 				Str("recording_id", sr.ID).
 				Str("stream", sr.Stream).
 				Msg("[segments] underlying ffmpeg exited unexpectedly, marking inactive")
+			sr.State = StateFailed
 			sr.Active = false
 		}
 		sr.mu.Unlock()
@@ -172,8 +287,33 @@ func (sr *SegmentedRecording) startNextSegment() error {
 	return nil
 }
 
+// keyframeHandoverTimeout is how long the previous segment keeps recording
+// while the next one waits for a keyframe
+const keyframeHandoverTimeout = time.Second * 10
+
+// handover stops the previous segment once the next one has written its
+// first frame. ffmpeg drops the packets before the first keyframe of a
+// stream copy, so the segments overlap by up to a GOP instead of missing one.
+func (sr *SegmentedRecording) handover(previous, next *Recording) {
+	deadline := time.Now().Add(keyframeHandoverTimeout)
+	for time.Now().Before(deadline) && next.processAlive() && !next.hasFrames() {
+		time.Sleep(time.Millisecond * 200)
+	}
+	
+	log.Debug().
+		Str("recording_id", sr.ID).
+		Str("segment", previous.Config.Filename).
+		Msg("[segments] next segment started, stopping previous")
+	
+	completedFile := previous.Config.Filename
+	previous.Stop()
+	if completedFile != "" {
+		onSegmentComplete(sr.Stream, completedFile)
+	}
+}
+
 func (sr *SegmentedRecording) manageSegments() {
-	ticker := time.NewTicker(time.Second * 30) // Check every 30 seconds
+	ticker := time.NewTicker(time.Second * 5) // Check every 5 seconds
 	defer ticker.Stop()
 
 	for sr.Active {
@@ -201,25 +341,26 @@ func (sr *SegmentedRecording) manageSegments() {
 }
 
 func (sr *SegmentedRecording) shouldStartNewSegment() bool {
-	cfg := GlobalRecordingConfig
+	streamConfig := GetStreamRecordingConfig(sr.Stream)
 	
-	if sr.currentRecording == nil {
+	// With the ffmpeg segmenter the recorder already cuts the files
+	if sr.currentRecording == nil || !isManagedSegmentation(streamConfig) {
 		return false
 	}
 
 	// Check duration-based segmentation
-	if cfg.EnableSegments && cfg.SegmentDuration > 0 {
+	if streamConfig.SegmentDuration > 0 {
 		segmentDuration := time.Since(sr.segmentStartTime)
-		if segmentDuration >= cfg.SegmentDuration {
+		if segmentDuration >= streamConfig.SegmentDuration {
 			return true
 		}
 	}
 
 	// Check size-based segmentation
-	if cfg.MaxFileSize > 0 {
+	if streamConfig.MaxFileSize > 0 {
 		if stat, err := os.Stat(sr.currentRecording.Config.Filename); err == nil {
 			sizeMB := stat.Size() / 1024 / 1024
-			if sizeMB >= cfg.MaxFileSize {
+			if sizeMB >= streamConfig.MaxFileSize {
 				return true
 			}
 		}
@@ -237,6 +378,7 @@ func (sr *SegmentedRecording) GetStatus() map[string]interface{} {
 		"stream":          sr.Stream,
 		"type":            "segmented",
 		"active":          sr.Active,
+		"state":           sr.State,
 		"start_time":      sr.StartTime,
 		"current_segment": sr.currentSegment,
 		"total_duration":  time.Since(sr.StartTime),
@@ -252,72 +394,53 @@ func (sr *SegmentedRecording) GetStatus() map[string]interface{} {
 
 // GetAllSegments returns information about all segments for this recording
 func (sr *SegmentedRecording) GetAllSegments() ([]map[string]interface{}, error) {
-	// Find all segment files
-	baseDir := filepath.Dir(sr.Config.Filename)
-	baseName := filepath.Base(sr.Config.Filename)
-	ext := filepath.Ext(baseName)
-	nameWithoutExt := baseName[:len(baseName)-len(ext)]
+	sr.mu.Lock()
+	sr.compactSegments()
+	files := append([]SegmentInfo(nil), sr.segments...)
+	recordings := append([]*Recording(nil), sr.completed...)
+	if sr.currentRecording != nil {
+		recordings = append(recordings, sr.currentRecording)
+	}
+	sr.mu.Unlock()
+
+	for _, recording := range recordings {
+		files = append(files, recording.completedSegments()...)
+	}
 
 	var segments []map[string]interface{}
 
-	// Find all files that match the segment pattern
-	err := filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
+	for _, segment := range files {
+		info, err := os.Stat(segment.Path)
 		if err != nil {
-			return err
-		}
-
-		if info.IsDir() {
-			return nil
-		}
-
-		filename := info.Name()
-		if !strings.Contains(filename, nameWithoutExt) {
-			return nil
+			continue // removed by cleanup
 		}
 
 		segments = append(segments, map[string]interface{}{
-			"path":     path,
-			"size":     info.Size(),
-			"size_mb":  info.Size() / 1024 / 1024,
-			"modified": info.ModTime(),
-			"duration": sr.estimateFileDuration(path),
+			"path":       segment.Path,
+			"size":       info.Size(),
+			"size_mb":    info.Size() / 1024 / 1024,
+			"modified":   info.ModTime(),
+			"start_time": segment.StartTime,
+			"end_time":   segment.EndTime,
+			"duration":   segment.EndTime.Sub(segment.StartTime),
 		})
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
 	}
 
-	// Sort segments by modification time
-	sort.Slice(segments, func(i, j int) bool {
-		t1 := segments[i]["modified"].(time.Time)
-		t2 := segments[j]["modified"].(time.Time)
-		return t1.Before(t2)
-	})
-
 	return segments, nil
 }
 
-// estimateFileDuration attempts to estimate file duration (basic implementation)
-func (sr *SegmentedRecording) estimateFileDuration(filePath string) time.Duration {
-	// This is a simple estimation based on file creation/modification patterns
-	// In a real implementation, you might want to use ffprobe or similar tools
-	cfg := GlobalRecordingConfig
-	
-	if cfg.EnableSegments && cfg.SegmentDuration > 0 {
-		return cfg.SegmentDuration
-	}
-
-	// Fallback estimation based on file size (very rough)
-	if stat, err := os.Stat(filePath); err == nil {
-		sizeMB := stat.Size() / 1024 / 1024
-		// Assume roughly 1MB per minute for typical video (very rough estimate)
-		return time.Duration(sizeMB) * time.Minute
+// compactSegments keeps only the file list of segments whose recorder has
+// exited. Must be called with sr.mu held.
+func (sr *SegmentedRecording) compactSegments() {
+	var running []*Recording
+	for _, recording := range sr.completed {
+		if recording.processAlive() {
+			running = append(running, recording)
+		} else {
+			sr.segments = append(sr.segments, recording.completedSegments()...)
+		}
 	}
-
-	return 0
+	sr.completed = running
 }
 
 // SegmentedRecordingManager manages multiple segmented recordings
@@ -335,15 +458,38 @@ func GetSegmentedRecordingManager() *SegmentedRecordingManager {
 }
 
 func (srm *SegmentedRecordingManager) StartSegmentedRecording(id, streamName string, config RecordConfig) error {
+	err := diskMonitor.allowRecording()
+	if err == nil {
+		err = quotaMonitor.allowRecording(streamName)
+	}
+	if err != nil {
+		recordingMetrics.addFailedStart(streamName)
+		streamErr := newStreamError(id, err)
+		setStreamError(streamName, streamErr)
+		addFailedStartHistory(id, streamName, config, true, streamErr)
+		return err
+	}
+
+	// Segments and resumes reuse the slot of the recording
+	release, err := acquireRecordingSlots(streamName)
+	if err != nil {
+		return err
+	}
+
 	srm.mu.Lock()
 	defer srm.mu.Unlock()
 
 	if _, exists := srm.recordings[id]; exists {
+		release()
 		return fmt.Errorf("segmented recording with ID %s already exists", id)
 	}
 
 	recording := NewSegmentedRecording(id, streamName, config)
 	if err := recording.Start(); err != nil {
+		release()
+		streamErr := newStreamError(id, err)
+		setStreamError(streamName, streamErr)
+		addFailedStartHistory(id, streamName, config, true, streamErr)
 		return err
 	}
 
@@ -354,15 +500,19 @@ func (srm *SegmentedRecordingManager) StartSegmentedRecording(id, streamName str
 		for recording.Active {
 			time.Sleep(time.Second)
 		}
+		release()
 		srm.mu.Lock()
 		delete(srm.recordings, id)
 		srm.mu.Unlock()
+		recording.addToHistory()
 	}()
 
 	return nil
 }
 
-func (srm *SegmentedRecordingManager) StopSegmentedRecording(id string) error {
+// StopSegmentedRecording stops a segmented recording, the reason is kept in
+// its history
+func (srm *SegmentedRecordingManager) StopSegmentedRecording(id, reason string) error {
 	srm.mu.Lock()
 	defer srm.mu.Unlock()
 
@@ -371,6 +521,7 @@ func (srm *SegmentedRecordingManager) StopSegmentedRecording(id string) error {
 		return fmt.Errorf("segmented recording with ID %s not found", id)
 	}
 
+	recording.setStopReason(reason)
 	err := recording.Stop()
 	delete(srm.recordings, id)
 	return err
@@ -393,11 +544,12 @@ func (srm *SegmentedRecordingManager) ListSegmentedRecordings() map[string]*Segm
 	return result
 }
 
-func (srm *SegmentedRecordingManager) StopAll() {
+func (srm *SegmentedRecordingManager) StopAll(reason string) {
 	srm.mu.Lock()
 	defer srm.mu.Unlock()
 
 	for id, recording := range srm.recordings {
+		recording.setStopReason(reason)
 		recording.Stop()
 		delete(srm.recordings, id)
 	}
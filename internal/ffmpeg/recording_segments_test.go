@@ -0,0 +1,70 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentationStrategy(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+
+	// A segment that ran for its whole duration and reached max_file_size
+	file := filepath.Join(dir, "cam1_seg1.mp4")
+	require.Nil(t, os.WriteFile(file, make([]byte, 2<<20), 0644))
+
+	newSegmented := func() *SegmentedRecording {
+		sr := NewSegmentedRecording("cam1_1", "cam1", RecordConfig{})
+		sr.currentRecording = &Recording{Config: RecordConfig{Filename: file}}
+		sr.segmentStartTime = time.Now().Add(-time.Hour)
+		return sr
+	}
+
+	for _, test := range []struct {
+		segmenter string
+		recorder  bool // the recorder process splits its output
+		manager   bool // SegmentedRecording rotates the recorder
+	}{
+		{segmenter: "ffmpeg", recorder: true},
+		{segmenter: "manager", manager: true},
+	} {
+		t.Run(test.segmenter, func(t *testing.T) {
+			setRecordingConfig(&RecordingConfig{
+				EnableSegments:  true,
+				Segmenter:       test.segmenter,
+				SegmentDuration: time.Minute,
+			})
+			streamConfig := GetStreamRecordingConfig("cam1")
+
+			require.Equal(t, test.recorder, recorderSegments(streamConfig, RecordConfig{}))
+			require.Equal(t, test.manager, newSegmented().shouldStartNewSegment())
+
+			// Event recordings are single bounded files either way
+			require.False(t, recorderSegments(streamConfig, RecordConfig{Event: true}))
+
+			// Only the size limit is reached
+			GetRecordingConfig().SegmentDuration = 24 * time.Hour
+			GetRecordingConfig().MaxFileSize = 1
+			require.Equal(t, test.manager, newSegmented().shouldStartNewSegment())
+		})
+	}
+
+	// A stream can use the other strategy
+	setRecordingConfig(&RecordingConfig{
+		EnableSegments:  true,
+		Segmenter:       "ffmpeg",
+		SegmentDuration: time.Minute,
+		Streams: map[string]StreamRecordingConfig{
+			"cam1": {Segmenter: "manager"},
+		},
+	})
+	require.False(t, recorderSegments(GetStreamRecordingConfig("cam1"), RecordConfig{}))
+	require.True(t, newSegmented().shouldStartNewSegment())
+	require.True(t, recorderSegments(GetStreamRecordingConfig("cam2"), RecordConfig{}))
+}
@@ -0,0 +1,96 @@
+package ffmpeg
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// ShutdownRecordings stops all running recordings so ffmpeg can finalize
+// its files (write the MP4 moov atom) before go2rtc exits. Processes that
+// don't exit within ShutdownTimeout are killed.
+func ShutdownRecordings() {
+	timeout := stopTimeout()
+
+	// Keep the descriptors so the recordings resume on the next start
+	recordingState.freeze()
+
+	// Exports and merges would be cut off anyway, their ffmpeg exits with go2rtc
+	jobs.CancelAll()
+
+	// Cameras keep subscriptions until they terminate and may limit them
+	unsubscribeONVIF()
+
+	// Collect processes before stopping, the managers forget the recordings
+	recordings := trackedRecordings("")
+
+	type exiting struct {
+		recording *Recording
+		process   *os.Process
+		done      chan struct{}
+	}
+
+	var pending []exiting
+	for _, recording := range recordings {
+		recording.mu.Lock()
+		if (recording.Active || recording.State == StatePaused) && recording.done != nil {
+			var process *os.Process
+			if recording.cmd != nil {
+				process = recording.cmd.Process
+			}
+			pending = append(pending, exiting{recording, process, recording.done})
+		}
+		recording.mu.Unlock()
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	log.Info().Int("recordings", len(pending)).Dur("timeout", timeout).Msg("[recording] shutting down, finalizing recordings")
+
+	// go2rtc exits before the managers would add them to the history
+	regular := GetRecordingManager().ListRecordings()
+	segmented := GetSegmentedRecordingManager().ListSegmentedRecordings()
+
+	GetSegmentedRecordingManager().StopAll(ExitShutdown)
+	GetRecordingManager().StopAll(ExitShutdown)
+	preBufferManager.StopAll()
+
+	expired := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() { close(expired) })
+	defer timer.Stop()
+
+	var wg sync.WaitGroup
+
+	for _, p := range pending {
+		wg.Add(1)
+		go func(p exiting) {
+			defer wg.Done()
+			select {
+			case <-p.done:
+			case <-expired:
+				log.Warn().Str("recording_id", p.recording.ID).Msg("[recording] ffmpeg did not exit in time, killing")
+				if p.process != nil {
+					_ = p.process.Kill()
+				}
+				// Allow the reaper to finish up after the kill
+				select {
+				case <-p.done:
+				case <-time.After(time.Second * 2):
+				}
+			}
+		}(p)
+	}
+
+	wg.Wait()
+
+	for _, recording := range regular {
+		recording.addToHistory()
+	}
+	for _, recording := range segmented {
+		recording.addToHistory()
+	}
+
+	log.Info().Msg("[recording] all recordings finalized")
+}
@@ -0,0 +1,286 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+const (
+	snapshotDir           = "snapshots"
+	snapshotDateLayout    = "2006-01-02"
+	snapshotTimeLayout    = "2006-01-02_15-04-05"
+	snapshotCheckInterval = time.Second * 5
+	snapshotTimeout       = time.Second * 30
+)
+
+// startSnapshots starts the capture routine if any stream takes snapshots
+func startSnapshots() {
+	enabled := GetRecordingConfig().SnapshotInterval > 0
+	for _, streamConfig := range GetRecordingConfig().Streams {
+		if streamConfig.SnapshotInterval > 0 {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return
+	}
+
+	go snapshotRoutine()
+
+	log.Info().Msg("[snapshot] capturing periodic snapshots of recorded streams")
+}
+
+// snapshotRoutine captures a JPEG of every recorded stream whose
+// snapshot_interval passed, one capture per stream at a time, and removes
+// expired snapshots once an hour
+func snapshotRoutine() {
+	last := map[string]time.Time{}
+	running := map[string]bool{}
+	var mu sync.Mutex
+
+	var pruned time.Time
+
+	ticker := time.NewTicker(snapshotCheckInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, streamName := range snapshotStreams() {
+			interval := GetStreamRecordingConfig(streamName).SnapshotInterval
+			if interval <= 0 {
+				continue
+			}
+
+			mu.Lock()
+			due := !running[streamName] && now.Sub(last[streamName]) >= interval
+			if due {
+				last[streamName] = now
+				running[streamName] = true
+			}
+			mu.Unlock()
+
+			if !due {
+				continue
+			}
+
+			go func(streamName string) {
+				if err := captureSnapshot(streamName, now); err != nil {
+					log.Debug().Err(err).Str("stream", streamName).Msg("[snapshot] capture failed")
+				}
+				mu.Lock()
+				running[streamName] = false
+				mu.Unlock()
+			}(streamName)
+		}
+
+		if now.Sub(pruned) >= time.Hour {
+			pruned = now
+			go pruneSnapshots(now)
+		}
+	}
+}
+
+// snapshotStreams returns the streams that are recorded, continuously or on events
+func snapshotStreams() []string {
+	cfg := GetRecordingConfig()
+
+	if len(cfg.Streams) == 0 {
+		if cfg.AutoStart {
+			return streams.GetAllNames()
+		}
+		return nil
+	}
+
+	var names []string
+	for streamName, streamConfig := range configuredStreams(cfg) {
+		if streamConfig.Enabled != nil && !*streamConfig.Enabled {
+			continue
+		}
+		names = append(names, streamName)
+	}
+	return names
+}
+
+// snapshotBasePath returns the directory holding a stream's snapshots
+func snapshotBasePath(streamName string) string {
+	base := GetRecordingConfig().BasePath
+	if streamConfig, ok := lookupStreamConfig(GetRecordingConfig(), streamName); ok && streamConfig.BasePath != "" {
+		base = streamConfig.BasePath
+	}
+	return filepath.Join(base, streamName, snapshotDir)
+}
+
+// snapshotPath returns {base_path}/{stream}/snapshots/{date}/{stream}_{timestamp}.jpg
+func snapshotPath(streamName string, t time.Time) string {
+	t = t.In(templateLocation())
+	return filepath.Join(
+		snapshotBasePath(streamName),
+		t.Format(snapshotDateLayout),
+		streamName+"_"+t.Format(snapshotTimeLayout)+".jpg",
+	)
+}
+
+// captureSnapshot writes one frame of the stream's recording source
+func captureSnapshot(streamName string, t time.Time) error {
+	path := snapshotPath(streamName, t)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	source := ResolveDirectSource(streamName)
+
+	args := []string{"-hide_banner", "-v", "error"}
+	if strings.HasPrefix(source, "rtsp") {
+		args = append(args, "-rtsp_transport", "tcp")
+	}
+	args = append(args, "-i", source, "-frames:v", "1", "-q:v", "4", "-f", "image2", "-y", path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	if out, err := exec.CommandContext(ctx, ffmpegBin(), args...).CombinedOutput(); err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("%w: %s", err, extractFFmpegError(string(out)))
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		_ = os.Remove(path)
+		return errors.New("ffmpeg produced no frame")
+	}
+
+	return nil
+}
+
+// pruneSnapshots removes the days of snapshots older than each stream's
+// snapshot_retention_days, or its recording retention if that isn't set
+func pruneSnapshots(now time.Time) {
+	for _, pool := range storagePools() {
+		entries, err := os.ReadDir(pool)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			streamName := entry.Name()
+			dir := filepath.Join(pool, streamName, snapshotDir)
+
+			streamConfig := GetStreamRecordingConfig(streamName)
+			retention := streamConfig.RetentionDuration()
+			if streamConfig.SnapshotRetentionDays > 0 {
+				retention = time.Duration(streamConfig.SnapshotRetentionDays) * 24 * time.Hour
+			}
+
+			for _, date := range snapshotDates(dir) {
+				day, err := time.ParseInLocation(snapshotDateLayout, date, templateLocation())
+				if err != nil || now.Sub(day.AddDate(0, 0, 1)) < retention {
+					continue
+				}
+				if err = os.RemoveAll(filepath.Join(dir, date)); err != nil {
+					log.Warn().Err(err).Str("stream", streamName).Str("date", date).Msg("[snapshot] failed to remove expired snapshots")
+					continue
+				}
+				log.Debug().Str("stream", streamName).Str("date", date).Msg("[snapshot] removed expired snapshots")
+			}
+		}
+	}
+}
+
+// snapshotDates returns the date directories of a snapshot directory, oldest first
+func snapshotDates(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var dates []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dates = append(dates, entry.Name())
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// Snapshot is a captured still of a stream
+type Snapshot struct {
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+	Path   string    `json:"-"`
+}
+
+// listSnapshots returns the snapshots of a stream taken on one day, oldest first
+func listSnapshots(streamName, date string) []Snapshot {
+	dir := filepath.Join(snapshotBasePath(streamName), date)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	prefix := streamName + "_"
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || filepath.Ext(name) != ".jpg" {
+			continue
+		}
+		timestamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".jpg")
+		t, err := time.ParseInLocation(snapshotTimeLayout, timestamp, templateLocation())
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Stream: streamName, Time: t, Path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+	return snapshots
+}
+
+// findSnapshot returns the snapshot of a stream closest to t, or the latest
+// one if t is zero
+func findSnapshot(streamName string, t time.Time) (*Snapshot, bool) {
+	if t.IsZero() {
+		dates := snapshotDates(snapshotBasePath(streamName))
+		for i := len(dates) - 1; i >= 0; i-- {
+			if snapshots := listSnapshots(streamName, dates[i]); len(snapshots) > 0 {
+				return &snapshots[len(snapshots)-1], true
+			}
+		}
+		return nil, false
+	}
+
+	// The closest snapshot may be on the day before or after
+	t = t.In(templateLocation())
+
+	var best *Snapshot
+	var bestDiff time.Duration
+	for _, day := range []time.Time{t.AddDate(0, 0, -1), t, t.AddDate(0, 0, 1)} {
+		snapshots := listSnapshots(streamName, day.Format(snapshotDateLayout))
+		for i := range snapshots {
+			diff := snapshots[i].Time.Sub(t)
+			if diff < 0 {
+				diff = -diff
+			}
+			if best == nil || diff < bestDiff {
+				best, bestDiff = &snapshots[i], diff
+			}
+		}
+	}
+	return best, best != nil
+}
@@ -0,0 +1,222 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordingStall describes a recording whose output stopped growing
+type RecordingStall struct {
+	RecordingID string        `json:"recording_id"`
+	Stream      string        `json:"stream"`
+	File        string        `json:"file"`
+	Size        int64         `json:"size"`
+	StalledFor  time.Duration `json:"stalled_for"`
+}
+
+// recordingProgress is the last observed output of a recording
+type recordingProgress struct {
+	Stream     string    `json:"stream"`
+	File       string    `json:"file"`
+	Size       int64     `json:"size"`
+	GrownAt    time.Time `json:"grown_at"`
+	Restarts   int       `json:"restarts"`
+	restarting bool
+}
+
+// stallMonitor watches the output of every active recording, including manual
+// and scheduled ones the stream watchdog doesn't know about, and restarts a
+// recording once nothing was written for stall_timeout
+type stallMonitor struct {
+	progress map[string]*recordingProgress // recording ID -> progress
+	mu       sync.Mutex
+}
+
+var recordingStalls = &stallMonitor{progress: make(map[string]*recordingProgress)}
+
+// stallMonitorRoutine checks the recordings every stall_check_interval
+func stallMonitorRoutine() {
+	interval := GetRecordingConfig().StallCheckInterval
+	if interval <= 0 {
+		interval = time.Second * 15
+	}
+
+	log.Info().
+		Dur("interval", interval).
+		Dur("stall_timeout", GetRecordingConfig().StallTimeout).
+		Msg("[watchdog] monitoring recordings for stalled output")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		recordingStalls.check()
+	}
+}
+
+// stallTarget is a recording that can be restarted in place
+type stallTarget struct {
+	id        string
+	recording *Recording // writes the output
+	restart   func() (string, error)
+}
+
+func (m *stallMonitor) check() {
+	var targets []stallTarget
+	for id, recording := range GetRecordingManager().ListRecordings() {
+		targets = append(targets, stallTarget{id: id, recording: recording, restart: recording.Rotate})
+	}
+	for id, segmented := range GetSegmentedRecordingManager().ListSegmentedRecordings() {
+		segmented.mu.Lock()
+		current := segmented.currentRecording
+		segmented.mu.Unlock()
+		if current != nil {
+			targets = append(targets, stallTarget{id: id, recording: current, restart: segmented.Rotate})
+		}
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool, len(targets))
+
+	for _, target := range targets {
+		seen[target.id] = true
+
+		target.recording.mu.Lock()
+		writing := target.recording.State == StateRecording
+		target.recording.mu.Unlock()
+
+		file := target.recording.outputFile()
+		var size int64
+		if info, err := os.Stat(file); err == nil {
+			size = info.Size()
+		}
+
+		m.mu.Lock()
+		progress := m.progress[target.id]
+		if progress == nil || !writing {
+			// New or paused recordings start a fresh grace period
+			if progress == nil {
+				progress = &recordingProgress{Stream: target.recording.Stream}
+				m.progress[target.id] = progress
+			}
+			progress.File, progress.Size, progress.GrownAt = file, size, now
+			m.mu.Unlock()
+			continue
+		}
+
+		// A new file counts as progress, segments rotate to a smaller size
+		if file != progress.File || size != progress.Size {
+			progress.File, progress.Size, progress.GrownAt = file, size, now
+		}
+
+		stalledFor := now.Sub(progress.GrownAt)
+		if progress.restarting || stalledFor < GetRecordingConfig().StallTimeout {
+			m.mu.Unlock()
+			continue
+		}
+
+		progress.restarting = true
+		progress.Restarts++
+		m.mu.Unlock()
+
+		stall := RecordingStall{
+			RecordingID: target.id,
+			Stream:      target.recording.Stream,
+			File:        file,
+			Size:        size,
+			StalledFor:  stalledFor,
+		}
+
+		log.Error().
+			Str("recording_id", stall.RecordingID).
+			Str("stream", stall.Stream).
+			Str("file", stall.File).
+			Dur("stalled_for", stalledFor).
+			Msg("[watchdog] recording output stalled, restarting")
+
+		notify(NotifyRecordingStalled, stall.Stream, stall)
+
+		go m.restart(target)
+	}
+
+	// Forget recordings that ended
+	m.mu.Lock()
+	for id := range m.progress {
+		if !seen[id] {
+			delete(m.progress, id)
+		}
+	}
+	m.mu.Unlock()
+}
+
+// restart finalizes what was written so far and continues in a new file
+func (m *stallMonitor) restart(target stallTarget) {
+	_, err := target.restart()
+
+	m.mu.Lock()
+	if progress := m.progress[target.id]; progress != nil {
+		progress.restarting = false
+		progress.GrownAt = time.Now()
+	}
+	m.mu.Unlock()
+
+	if err != nil {
+		log.Error().Err(err).Str("recording_id", target.id).Msg("[watchdog] failed to restart stalled recording")
+		return
+	}
+	log.Info().Str("recording_id", target.id).Msg("[watchdog] restarted stalled recording")
+}
+
+// Status returns the last observed output of every active recording
+func (m *stallMonitor) Status() map[string]recordingProgress {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := make(map[string]recordingProgress, len(m.progress))
+	for id, progress := range m.progress {
+		status[id] = *progress
+	}
+	return status
+}
+
+// outputFile returns the file the recorder is currently writing
+func (r *Recording) outputFile() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.segments == nil {
+		// Recordings with a pre-roll write the live part next to the target
+		if _, err := os.Stat(r.Config.Filename + ".part"); err == nil {
+			return r.Config.Filename + ".part"
+		}
+		return r.Config.Filename
+	}
+
+	// The segment muxer names the files itself, take its newest one
+	entries, err := os.ReadDir(r.segments.dir)
+	if err != nil {
+		return ""
+	}
+
+	prefix, ext := r.Stream+"_", filepath.Ext(r.Config.Filename)
+
+	var newest string
+	var newestTime time.Time
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || filepath.Ext(name) != ext {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(r.StartTime) {
+			continue
+		}
+		if info.ModTime().After(newestTime) {
+			newest, newestTime = filepath.Join(r.segments.dir, name), info.ModTime()
+		}
+	}
+	return newest
+}
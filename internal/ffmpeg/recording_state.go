@@ -0,0 +1,347 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// persistedRecording is the on-disk descriptor of a running recording
+type persistedRecording struct {
+	ID        string       `json:"id"`
+	Stream    string       `json:"stream"`
+	PID       int          `json:"pid,omitempty"`
+	StartTime time.Time    `json:"start_time"`
+	Config    RecordConfig `json:"config"`
+}
+
+// RecordingStateStore persists the running recordings, so a restart of
+// go2rtc can reap orphaned ffmpeg processes, repair interrupted files and
+// resume recordings that should still be running
+type RecordingStateStore struct {
+	recordings map[string]*persistedRecording
+	frozen     bool // shutting down, keep descriptors for the next start
+	mu         sync.Mutex
+}
+
+var recordingState = &RecordingStateStore{
+	recordings: make(map[string]*persistedRecording),
+}
+
+// getStatePath returns the location of the persisted recording state
+func getStatePath() string {
+	if GetRecordingConfig().StatePath != "" {
+		return GetRecordingConfig().StatePath
+	}
+	return filepath.Join(GetRecordingConfig().BasePath, ".recordings.state")
+}
+
+// add stores a started recording. Must be called with r.mu held.
+func (s *RecordingStateStore) add(r *Recording) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.frozen {
+		return
+	}
+	s.recordings[r.ID] = &persistedRecording{
+		ID:        r.ID,
+		Stream:    r.Stream,
+		PID:       r.PID,
+		StartTime: r.StartTime,
+		Config:    r.Config,
+	}
+	s.save()
+}
+
+// remove forgets a recording that has finished
+func (s *RecordingStateStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.recordings[id]; !ok || s.frozen {
+		return
+	}
+	delete(s.recordings, id)
+	s.save()
+}
+
+// freeze stops tracking changes, so recordings stopped by a graceful
+// shutdown are resumed on the next start
+func (s *RecordingStateStore) freeze() {
+	s.mu.Lock()
+	s.frozen = true
+	s.mu.Unlock()
+}
+
+// save writes the state file, must be called with s.mu held
+func (s *RecordingStateStore) save() {
+	recordings := make([]*persistedRecording, 0, len(s.recordings))
+	for _, rec := range s.recordings {
+		recordings = append(recordings, rec)
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].ID < recordings[j].ID
+	})
+
+	data, err := json.MarshalIndent(recordings, "", "  ")
+	if err != nil {
+		return
+	}
+
+	path := getStatePath()
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("[recovery] failed to save recording state")
+	}
+}
+
+// load reads descriptors left behind by the previous run
+func (s *RecordingStateStore) load() ([]*persistedRecording, error) {
+	data, err := os.ReadFile(getStatePath())
+	if err != nil {
+		return nil, err
+	}
+
+	var recordings []*persistedRecording
+	if err = json.Unmarshal(data, &recordings); err != nil {
+		return nil, err
+	}
+	return recordings, nil
+}
+
+// recoverRecordingState cleans up after recordings that were running when
+// go2rtc last exited and resumes the ones that should still be running.
+// The state file is read synchronously so new recordings can't overwrite it.
+func recoverRecordingState() {
+	recordings, err := recordingState.load()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Msg("[recovery] failed to read recording state")
+		}
+		return
+	}
+	if len(recordings) == 0 {
+		return
+	}
+
+	// The state now only describes recordings started by this run
+	recordingState.mu.Lock()
+	recordingState.save()
+	recordingState.mu.Unlock()
+
+	log.Info().Int("recordings", len(recordings)).Msg("[recovery] recovering recordings interrupted by restart")
+
+	go func() {
+		addInterruptedHistory(recordings)
+
+		for _, rec := range recordings {
+			if rec.PID > 0 {
+				reapOrphanedProcess(rec)
+			}
+			repairInterruptedFiles(rec)
+			recordingIndex.UpdateDir(filepath.Dir(rec.Config.Filename))
+		}
+
+		// Give streams the same time to come up as auto-recording does
+		time.Sleep(time.Second * 10)
+
+		resumed := make(map[string]bool)
+		for _, rec := range recordings {
+			resumeRecording(rec, resumed)
+		}
+	}()
+}
+
+// reapOrphanedProcess stops an ffmpeg process that outlived the previous run,
+// letting it finalize the file first
+func reapOrphanedProcess(rec *persistedRecording) {
+	// Only touch the PID if it still belongs to our ffmpeg, PIDs get reused
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", rec.PID))
+	if err != nil {
+		return // gone, or no procfs to verify with
+	}
+	args := strings.ReplaceAll(string(cmdline), "\x00", " ")
+	if !strings.Contains(args, filepath.Base(ffmpegBin())) || !strings.Contains(args, filepath.Dir(rec.Config.Filename)) {
+		return
+	}
+
+	process, err := os.FindProcess(rec.PID)
+	if err != nil {
+		return
+	}
+
+	log.Warn().Int("pid", rec.PID).Str("recording_id", rec.ID).Msg("[recovery] stopping orphaned ffmpeg process")
+
+	_ = process.Signal(os.Interrupt)
+	for i := 0; i < 20; i++ {
+		if _, err = os.Stat(fmt.Sprintf("/proc/%d", rec.PID)); err != nil {
+			return
+		}
+		time.Sleep(time.Millisecond * 500)
+	}
+	_ = process.Kill()
+}
+
+// repairInterruptedFiles finalizes the files the recording was writing
+func repairInterruptedFiles(rec *persistedRecording) {
+	// A pre-roll recording leaves its live part behind
+	if part := rec.Config.Filename + ".part"; fileExists(part) {
+		if err := finalizePreRoll(nil, part, rec.Config.Filename); err != nil {
+			log.Warn().Err(err).Str("file", part).Msg("[recovery] failed to finalize interrupted pre-roll recording")
+		}
+	}
+
+	// Segment names are chosen by ffmpeg, check everything written since the start
+	dir := filepath.Dir(rec.Config.Filename)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name())
+		info, err := file.Info()
+		if err != nil || info.IsDir() || !isVideoFile(strings.ToLower(filepath.Ext(path))) {
+			continue
+		}
+		if info.ModTime().Before(rec.StartTime) || extractStreamName(path, file.Name()) != rec.Stream {
+			continue
+		}
+
+		if err = repairRecording(path); err != nil {
+			log.Warn().Err(err).Str("file", path).Msg("[recovery] interrupted recording could not be repaired")
+		}
+	}
+}
+
+// repairRecording remuxes a file that ffprobe can't read, replacing it on success
+func repairRecording(path string) error {
+	if _, err := getRecordingDetailedInfo(&RecordingFile{Path: path}); err == nil {
+		return nil // readable as is
+	}
+
+	if err := remuxReadable(path); err != nil {
+		return err
+	}
+
+	log.Info().Str("file", path).Msg("[recovery] repaired interrupted recording")
+	return nil
+}
+
+// remuxReadable copies the readable part of a file into a new container and
+// replaces the original with it
+func remuxReadable(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(path)
+	tmp := strings.TrimSuffix(path, ext) + ".repair" + ext
+	input := recordingInput(path)
+
+	args := []string{"-hide_banner", "-v", "error"}
+	args = append(args, ffmpegProtocolArgs(input)...)
+	args = append(args,
+		"-err_detect", "ignore_err",
+		"-i", input,
+		"-map", "0", "-c", "copy",
+		"-y", tmp,
+	)
+	cmd := exec.Command(ffmpegBin(), args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%w: %s", err, extractFFmpegError(string(out)))
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// An encrypted recording stays encrypted
+	if input != path {
+		if err = encryptRecordingFile(path); err != nil {
+			return err
+		}
+	}
+
+	// Recording times are derived from the file name and modification time
+	_ = os.Chtimes(path, time.Now(), stat.ModTime())
+	return nil
+}
+
+// resumeRecording restarts a manual or scheduled recording into a new file.
+// Auto recordings are restarted by auto-recording and event recordings by
+// their triggers, so they are skipped here.
+func resumeRecording(rec *persistedRecording, resumed map[string]bool) {
+	id, segmented := segmentedRecordingID(rec.ID)
+
+	if resumed[id] || strings.HasPrefix(id, "auto_") || strings.HasPrefix(id, "event_") {
+		return
+	}
+	resumed[id] = true
+
+	config := rec.Config
+	config.PreRoll = 0
+	if config.Duration > 0 {
+		if config.Duration = time.Until(rec.StartTime.Add(rec.Config.Duration)); config.Duration <= 0 {
+			return // would have finished by now
+		}
+	}
+	config.Filename = resumedFilename(config.Filename, rec.StartTime)
+
+	var err error
+	if segmented {
+		err = GetSegmentedRecordingManager().StartSegmentedRecording(id, rec.Stream, config)
+	} else {
+		err = GetRecordingManager().StartRecording(id, rec.Stream, config)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("recording_id", id).Str("stream", rec.Stream).Msg("[recovery] failed to resume recording")
+		return
+	}
+
+	log.Info().Str("recording_id", id).Str("stream", rec.Stream).Str("filename", config.Filename).Msg("[recovery] resumed recording")
+}
+
+// segmentedRecordingID returns the ID of the segmented recording a segment
+// ID "ID_segN" belongs to, other IDs are returned as is
+func segmentedRecordingID(id string) (string, bool) {
+	if i := strings.LastIndex(id, "_seg"); i > 0 {
+		if _, err := strconv.Atoi(id[i+4:]); err == nil {
+			return id[:i], true
+		}
+	}
+	return id, false
+}
+
+// resumedFilename returns a new filename for a resumed recording, replacing
+// the original start timestamp so the old file isn't overwritten
+func resumedFilename(filename string, started time.Time) string {
+	loc := templateLocation()
+	now := time.Now().In(loc).Format("2006-01-02_15-04-05")
+	if old := started.In(loc).Format("2006-01-02_15-04-05"); strings.Contains(filename, old) {
+		return uniqueRecordingPath(strings.ReplaceAll(filename, old, now))
+	}
+	ext := filepath.Ext(filename)
+	return uniqueRecordingPath(strings.TrimSuffix(filename, ext) + "_" + now + ext)
+}
+
+// fileExists reports whether a regular file exists at path
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,140 @@
+package ffmpeg
+
+import (
+	"math"
+	"time"
+)
+
+// growthWindow is the period the daily growth of a stream is averaged over,
+// the window before it gives the trend
+const growthWindow = 7 * 24 * time.Hour
+
+// StreamStorageStats is the storage used by the recordings of a stream and
+// how fast it grows
+type StreamStorageStats struct {
+	Recordings     int       `json:"recordings"`
+	SizeMB         int64     `json:"size_mb"`
+	Oldest         time.Time `json:"oldest"`
+	Newest         time.Time `json:"newest"`
+	DailyGrowthMB  float64   `json:"daily_growth_mb"`             // average of the last 7 days
+	TrendPercent   *float64  `json:"trend_percent,omitempty"`     // growth change against the 7 days before
+	MaxTotalSizeMB int64     `json:"max_total_size_mb,omitempty"` // the stream's own max_total_size
+	DaysUntilLimit *float64  `json:"days_until_limit,omitempty"`  // until cleanup enforces max_total_size
+	DaysRecorded   float64   `json:"days_recorded"`               // from the oldest to the newest recording
+	growthBytes    [2]int64  // recorded in the last window and the one before
+	size           int64
+}
+
+// streamStorageStats groups recordings by stream and estimates their growth
+// from the recording start times
+func streamStorageStats(recordings []CleanupRecordingInfo, now time.Time) map[string]*StreamStorageStats {
+	stats := map[string]*StreamStorageStats{}
+
+	for _, rec := range recordings {
+		stream := stats[rec.Stream]
+		if stream == nil {
+			stream = &StreamStorageStats{}
+			stats[rec.Stream] = stream
+		}
+
+		recorded := rec.RecordingTime
+		if recorded.IsZero() {
+			recorded = rec.ModTime
+		}
+
+		stream.Recordings++
+		stream.size += rec.Size
+		if stream.Oldest.IsZero() || recorded.Before(stream.Oldest) {
+			stream.Oldest = recorded
+		}
+		if recorded.After(stream.Newest) {
+			stream.Newest = recorded
+		}
+
+		switch age := now.Sub(recorded); {
+		case age < growthWindow:
+			stream.growthBytes[0] += rec.Size
+		case age < 2*growthWindow:
+			stream.growthBytes[1] += rec.Size
+		}
+	}
+
+	for name, stream := range stats {
+		stream.SizeMB = stream.size / 1024 / 1024
+		stream.DaysRecorded = round1(stream.Newest.Sub(stream.Oldest).Hours() / 24)
+
+		// A stream recording for two days has two days of growth, not seven
+		window := min(now.Sub(stream.Oldest), growthWindow)
+		if window < time.Hour {
+			continue // too little to tell
+		}
+		growth := float64(stream.growthBytes[0]) / 1024 / 1024 / (window.Hours() / 24)
+		stream.DailyGrowthMB = round1(growth)
+
+		// Cleanup may already have thinned out the window before
+		if now.Sub(stream.Oldest) >= 2*growthWindow && stream.growthBytes[1] > 0 {
+			trend := round1((float64(stream.growthBytes[0])/float64(stream.growthBytes[1]) - 1) * 100)
+			stream.TrendPercent = &trend
+		}
+
+		if limit := GetStreamRecordingConfig(name).MaxTotalSize; limit > 0 {
+			stream.MaxTotalSizeMB = limit
+			if growth > 0 {
+				stream.DaysUntilLimit = daysUntil(float64(limit*1024*1024-stream.size), growth*1024*1024)
+			}
+		}
+	}
+
+	return stats
+}
+
+// storageForecast estimates when the global max_total_size and the disk are
+// reached at the current growth of all streams
+func storageForecast(stats map[string]*StreamStorageStats, totalSize int64, disk DiskStatus) map[string]any {
+	var growth float64 // MB per day
+	for _, stream := range stats {
+		growth += stream.DailyGrowthMB
+	}
+
+	forecast := map[string]any{
+		"daily_growth_mb": round1(growth),
+	}
+	if growth <= 0 {
+		return forecast
+	}
+
+	if limit := GetRecordingConfig().MaxTotalSize; limit > 0 {
+		forecast["max_total_size_mb"] = limit
+		forecast["days_until_max_total_size"] = daysUntil(float64(limit*1024*1024-totalSize), growth*1024*1024)
+	}
+
+	// The cold tier only receives files already counted on the hot pools
+	free := disk.FreeBytes
+	if len(disk.Pools) > 0 {
+		free = 0
+		for _, pool := range disk.Pools {
+			if pool.Path != GetRecordingConfig().ColdPath {
+				free += pool.FreeBytes
+			}
+		}
+	}
+	if disk.TotalBytes > 0 {
+		forecast["days_until_disk_full"] = daysUntil(float64(free), growth*1024*1024)
+	}
+
+	return forecast
+}
+
+// daysUntil returns the days until remaining bytes are used up at a daily
+// growth, 0 if there are none left
+func daysUntil(remaining, daily float64) *float64 {
+	days := 0.0
+	if remaining > 0 {
+		days = round1(remaining / daily)
+	}
+	return &days
+}
+
+func round1(value float64) float64 {
+	return math.Round(value*10) / 10
+}
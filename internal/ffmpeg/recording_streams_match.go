@@ -0,0 +1,136 @@
+package ffmpeg
+
+import (
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+// streamGroupPrefix marks a streams key that applies to a stream_groups entry
+const streamGroupPrefix = "group:"
+
+// isStreamPattern reports whether a streams key applies to several streams:
+// a glob such as "camera_*" or a group such as "group:outdoor"
+func isStreamPattern(key string) bool {
+	return strings.HasPrefix(key, streamGroupPrefix) || strings.ContainsAny(key, "*?[")
+}
+
+// lookupStreamConfig returns the stream's entry of recording.streams with the
+// matching wildcard and group entries merged in. Group entries override
+// wildcards and the stream's own entry overrides both, setting by setting.
+func lookupStreamConfig(cfg *RecordingConfig, streamName string) (StreamRecordingConfig, bool) {
+	explicit, exists := cfg.Streams[streamName]
+
+	var patterns []string
+	for key := range cfg.Streams {
+		if isStreamPattern(key) && matchStreamKey(cfg, key, streamName) {
+			patterns = append(patterns, key)
+		}
+	}
+	if len(patterns) == 0 {
+		return explicit, exists
+	}
+
+	// Wildcards first, then groups; longer keys are more specific
+	sort.Slice(patterns, func(i, j int) bool {
+		gi := strings.HasPrefix(patterns[i], streamGroupPrefix)
+		gj := strings.HasPrefix(patterns[j], streamGroupPrefix)
+		if gi != gj {
+			return gj
+		}
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) < len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	var merged StreamRecordingConfig
+	for _, key := range patterns {
+		overlayStreamConfig(&merged, cfg.Streams[key])
+	}
+	if exists {
+		overlayStreamConfig(&merged, explicit)
+	}
+	return merged, true
+}
+
+// configuredStreams returns the entries of recording.streams by stream name,
+// with wildcard and group keys resolved against the known streams
+func configuredStreams(cfg *RecordingConfig) map[string]StreamRecordingConfig {
+	hasPatterns := false
+	for key := range cfg.Streams {
+		if isStreamPattern(key) {
+			hasPatterns = true
+			break
+		}
+	}
+	if !hasPatterns {
+		return cfg.Streams
+	}
+
+	names := streams.GetAllNames()
+	for key := range cfg.Streams {
+		if !isStreamPattern(key) {
+			names = append(names, key)
+		}
+	}
+	for _, members := range cfg.StreamGroups {
+		for _, member := range members {
+			if !isStreamPattern(member) {
+				names = append(names, member)
+			}
+		}
+	}
+
+	resolved := make(map[string]StreamRecordingConfig, len(names))
+	for _, name := range names {
+		if _, ok := resolved[name]; ok {
+			continue
+		}
+		if streamConfig, ok := lookupStreamConfig(cfg, name); ok {
+			resolved[name] = streamConfig
+		}
+	}
+	return resolved
+}
+
+// matchStreamKey reports whether a wildcard or group key applies to a stream
+func matchStreamKey(cfg *RecordingConfig, key, streamName string) bool {
+	group, ok := strings.CutPrefix(key, streamGroupPrefix)
+	if !ok {
+		matched, _ := path.Match(key, streamName)
+		return matched
+	}
+
+	for _, member := range cfg.StreamGroups[group] {
+		if member == streamName {
+			return true
+		}
+		if matched, _ := path.Match(member, streamName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// overlayStreamConfig copies every setting of src that is set over dst
+func overlayStreamConfig(dst *StreamRecordingConfig, src StreamRecordingConfig) {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src)
+	for i := 0; i < sv.NumField(); i++ {
+		if field := sv.Field(i); !field.IsZero() {
+			dv.Field(i).Set(field)
+		}
+	}
+
+	// Retention and thinning are replaced as a pair, like over the global ones
+	if src.RetentionDays > 0 || src.RetentionHours > 0 {
+		dst.RetentionDays, dst.RetentionHours = src.RetentionDays, src.RetentionHours
+	}
+	if src.ThinAfterDays > 0 || src.ThinHourlyDays > 0 {
+		dst.ThinAfterDays, dst.ThinHourlyDays = src.ThinAfterDays, src.ThinHourlyDays
+	}
+}
@@ -0,0 +1,147 @@
+package ffmpeg
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// tagRegexp limits tags to short labels usable in URLs and the config
+var tagRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_:.-]{0,63}$`)
+
+// normalizeTags lowercases and trims tags, dropping empty and invalid ones
+func normalizeTags(tags []string) []string {
+	var result []string
+	for _, value := range tags {
+		// ?tag=person,vehicle is the same as ?tag=person&tag=vehicle
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tagRegexp.MatchString(tag) {
+				result = append(result, tag)
+			}
+		}
+	}
+	return result
+}
+
+// hasTags reports whether tags contains all of wanted
+func hasTags(tags, wanted []string) bool {
+	for _, want := range wanted {
+		found := false
+		for _, tag := range tags {
+			if tag == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// tagRetention returns how long tag_retention keeps a recording with these
+// tags, the longest of its tags. ok is false if none of them has a
+// retention, 0 means forever.
+func tagRetention(tags []string) (retention time.Duration, ok bool) {
+	for _, tag := range tags {
+		days, found := GetRecordingConfig().TagRetention[tag]
+		if !found {
+			continue
+		}
+		if days <= 0 {
+			return 0, true
+		}
+		if d := time.Duration(days) * 24 * time.Hour; !ok || d > retention {
+			retention = d
+		}
+		ok = true
+	}
+	return retention, ok
+}
+
+// retainedForever reports whether a tag keeps the recording from being
+// deleted by cleanup
+func retainedForever(tags []string) bool {
+	retention, ok := tagRetention(tags)
+	return ok && retention == 0
+}
+
+// apiRecordingTags lists, adds and removes the tags of a recording:
+//
+//	GET    /api/recordings/tags              all tags with their recording counts
+//	GET    /api/recordings/tags?id=ID
+//	POST   /api/recordings/tags?id=ID&tag=person&tag=vehicle
+//	DELETE /api/recordings/tags?id=ID&tag=false-alarm
+func apiRecordingTags(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	id := query.Get("id")
+	if id == "" {
+		if r.Method != "GET" {
+			http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+			return
+		}
+
+		counts := recordingIndex.tagCounts()
+		tags := make([]string, 0, len(counts))
+		for tag := range counts {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+
+		api.ResponseJSON(w, map[string]any{
+			"tags":   tags,
+			"counts": counts,
+		})
+		return
+	}
+
+	recording := recordingIndex.Get(id)
+	if recording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	var tags []string
+	if r.Method == "POST" || r.Method == "DELETE" {
+		if tags = normalizeTags(query["tag"]); len(tags) == 0 {
+			http.Error(w, "Missing or invalid 'tag' parameter, use lowercase letters, digits and _ : . -", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		recording = recordingIndex.setTags(recording.ID, tags, nil)
+	case "DELETE":
+		recording = recordingIndex.setTags(recording.ID, nil, tags)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if recording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method != "GET" {
+		// Persist right away, a retained tag must survive a crash
+		recordingIndex.Save()
+		log.Info().Str("recording_id", recording.ID).Strs("tags", recording.Tags).Msg("[api] recording tags changed")
+	}
+
+	result := recording.Tags
+	if result == nil {
+		result = []string{}
+	}
+	api.ResponseJSON(w, map[string]any{
+		"recording_id": recording.ID,
+		"tags":         result,
+	})
+}
@@ -0,0 +1,41 @@
+package ffmpeg
+
+import "time"
+
+// thinRecordings returns the recordings a thinning policy removes. Everything
+// newer than thin_after_days is kept, then the first recording of every hour
+// for thin_hourly_days and the first recording of every day after that, until
+// retention deletes them. Event recordings are never thinned. Recordings must
+// be sorted oldest first.
+func thinRecordings(recordings []CleanupRecordingInfo, streamConfig StreamRecordingConfig, now time.Time) []CleanupRecordingInfo {
+	if streamConfig.ThinAfterDays <= 0 {
+		return nil
+	}
+
+	hourlyFrom := now.AddDate(0, 0, -streamConfig.ThinAfterDays)
+	dailyFrom := hourlyFrom.AddDate(0, 0, -streamConfig.ThinHourlyDays)
+
+	kept := make(map[string]bool)
+
+	var thinned []CleanupRecordingInfo
+	for _, rec := range recordings {
+		if !rec.RecordingTime.Before(hourlyFrom) || isEventRecordingFile(rec.Path) {
+			continue
+		}
+
+		var bucket string
+		if rec.RecordingTime.Before(dailyFrom) {
+			bucket = rec.RecordingTime.Format("2006-01-02")
+		} else {
+			bucket = rec.RecordingTime.Format("2006-01-02 15")
+		}
+
+		if !kept[bucket] {
+			kept[bucket] = true
+			continue
+		}
+		thinned = append(thinned, rec)
+	}
+
+	return thinned
+}
@@ -0,0 +1,237 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cleanupWindow is the daily time range cleanup may run in, it may cross midnight
+type cleanupWindow struct {
+	start, end time.Duration // since midnight
+}
+
+// parseCleanupWindow parses "02:00-05:00"
+func parseCleanupWindow(value string) (*cleanupWindow, error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return nil, errors.New("expected HH:MM-HH:MM")
+	}
+
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, errors.New("start and end are the same")
+	}
+
+	return &cleanupWindow{start: start, end: end}, nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.New("invalid time " + value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains reports whether t is inside the window, in local time
+func (w *cleanupWindow) contains(t time.Time) bool {
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.start < w.end {
+		return clock >= w.start && clock < w.end
+	}
+	return clock >= w.start || clock < w.end // crosses midnight
+}
+
+// inCleanupWindow reports whether scheduled cleanup may run now
+func inCleanupWindow() bool {
+	window, err := parseCleanupWindow(GetRecordingConfig().CleanupWindow)
+	return err != nil || window.contains(time.Now())
+}
+
+// cleanupPacer spreads file removals so cleanup doesn't compete with the
+// recorders for disk I/O
+type cleanupPacer struct {
+	next time.Time
+	mu   sync.Mutex
+}
+
+var cleanupThrottle cleanupPacer
+
+// wait blocks until the next file may be deleted, archived or moved under
+// cleanup_files_per_minute
+func (p *cleanupPacer) wait() {
+	limit := GetRecordingConfig().CleanupFilesPerMinute
+	if limit <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	delay := p.next.Sub(now)
+	p.next = p.next.Add(time.Minute / time.Duration(limit))
+	p.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+// rateLimitedReader keeps the average read rate at or below bytesPerSec
+type rateLimitedReader struct {
+	r           io.Reader
+	bytesPerSec float64
+	started     time.Time
+	read        int64
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	// Small reads keep the rate smooth
+	if chunk := int(l.bytesPerSec / 10); chunk > 0 && len(p) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+
+	expected := time.Duration(float64(l.read) / l.bytesPerSec * float64(time.Second))
+	if elapsed := time.Since(l.started); expected > elapsed {
+		time.Sleep(expected - elapsed)
+	}
+	return n, err
+}
+
+// copyFileThrottled copies a file for archive and cold tier moves, limited to
+// archive_rate_limit MB/s
+func copyFileThrottled(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = in
+	if limit := GetRecordingConfig().ArchiveRateLimit; limit > 0 {
+		r = &rateLimitedReader{r: in, bytesPerSec: limit * 1024 * 1024, started: time.Now()}
+	}
+
+	if _, err = io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// bandwidthPacer hands out send times for bytes at a rate, in order of the
+// writes it paces
+type bandwidthPacer struct {
+	next time.Time
+	mu   sync.Mutex
+}
+
+// downloadBandwidth is shared by all downloads under download_total_rate_limit
+var downloadBandwidth bandwidthPacer
+
+// reserve returns how long to wait before n bytes may be sent at bytesPerSec
+func (p *bandwidthPacer) reserve(n int, bytesPerSec float64) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	delay := p.next.Sub(now)
+	p.next = p.next.Add(time.Duration(float64(n) / bytesPerSec * float64(time.Second)))
+	return delay
+}
+
+// throttledWriter sends a download at no more than download_rate_limit and
+// its share of download_total_rate_limit
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	own         bandwidthPacer
+	perDownload float64 // bytes per second, 0 = unlimited
+	total       float64
+}
+
+// throttleDownload returns the writer for a recording download, w itself if
+// downloads aren't limited. The limits are read per download, so changes
+// apply to the next one.
+func throttleDownload(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	perDownload := GetRecordingConfig().DownloadRateLimit * 1024 * 1024
+	total := GetRecordingConfig().DownloadTotalRateLimit * 1024 * 1024
+	if perDownload <= 0 && total <= 0 {
+		return w
+	}
+	return &throttledWriter{ResponseWriter: w, ctx: r.Context(), perDownload: perDownload, total: total}
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	// Small writes keep the rate smooth and let other downloads take turns
+	chunk := len(p)
+	for _, limit := range []float64{t.perDownload, t.total} {
+		if size := int(limit / 10); limit > 0 && size > 0 && size < chunk {
+			chunk = size
+		}
+	}
+
+	var written int
+	for len(p) > 0 {
+		n := min(chunk, len(p))
+
+		var delay time.Duration
+		if t.perDownload > 0 {
+			delay = t.own.reserve(n, t.perDownload)
+		}
+		if t.total > 0 {
+			delay = max(delay, downloadBandwidth.reserve(n, t.total))
+		}
+
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-t.ctx.Done():
+				timer.Stop()
+				return written, t.ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		n, err := t.ResponseWriter.Write(p[:n])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (t *throttledWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
@@ -0,0 +1,50 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthPacer(t *testing.T) {
+	var p bandwidthPacer
+
+	// The first bytes go right away, the next ones wait for the time they take
+	require.Zero(t, p.reserve(1000, 1000))
+	delay := p.reserve(500, 1000)
+	require.InDelta(t, time.Second, delay, float64(time.Millisecond*50))
+	delay = p.reserve(500, 1000)
+	require.InDelta(t, time.Second*3/2, delay, float64(time.Millisecond*50))
+}
+
+func TestThrottleDownload(t *testing.T) {
+	cfg := GetRecordingConfig()
+	t.Cleanup(func() { setRecordingConfig(cfg) })
+	setRecordingConfig(&RecordingConfig{})
+
+	r := httptest.NewRequest("GET", "/api/recordings?download=x", nil)
+	w := httptest.NewRecorder()
+	require.Equal(t, w, throttleDownload(w, r))
+
+	// 100 KB/s, the second half of 20 KB takes about 100ms
+	GetRecordingConfig().DownloadRateLimit = 100.0 / 1024
+	data := bytes.Repeat([]byte{1}, 20*1024)
+
+	started := time.Now()
+	n, err := throttleDownload(w, r).Write(data)
+	require.Nil(t, err)
+	require.Equal(t, len(data), n)
+	require.Equal(t, data, w.Body.Bytes())
+	require.GreaterOrEqual(t, time.Since(started), time.Millisecond*80)
+
+	// A closed connection stops the download instead of waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n, err = throttleDownload(httptest.NewRecorder(), r.WithContext(ctx)).Write(data)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, n, len(data))
+}
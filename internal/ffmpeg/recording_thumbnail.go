@@ -0,0 +1,147 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// handleRecordingThumbnail serves a JPEG frame extracted from a recording,
+// generating and caching it on first request
+func handleRecordingThumbnail(w http.ResponseWriter, r *http.Request, query map[string][]string) {
+	recordingID := getQueryParam(query, "thumbnail")
+	if recordingID == "" {
+		http.Error(w, "Recording ID required", http.StatusBadRequest)
+		return
+	}
+
+	recording := recordingIndex.Get(recordingID)
+	if recording == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	offset := GetRecordingConfig().ThumbnailOffset
+	if s := getQueryParam(query, "offset"); s != "" {
+		seconds, err := strconv.ParseFloat(s, 64)
+		if err != nil || seconds < 0 {
+			http.Error(w, "Invalid 'offset' parameter", http.StatusBadRequest)
+			return
+		}
+		offset = time.Duration(seconds * float64(time.Second))
+	}
+
+	thumbPath, err := getRecordingThumbnail(recording, offset)
+	if err != nil {
+		log.Warn().Err(err).Str("recording", recordingID).Msg("[recording] failed to generate thumbnail")
+		http.Error(w, fmt.Sprintf("Failed to generate thumbnail: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	file, err := os.Open(thumbPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to open thumbnail: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get thumbnail info: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	setFileETag(w, info)
+	http.ServeContent(w, r, filepath.Base(thumbPath), info.ModTime(), file)
+}
+
+// getThumbnailDir returns the directory used to cache thumbnails
+func getThumbnailDir() string {
+	if GetRecordingConfig().ThumbnailPath != "" {
+		return GetRecordingConfig().ThumbnailPath
+	}
+	return filepath.Join(GetRecordingConfig().BasePath, ".thumbs")
+}
+
+// getRecordingThumbnail returns the path of a cached thumbnail for the
+// recording, extracting a new frame with ffmpeg if needed
+func getRecordingThumbnail(recording *RecordingFile, offset time.Duration) (string, error) {
+	dir := getThumbnailDir()
+	thumbPath := filepath.Join(dir, fmt.Sprintf("%s_%d.jpg", recording.ID, offset.Milliseconds()))
+
+	// Reuse the cached frame unless the recording changed after it was generated
+	if thumbInfo, err := os.Stat(thumbPath); err == nil {
+		if fileInfo, err := os.Stat(recording.Path); err == nil && !fileInfo.ModTime().After(thumbInfo.ModTime()) {
+			return thumbPath, nil
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	input := recordingInput(recording.Path)
+	if err := extractFrame(input, offset, thumbPath); err != nil {
+		// Short or still-growing recordings may not reach the offset, fall back to the first frame
+		if offset == 0 {
+			return "", err
+		}
+		if err = extractFrame(input, 0, thumbPath); err != nil {
+			return "", err
+		}
+	}
+
+	return thumbPath, nil
+}
+
+// extractFrame writes a single JPEG frame from the input at the given offset
+func extractFrame(input string, offset time.Duration, output string) error {
+	cmd := exec.Command(ffmpegBin(),
+		"-hide_banner", "-v", "error",
+		"-ss", formatSeconds(offset),
+		"-i", input,
+		"-frames:v", "1",
+		"-vf", "scale=320:-2",
+		"-q:v", "4",
+		"-y", output,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, extractFFmpegError(string(out)))
+	}
+	if info, err := os.Stat(output); err != nil || info.Size() == 0 {
+		return fmt.Errorf("ffmpeg produced no frame")
+	}
+	return nil
+}
+
+// ThumbnailBatch is the result of a thumbnails job
+type ThumbnailBatch struct {
+	Generated int      `json:"generated"` // cached thumbnails included
+	Failed    []string `json:"failed,omitempty"`
+}
+
+// generateThumbnails caches the thumbnails of the recordings ahead of a
+// listing, one at a time
+func generateThumbnails(ctx context.Context, recordings []RecordingFile, progress func(float64)) (*ThumbnailBatch, error) {
+	batch := &ThumbnailBatch{}
+	for i := range recordings {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := getRecordingThumbnail(&recordings[i], GetRecordingConfig().ThumbnailOffset); err != nil {
+			log.Debug().Err(err).Str("recording", recordings[i].ID).Msg("[jobs] failed to generate thumbnail")
+			batch.Failed = append(batch.Failed, recordings[i].ID)
+		} else {
+			batch.Generated++
+		}
+		progress(float64(i+1) / float64(len(recordings)))
+	}
+	return batch, nil
+}
@@ -0,0 +1,138 @@
+package ffmpeg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TierCold marks recordings that were moved to cold_path
+const TierCold = "cold"
+
+// moveToColdTier moves recordings older than hot_days from the base_path
+// pools to cold_path. Files keep their path relative to the pool, so their
+// IDs, legal holds and upload state carry over and links keep working.
+func moveToColdTier(result *CleanupResult) {
+	cfg := GetRecordingConfig()
+	if cfg.ColdPath == "" || cfg.HotDays <= 0 {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -cfg.HotDays)
+
+	var moved int
+	var movedBytes int64
+
+	for _, pool := range hotPools() {
+		recordings, err := findRecordingFiles(pool)
+		if err != nil {
+			log.Warn().Err(err).Str("path", pool).Msg("[cleanup] failed to read storage pool")
+		}
+
+		for _, rec := range recordings {
+			if !rec.RecordingTime.Before(cutoff) || isActiveRecording(rec.Size, rec.ModTime) {
+				continue
+			}
+
+			rel, err := filepath.Rel(pool, rec.Path)
+			if err != nil {
+				continue
+			}
+			target := filepath.Join(cfg.ColdPath, rel)
+
+			cleanupThrottle.wait()
+
+			if err = moveRecordingFile(rec.Path, target); err != nil {
+				log.Error().Err(err).Str("file", rec.Path).Str("target", target).Msg("[cleanup] failed to move file to cold tier")
+				continue
+			}
+
+			recordingIndex.move(rec.Path, target)
+
+			moved++
+			movedBytes += rec.Size
+			if result != nil {
+				result.FilesTiered++
+				result.TieredFiles = append(result.TieredFiles, rec.Path)
+			}
+
+			log.Debug().Str("file", rec.Path).Str("target", target).Msg("[cleanup] moved file to cold tier")
+		}
+	}
+
+	if moved > 0 {
+		log.Info().
+			Int("files", moved).
+			Int64("size_mb", movedBytes/1024/1024).
+			Str("cold_path", cfg.ColdPath).
+			Int("hot_days", cfg.HotDays).
+			Msg("[cleanup] moved recordings to cold tier")
+	}
+}
+
+// moveRecordingFile moves a recording with its detection and hold sidecars, copying
+// when the target is on another filesystem
+func moveRecordingFile(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return errors.New("target already exists")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	if err := moveFile(src, dst); err != nil {
+		return err
+	}
+
+	sidecar := strings.TrimSuffix(src, filepath.Ext(src)) + ".json"
+	if _, err := os.Stat(sidecar); err == nil {
+		target := strings.TrimSuffix(dst, filepath.Ext(dst)) + ".json"
+		if err = moveFile(sidecar, target); err != nil {
+			log.Warn().Err(err).Str("file", sidecar).Msg("[cleanup] failed to move detection sidecar")
+		}
+	}
+
+	if hasHold(src) {
+		if err := os.Rename(holdPath(src), holdPath(dst)); err != nil {
+			// The hold must not get lost with the move
+			if err = writeHold(dst, true); err != nil {
+				log.Warn().Err(err).Str("file", dst).Msg("[cleanup] failed to move legal hold")
+			}
+			_ = writeHold(src, false)
+		}
+	}
+
+	return nil
+}
+
+// moveFile renames src to dst, or copies and removes it if a rename isn't
+// possible, e.g. from a local disk to an NFS mount
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	// Not a recording extension, so a half copied file is never indexed
+	tmp := dst + ".tmp"
+	if err = copyFileThrottled(src, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	// Recording times are derived from the file name and modification time
+	_ = os.Chtimes(dst, time.Now(), info.ModTime())
+
+	return os.Remove(src)
+}
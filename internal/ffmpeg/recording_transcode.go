@@ -0,0 +1,241 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+// transcodeIdleTimeout is how long a transcoded playback stream may go
+// unwatched before it's removed and stops counting against max_transcodes
+const transcodeIdleTimeout = time.Minute
+
+// transcodeOptions are the ?transcode= parameters of download and play
+type transcodeOptions struct {
+	codec   string // "h264" re-encodes, "copy" only remuxes to fragmented MP4
+	height  int    // scale to this height keeping the aspect ratio, 0 keeps it
+	bitrate string // cap the video bitrate, e.g. "1M" or "800k"
+}
+
+var bitrateRegexp = regexp.MustCompile(`^[1-9][0-9]*[kKmM]?$`)
+
+// parseTranscodeOptions reads transcode, height and bitrate, or the export
+// profile of profile. It returns nil without any of them. height and bitrate
+// imply transcode=h264.
+func parseTranscodeOptions(query map[string][]string) (*transcodeOptions, error) {
+	codec := getQueryParam(query, "transcode")
+	height := getQueryParam(query, "height")
+	bitrate := getQueryParam(query, "bitrate")
+
+	if name := getQueryParam(query, "profile"); name != "" {
+		if codec != "" || height != "" || bitrate != "" {
+			return nil, errors.New("profile can't be combined with transcode, height or bitrate")
+		}
+		profile, err := lookupExportProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		return profile.transcodeOptions()
+	}
+
+	if codec == "" && height == "" && bitrate == "" {
+		return nil, nil
+	}
+
+	opts := &transcodeOptions{codec: strings.ToLower(codec), bitrate: bitrate}
+	switch opts.codec {
+	case "":
+		opts.codec = "h264"
+	case "h264", "copy":
+	default:
+		return nil, fmt.Errorf("unsupported transcode %q, use h264 or copy", codec)
+	}
+
+	if height != "" {
+		h, err := strconv.Atoi(height)
+		if err != nil || h < 144 || h > 2160 {
+			return nil, errors.New("height must be between 144 and 2160")
+		}
+		opts.height = h &^ 1 // libx264 needs even dimensions
+	}
+	if bitrate != "" && !bitrateRegexp.MatchString(bitrate) {
+		return nil, errors.New("bitrate must be a number with an optional k or M suffix")
+	}
+	if opts.codec == "copy" && (opts.height != 0 || bitrate != "") {
+		return nil, errors.New("height and bitrate need transcode=h264")
+	}
+
+	return opts, nil
+}
+
+// name identifies the variant, e.g. "h264_720"
+func (o *transcodeOptions) name() string {
+	name := o.codec
+	if o.height != 0 {
+		name += "_" + strconv.Itoa(o.height)
+	}
+	if o.bitrate != "" {
+		name += "_" + o.bitrate
+	}
+	return name
+}
+
+// videoArgs are the ffmpeg video and audio encoding arguments
+func (o *transcodeOptions) videoArgs() []string {
+	if o.codec == "copy" {
+		return []string{"-c", "copy"}
+	}
+
+	args := strings.Fields(defaults["h264"])
+	if o.height != 0 {
+		args = append(args, "-vf", "scale=-2:"+strconv.Itoa(o.height))
+	}
+	if o.bitrate != "" {
+		args = append(args, "-maxrate", o.bitrate, "-bufsize", o.bitrate)
+	}
+	return append(args, strings.Fields(defaults["aac"])...)
+}
+
+// source is the go2rtc ffmpeg source that plays a recording transcoded
+func (o *transcodeOptions) source(input string) (string, error) {
+	// go2rtc cuts the input at the first #
+	if strings.Contains(input, "#") {
+		return "", fmt.Errorf("can't transcode a path with #: %s", input)
+	}
+	quoted, err := execArgs(input)
+	if err != nil {
+		return "", err
+	}
+
+	source := "ffmpeg:" + quoted + "#video=h264#audio=opus"
+	if o.height != 0 {
+		source += "#width=-2#height=" + strconv.Itoa(o.height)
+	}
+	if o.bitrate != "" {
+		source += "#raw=-maxrate " + o.bitrate + " -bufsize " + o.bitrate
+	}
+	return source, nil
+}
+
+// Transcoding downloads and the transcoded playback streams count against
+// max_transcodes together
+var transcodes struct {
+	mu        sync.Mutex
+	downloads int
+	plays     map[string]time.Time // stream name, last time it was watched
+}
+
+// activeTranscodesLocked removes transcoded playback streams nobody watched
+// for a while and returns how many transcodes are running
+func activeTranscodesLocked(now time.Time) int {
+	for name, seen := range transcodes.plays {
+		stream := streams.Get(name)
+		switch {
+		case stream == nil:
+			delete(transcodes.plays, name)
+		case stream.HasConsumers():
+			transcodes.plays[name] = now
+		case now.Sub(seen) > transcodeIdleTimeout:
+			streams.Delete(name)
+			delete(transcodes.plays, name)
+			log.Debug().Str("stream_name", name).Msg("[recording] removed idle transcode stream")
+		}
+	}
+	return transcodes.downloads + len(transcodes.plays)
+}
+
+// acquireTranscodeDownload takes a transcode slot, false if all are in use
+func acquireTranscodeDownload() (release func(), ok bool) {
+	transcodes.mu.Lock()
+	defer transcodes.mu.Unlock()
+
+	if activeTranscodesLocked(time.Now()) >= GetRecordingConfig().MaxTranscodes {
+		return nil, false
+	}
+	transcodes.downloads++
+
+	return func() {
+		transcodes.mu.Lock()
+		transcodes.downloads--
+		transcodes.mu.Unlock()
+	}, true
+}
+
+// acquireTranscodePlay takes a transcode slot for a playback stream until
+// it's left idle. Playing a variant that already exists is always allowed.
+func acquireTranscodePlay(streamName string) bool {
+	transcodes.mu.Lock()
+	defer transcodes.mu.Unlock()
+
+	now := time.Now()
+	active := activeTranscodesLocked(now)
+	if _, ok := transcodes.plays[streamName]; ok {
+		return true
+	}
+	if active >= GetRecordingConfig().MaxTranscodes {
+		return false
+	}
+
+	if transcodes.plays == nil {
+		transcodes.plays = map[string]time.Time{}
+	}
+	transcodes.plays[streamName] = now
+	return true
+}
+
+// transcodeUnavailable responds to a request over the max_transcodes limit
+func transcodeUnavailable(w http.ResponseWriter) {
+	if GetRecordingConfig().MaxTranscodes <= 0 {
+		http.Error(w, "Transcoding is disabled, set 'max_transcodes'", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Retry-After", "30")
+	http.Error(w, "Too many transcodes running, try again later", http.StatusServiceUnavailable)
+}
+
+// serveTranscodedRecording streams a recording through ffmpeg as fragmented
+// MP4. The output has no known length, so it can't be seeked with ranges.
+// Only re-encoding counts against max_transcodes, a remux is cheap.
+func serveTranscodedRecording(w http.ResponseWriter, r *http.Request, recording *RecordingFile, opts *transcodeOptions, disposition string) {
+	if opts.codec != "copy" {
+		release, ok := acquireTranscodeDownload()
+		if !ok {
+			transcodeUnavailable(w)
+			return
+		}
+		defer release()
+	}
+
+	input := recordingInput(recording.Path)
+
+	args := []string{"-hide_banner", "-v", "error"}
+	args = append(args, ffmpegProtocolArgs(input)...)
+	args = append(args, "-i", input, "-map", "0:v?", "-map", "0:a?")
+	args = append(args, opts.videoArgs()...)
+	args = append(args,
+		"-movflags", "frag_keyframe+empty_moov+default_base_moof",
+		"-f", "mp4", "pipe:1",
+	)
+
+	filename := strings.TrimSuffix(recording.Filename, filepath.Ext(recording.Filename)) + "_" + opts.name() + ".mp4"
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=\"%s\"", disposition, filename))
+
+	cmd := exec.CommandContext(r.Context(), ffmpegBin(), args...)
+	cmd.Stdout = w
+
+	log.Debug().Str("recording_id", recording.ID).Str("transcode", opts.name()).Msg("[recording] transcoding download")
+
+	if err := cmd.Run(); err != nil && r.Context().Err() == nil {
+		log.Warn().Err(err).Str("recording_id", recording.ID).Msg("[api] failed to transcode recording")
+	}
+}
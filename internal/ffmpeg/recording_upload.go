@@ -0,0 +1,360 @@
+package ffmpeg
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+)
+
+// RecordingUploadConfig offloads finished segments to S3-compatible storage
+type RecordingUploadConfig struct {
+	Endpoint    string        `yaml:"endpoint"` // e.g. "https://s3.eu-west-1.amazonaws.com" or "http://minio:9000"
+	Region      string        `yaml:"region"`   // Defaults to us-east-1
+	Bucket      string        `yaml:"bucket"`   // Bucket name template, uploads are enabled when set
+	Prefix      string        `yaml:"prefix"`   // Object key prefix template (default "{stream}/{date}")
+	AccessKey   string        `yaml:"access_key"`
+	SecretKey   string        `yaml:"secret_key"`
+	PathStyle   bool          `yaml:"path_style"`   // Bucket in the path instead of the host name (MinIO)
+	MaxRetries  int           `yaml:"max_retries"`  // Attempts before an upload is marked failed (default 5)
+	DeleteLocal bool          `yaml:"delete_local"` // Remove local copies once uploaded
+	KeepLocal   time.Duration `yaml:"keep_local"`   // With delete_local, keep uploaded files this long (hot window)
+}
+
+// Upload states
+const (
+	UploadPending  = "pending"
+	UploadUploaded = "uploaded"
+	UploadFailed   = "failed"
+)
+
+// UploadStatus tracks the offload of a single recording
+type UploadStatus struct {
+	State      string    `json:"state"`
+	Bucket     string    `json:"bucket,omitempty"`
+	Key        string    `json:"key,omitempty"`
+	ETag       string    `json:"etag,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	UploadedAt time.Time `json:"uploaded_at,omitempty"`
+}
+
+type uploadJob struct {
+	path     string
+	attempts int
+	next     time.Time
+}
+
+// recordingUploader uploads segments as they complete. The queue survives
+// restarts through the upload status kept in the recording index.
+type recordingUploader struct {
+	client *s3Client
+	queue  []*uploadJob
+	queued map[string]bool
+	wake   chan struct{}
+	mu     sync.Mutex
+}
+
+var uploader *recordingUploader
+
+// startRecordingUploader starts offloading finished segments if a bucket is configured
+func startRecordingUploader() {
+	cfg := GetRecordingConfig().Upload
+	if cfg.Bucket == "" {
+		return
+	}
+
+	client, err := newS3Client(cfg)
+	if err != nil {
+		log.Error().Err(err).Str("endpoint", cfg.Endpoint).Msg("[upload] invalid s3 endpoint, uploads disabled")
+		return
+	}
+
+	uploader = &recordingUploader{
+		client: client,
+		queued: make(map[string]bool),
+		wake:   make(chan struct{}, 1),
+	}
+
+	subscribeNotifications(uploader.handle)
+	go uploader.run()
+}
+
+func (u *recordingUploader) handle(n RecordingNotification) {
+	if n.Type != NotifySegmentComplete {
+		return
+	}
+	if recording, ok := n.Data.(*RecordingFile); ok && recording != nil {
+		recordingIndex.setUpload(recording.Path, &UploadStatus{State: UploadPending})
+		u.enqueue(recording.Path, 0)
+	}
+}
+
+func (u *recordingUploader) enqueue(path string, attempts int) {
+	u.mu.Lock()
+	if !u.queued[path] {
+		u.queued[path] = true
+		u.queue = append(u.queue, &uploadJob{path: path, attempts: attempts})
+	}
+	u.mu.Unlock()
+
+	select {
+	case u.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (u *recordingUploader) run() {
+	recordingIndex.ensureLoaded()
+
+	// Resume uploads interrupted by a restart
+	for _, entry := range recordingIndex.uploadEntries() {
+		if entry.Upload.State == UploadPending {
+			u.enqueue(entry.Path, entry.Upload.Attempts)
+		}
+	}
+
+	log.Info().
+		Str("endpoint", GetRecordingConfig().Upload.Endpoint).
+		Str("bucket", GetRecordingConfig().Upload.Bucket).
+		Msg("[upload] offloading finished recordings")
+
+	var lastSweep time.Time
+
+	for {
+		for job := u.next(); job != nil; job = u.next() {
+			u.upload(job)
+		}
+
+		if time.Since(lastSweep) >= time.Minute {
+			expireUploadedRecordings()
+			lastSweep = time.Now()
+		}
+
+		timer := time.NewTimer(u.wait())
+		select {
+		case <-u.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// next removes and returns the first job that is due, or nil
+func (u *recordingUploader) next() *uploadJob {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := time.Now()
+	for i, job := range u.queue {
+		if !job.next.After(now) {
+			u.queue = append(u.queue[:i], u.queue[i+1:]...)
+			return job
+		}
+	}
+	return nil
+}
+
+// wait returns the time until the next retry is due, at most a minute
+func (u *recordingUploader) wait() time.Duration {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	wait := time.Minute
+	for _, job := range u.queue {
+		if d := time.Until(job.next); d < wait {
+			wait = d
+		}
+	}
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
+
+func (u *recordingUploader) upload(job *uploadJob) {
+	cfg := GetRecordingConfig().Upload
+
+	recording := recordingIndex.GetByPath(job.path)
+	if recording == nil {
+		u.forget(job.path) // deleted before it could be uploaded
+		return
+	}
+
+	bucket, key := uploadTarget(recording)
+	job.attempts++
+
+	start := time.Now()
+	etag, err := u.client.putFile(bucket, key, job.path)
+	if err == nil {
+		u.forget(job.path)
+		recordingIndex.setUpload(job.path, &UploadStatus{
+			State:      UploadUploaded,
+			Bucket:     bucket,
+			Key:        key,
+			ETag:       etag,
+			Attempts:   job.attempts,
+			UploadedAt: time.Now(),
+		})
+		log.Debug().
+			Str("recording_id", recording.ID).
+			Str("bucket", bucket).
+			Str("key", key).
+			Dur("took", time.Since(start)).
+			Msg("[upload] uploaded recording")
+		return
+	}
+
+	maxRetries := cfg.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 5
+	}
+
+	status := &UploadStatus{State: UploadPending, Bucket: bucket, Key: key, Attempts: job.attempts, Error: err.Error()}
+
+	if job.attempts >= maxRetries {
+		status.State = UploadFailed
+		u.forget(job.path)
+		log.Error().Err(err).Str("recording_id", recording.ID).Int("attempts", job.attempts).Msg("[upload] giving up on recording")
+	} else {
+		// Exponential backoff: 30s, 1m, 2m, ... capped at an hour
+		backoff := time.Second * 30 << (job.attempts - 1)
+		if backoff > time.Hour || backoff <= 0 {
+			backoff = time.Hour
+		}
+		job.next = time.Now().Add(backoff)
+
+		u.mu.Lock()
+		u.queue = append(u.queue, job)
+		u.mu.Unlock()
+
+		log.Warn().Err(err).Str("recording_id", recording.ID).Dur("retry_in", backoff).Msg("[upload] upload failed")
+	}
+
+	recordingIndex.setUpload(job.path, status)
+}
+
+func (u *recordingUploader) forget(path string) {
+	u.mu.Lock()
+	delete(u.queued, path)
+	u.mu.Unlock()
+}
+
+// retry queues failed uploads again, all of them if id is empty
+func (u *recordingUploader) retry(id string) int {
+	var n int
+	for _, entry := range recordingIndex.uploadEntries() {
+		if entry.Upload.State != UploadFailed || (id != "" && entry.ID != id) {
+			continue
+		}
+		recordingIndex.setUpload(entry.Path, &UploadStatus{State: UploadPending})
+		u.enqueue(entry.Path, 0)
+		n++
+	}
+	return n
+}
+
+// uploadTarget returns the bucket and object key for a recording
+func uploadTarget(recording *RecordingFile) (bucket, key string) {
+	cfg := GetRecordingConfig().Upload
+
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = "{stream}/{date}"
+	}
+
+	bucket = expandUploadTemplate(cfg.Bucket, recording)
+	key = strings.Trim(expandUploadTemplate(prefix, recording), "/") + "/" + filepath.Base(recording.Path)
+	return bucket, strings.TrimPrefix(key, "/")
+}
+
+func expandUploadTemplate(template string, recording *RecordingFile) string {
+	t := recording.StartTime
+	return strings.NewReplacer(
+		"{stream}", recording.StreamName,
+		"{year}", t.Format("2006"),
+		"{month}", t.Format("01"),
+		"{day}", t.Format("02"),
+		"{hour}", t.Format("15"),
+		"{date}", t.Format("2006-01-02"),
+	).Replace(template)
+}
+
+// expireUploadedRecordings removes local copies of uploaded recordings that
+// left the hot window
+func expireUploadedRecordings() {
+	cfg := GetRecordingConfig().Upload
+	if !cfg.DeleteLocal {
+		return
+	}
+
+	var expired []RecordingFile
+	for _, entry := range recordingIndex.uploadEntries() {
+		if entry.Upload.State != UploadUploaded || time.Since(entry.Upload.UploadedAt) < cfg.KeepLocal {
+			continue
+		}
+		if recording := entry.recordingFile(); recording != nil {
+			expired = append(expired, *recording)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	result := deleteRecordings(expired, false)
+	log.Info().
+		Int("deleted", len(result.Deleted)).
+		Int64("freed_bytes", result.FreedBytes).
+		Msg("[upload] removed local copies of uploaded recordings")
+}
+
+// apiRecordingUploads reports the upload queue and retries failed uploads:
+//
+//	GET  /api/recordings/uploads[?state=failed]
+//	POST /api/recordings/uploads?retry=ID|all
+func apiRecordingUploads(w http.ResponseWriter, r *http.Request) {
+	if uploader == nil {
+		http.Error(w, "Uploads not configured", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		state := r.URL.Query().Get("state")
+
+		counts := map[string]int{UploadPending: 0, UploadUploaded: 0, UploadFailed: 0}
+		recordings := []RecordingFile{}
+		for _, entry := range recordingIndex.uploadEntries() {
+			counts[entry.Upload.State]++
+			if state != "" && entry.Upload.State != state {
+				continue
+			}
+			if recording := entry.recordingFile(); recording != nil {
+				recordings = append(recordings, *recording)
+			}
+		}
+
+		api.ResponseJSON(w, map[string]any{
+			"bucket":     GetRecordingConfig().Upload.Bucket,
+			"counts":     counts,
+			"recordings": recordings,
+		})
+
+	case "POST":
+		id := r.URL.Query().Get("retry")
+		if id == "" {
+			http.Error(w, "Missing 'retry' parameter", http.StatusBadRequest)
+			return
+		}
+		if id == "all" {
+			id = ""
+		}
+		api.ResponseJSON(w, map[string]any{"queued": uploader.retry(id)})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,164 @@
+package ffmpeg
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxVerifyRestarts limits the restarts of a stream whose segments stay
+// unusable, a camera sending broken data isn't fixed by restarting it forever
+const maxVerifyRestarts = 3
+
+// verifiedStream is what the verification remembers of a stream
+type verifiedStream struct {
+	videoCodec string
+	audioCodec string
+	corrupt    int // unusable segments in a row
+}
+
+var segmentVerifier = struct {
+	streams map[string]*verifiedStream
+	mu      sync.Mutex
+}{streams: make(map[string]*verifiedStream)}
+
+// SegmentCorruption describes a finished segment that failed verification
+type SegmentCorruption struct {
+	Stream    string `json:"stream"`
+	File      string `json:"file"`
+	Error     string `json:"error"`
+	Restarted bool   `json:"restarted"` // the capture was restarted into a new file
+}
+
+// verifiedStreamOf returns what is known of a stream. Must be called with
+// segmentVerifier.mu held.
+func verifiedStreamOf(streamName string) *verifiedStream {
+	stream := segmentVerifier.streams[streamName]
+	if stream == nil {
+		stream = &verifiedStream{}
+		segmentVerifier.streams[streamName] = stream
+	}
+	return stream
+}
+
+// verifySegments reports whether finished segments of the stream are verified
+func verifySegments(streamName string) bool {
+	verify := GetStreamRecordingConfig(streamName).VerifySegments
+	return verify != nil && *verify
+}
+
+// verifySegment probes a finished segment right away: it must have a
+// duration, an MP4 must have its index and the codecs must be those of the
+// stream's previous segment. Nil if ffprobe isn't available.
+func verifySegment(streamName, path string) *RecordingHealth {
+	health := &RecordingHealth{Status: HealthOK, CheckedAt: time.Now()}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp4", ".m4v", ".mov":
+		var indexed bool
+		err := walkMP4Atoms(path, func(typ string) bool {
+			indexed = typ == "moov"
+			return indexed
+		})
+		if err != nil || !indexed {
+			health.Status, health.Error = HealthCorrupt, "no index (moov atom)"
+			return health
+		}
+	}
+
+	info, err := getRecordingDetailedInfo(&RecordingFile{Path: path})
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil
+	}
+
+	segmentVerifier.mu.Lock()
+	defer segmentVerifier.mu.Unlock()
+
+	stream := verifiedStreamOf(streamName)
+
+	switch {
+	case err != nil:
+		health.Status, health.Error = HealthCorrupt, "unreadable"
+	case info.Duration <= 0:
+		health.Status, health.Error = HealthCorrupt, "zero duration"
+	case stream.videoCodec != "" && info.VideoCodec != stream.videoCodec:
+		health.Status = HealthCorrupt
+		health.Error = fmt.Sprintf("video codec changed from %s to %s", stream.videoCodec, cmp.Or(info.VideoCodec, "none"))
+	case stream.audioCodec != "" && info.AudioCodec != stream.audioCodec:
+		health.Status = HealthCorrupt
+		health.Error = fmt.Sprintf("audio codec changed from %s to %s", stream.audioCodec, cmp.Or(info.AudioCodec, "none"))
+	default:
+		health.Duration = info.Duration
+	}
+
+	// A lasting codec change only flags the segment it happened in
+	if err == nil && info.VideoCodec != "" {
+		stream.videoCodec, stream.audioCodec = info.VideoCodec, info.AudioCodec
+	}
+
+	return health
+}
+
+// onCorruptSegment announces an unusable segment and restarts the capture of
+// the stream into a new file, at most maxVerifyRestarts times in a row
+func onCorruptSegment(streamName, path string, health *RecordingHealth) {
+	segmentVerifier.mu.Lock()
+	stream := verifiedStreamOf(streamName)
+	stream.corrupt++
+	restart := stream.corrupt <= maxVerifyRestarts
+	segmentVerifier.mu.Unlock()
+
+	corruption := SegmentCorruption{Stream: streamName, File: path, Error: health.Error, Restarted: restart}
+
+	log.Error().
+		Str("stream", streamName).
+		Str("file", path).
+		Str("error", health.Error).
+		Bool("restart", restart).
+		Msg("[verify] finished segment is unusable")
+
+	notify(NotifySegmentCorrupt, streamName, corruption)
+
+	if restart {
+		// Called from the recorder itself, which the restart waits for
+		go restartCapture(streamName)
+	}
+}
+
+// onVerifiedSegment resets the restarts after a usable segment
+func onVerifiedSegment(streamName string) {
+	segmentVerifier.mu.Lock()
+	if stream := segmentVerifier.streams[streamName]; stream != nil {
+		stream.corrupt = 0
+	}
+	segmentVerifier.mu.Unlock()
+}
+
+// restartCapture finalizes the running recordings of a stream and continues
+// them in new files with a new recorder
+func restartCapture(streamName string) {
+	var rotate []func() (string, error)
+	for _, recording := range GetRecordingManager().ListRecordings() {
+		if recording.Stream == streamName {
+			rotate = append(rotate, recording.Rotate)
+		}
+	}
+	for _, segmented := range GetSegmentedRecordingManager().ListSegmentedRecordings() {
+		if segmented.Stream == streamName {
+			rotate = append(rotate, segmented.Rotate)
+		}
+	}
+
+	for _, restart := range rotate {
+		if _, err := restart(); err != nil {
+			log.Warn().Err(err).Str("stream", streamName).Msg("[verify] failed to restart capture")
+			continue
+		}
+		log.Info().Str("stream", streamName).Msg("[verify] restarted capture after an unusable segment")
+	}
+}
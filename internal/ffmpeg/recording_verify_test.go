@@ -0,0 +1,53 @@
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mp4Atom(typ string, size int) []byte {
+	atom := make([]byte, size)
+	binary.BigEndian.PutUint32(atom, uint32(size))
+	copy(atom[4:], typ)
+	return atom
+}
+
+func TestVerifySegment(t *testing.T) {
+	dir := t.TempDir()
+
+	// Cut off before ffmpeg wrote the moov atom
+	path := filepath.Join(dir, "cam1.mp4")
+	require.Nil(t, os.WriteFile(path, append(mp4Atom("ftyp", 16), mp4Atom("mdat", 64)...), 0644))
+
+	health := verifySegment("cam1", path)
+	require.Equal(t, HealthCorrupt, health.Status)
+	require.Equal(t, "no index (moov atom)", health.Error)
+
+	first, err := moovFirst(path)
+	require.Nil(t, err)
+	require.False(t, first)
+
+	t.Cleanup(func() { delete(segmentVerifier.streams, "cam1") })
+
+	var restarts []bool
+	unsubscribe := subscribeNotifications(func(n RecordingNotification) {
+		if corruption, ok := n.Data.(SegmentCorruption); ok {
+			restarts = append(restarts, corruption.Restarted)
+		}
+	})
+	defer unsubscribe()
+
+	// Restarting stops after maxVerifyRestarts unusable segments in a row
+	for range maxVerifyRestarts + 1 {
+		onCorruptSegment("cam1", path, health)
+	}
+	require.Equal(t, []bool{true, true, true, false}, restarts)
+
+	onVerifiedSegment("cam1")
+	onCorruptSegment("cam1", path, health)
+	require.True(t, restarts[len(restarts)-1])
+}
@@ -0,0 +1,184 @@
+package ffmpeg
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// dirWatcher reports changes in watched directories, see newDirWatcher
+type dirWatcher interface {
+	Add(dir string) error // watch one directory, not its subdirectories
+	Events() <-chan watchEvent
+	Close() error
+}
+
+// watchEvent is a file or directory that appeared, changed or went away
+type watchEvent struct {
+	Path     string
+	Dir      bool
+	Removed  bool
+	Overflow bool // events were lost, the watched trees must be scanned again
+}
+
+// indexWatcher keeps the recording index up to date from file system events,
+// so the full reconciliation only has to run every index_rescan_interval to
+// catch what events miss, like changes made by other hosts on network shares.
+// Events are collected for a second, a file being written is stat'ed once.
+type indexWatcher struct {
+	watcher dirWatcher
+	roots   []string
+	pending map[string]bool // changed files, removed directories are true
+	rescan  bool
+	active  atomic.Bool
+}
+
+var indexWatch = &indexWatcher{}
+
+// start watches all directories below the roots. Returns false if watching
+// isn't possible, the index is then reconciled every index_interval.
+func (iw *indexWatcher) start(roots []string) bool {
+	watcher, err := newDirWatcher()
+	if err != nil {
+		log.Info().Err(err).Msg("[index] not watching recordings, reconciling every index_interval")
+		return false
+	}
+
+	iw.watcher = watcher
+	iw.roots = roots
+	iw.pending = make(map[string]bool)
+
+	for _, root := range roots {
+		if err = iw.addTree(root, false); err != nil {
+			_ = watcher.Close()
+			go func() {
+				for range watcher.Events() {
+				}
+			}()
+			log.Warn().Err(err).Msg("[index] failed to watch recordings, reconciling every index_interval")
+			return false
+		}
+	}
+
+	iw.active.Store(true)
+	go iw.run()
+
+	log.Debug().Strs("paths", roots).Msg("[index] watching recordings")
+	return true
+}
+
+// Active reports whether the index follows the file system, so the files of
+// the storage pools can be taken from it instead of walking them
+func (iw *indexWatcher) Active() bool {
+	return iw.active.Load()
+}
+
+func (iw *indexWatcher) run() {
+	// Changes wait for the first load, it reconciles with disk anyway
+	recordingIndex.ensureLoaded()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-iw.watcher.Events():
+			if !ok {
+				return
+			}
+			if iw.Active() {
+				iw.handle(event)
+			}
+		case <-ticker.C:
+			if iw.Active() {
+				iw.flush()
+			}
+		}
+	}
+}
+
+func (iw *indexWatcher) handle(event watchEvent) {
+	switch {
+	case event.Overflow:
+		iw.rescan = true
+	case event.Dir && !event.Removed:
+		// A new stream or date directory may have files before it is watched
+		if err := iw.addTree(event.Path, true); err != nil {
+			iw.stop(err)
+		}
+	case event.Dir || isVideoFile(strings.ToLower(filepath.Ext(event.Path))):
+		iw.pending[event.Path] = event.Dir
+	case strings.EqualFold(filepath.Ext(event.Path), ".json"):
+		iw.pending[event.Path] = false // detection sidecar
+	}
+}
+
+// flush applies the collected changes to the index, removed directories
+// first so files of a directory created again at the same path stay
+func (iw *indexWatcher) flush() {
+	if iw.rescan {
+		iw.rescan = false
+		clear(iw.pending)
+		iw.resync()
+		return
+	}
+
+	for path, dir := range iw.pending {
+		if dir {
+			recordingIndex.RemoveDir(path)
+		}
+	}
+	for path, dir := range iw.pending {
+		switch {
+		case dir:
+		case strings.EqualFold(filepath.Ext(path), ".json"):
+			recordingIndex.UpdateSidecar(path)
+		default:
+			recordingIndex.Update(path)
+		}
+	}
+	clear(iw.pending)
+}
+
+// resync watches directories missed since the start, like those created while
+// events were lost, and reconciles the index
+func (iw *indexWatcher) resync() {
+	for _, root := range iw.roots {
+		if err := iw.addTree(root, false); err != nil {
+			iw.stop(err)
+			break
+		}
+	}
+	recordingIndex.Reconcile()
+}
+
+// addTree watches dir and its subdirectories, queueing the files in them
+// for the index if queue is set
+func (iw *indexWatcher) addTree(dir string, queue bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // gone again or unreadable, the rescan catches it
+		}
+		if d.IsDir() {
+			err = iw.watcher.Add(path)
+			if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+				return nil
+			}
+			return err
+		}
+		if queue && isVideoFile(strings.ToLower(filepath.Ext(path))) {
+			iw.pending[path] = false
+		}
+		return nil
+	})
+}
+
+// stop gives up watching, mostly because the watch limit was reached
+func (iw *indexWatcher) stop(err error) {
+	log.Warn().Err(err).Msg("[index] stopped watching recordings, reconciling every index_interval")
+	iw.active.Store(false)
+	_ = iw.watcher.Close()
+}
@@ -0,0 +1,121 @@
+//go:build linux
+
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+
+// inotifyWatcher watches single directories with inotify. The descriptor is
+// non-blocking, so reads wait in the runtime poller and close ends them.
+type inotifyWatcher struct {
+	fd     int
+	file   *os.File
+	events chan watchEvent
+	dirs   map[int32]string // watch descriptor -> directory
+	mu     sync.Mutex
+}
+
+func newDirWatcher() (dirWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &inotifyWatcher{
+		fd:     fd,
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		events: make(chan watchEvent, 1024),
+		dirs:   make(map[int32]string),
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+
+	w.mu.Lock()
+	w.dirs[int32(wd)] = dir
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *inotifyWatcher) Events() <-chan watchEvent {
+	return w.events
+}
+
+func (w *inotifyWatcher) Close() error {
+	return w.file.Close()
+}
+
+// read decodes events until the watcher is closed. A full channel blocks it,
+// then the kernel queue overflows and the index is rescanned.
+func (w *inotifyWatcher) read() {
+	defer close(w.events)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:]))
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+			offset += syscall.SizeofInotifyEvent
+
+			name := strings.TrimRight(string(buf[offset:min(offset+nameLen, n)]), "\x00")
+			offset += nameLen
+
+			if event, ok := w.event(wd, mask, name); ok {
+				w.events <- event
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) event(wd int32, mask uint32, name string) (watchEvent, bool) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		return watchEvent{Overflow: true}, true
+	}
+
+	w.mu.Lock()
+	dir, ok := w.dirs[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.dirs, wd) // the directory is gone, the kernel dropped the watch
+	}
+	w.mu.Unlock()
+
+	if !ok {
+		return watchEvent{}, false
+	}
+
+	if name == "" {
+		// The watched directory itself, moves are reported by its parent
+		if mask&syscall.IN_DELETE_SELF == 0 {
+			return watchEvent{}, false
+		}
+		return watchEvent{Path: dir, Dir: true, Removed: true}, true
+	}
+
+	return watchEvent{
+		Path:    filepath.Join(dir, name),
+		Dir:     mask&syscall.IN_ISDIR != 0,
+		Removed: mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0,
+	}, true
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package ffmpeg
+
+import "errors"
+
+func newDirWatcher() (dirWatcher, error) {
+	return nil, errors.New("watching directories not supported on this platform")
+}
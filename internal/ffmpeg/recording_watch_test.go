@@ -0,0 +1,75 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirWatcher(t *testing.T) {
+	w, err := newDirWatcher()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer w.Close()
+
+	root := t.TempDir()
+	require.Nil(t, w.Add(root))
+
+	next := func() watchEvent {
+		select {
+		case event := <-w.Events():
+			return event
+		case <-time.After(time.Second * 5):
+			t.Fatal("no event")
+			return watchEvent{}
+		}
+	}
+
+	dir := filepath.Join(root, "cam1")
+	require.Nil(t, os.Mkdir(dir, 0755))
+	require.Equal(t, watchEvent{Path: dir, Dir: true}, next())
+	require.Nil(t, w.Add(dir))
+
+	path := filepath.Join(dir, "cam1_20240101_120000.mp4")
+	require.Nil(t, os.WriteFile(path, []byte("recording"), 0644))
+	require.Equal(t, watchEvent{Path: path}, next())
+
+	require.Nil(t, os.Remove(path))
+	for event := next(); !event.Removed; event = next() {
+		require.Equal(t, path, event.Path) // writes of the file before
+	}
+
+	require.Nil(t, os.Remove(dir))
+	event := next()
+	require.True(t, event.Dir && event.Removed)
+	require.Equal(t, dir, event.Path)
+}
+
+func TestRecordingIndexRemoveDir(t *testing.T) {
+	idx := &RecordingIndex{
+		entries: make(map[string]*indexEntry),
+		byPath:  make(map[string]string),
+		aliases: make(map[string]string),
+	}
+	idx.once.Do(func() {}) // loaded
+
+	root := t.TempDir()
+	for _, name := range []string{"cam1/a.mp4", "cam1/b.mp4", "cam10/c.mp4"} {
+		path := filepath.Join(root, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.Nil(t, os.WriteFile(path, nil, 0644))
+		idx.Update(path)
+	}
+
+	require.Len(t, idx.entriesUnder(filepath.Join(root, "cam1")), 2)
+	require.Len(t, idx.entriesUnder(root), 3)
+
+	idx.RemoveDir(filepath.Join(root, "cam1"))
+	entries := idx.entriesUnder(root)
+	require.Len(t, entries, 1)
+	require.Equal(t, filepath.Join(root, "cam10/c.mp4"), entries[0].Path)
+}
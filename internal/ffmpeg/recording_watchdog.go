@@ -3,10 +3,7 @@ package ffmpeg
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -44,16 +41,16 @@ var globalWatchdogState = &WatchdogState{
 
 // StartWatchdog starts the continuous watchdog monitoring
 func StartWatchdog() {
-	if !GlobalRecordingConfig.WatchdogEnabled {
+	if !GetRecordingConfig().WatchdogEnabled {
 		log.Info().Msg("[watchdog] watchdog disabled in config")
 		return
 	}
 
 	go watchdogRoutine()
 	log.Info().
-		Dur("interval", GlobalRecordingConfig.WatchdogInterval).
-		Int("stall_threshold", GlobalRecordingConfig.StallThreshold).
-		Int64("min_growth_rate", GlobalRecordingConfig.MinFileGrowthRate).
+		Dur("interval", GetRecordingConfig().WatchdogInterval).
+		Int("stall_threshold", GetRecordingConfig().StallThreshold).
+		Int64("min_growth_rate", GetRecordingConfig().MinFileGrowthRate).
 		Msg("[watchdog] started continuous monitoring")
 }
 
@@ -62,7 +59,7 @@ func watchdogRoutine() {
 	// Initial delay for system startup
 	time.Sleep(time.Minute)
 
-	interval := GlobalRecordingConfig.WatchdogInterval
+	interval := GetRecordingConfig().WatchdogInterval
 	if interval <= 0 {
 		interval = 30 * time.Second
 	}
@@ -159,7 +156,7 @@ func checkStreamFileGrowth(streamName string) {
 				Str("stream", streamName).
 				Msg("[watchdog] no process and no active file")
 		}
-		state.IsStuck = state.ConsecutiveStalls >= GlobalRecordingConfig.StallThreshold
+		state.IsStuck = state.ConsecutiveStalls >= GetRecordingConfig().StallThreshold
 		return
 	}
 
@@ -167,7 +164,7 @@ func checkStreamFileGrowth(streamName string) {
 	stat, err := os.Stat(currentFile)
 	if err != nil {
 		state.ConsecutiveStalls++
-		state.IsStuck = state.ConsecutiveStalls >= GlobalRecordingConfig.StallThreshold
+		state.IsStuck = state.ConsecutiveStalls >= GetRecordingConfig().StallThreshold
 		return
 	}
 
@@ -195,7 +192,7 @@ func checkStreamFileGrowth(streamName string) {
 
 		state.FileGrowthRate = float64(growth) / timeSinceLastCheck.Seconds()
 
-		minGrowthRate := GlobalRecordingConfig.MinFileGrowthRate
+		minGrowthRate := GetRecordingConfig().MinFileGrowthRate
 		if minGrowthRate <= 0 {
 			minGrowthRate = 1000 // 1KB/s default
 		}
@@ -223,7 +220,7 @@ func checkStreamFileGrowth(streamName string) {
 	state.LastFileSize = currentSize
 	state.CurrentFileSize = currentSize
 	state.LastCheckTime = time.Now()
-	state.IsStuck = state.ConsecutiveStalls >= GlobalRecordingConfig.StallThreshold
+	state.IsStuck = state.ConsecutiveStalls >= GetRecordingConfig().StallThreshold
 
 	if state.IsStuck {
 		log.Warn().
@@ -238,36 +235,48 @@ func checkStreamFileGrowth(streamName string) {
 
 // findActiveRecordingFile finds the most recent recording file for a stream
 func findActiveRecordingFile(streamName string) string {
-	cfg := GlobalRecordingConfig
-	streamDir := filepath.Join(cfg.BasePath, streamName)
-
 	var newestFile string
 	var newestTime time.Time
 
-	// Walk the stream directory
-	err := filepath.Walk(streamDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info == nil || info.IsDir() {
-			return nil
+	// Walk the stream directory of every storage pool
+	for _, basePath := range storagePools() {
+		streamDir := filepath.Join(basePath, streamName)
+
+		if indexWatch.Active() {
+			// The index follows the growing file about every second
+			for _, entry := range recordingIndex.entriesUnder(streamDir) {
+				if isRecordingFile(filepath.Ext(entry.Path)) && time.Since(entry.ModTime) < 5*time.Minute && entry.ModTime.After(newestTime) {
+					newestTime = entry.ModTime
+					newestFile = entry.Path
+				}
+			}
+			continue
 		}
 
-		if !isRecordingFile(filepath.Ext(path)) {
-			return nil
-		}
+		err := filepath.Walk(streamDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info == nil || info.IsDir() {
+				return nil
+			}
 
-		// Check if file is being written (modified within last 5 minutes)
-		modAge := time.Since(info.ModTime())
-		if modAge < 5*time.Minute {
-			if info.ModTime().After(newestTime) {
-				newestTime = info.ModTime()
-				newestFile = path
+			if !isRecordingFile(filepath.Ext(path)) {
+				return nil
 			}
-		}
 
-		return nil
-	})
+			// Check if file is being written (modified within last 5 minutes)
+			modAge := time.Since(info.ModTime())
+			if modAge < 5*time.Minute {
+				if info.ModTime().After(newestTime) {
+					newestTime = info.ModTime()
+					newestFile = path
+				}
+			}
 
-	if err != nil {
-		log.Debug().Err(err).Str("stream", streamName).Msg("[watchdog] error walking stream directory")
+			return nil
+		})
+
+		if err != nil {
+			log.Debug().Err(err).Str("stream", streamName).Msg("[watchdog] error walking stream directory")
+		}
 	}
 
 	return newestFile
@@ -275,22 +284,10 @@ func findActiveRecordingFile(streamName string) string {
 
 // getFFmpegPIDForStream returns the PID of FFmpeg process for a stream
 func getFFmpegPIDForStream(streamName string) int {
-	cmd := fmt.Sprintf("pgrep -f 'ffmpeg.*%s' | head -1", streamName)
-	result, err := exec.Command("sh", "-c", cmd).Output()
-	if err != nil {
-		return 0
-	}
-
-	pidStr := strings.TrimSpace(string(result))
-	if pidStr == "" {
-		return 0
-	}
-
-	pid, err := strconv.Atoi(pidStr)
-	if err != nil {
-		return 0
+	if pids := trackedFFmpegPIDs(streamName); len(pids) > 0 {
+		return pids[0]
 	}
-	return pid
+	return 0
 }
 
 // evaluateAndRecover evaluates stream states and triggers recovery if needed
@@ -301,7 +298,7 @@ func evaluateAndRecover() {
 		}
 
 		// Check recovery cooldown
-		cooldown := GlobalRecordingConfig.RecoveryCooldown
+		cooldown := GetRecordingConfig().RecoveryCooldown
 		if cooldown <= 0 {
 			cooldown = 2 * time.Minute
 		}
@@ -315,7 +312,7 @@ func evaluateAndRecover() {
 		}
 
 		// Check max recovery attempts
-		maxAttempts := GlobalRecordingConfig.MaxRecoveryAttempts
+		maxAttempts := GetRecordingConfig().MaxRecoveryAttempts
 		if maxAttempts <= 0 {
 			maxAttempts = 5
 		}
@@ -341,22 +338,11 @@ func performStreamRecovery(streamName string, state *StreamHealthState) {
 	log.Info().
 		Str("stream", streamName).
 		Int("attempt", state.RecoveryAttempts).
-		Int("max_attempts", GlobalRecordingConfig.MaxRecoveryAttempts).
+		Int("max_attempts", GetRecordingConfig().MaxRecoveryAttempts).
 		Int("pid", state.FFmpegPID).
 		Msg("[watchdog] attempting stream recovery")
 
-	// Step 1: Kill any stuck FFmpeg processes for this stream
-	if state.FFmpegPID > 0 {
-		log.Info().
-			Str("stream", streamName).
-			Int("pid", state.FFmpegPID).
-			Msg("[watchdog] killing stuck FFmpeg process")
-
-		// Force kill (SIGKILL) since it's stuck
-		exec.Command("kill", "-9", strconv.Itoa(state.FFmpegPID)).Run()
-	}
-
-	// Also use the broader kill function to catch any we missed
+	// Step 1: Kill any stuck FFmpeg processes for this stream (SIGKILL, since it's stuck)
 	if err := killFFmpegProcessesForStream(streamName); err != nil {
 		log.Warn().Err(err).Str("stream", streamName).Msg("[watchdog] error during process cleanup")
 	}
@@ -422,12 +408,14 @@ func GetWatchdogStatus() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"enabled":         GlobalRecordingConfig.WatchdogEnabled,
-		"interval":        GlobalRecordingConfig.WatchdogInterval.String(),
-		"stall_threshold": GlobalRecordingConfig.StallThreshold,
+		"enabled":         GetRecordingConfig().WatchdogEnabled,
+		"interval":        GetRecordingConfig().WatchdogInterval.String(),
+		"stall_threshold": GetRecordingConfig().StallThreshold,
 		"last_full_check": globalWatchdogState.LastFullCheck,
 		"system_healthy":  globalWatchdogState.SystemHealthy,
 		"stream_states":   streamStatuses,
+		"stall_timeout":   GetRecordingConfig().StallTimeout.String(),
+		"recordings":      recordingStalls.Status(),
 	}
 }
 
@@ -461,8 +449,8 @@ func performAggressiveRecovery() {
 	killAllFFmpegRecordingProcesses()
 
 	// Step 2: Clear all internal recording state
-	GetRecordingManager().StopAll()
-	GetSegmentedRecordingManager().StopAll()
+	GetRecordingManager().StopAll(ExitRecovery)
+	GetSegmentedRecordingManager().StopAll(ExitRecovery)
 
 	// Step 3: Reset watchdog state
 	globalWatchdogState.mu.Lock()
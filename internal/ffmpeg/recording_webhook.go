@@ -0,0 +1,54 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// alertTypes are the notifications that need someone's attention
+var alertTypes = map[string]bool{
+	NotifyRecordingStalled: true,
+	NotifyDiskWarning:      true,
+	NotifyResourceLimit:    true,
+	NotifySegmentCorrupt:   true,
+}
+
+// startAlertWebhook posts alerts as JSON to alert_webhook
+func startAlertWebhook() {
+	url := GetRecordingConfig().AlertWebhook
+	if url == "" {
+		return
+	}
+
+	client := &http.Client{Timeout: time.Second * 10}
+
+	subscribeNotifications(func(n RecordingNotification) {
+		if !alertTypes[n.Type] {
+			return
+		}
+		// Handlers must not block the recorder
+		go postAlert(client, url, n)
+	})
+
+	log.Info().Str("url", url).Msg("[watchdog] sending alerts to webhook")
+}
+
+func postAlert(client *http.Client, url string, n RecordingNotification) {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return
+	}
+
+	res, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Warn().Err(err).Str("url", url).Str("type", n.Type).Msg("[watchdog] failed to send alert")
+		return
+	}
+	res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		log.Warn().Int("status", res.StatusCode).Str("url", url).Str("type", n.Type).Msg("[watchdog] alert webhook rejected alert")
+	}
+}
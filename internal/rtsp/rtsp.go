@@ -84,12 +84,22 @@ func HandleFunc(handler Handler) {
 	handlers = append(handlers, handler)
 }
 
+// StreamHandler creates a stream on demand for a path that isn't a configured
+// stream, e.g. a replay of recordings. remoteAddr is the client's host:port.
+type StreamHandler func(name string, query url.Values, remoteAddr string) (*streams.Stream, error)
+
+// HandleStreamFunc handles DESCRIBE requests to paths starting with prefix
+func HandleStreamFunc(prefix string, handler StreamHandler) {
+	streamHandlers[prefix] = handler
+}
+
 var Port string
 
 // internal
 
 var log zerolog.Logger
 var handlers []Handler
+var streamHandlers = map[string]StreamHandler{}
 var defaultMedias []*core.Media
 
 func rtspHandler(rawURL string) (core.Producer, error) {
@@ -171,7 +181,9 @@ func tcpHandler(conn *rtsp.Conn) {
 
 			stream := streams.Get(name)
 			if stream == nil {
-				return
+				if stream = handleStream(name, conn.URL.Query(), conn.Connection.RemoteAddr); stream == nil {
+					return
+				}
 			}
 
 			log.Debug().Str("stream", name).Msg("[rtsp] new consumer")
@@ -285,6 +297,23 @@ func tcpHandler(conn *rtsp.Conn) {
 	_ = conn.Close()
 }
 
+// handleStream creates the stream of a path with a stream handler, nil if no
+// handler matches or it fails
+func handleStream(name string, query url.Values, remoteAddr string) *streams.Stream {
+	for prefix, handler := range streamHandlers {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stream, err := handler(name, query, remoteAddr)
+		if err != nil {
+			log.Warn().Err(err).Str("stream", name).Msg("[rtsp] can't create stream")
+			return nil
+		}
+		return stream
+	}
+	return nil
+}
+
 func ParseQuery(query map[string][]string) []*core.Media {
 	if v := query["mp4"]; v != nil {
 		return []*core.Media{
@@ -76,6 +76,13 @@ func (s *Stream) RemoveConsumer(cons core.Consumer) {
 	s.stopProducers()
 }
 
+// HasConsumers reports whether anything is watching the stream
+func (s *Stream) HasConsumers() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.consumers) > 0
+}
+
 func (s *Stream) AddProducer(prod core.Producer) {
 	producer := &Producer{conn: prod, state: stateExternal, url: "external"}
 	s.mu.Lock()
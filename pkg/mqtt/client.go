@@ -64,6 +64,27 @@ func (c *Client) Publish(topic string, payload []byte) (err error) {
 	return
 }
 
+// PublishRetain sends QoS 0 message that the broker keeps for new subscribers
+func (c *Client) PublishRetain(topic string, payload []byte) (err error) {
+	if err = c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
+		return
+	}
+
+	msg := NewPublishRetain(topic, payload)
+	_, err = c.conn.Write(msg.b)
+	return
+}
+
+// Ping sends keepalive request, PINGRESP will be skipped by Read
+func (c *Client) Ping() (err error) {
+	if err = c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
+		return
+	}
+
+	_, err = c.conn.Write([]byte{PINGREQ, 0})
+	return
+}
+
 func (c *Client) Read() (string, []byte, error) {
 	if err := c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
 		return "", nil, err
@@ -16,7 +16,9 @@ const (
 	PUBACK    = 0x40
 	SUBSCRIBE = 0x82
 	SUBACK    = 0x90
+	PINGREQ   = 0xC0
 	QOS1      = 0x02
+	RETAIN    = 0x01
 )
 
 func (m *Message) WriteByte(b byte) {
@@ -93,6 +95,16 @@ func NewPublish(topic string, payload []byte) *Message {
 	return m
 }
 
+func NewPublishRetain(topic string, payload []byte) *Message {
+	m := &Message{}
+	m.WriteByte(PUBLISH | RETAIN)
+	m.WriteLen(2 + len(topic) + len(payload))
+
+	m.WriteString(topic)
+	m.WriteBytes(payload)
+	return m
+}
+
 func NewPublishQOS1(mid uint16, topic string, payload []byte) *Message {
 	m := &Message{}
 	m.WriteByte(PUBLISH | QOS1)
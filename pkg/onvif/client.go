@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -20,6 +21,7 @@ type Client struct {
 	deviceURL string
 	mediaURL  string
 	imaginURL string
+	eventURL  string
 }
 
 func NewClient(rawURL string) (*Client, error) {
@@ -44,6 +46,7 @@ func NewClient(rawURL string) (*Client, error) {
 
 	client.mediaURL = FindTagValue(b, "Media.+?XAddr")
 	client.imaginURL = FindTagValue(b, "Imaging.+?XAddr")
+	client.eventURL = FindTagValue(b, "Events.+?XAddr")
 
 	return client, nil
 }
@@ -158,6 +161,46 @@ func (c *Client) GetServiceCapabilities() ([]byte, error) {
 	)
 }
 
+// CreatePullPointSubscription - return subscription address for PullMessages
+func (c *Client) CreatePullPointSubscription() (string, error) {
+	b, err := c.Request(
+		c.eventURL, `<tev:CreatePullPointSubscription xmlns:tev="http://www.onvif.org/ver10/events/wsdl"><tev:InitialTerminationTime>PT60S</tev:InitialTerminationTime></tev:CreatePullPointSubscription>`,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	addr := FindTagValue(b, "SubscriptionReference.+?Address")
+	if addr == "" {
+		return "", errors.New("onvif: can't find subscription address")
+	}
+	return addr, nil
+}
+
+// PullMessages - wait for events up to timeout. Not every camera extends the
+// subscription on a pull, call Renew before it terminates
+func (c *Client) PullMessages(address string, timeout time.Duration) ([]byte, error) {
+	return c.Request(
+		address, `<tev:PullMessages xmlns:tev="http://www.onvif.org/ver10/events/wsdl"><tev:Timeout>PT`+
+			strconv.Itoa(int(timeout.Seconds()))+`S</tev:Timeout><tev:MessageLimit>10</tev:MessageLimit></tev:PullMessages>`,
+	)
+}
+
+// Renew - extend the subscription to terminate after duration from now
+func (c *Client) Renew(address string, duration time.Duration) error {
+	_, err := c.Request(
+		address, `<wsnt:Renew xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2"><wsnt:TerminationTime>PT`+
+			strconv.Itoa(int(duration.Seconds()))+`S</wsnt:TerminationTime></wsnt:Renew>`,
+	)
+	return err
+}
+
+// Unsubscribe - end the subscription before it terminates
+func (c *Client) Unsubscribe(address string) error {
+	_, err := c.Request(address, `<wsnt:Unsubscribe xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2"/>`)
+	return err
+}
+
 func (c *Client) DeviceRequest(operation string) ([]byte, error) {
 	switch operation {
 	case DeviceGetServices:
@@ -71,8 +71,19 @@ func ReplaceEnvVars(text string) string {
 	})
 }
 
+var shutdownHooks []func()
+
+// OnShutdown registers a function that RunUntilSignal calls before returning
+func OnShutdown(hook func()) {
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
 func RunUntilSignal() {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	println("exit with signal:", (<-sigs).String())
+
+	for _, hook := range shutdownHooks {
+		hook()
+	}
 }